@@ -3,239 +3,1410 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"flag"
-	"fmt"
 	"github.com/joho/godotenv"
-	"math"
-	"math/rand"
-	"net/http"
+	"log/slog"
+	"net"
 	"os"
+	"os/signal"
 	"strconv"
-	"sync"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/fatih/color"
+	"github.com/mayckol/rest-client/pkg/loadtest"
 )
 
 // main is the entry point for the application. It parses command-line flags and optional .env configuration,
 // and then starts the load test with the specified parameters.
 func main() {
+	// PLAIN is checked before anything is printed, so that it applies to every command.
+	if getEnvAsBool("PLAIN", false) {
+		plainOutput()
+	}
+	defer func() { restoreOutput() }()
+	// "restclient report results.bin [flags]" renders the reports of a run saved with --out.
+	if len(os.Args) > 1 && os.Args[1] == "report" {
+		exit(runReportCommand(os.Args[2:]))
+	}
+	// "restclient compare a.json b.json" diffs the summaries of two runs, of this tool or
+	// of another one.
+	if len(os.Args) > 1 && os.Args[1] == "compare" {
+		exit(runCompareCommand(os.Args[2:]))
+	}
+	// "restclient verify report.json --key public.pem" checks the signature of a report.
+	if len(os.Args) > 1 && os.Args[1] == "verify" {
+		exit(runVerifyCommand(os.Args[2:]))
+	}
+	// "restclient record --out session.har" records the requests going through a local
+	// proxy for --har.
+	if len(os.Args) > 1 && os.Args[1] == "record" {
+		exit(runRecordCommand(os.Args[2:]))
+	}
+	// "restclient monitor [flags]" runs the scenario as a synthetic check once per
+	// --interval instead of running a load test.
+	monitor := len(os.Args) > 1 && os.Args[1] == "monitor"
+	if monitor {
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+	}
+	// "restclient verify-run report.json [flags]" checks a report against the given flags
+	// instead of running a load test.
+	verifyReport := ""
+	if len(os.Args) > 1 && os.Args[1] == "verify-run" {
+		if len(os.Args) < 3 {
+			color.Red("❌ Usage: restclient verify-run report.json [flags of the run]")
+			exit(2)
+		}
+		verifyReport = os.Args[2]
+		os.Args = append(os.Args[:1], os.Args[3:]...)
+	}
+
 	envPath := flag.String("envpath", "", "📂 Path to the .env file")
-	url := flag.String("url", "", "🌐 URL of the service to be tested")
+	targetsPath := flag.String("targets", "", "🎯 File of targets in Vegeta format: METHOD URL lines with optional headers and @body references")
+	postmanPath := flag.String("postman", "", "📮 Postman collection (format v2.1) whose requests are sent as weighted targets")
+	postmanEnvPath := flag.String("postman-env", "", "📮 Postman environment file resolving the variables of the --postman collection")
+	fromCurl := flag.String("from-curl", "", "🌀 curl command, or file of curl commands, whose requests are sent as targets")
+	harPath := flag.String("har", "", "🗂️ HAR file exported from a browser, whose requests are replayed in order with their methods, headers and bodies")
+	harTiming := flag.Bool("har-timing", false, "⏱️ Replay the --har requests once, at their original pace")
+	var urls stringList
+	flag.Var(&urls, "url", "🌐 URL of the service to be tested, repeatable as [weight:][METHOD ]URL to mix weighted targets")
 	requests := flag.Int("requests", 100, "📊 Total number of requests")
+	duration := flag.Duration("duration", 0, "⏱️ Run for this long instead of a fixed number of requests (e.g. 30s or 5m)")
+	startAt := flag.String("start-at", "", "⏰ Wait until this time to start the run: a local time of day such as 02:00, or an RFC 3339 time")
+	window := flag.Duration("window", 0, "⏰ Abort the run unless it is over within this long after --start-at (e.g. 2h for a 02:00-04:00 maintenance window)")
+	warmup := flag.Duration("warmup", 0, "🔥 Send traffic for this long before the measured run and leave it out of the report")
+	abortOnErrorRate := flag.String("abort-on-error-rate", "", "🚨 Abort the run when the error rate over the last --abort-window requests exceeds this percentage (e.g. 10%)")
+	abortWindow := flag.Int("abort-window", 100, "🚨 Number of most recent requests the --abort-on-error-rate rate is computed over")
+	maxDuration := flag.Duration("max-duration", 0, "⛔ Hard ceiling on the whole run: abort it, cancelling in-flight requests, once it has lasted this long (0 for no limit)")
+	drainTimeout := flag.Duration("drain-timeout", 10*time.Second, "⏳ Time in-flight requests get to complete once the run ends, before they are cancelled")
 	concurrency := flag.Int("concurrency", 10, "🚀 Number of simultaneous calls")
-	verb := flag.String("verb", "GET", "🔀 HTTP method to use (GET or POST)")
+	rate := flag.Float64("rate", 0, "🚦 Cap the requests of all workers together at this many per second, spread evenly (0 for no cap)")
+	loadSteps := flag.String("steps", "", "🪜 Load stages of the run, as concurrencies such as 10c:1m,50c:1m,100c:1m or arrival rates such as 100rps:1m,200rps:1m")
+	spike := flag.String("spike", "", "⚡ Spike profile of an open-model run, e.g. base=50rps,peak=500rps,at=2m,for=30s, on at most --concurrency virtual users")
+	arrivalRate := flag.Float64("arrival-rate", 0, "🚦 Start this many requests per second whether or not the previous ones completed, on at most --concurrency virtual users (0 for closed-loop workers)")
+	maxQueue := flag.Int("max-queue", 0, "🚦 Number of arrivals of an open-model run that wait for a busy virtual user before the next ones are shed (0 to shed them right away)")
+	var thresholdExprs stringList
+	flag.Var(&thresholdExprs, "threshold", "🎯 Fail the run, with a non-zero exit code, unless this holds after it, e.g. p99<500ms or error_rate<1% (repeatable)")
+	var curveOutputs stringList
+	flag.Var(&curveOutputs, "curve-out", "📈 Write the latency vs throughput curve of --concurrency-sweep to this CSV file, or an HTML chart with a .html name (repeatable)")
+	monitorInterval := flag.Duration("interval", 30*time.Second, "🛰️ Time between the checks of restclient monitor")
+	metricsListen := flag.String("metrics-listen", "", "📡 Serve the uptime and latency metrics of restclient monitor on this address, e.g. :9464")
+	alertWebhook := flag.String("alert-webhook", "", "🚨 URL restclient monitor posts a JSON alert to when its checks start failing and when they recover")
+	alertAfter := flag.Int("alert-after", 1, "🚨 Consecutive failed checks before restclient monitor raises an alert")
+	concurrencySweep := flag.String("concurrency-sweep", "", "📈 Run once per comma-separated concurrency level (e.g. 1,10,50,100) and compare RPS and percentiles in one table")
+	thinkTimeFlag := flag.Duration("think-time", 0, "💭 Pause of every virtual user between two requests, modeling real users instead of a tight loop")
+	thinkJitter := flag.Duration("think-jitter", 0, "💭 Random deviation of up to this much added to or removed from every --think-time pause")
+	expectStatus := flag.String("expect-status", "", "🧪 Comma-separated status codes every response must have (e.g. 200,201); others count as failed assertions and fail the run")
+	var bodyContains, bodyJSONPaths stringList
+	flag.Var(&bodyContains, "assert-body-contains", "🧪 Check that sampled response bodies contain this text (repeatable)")
+	flag.Var(&bodyJSONPaths, "assert-jsonpath", "🧪 Check sampled JSON response bodies against a JSONPath expression, e.g. '$.status == \"ok\"' (repeatable)")
+	assertSample := flag.String("assert-sample", "100%", "🧪 Percentage of responses the body assertions and --response-schema are checked against")
+	responseSchemaPath := flag.String("response-schema", "", "📐 JSON Schema that sampled 2xx response bodies are validated against")
+	scriptPath := flag.String("script", "", "📜 Lua script defining before_request and after_response hooks to mutate requests and fail responses")
+	clientMixFlag := flag.String("client-mix", "", "📱 Spread workers over device/network profiles by percentage, e.g. mobile-3g:30,desktop:70 (mobile-3g, mobile-lte, desktop-fiber)")
+	verb := flag.String("verb", "GET", "🔀 HTTP method to use (GET, POST, PUT, PATCH, DELETE, ...)")
 	jsonPath := flag.String("jsonpath", "", "📄 Path to JSON file to use as body for POST requests")
-	randIDType := flag.String("rand-id-type", "string", "🔢 Type of random ID to generate (number or string)")
+	randIDType := flag.String("rand-id-type", "string", "🔢 Type of random ID to generate (number, string, uuid, uuidv7, ulid or seq)")
+	seqStart := flag.Int64("seq-start", 1, "🔢 First value of the shared sequence used by the seq ID type")
 	randIDChrs := flag.Int("rand-id-chrs", 10, "🔤 Number of characters or digits for the random ID")
+	disableKeepAlive := flag.Bool("disable-keepalive", false, "🔌 Disable HTTP keep-alive (open a new connection for every request)")
+	maxIdleConns := flag.Int("max-idle-conns", 100, "💤 Maximum number of idle connections kept in the pool")
+	maxConnsPerHost := flag.Int("max-conns-per-host", 0, "🔗 Maximum number of connections per host (0 means no limit)")
+	timeout := flag.Duration("timeout", 30*time.Second, "⏱️ Overall timeout for each request")
+	retries := flag.Int("retries", 0, "🔂 Times a request is sent again after a network error, an unexpected status or, without --expect-status, a 5xx")
+	hedgeAfter := flag.String("hedge-after", "", "🪞 Send a duplicate of requests still unanswered after this delay (e.g. 50ms) or latency percentile (e.g. p95) and use the first response")
+	deadlineHeader := flag.String("deadline-header", "", "⏳ Send the time the client waits for each request in this header (e.g. X-Request-Timeout or grpc-timeout)")
+	deadlineFormat := flag.String("deadline-format", "", "⏳ Format of the --deadline-header value: ms, s or grpc (default: grpc for grpc-timeout, else ms)")
+	giveUpAfter := flag.Duration("client-gives-up-after", 0, "🏃 Cancel requests still running after this long, like a user leaving, and count them as abandoned instead of failed")
+	connectTimeout := flag.Duration("connect-timeout", 30*time.Second, "🔌 Timeout for establishing a TCP connection")
+	tlsHandshakeTimeout := flag.Duration("tls-handshake-timeout", 10*time.Second, "🔐 Timeout for the TLS handshake")
+	responseHeaderTimeout := flag.Duration("response-header-timeout", 0, "📨 Timeout for receiving response headers after the request is sent (0 means no limit)")
+	disableHappyEyeballs := flag.Bool("disable-happy-eyeballs", false, "👀 Disable happy-eyeballs (RFC 6555) racing between IPv6 and IPv4 when dialing")
+	ipv4Only := flag.Bool("4", false, "🌐 Connect over IPv4 only, resolving names to their IPv4 addresses")
+	ipv6Only := flag.Bool("6", false, "🌐 Connect over IPv6 only, resolving names to their IPv6 addresses")
+	dnsDelay := flag.Duration("dns-delay", 0, "🐌 Delay added to every DNS query to simulate a slow resolver")
+	doh := flag.String("doh", "", "🔐 Resolve target names over DNS-over-HTTPS with this endpoint (e.g. https://1.1.1.1/dns-query)")
+	dnsCache := flag.String("dns-cache", "", "📌 Resolve target names once and pin them for the run (pin), or again once their answer is older than this (e.g. 30s)")
+	var resolveEntries stringList
+	flag.Var(&resolveEntries, "resolve", "📌 Connect to this address for a host and port instead of resolving it, as host:port:addr like curl, keeping the Host header and TLS SNI (repeatable)")
+	hostHeader := flag.String("host-header", "", "🏷️ Send this Host header with every request, e.g. to hit a load balancer IP with the virtual host it routes on")
+	sni := flag.String("sni", "", "🏷️ TLS server name to present and verify the certificate against (default: the --host-header name, else the URL host)")
+	insecure := flag.Bool("insecure", false, "🔓 Skip TLS certificate verification for every host")
+	var insecureHosts stringList
+	flag.Var(&insecureHosts, "insecure-host", "🔓 Skip TLS certificate verification for this host only, as name, name:port or *.domain (repeatable)")
+	cookies := flag.String("cookies", "vu", "🍪 Cookie jar of the run: one per virtual user, one shared by all, or none (vu, shared or off)")
+	feedCapture := flag.String("feed-capture", "", "🧺 Dotted path of a JSON response field to capture into the feed pool (e.g. id or data.id)")
+	feedSize := flag.Int("feed-size", 1000, "🧺 Maximum number of recent values kept in the feed pool")
+	readAfterWrite := flag.String("read-after-write", "", "🔁 After every write, GET this URL with {{feed}} set to the --feed-capture value of its response and report entities not found yet")
+	readAfterWriteDelay := flag.Duration("read-after-write-delay", 0, "🔁 Pause between a write and its --read-after-write read-back")
+	rwRatio := flag.String("rw-ratio", "", "⚖️ Read:write ratio between the read and write endpoint sets (e.g. 90:10)")
+	maxRedirects := flag.Int("max-redirects", 10, "↪️ Maximum number of redirects to follow before the 3xx response is counted as is")
+	noFollowRedirects := flag.Bool("no-follow-redirects", false, "⛔ Do not follow redirects, count 3xx responses as terminal status codes")
+	slowThreshold := flag.Duration("slow-threshold", 0, "🐢 Tag requests slower than this duration and list the slowest ones in the report (0 disables)")
+	slowTop := flag.Int("slow-top", 10, "🐢 Number of slowest requests listed in the report")
+	latencyPrecision := flag.Int("latency-precision", 3, "📏 Significant digits latencies are recorded with, from 1 to 5")
+	maxLatency := flag.Duration("max-latency", time.Hour, "📏 Highest latency tracked; longer ones are recorded as this value")
+	maxBody := flag.String("max-body", "", "🧱 Stop reading response bodies beyond this size, e.g. 1MB or 512KiB (default: unlimited)")
+	acceptEncoding := flag.String("accept-encoding", "", "🗜️ Accept-Encoding header to send (e.g. gzip, br or gzip, br)")
+	gzipRequestBody := flag.Bool("gzip-body", false, "🗜️ Gzip-compress request bodies and send them with Content-Encoding: gzip")
+	probeRequests := flag.Int("probe-requests", 0, "🩺 Number of probe requests sent before and after the load to check recovery (0 disables)")
+	probeInterval := flag.Duration("probe-interval", 200*time.Millisecond, "🩺 Pause between probe requests")
+	probeTolerance := flag.Float64("probe-tolerance", 1.5, "🩺 Maximum ratio between post-load and baseline median latency to consider the target recovered")
+	probeURL := flag.String("probe-url", "", "🩺 URL probed before and after the load (defaults to the tested URL)")
+	primePath := flag.String("prime", "", "🔥 File of URLs, one per line, each requested once in order before the run to warm caches")
+	primeInterval := flag.Duration("prime-interval", 100*time.Millisecond, "🔥 Pause between two priming requests")
+	setupPath := flag.String("setup", "", "🧰 Targets file of steps sent once, in order, before the load, e.g. to log in or seed data")
+	teardownPath := flag.String("teardown", "", "🧹 Targets file of steps sent once, in order, after the load, e.g. to clean up")
+	cooldown := flag.Duration("cooldown", 0, "🧊 Time to wait after the load while the health monitor keeps sampling")
+	healthURL := flag.String("health-url", "", "💓 URL sampled on a side channel during the load and the cooldown")
+	healthInterval := flag.Duration("health-interval", 5*time.Second, "💓 Interval between health samples")
+	dataPath := flag.String("data", "", "🗃️ CSV or JSONL file whose rows are exposed to URL, header and body templates as {{.column}}")
+	dataMode := flag.String("data-mode", "sequential", "🗃️ How rows are consumed: sequential, random or once")
+	dataPer := flag.String("data-per", "request", "🗃️ Pull a new row for every request or once per virtual user (request or vu)")
+	rerandomize := flag.Bool("rerandomize", false, "🎲 Generate new random field values for every request instead of once per worker")
+	var failoverURLs stringList
+	flag.Var(&failoverURLs, "failover-url", "🛟 Fallback base URL tried in order when a request matches the failover policy (repeatable)")
+	failoverOn := flag.String("failover-on", "network,5xx", "🛟 Comma-separated failover conditions: network, 5xx or status codes such as 429")
+	var randFields stringList
+	flag.Var(&randFields, "rand-field", "🎲 Randomize a JSON field as path=type:length, e.g. user.id=string:12 or items[*].qty=number:3 (repeatable)")
+	connLogPath := flag.String("conn-log", "", "🔌 Write connection lifecycle events (open, reuse, close, error) to this NDJSON file")
+	saveBaselinePath := flag.String("save-baseline", "", "📊 Save the run to this file as a baseline for --compare")
+	comparePath := flag.String("compare", "", "📊 Compare the run against a baseline saved with --save-baseline and fail on latency or throughput regressions")
+	latencyTolerance := flag.String("latency-tolerance", "10%", "📊 Latency increase over the --compare baseline tolerated before it counts as a regression")
+	throughputTolerance := flag.String("throughput-tolerance", "10%", "📊 Throughput decrease below the --compare baseline tolerated before it counts as a regression")
+	region := flag.String("region", "", "🌍 Region label of this load generator, saved with --out so restclient report breaks merged runs down per region")
+	hostsPath := flag.String("hosts", "", "🛰️ File of SSH destinations, one per line, to run the load test on all of them at once and merge their results")
+	remoteBin := flag.String("remote-bin", "restclient", "🛰️ Command starting restclient on the --hosts")
+	resultsPath := flag.String("out", "", "💾 Save the raw results of every request to this file, to render the reports again later with \"restclient report\"")
+	reportHTMLPath := flag.String("report-html", "", "🖥️ Write an HTML report of the run to this file")
+	reportJSONPath := flag.String("report-json", "", "📑 Write a JSON report with a manifest of the run inputs (settings, files and tool version) to this file")
+	signKeyPath := flag.String("sign-key", "", "🔏 Sign the --report-json report with this Ed25519 private key (PEM), to check it with \"restclient verify\"")
+	captureResponses := flag.String("capture-responses", "", "📸 Save the request/response pairs of a sample of the traffic, as a number of pairs such as 50 or a percentage such as 5%")
+	captureDir := flag.String("capture-dir", "captures", "📸 Directory the --capture-responses pairs are saved to, one JSON file each")
+	noColor := flag.Bool("no-color", false, "🎨 Print the output without colors; also set by the NO_COLOR environment variable")
+	plainMode := flag.Bool("plain", false, "🎨 Print plain ASCII output, without colors and emojis, for CI and cron logs")
+	logFormat := flag.String("log-format", "text", "🪵 Format of the diagnostics (errors, warnings and progress of the run): text on the console, or json lines on stderr")
+	logLevel := flag.String("log-level", "info", "🪵 Lowest level of the diagnostics printed: debug, info, warn or error")
+	verbose := flag.Bool("v", false, "🔍 Print the request and response headers of the first --verbose-requests requests")
+	veryVerbose := flag.Bool("vv", false, "🔍 Like -v, with the request and response bodies")
+	verboseRequests := flag.Int("verbose-requests", 5, "🔍 Number of requests printed by -v and -vv")
+	reportInterval := flag.Duration("report-interval", 0, "🕒 Print an interim report with rolling stats, cumulative errors and memory usage every interval of a long run, e.g. 10m (0 for none)")
+	interimOutPath := flag.String("interim-out", "", "🕒 Also write every --report-interval interim report to this NDJSON file")
+	streamPath := flag.String("stream", "", "📡 Write a JSON line of live stats (RPS, error rate, rolling percentiles) every second to this file, or - for the standard output with the reports on the standard error")
+	statsdAddr := flag.String("statsd", "", "📊 Send request counters and latency timings to the StatsD server at this host:port, e.g. a Datadog agent")
+	statsdPrefix := flag.String("statsd-prefix", "restclient", "📊 Prefix of the StatsD metric names")
+	dogStatsD := flag.Bool("dogstatsd", false, "📊 Tag the StatsD metrics with the status, method and target, DogStatsD style")
+	var statsdTags stringList
+	flag.Var(&statsdTags, "statsd-tag", "📊 Tag added to every DogStatsD metric, as key:value (repeatable)")
+	errorLogPath := flag.String("error-log", "", "🧯 Write every failed request (network error or unexpected status) with its truncated response body to this NDJSON file instead of the console")
+	decisionLogPath := flag.String("decision-log", "", "🎲 Write the target selection draws of every request (read/write class, endpoint, weight branch) to this NDJSON file")
+	rawLogPath := flag.String("raw-log", "", "🧾 Write every request/response pair (headers and truncated bodies) to this NDJSON file")
+	var ageRecipients, gpgRecipients, redactHeaders, redactFields stringList
+	flag.Var(&ageRecipients, "encrypt-age", "🔒 Encrypt output files for this age recipient (repeatable)")
+	flag.Var(&gpgRecipients, "encrypt-gpg", "🔒 Encrypt output files for this GPG recipient (repeatable)")
+	flag.Var(&redactHeaders, "redact-header", "🙈 Header whose value is redacted in output files (repeatable)")
+	flag.Var(&redactFields, "redact-field", "🙈 JSON body field redacted in output files, as a path like card.number or items[*].token (repeatable)")
+	noDefaultRedaction := flag.Bool("no-default-redaction", false, "🙈 Do not redact the Authorization, Cookie and other credential headers by default")
+	inlineBody := flag.String("body", "", "📦 Inline request body template sent with any method, e.g. '{\"x\":1}'")
+	bodyFile := flag.String("body-file", "", "📦 Path to a request body template sent with any method")
+	seed := flag.Int64("seed", 0, "🌱 Seed for reproducible random data; every worker, the feed pool and the data file get an isolated stream (default: random)")
+	rawBody := flag.Bool("raw-body", false, "🧱 Send the body as is, without template rendering or random fields")
+	contentType := flag.String("content-type", "", "🏷️ Content-Type of request bodies (default: detected from the body)")
+	var formFields stringList
+	flag.Var(&formFields, "form", "📮 Send an application/x-www-form-urlencoded body field as key=value, the value may be a template (repeatable)")
+	var files stringList
+	flag.Var(&files, "file", "📎 Upload a file in a multipart/form-data body as field=@path, streamed from disk (repeatable)")
+	var multipartFields stringList
+	flag.Var(&multipartFields, "form-field", "📎 Add a text field to the multipart/form-data body as key=value, the value may be a template (repeatable)")
+	rotateAccept := flag.String("rotate-accept", "", "🔄 Rotate the Accept header across requests over this comma-separated list, or \"default\" for json, xml, html and an unsupported type")
+	rotateLocale := flag.String("rotate-locale", "", "🌍 Rotate the Accept-Language header across requests over this comma-separated list, e.g. en-US,fr-FR,ja-JP")
+	openAPIPath := flag.String("openapi", "", "📐 OpenAPI 3 spec (JSON) that sample requests are validated against before the run")
+	openAPISamples := flag.Int("openapi-samples", 5, "📐 Number of sample requests rendered per target for --openapi validation")
+	openAPIOperations := flag.String("openapi-operations", "", "📐 Generate targets from the --openapi spec for these operationIds, comma separated, or all")
+	openAPIServer := flag.String("openapi-server", "", "📐 Base URL of the --openapi-operations targets, instead of the first server of the spec")
+	validateOnly := flag.Bool("validate-only", false, "📐 Stop after the --openapi validation without sending any traffic")
+	var headers stringList
+	flag.Var(&headers, "header", "🏷️ Request header as \"Name: value\", the value may be a template (repeatable)")
+	var readURLs, writeURLs stringList
+	flag.Var(&readURLs, "read-url", "📖 URL of a read endpoint, sent with GET (repeatable)")
+	flag.Var(&writeURLs, "write-url", "✍️ URL of a write endpoint, sent with --verb or POST (repeatable)")
 
 	flag.Parse()
+	if *noColor {
+		color.NoColor = true
+	}
+	if *plainMode {
+		plainOutput()
+	}
+	if *streamPath == "-" || os.Getenv("STREAM") == "-" {
+		reportsToStderr()
+	}
 
 	// Load .env file if specified
 	if *envPath != "" {
 		err := godotenv.Load(*envPath)
 		if err != nil {
 			color.Red("❌ Error loading .env file from %s: %v", *envPath, err)
-			return
+			exit(2)
 		}
 		color.Cyan("📝 Loaded .env file from %s", *envPath)
 	} else {
 		color.Cyan("📝 No .env file path provided, skipping .env loading.")
 	}
+	// The .env file may turn colors off as well; like the color package, any value of
+	// NO_COLOR does.
+	if os.Getenv("NO_COLOR") != "" {
+		color.NoColor = true
+	}
+	if getEnvAsBool("PLAIN", *plainMode) {
+		plainOutput()
+	}
+	finalLogFormat := getEnv("LOG_FORMAT", *logFormat)
+	finalLogLevel, levelErr := loadtest.ParseLogLevel(getEnv("LOG_LEVEL", *logLevel))
+	if levelErr != nil {
+		color.Red("❌ Invalid --log-level value: %v", levelErr)
+		exit(2)
+	}
+	var logger *slog.Logger
+	switch finalLogFormat {
+	case "text":
+		logger = loadtest.NewConsoleLogger(finalLogLevel)
+	case "json":
+		// JSON records go to stderr, so the reports on stdout stay readable.
+		logger = slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: finalLogLevel}))
+	default:
+		color.Red("❌ Invalid --log-format value %q, expected text or json.", finalLogFormat)
+		exit(2)
+	}
 
 	// Use environment variables if they exist, else fall back to flags
-	finalURL := getEnv("URL", *url)
+	finalURLs := getEnvAsLines("URL", urls)
+	finalTargetsPath := getEnv("TARGETS", *targetsPath)
+	finalPostmanPath := getEnv("POSTMAN", *postmanPath)
+	finalPostmanEnvPath := getEnv("POSTMAN_ENV", *postmanEnvPath)
+	finalFromCurl := getEnv("FROM_CURL", *fromCurl)
+	finalHARPath := getEnv("HAR", *harPath)
+	finalHARTiming := getEnvAsBool("HAR_TIMING", *harTiming)
 	finalRequests := getEnvAsInt("REQUESTS", *requests)
+	finalDuration := getEnvAsDuration("DURATION", *duration)
+	finalStartAt := getEnv("START_AT", *startAt)
+	finalWindow := getEnvAsDuration("WINDOW", *window)
+	finalWarmup := getEnvAsDuration("WARMUP", *warmup)
+	finalDrainTimeout := getEnvAsDuration("DRAIN_TIMEOUT", *drainTimeout)
+	finalMaxDuration := getEnvAsDuration("MAX_DURATION", *maxDuration)
+	finalAbortOnErrorRate := getEnv("ABORT_ON_ERROR_RATE", *abortOnErrorRate)
+	finalAbortWindow := getEnvAsInt("ABORT_WINDOW", *abortWindow)
 	finalConcurrency := getEnvAsInt("CONCURRENCY", *concurrency)
+	finalRate := getEnvAsFloat("RATE", *rate)
+	finalArrivalRate := getEnvAsFloat("ARRIVAL_RATE", *arrivalRate)
+	finalMaxQueue := getEnvAsInt("MAX_QUEUE", *maxQueue)
+	finalSpike := getEnv("SPIKE", *spike)
+	finalSteps := getEnv("STEPS", *loadSteps)
+	finalReportInterval := getEnvAsDuration("REPORT_INTERVAL", *reportInterval)
+	finalInterimOutPath := getEnv("INTERIM_OUT", *interimOutPath)
+	finalStreamPath := getEnv("STREAM", *streamPath)
+	if finalStreamPath == "-" {
+		// The .env file may set it as well.
+		reportsToStderr()
+	}
+	finalStatsDAddr := getEnv("STATSD", *statsdAddr)
+	finalStatsDPrefix := getEnv("STATSD_PREFIX", *statsdPrefix)
+	finalDogStatsD := getEnvAsBool("DOGSTATSD", *dogStatsD)
+	finalStatsDTags := getEnvAsList("STATSD_TAGS", statsdTags)
+	finalConcurrencySweep := getEnv("CONCURRENCY_SWEEP", *concurrencySweep)
+	finalCurveOutputs := getEnvAsList("CURVE_OUT", curveOutputs)
+	finalThresholds := getEnvAsList("THRESHOLDS", thresholdExprs)
+	finalMonitorInterval := getEnvAsDuration("MONITOR_INTERVAL", *monitorInterval)
+	finalMetricsListen := getEnv("METRICS_LISTEN", *metricsListen)
+	finalAlertWebhook := getEnv("ALERT_WEBHOOK", *alertWebhook)
+	finalAlertAfter := getEnvAsInt("ALERT_AFTER", *alertAfter)
+	finalThinkTime := getEnvAsDuration("THINK_TIME", *thinkTimeFlag)
+	finalThinkJitter := getEnvAsDuration("THINK_JITTER", *thinkJitter)
+	finalClientMix := getEnv("CLIENT_MIX", *clientMixFlag)
+	finalExpectStatus := getEnv("EXPECT_STATUS", *expectStatus)
+	finalBodyContains := getEnvAsLines("ASSERT_BODY_CONTAINS", bodyContains)
+	finalBodyJSONPaths := getEnvAsLines("ASSERT_JSONPATH", bodyJSONPaths)
+	finalAssertSample := getEnv("ASSERT_SAMPLE", *assertSample)
+	finalResponseSchemaPath := getEnv("RESPONSE_SCHEMA", *responseSchemaPath)
+	finalScriptPath := getEnv("SCRIPT", *scriptPath)
 	finalVerb := getEnv("VERB", *verb)
 	finalJsonPath := getEnv("JSONPATH", *jsonPath)
 	finalRandIDType := getEnv("RAND_ID_TYPE", *randIDType)
+	// The random id is only added to --jsonpath bodies, as it always was, unless
+	// --rand-id-type asks for it on every JSON body.
+	if finalJsonPath == "" && !flagPassed("rand-id-type", "RAND_ID_TYPE") {
+		finalRandIDType = ""
+	}
+	finalSeqStart := int64(getEnvAsInt("SEQ_START", int(*seqStart)))
 	finalRandIDChrs := getEnvAsInt("RAND_ID_CHRS", *randIDChrs)
+	finalDisableKeepAlive := getEnvAsBool("DISABLE_KEEPALIVE", *disableKeepAlive)
+	finalMaxIdleConns := getEnvAsInt("MAX_IDLE_CONNS", *maxIdleConns)
+	finalMaxConnsPerHost := getEnvAsInt("MAX_CONNS_PER_HOST", *maxConnsPerHost)
+	finalTimeout := getEnvAsDuration("TIMEOUT", *timeout)
+	finalRetries := getEnvAsInt("RETRIES", *retries)
+	finalGiveUpAfter := getEnvAsDuration("CLIENT_GIVES_UP_AFTER", *giveUpAfter)
+	finalDeadlineHeader := getEnv("DEADLINE_HEADER", *deadlineHeader)
+	finalDeadlineFormat := getEnv("DEADLINE_FORMAT", *deadlineFormat)
+	finalHedgeAfter := getEnv("HEDGE_AFTER", *hedgeAfter)
+	finalConnectTimeout := getEnvAsDuration("CONNECT_TIMEOUT", *connectTimeout)
+	finalTLSHandshakeTimeout := getEnvAsDuration("TLS_HANDSHAKE_TIMEOUT", *tlsHandshakeTimeout)
+	finalResponseHeaderTimeout := getEnvAsDuration("RESPONSE_HEADER_TIMEOUT", *responseHeaderTimeout)
+	finalDisableHappyEyeballs := getEnvAsBool("DISABLE_HAPPY_EYEBALLS", *disableHappyEyeballs)
+	finalIPv4Only := getEnvAsBool("IPV4_ONLY", *ipv4Only)
+	finalIPv6Only := getEnvAsBool("IPV6_ONLY", *ipv6Only)
+	finalDNSDelay := getEnvAsDuration("DNS_DELAY", *dnsDelay)
+	finalDoH := getEnv("DOH", *doh)
+	finalResolve := getEnvAsList("RESOLVE", resolveEntries)
+	finalDNSCache := getEnv("DNS_CACHE", *dnsCache)
+	finalHostHeader := getEnv("HOST_HEADER", *hostHeader)
+	finalSNI := getEnv("SNI", *sni)
+	if finalSNI == "" && finalHostHeader != "" {
+		finalSNI = finalHostHeader
+		if host, _, err := net.SplitHostPort(finalHostHeader); err == nil {
+			finalSNI = host
+		}
+	}
+	finalInsecure := getEnvAsBool("INSECURE", *insecure)
+	finalInsecureHosts := getEnvAsList("INSECURE_HOSTS", insecureHosts)
+	finalFeedCapture := getEnv("FEED_CAPTURE", *feedCapture)
+	finalFeedSize := getEnvAsInt("FEED_SIZE", *feedSize)
+	finalReadAfterWrite := getEnv("READ_AFTER_WRITE", *readAfterWrite)
+	finalReadAfterWriteDelay := getEnvAsDuration("READ_AFTER_WRITE_DELAY", *readAfterWriteDelay)
+	finalRWRatio := getEnv("RW_RATIO", *rwRatio)
+	finalMaxRedirects := getEnvAsInt("MAX_REDIRECTS", *maxRedirects)
+	finalNoFollowRedirects := getEnvAsBool("NO_FOLLOW_REDIRECTS", *noFollowRedirects)
+	finalSlowThreshold := getEnvAsDuration("SLOW_THRESHOLD", *slowThreshold)
+	finalSlowTop := getEnvAsInt("SLOW_TOP", *slowTop)
+	finalLatencyPrecision := getEnvAsInt("LATENCY_PRECISION", *latencyPrecision)
+	finalMaxLatency := getEnvAsDuration("MAX_LATENCY", *maxLatency)
+	finalMaxBody := getEnv("MAX_BODY", *maxBody)
+	finalAcceptEncoding := getEnv("ACCEPT_ENCODING", *acceptEncoding)
+	finalGzipBody := getEnvAsBool("GZIP_BODY", *gzipRequestBody)
+	finalProbeRequests := getEnvAsInt("PROBE_REQUESTS", *probeRequests)
+	finalProbeInterval := getEnvAsDuration("PROBE_INTERVAL", *probeInterval)
+	finalProbeTolerance := getEnvAsFloat("PROBE_TOLERANCE", *probeTolerance)
+	finalProbeURL := getEnv("PROBE_URL", *probeURL)
+	finalPrimePath := getEnv("PRIME", *primePath)
+	finalPrimeInterval := getEnvAsDuration("PRIME_INTERVAL", *primeInterval)
+	finalSetupPath := getEnv("SETUP", *setupPath)
+	finalTeardownPath := getEnv("TEARDOWN", *teardownPath)
+	finalCooldown := getEnvAsDuration("COOLDOWN", *cooldown)
+	finalHealthURL := getEnv("HEALTH_URL", *healthURL)
+	finalHealthInterval := getEnvAsDuration("HEALTH_INTERVAL", *healthInterval)
+	finalDataPath := getEnv("DATA", *dataPath)
+	finalDataMode := getEnv("DATA_MODE", *dataMode)
+	finalDataPer := getEnv("DATA_PER", *dataPer)
+	finalCookies := getEnv("COOKIES", *cookies)
+	finalRerandomize := getEnvAsBool("RERANDOMIZE", *rerandomize)
+	finalFailoverURLs := getEnvAsList("FAILOVER_URLS", failoverURLs)
+	finalFailoverOn := getEnv("FAILOVER_ON", *failoverOn)
+	finalRandFields := getEnvAsList("RAND_FIELDS", randFields)
+	finalRawLogPath := getEnv("RAW_LOG", *rawLogPath)
+	verbosity := 0
+	switch {
+	case *veryVerbose:
+		verbosity = 2
+	case *verbose:
+		verbosity = 1
+	}
+	finalVerbosity := getEnvAsInt("VERBOSE", verbosity)
+	finalVerboseRequests := getEnvAsInt("VERBOSE_REQUESTS", *verboseRequests)
+	finalErrorLogPath := getEnv("ERROR_LOG", *errorLogPath)
+	finalDecisionLogPath := getEnv("DECISION_LOG", *decisionLogPath)
+	finalCaptureResponses := getEnv("CAPTURE_RESPONSES", *captureResponses)
+	finalCaptureDir := getEnv("CAPTURE_DIR", *captureDir)
+	finalReportJSONPath := getEnv("REPORT_JSON", *reportJSONPath)
+	finalReportHTMLPath := getEnv("REPORT_HTML", *reportHTMLPath)
+	finalSignKeyPath := getEnv("SIGN_KEY", *signKeyPath)
+	finalResultsPath := getEnv("OUT", *resultsPath)
+	finalRegion := getEnv("REGION", *region)
+	finalHostsPath := getEnv("HOSTS", *hostsPath)
+	finalRemoteBin := getEnv("REMOTE_BIN", *remoteBin)
+	finalSaveBaselinePath := getEnv("SAVE_BASELINE", *saveBaselinePath)
+	finalComparePath := getEnv("COMPARE", *comparePath)
+	finalLatencyTolerance := getEnv("LATENCY_TOLERANCE", *latencyTolerance)
+	finalThroughputTolerance := getEnv("THROUGHPUT_TOLERANCE", *throughputTolerance)
+	finalConnLogPath := getEnv("CONN_LOG", *connLogPath)
+	finalAgeRecipients := getEnvAsList("ENCRYPT_AGE", ageRecipients)
+	finalGPGRecipients := getEnvAsList("ENCRYPT_GPG", gpgRecipients)
+	finalRedactHeaders := getEnvAsList("REDACT_HEADERS", redactHeaders)
+	finalRedactFields := getEnvAsList("REDACT_FIELDS", redactFields)
+	finalNoDefaultRedaction := getEnvAsBool("NO_DEFAULT_REDACTION", *noDefaultRedaction)
+	finalHeaders := getEnvAsLines("HEADERS", headers)
+	finalRotateAccept := getEnvAsList("ROTATE_ACCEPT", loadtest.SplitList(*rotateAccept))
+	if len(finalRotateAccept) == 1 && finalRotateAccept[0] == "default" {
+		finalRotateAccept = loadtest.DefaultAcceptRotation
+	}
+	finalRotateLocale := getEnvAsList("ROTATE_LOCALE", loadtest.SplitList(*rotateLocale))
+	finalFormFields := getEnvAsLines("FORM", formFields)
+	finalBody := getEnv("BODY", *inlineBody)
+	finalBodyFile := getEnv("BODY_FILE", *bodyFile)
+	finalSeed := int64(getEnvAsInt("SEED", int(*seed)))
+	finalRawBody := getEnvAsBool("RAW_BODY", *rawBody)
+	finalContentType := getEnv("CONTENT_TYPE", *contentType)
+	finalFiles := getEnvAsLines("FILES", files)
+	finalMultipartFields := getEnvAsLines("FORM_FIELDS", multipartFields)
+	finalOpenAPIPath := getEnv("OPENAPI", *openAPIPath)
+	finalOpenAPISamples := getEnvAsInt("OPENAPI_SAMPLES", *openAPISamples)
+	finalOpenAPIOperations := loadtest.SplitList(getEnv("OPENAPI_OPERATIONS", *openAPIOperations))
+	finalOpenAPIServer := getEnv("OPENAPI_SERVER", *openAPIServer)
+	finalValidateOnly := getEnvAsBool("VALIDATE_ONLY", *validateOnly)
+	finalReadURLs := getEnvAsList("READ_URLS", readURLs)
+	finalWriteURLs := getEnvAsList("WRITE_URLS", writeURLs)
 
-	if finalURL == "" {
+	if finalHARPath != "" && (len(finalURLs) > 0 || finalTargetsPath != "" || finalPostmanPath != "" || finalFromCurl != "" || len(finalOpenAPIOperations) > 0 || len(finalReadURLs) > 0 || len(finalWriteURLs) > 0) {
+		color.Red("❌ --har cannot be combined with --url, --targets, --postman, --from-curl, --openapi-operations or --read-url/--write-url.")
+		exit(2)
+	}
+	if len(finalOpenAPIOperations) > 0 && finalOpenAPIPath == "" {
+		color.Red("❌ --openapi-operations requires --openapi.")
+		exit(2)
+	}
+	if finalOpenAPIServer != "" && len(finalOpenAPIOperations) == 0 {
+		color.Red("❌ --openapi-server requires --openapi-operations.")
+		exit(2)
+	}
+	if finalPostmanEnvPath != "" && finalPostmanPath == "" {
+		color.Red("❌ --postman-env requires --postman.")
+		exit(2)
+	}
+	if finalHARTiming && finalHARPath == "" {
+		color.Red("❌ --har-timing requires --har.")
+		exit(2)
+	}
+	if len(finalURLs) == 0 && finalTargetsPath == "" && finalPostmanPath == "" && finalFromCurl == "" && len(finalOpenAPIOperations) == 0 && finalHARPath == "" && len(finalReadURLs) == 0 && len(finalWriteURLs) == 0 {
 		color.Red("❌ The service URL is required. Set it via --url flag or in the .env file.")
-		return
+		exit(2)
 	}
 
-	color.Cyan("🏁 Starting the load test for %s...", finalURL)
-	runLoadTest(finalURL, finalRequests, finalConcurrency, finalVerb, finalJsonPath, finalRandIDType, finalRandIDChrs)
-}
+	targets, err := loadtest.ParseTargets(finalURLs, finalVerb)
+	if err != nil {
+		color.Red("❌ Invalid --url value: %v", err)
+		exit(2)
+	}
+	if finalTargetsPath != "" {
+		fileTargets, err := loadtest.LoadTargetsFile(finalTargetsPath, finalRawBody)
+		if err != nil {
+			color.Red("❌ Error loading targets file: %v", err)
+			exit(2)
+		}
+		targets = loadtest.MergeTargets(targets, fileTargets)
+	}
+	if finalPostmanPath != "" {
+		postmanTargets, err := loadtest.LoadPostmanCollection(finalPostmanPath, finalPostmanEnvPath, finalRawBody)
+		if err != nil {
+			color.Red("❌ Error loading Postman collection: %v", err)
+			exit(2)
+		}
+		targets = loadtest.MergeTargets(targets, postmanTargets)
+	}
+	var curlPath, curlTarget string
+	if finalFromCurl != "" {
+		curlTargets, err := loadtest.LoadCurl(finalFromCurl, finalRawBody)
+		if err != nil {
+			color.Red("❌ Error loading curl commands: %v", err)
+			exit(2)
+		}
+		targets = loadtest.MergeTargets(targets, curlTargets)
+		if !loadtest.IsCurlCommand(finalFromCurl) {
+			curlPath = finalFromCurl
+		} else {
+			curlTarget = curlTargets[0].Name()
+		}
+	}
+	if len(finalOpenAPIOperations) > 0 {
+		specTargets, err := loadtest.GenerateOpenAPITargets(finalOpenAPIPath, finalOpenAPIServer, finalOpenAPIOperations)
+		if err != nil {
+			color.Red("❌ Error generating targets from the OpenAPI spec: %v", err)
+			exit(2)
+		}
+		targets = loadtest.MergeTargets(targets, specTargets)
+	}
+	var generator loadtest.RequestGenerator
+	if finalHARPath != "" {
+		har, err := loadtest.LoadHAR(finalHARPath, finalHARTiming)
+		if err != nil {
+			color.Red("❌ Error loading HAR file: %v", err)
+			exit(2)
+		}
+		color.Cyan("🗂️  Replaying %d requests recorded over %v from %s", har.Len(), har.Duration().Round(time.Millisecond), finalHARPath)
+		generator = har
+	}
+	if len(targets) > 1 && len(finalReadURLs)+len(finalWriteURLs) > 0 {
+		color.Red("❌ Several --url targets cannot be combined with --read-url/--write-url.")
+		exit(2)
+	}
+
+	readWeight, writeWeight, err := loadtest.ParseRWRatio(finalRWRatio)
+	if err != nil {
+		color.Red("❌ Invalid read/write ratio %q: %v", finalRWRatio, err)
+		exit(2)
+	}
+	if finalDataPer != loadtest.DataPerRequest && finalDataPer != loadtest.DataPerVU {
+		color.Red("❌ Invalid --data-per value %q, expected request or vu.", finalDataPer)
+		exit(2)
+	}
+	switch finalCookies {
+	case loadtest.CookiesPerVU, loadtest.CookiesShared, loadtest.CookiesOff:
+	default:
+		color.Red("❌ Invalid --cookies value %q, expected vu, shared or off.", finalCookies)
+		exit(2)
+	}
+	if finalThinkTime < 0 || finalThinkJitter < 0 || finalWarmup < 0 || finalGiveUpAfter < 0 {
+		color.Red("❌ --think-time, --think-jitter, --warmup and --client-gives-up-after cannot be negative.")
+		exit(2)
+	}
+	if finalMaxDuration < 0 {
+		color.Red("❌ --max-duration cannot be negative.")
+		exit(2)
+	}
+	if finalMaxDuration > 0 && finalDuration > 0 && finalWarmup+finalDuration+finalCooldown >= finalMaxDuration {
+		color.Red("❌ --max-duration of %v leaves no time to finish a run of %v (--warmup, --duration and --cooldown).",
+			finalMaxDuration, finalWarmup+finalDuration+finalCooldown)
+		exit(2)
+	}
+	switch finalDeadlineFormat {
+	case "", "ms", "s", "grpc":
+	default:
+		color.Red("❌ Invalid --deadline-format value %q, expected ms, s or grpc.", finalDeadlineFormat)
+		exit(2)
+	}
+	if finalDeadlineFormat != "" && finalDeadlineHeader == "" {
+		color.Red("❌ --deadline-format requires --deadline-header.")
+		exit(2)
+	}
+	expectedStatuses, err := loadtest.ParseExpectedStatuses(finalExpectStatus)
+	if err != nil {
+		color.Red("❌ Invalid --expect-status value: %v", err)
+		exit(2)
+	}
+	var bodyAssertions []loadtest.BodyAssertion
+	for _, s := range finalBodyContains {
+		bodyAssertions = append(bodyAssertions, loadtest.NewContainsAssertion(s))
+	}
+	for _, expr := range finalBodyJSONPaths {
+		a, err := loadtest.ParseJSONPathAssertion(expr)
+		if err != nil {
+			color.Red("❌ Invalid --assert-jsonpath value: %v", err)
+			exit(2)
+		}
+		bodyAssertions = append(bodyAssertions, a)
+	}
+	assertSampleRate, err := loadtest.ParsePercentage(finalAssertSample)
+	if err != nil {
+		color.Red("❌ Invalid --assert-sample value: %v", err)
+		exit(2)
+	}
+	hedge, err := loadtest.ParseHedgeAfter(finalHedgeAfter)
+	if err != nil {
+		color.Red("❌ Invalid --hedge-after value: %v", err)
+		exit(2)
+	}
+	dohURL, err := loadtest.ParseDoHURL(finalDoH)
+	if err != nil {
+		color.Red("❌ Invalid --doh value: %v", err)
+		exit(2)
+	}
+	var resolver *loadtest.DoHResolver
+	if dohURL != "" {
+		resolver = loadtest.NewDoHResolver(dohURL, finalTimeout, finalDNSDelay)
+	}
+	resolveOverrides, err := loadtest.ParseResolve(finalResolve)
+	if err != nil {
+		color.Red("❌ Invalid --resolve value: %v", err)
+		exit(2)
+	}
+	ipVersion := 0
+	switch {
+	case finalIPv4Only && finalIPv6Only:
+		color.Red("❌ -4 and -6 cannot be combined.")
+		exit(2)
+	case finalIPv4Only:
+		ipVersion = 4
+	case finalIPv6Only:
+		ipVersion = 6
+	}
+	answerCache, err := loadtest.ParseDNSCache(finalDNSCache)
+	if err != nil {
+		color.Red("❌ Invalid --dns-cache value: %v", err)
+		exit(2)
+	}
+	responseCapture, err := loadtest.ParseResponseCapture(finalCaptureResponses)
+	if err != nil {
+		color.Red("❌ Invalid --capture-responses value: %v", err)
+		exit(2)
+	}
+	clientShares, err := loadtest.ParseClientMix(finalClientMix)
+	if err != nil {
+		color.Red("❌ Invalid --client-mix value: %v", err)
+		exit(2)
+	}
+	sweepLevels, err := loadtest.ParseConcurrencyLevels(finalConcurrencySweep)
+	if err != nil {
+		color.Red("❌ Invalid --concurrency-sweep value: %v", err)
+		exit(2)
+	}
+	if len(finalCurveOutputs) > 0 && len(sweepLevels) == 0 {
+		color.Red("❌ --curve-out requires --concurrency-sweep.")
+		exit(2)
+	}
+	if len(sweepLevels) > 0 && (finalReportJSONPath != "" || finalReportHTMLPath != "" || finalResultsPath != "" ||
+		finalSaveBaselinePath != "" || finalComparePath != "") {
+		color.Red("❌ --concurrency-sweep cannot be combined with --report-json, --report-html, --out, --save-baseline or --compare.")
+		exit(2)
+	}
+	runStart, err := loadtest.ParseStartAt(finalStartAt, time.Now())
+	if err != nil {
+		color.Red("❌ Invalid --start-at value: %v", err)
+		exit(2)
+	}
+	if finalWindow < 0 {
+		color.Red("❌ --window cannot be negative.")
+		exit(2)
+	}
+	if finalWindow > 0 && runStart.IsZero() {
+		// The window of a sweep covers all of its levels, not each run.
+		runStart = time.Now()
+	}
+	if monitor && (!runStart.IsZero() || finalWindow > 0) {
+		color.Red("❌ restclient monitor cannot be combined with --start-at or --window.")
+		exit(2)
+	}
+	var baseline *loadtest.Report
+	if finalComparePath != "" {
+		report, err := loadtest.ReadBaseline(finalComparePath)
+		if err != nil {
+			color.Red("❌ Error reading baseline %s: %v", finalComparePath, err)
+			exit(2)
+		}
+		baseline = &report
+	}
+	var tolerances loadtest.BaselineTolerances
+	if tolerances.Latency, err = loadtest.ParsePercentage(finalLatencyTolerance); err != nil {
+		color.Red("❌ Invalid --latency-tolerance value: %v", err)
+		exit(2)
+	}
+	if tolerances.Throughput, err = loadtest.ParsePercentage(finalThroughputTolerance); err != nil {
+		color.Red("❌ Invalid --throughput-tolerance value: %v", err)
+		exit(2)
+	}
+	var thresholds []loadtest.Threshold
+	for _, expr := range finalThresholds {
+		t, err := loadtest.ParseThreshold(expr)
+		if err != nil {
+			color.Red("❌ Invalid --threshold value: %v", err)
+			exit(2)
+		}
+		thresholds = append(thresholds, t)
+	}
+	if len(thresholds) > 0 && len(sweepLevels) > 0 {
+		color.Red("❌ --concurrency-sweep cannot be combined with --threshold.")
+		exit(2)
+	}
+	if monitor {
+		if finalMonitorInterval <= 0 || finalAlertAfter < 1 {
+			color.Red("❌ --interval must be positive and --alert-after at least 1.")
+			exit(2)
+		}
+		if len(sweepLevels) > 0 || finalReportJSONPath != "" || finalReportHTMLPath != "" || finalResultsPath != "" ||
+			finalSaveBaselinePath != "" || finalComparePath != "" {
+			color.Red("❌ restclient monitor cannot be combined with --concurrency-sweep, --report-json, --report-html, --out, --save-baseline or --compare.")
+			exit(2)
+		}
+	}
+	var signingKey *loadtest.SigningKey
+	if finalSignKeyPath != "" {
+		if finalReportJSONPath == "" {
+			color.Red("❌ --sign-key requires --report-json.")
+			exit(2)
+		}
+		signingKey, err = loadtest.LoadSigningKey(finalSignKeyPath)
+		if err != nil {
+			color.Red("❌ Error loading signing key: %v", err)
+			exit(2)
+		}
+	}
+	var abortErrorRate float64
+	if finalAbortOnErrorRate != "" {
+		abortErrorRate, err = loadtest.ParsePercentage(finalAbortOnErrorRate)
+		if err != nil {
+			color.Red("❌ Invalid --abort-on-error-rate value: %v", err)
+			exit(2)
+		}
+		if finalAbortWindow < 1 {
+			color.Red("❌ --abort-window must be at least 1.")
+			exit(2)
+		}
+	}
+	if finalLatencyPrecision < 1 || finalLatencyPrecision > 5 {
+		color.Red("❌ --latency-precision must be between 1 and 5.")
+		exit(2)
+	}
+	if finalMaxLatency <= 0 {
+		color.Red("❌ --max-latency must be positive.")
+		exit(2)
+	}
+	if finalReadAfterWrite != "" && finalFeedCapture == "" {
+		color.Red("❌ --read-after-write needs --feed-capture to find the written entity in the write response.")
+		exit(2)
+	}
+	var hosts []string
+	if finalHostsPath != "" {
+		if monitor || verifyReport != "" || len(sweepLevels) > 0 || finalValidateOnly || finalResultsPath != "" || finalSaveBaselinePath != "" {
+			color.Red("❌ --hosts cannot be combined with restclient monitor, verify-run, --concurrency-sweep, --validate-only, --out or --save-baseline.")
+			exit(2)
+		}
+		hosts, err = loadtest.ReadHosts(finalHostsPath)
+		if err != nil {
+			color.Red("❌ Error reading hosts: %v", err)
+			exit(2)
+		}
+	}
+	if finalRetries < 0 {
+		color.Red("❌ --retries cannot be negative.")
+		exit(2)
+	}
+	if finalRate < 0 {
+		color.Red("❌ --rate cannot be negative.")
+		exit(2)
+	}
+	if finalArrivalRate < 0 {
+		color.Red("❌ --arrival-rate cannot be negative.")
+		exit(2)
+	}
+	if finalArrivalRate > 0 && (finalRate > 0 || finalThinkTime > 0) {
+		color.Red("❌ --arrival-rate sets the pace of the requests, it cannot be combined with --rate or --think-time.")
+		exit(2)
+	}
+	if finalMaxQueue < 0 {
+		color.Red("❌ --max-queue cannot be negative.")
+		exit(2)
+	}
+	if finalReportInterval < 0 {
+		color.Red("❌ --report-interval cannot be negative.")
+		exit(2)
+	}
+	if finalInterimOutPath != "" && finalReportInterval == 0 {
+		color.Red("❌ --interim-out needs a --report-interval.")
+		exit(2)
+	}
+	parsedStatsDTags, err := loadtest.ParseStatsDTags(finalStatsDTags)
+	if err != nil {
+		color.Red("❌ Invalid --statsd-tag value: %v", err)
+		exit(2)
+	}
+	if len(parsedStatsDTags) > 0 && !finalDogStatsD {
+		color.Red("❌ --statsd-tag needs --dogstatsd: plain StatsD has no tags.")
+		exit(2)
+	}
+	if finalStatsDAddr == "" && (finalDogStatsD || len(parsedStatsDTags) > 0) {
+		color.Red("❌ --dogstatsd and --statsd-tag need a --statsd address.")
+		exit(2)
+	}
+	var spikeProfile *loadtest.SpikeProfile
+	if finalSpike != "" {
+		spikeProfile, err = loadtest.ParseSpike(finalSpike)
+		if err != nil {
+			color.Red("❌ Invalid --spike value: %v", err)
+			exit(2)
+		}
+		if finalArrivalRate > 0 || finalRate > 0 || finalThinkTime > 0 {
+			color.Red("❌ --spike sets the pace of the requests, it cannot be combined with --arrival-rate, --rate or --think-time.")
+			exit(2)
+		}
+		if finalDuration < spikeProfile.End() {
+			color.Red("❌ --spike needs a --duration of at least %v, the end of the burst.", spikeProfile.End())
+			exit(2)
+		}
+	}
+	var stageProfile *loadtest.StageProfile
+	if finalSteps != "" {
+		stageProfile, err = loadtest.ParseStages(finalSteps)
+		if err != nil {
+			color.Red("❌ Invalid --steps value: %v", err)
+			exit(2)
+		}
+		if spikeProfile != nil || finalArrivalRate > 0 {
+			color.Red("❌ --steps cannot be combined with --spike or --arrival-rate.")
+			exit(2)
+		}
+		if stageProfile.Rates() && (finalRate > 0 || finalThinkTime > 0) {
+			color.Red("❌ Rate stages set the pace of the requests, they cannot be combined with --rate or --think-time.")
+			exit(2)
+		}
+		if finalDuration > 0 && finalDuration != stageProfile.Duration() {
+			color.Red("❌ --steps lasts %v, it cannot be combined with a --duration of %v.", stageProfile.Duration(), finalDuration)
+			exit(2)
+		}
+		finalDuration = stageProfile.Duration()
+		if !stageProfile.Rates() {
+			finalConcurrency = stageProfile.MaxConcurrency()
+		}
+	}
+	if finalMaxQueue > 0 && finalArrivalRate == 0 && spikeProfile == nil && (stageProfile == nil || !stageProfile.Rates()) {
+		color.Red("❌ --max-queue needs an open-model run: --arrival-rate, --spike or rate --steps.")
+		exit(2)
+	}
+	maxBodyBytes, err := loadtest.ParseByteSize(finalMaxBody)
+	if err != nil {
+		color.Red("❌ Invalid --max-body value: %v", err)
+		exit(2)
+	}
+	bodySources := 0
+	for _, used := range []bool{finalJsonPath != "", finalBody != "", finalBodyFile != "", len(finalFormFields) > 0, len(finalFiles)+len(finalMultipartFields) > 0} {
+		if used {
+			bodySources++
+		}
+	}
+	if bodySources > 1 {
+		color.Red("❌ --jsonpath, --body, --body-file, --form and --file/--form-field are mutually exclusive.")
+		exit(2)
+	}
+	if finalValidateOnly && finalOpenAPIPath == "" {
+		color.Red("❌ --validate-only requires --openapi.")
+		exit(2)
+	}
+	failoverPolicy, err := loadtest.ParseFailoverPolicy(finalFailoverOn)
+	if err != nil {
+		color.Red("❌ Invalid failover policy: %v", err)
+		exit(2)
+	}
+	finalURL := strings.Join(finalURLs, ", ")
+	if finalTargetsPath != "" {
+		finalURL = strings.TrimPrefix(finalURL+", "+finalTargetsPath, ", ")
+	}
+	if finalPostmanPath != "" {
+		finalURL = strings.TrimPrefix(finalURL+", "+finalPostmanPath, ", ")
+	}
+	if curlPath != "" || curlTarget != "" {
+		finalURL = strings.TrimPrefix(finalURL+", "+curlPath+curlTarget, ", ")
+	}
+	if len(finalOpenAPIOperations) > 0 {
+		finalURL = strings.TrimPrefix(finalURL+", "+finalOpenAPIPath, ", ")
+	}
+	if finalHARPath != "" {
+		finalURL = finalHARPath
+	}
+	if finalURL == "" {
+		finalURL = strings.Join(append(append([]string{}, finalReadURLs...), finalWriteURLs...), ", ")
+	}
 
-// runLoadTest starts the load test with the specified parameters.
-// It uses a goroutine for each worker, sending concurrent requests to the target URL.
-func runLoadTest(url string, totalRequests int, concurrencyLevel int, verb string, jsonPath string, randIDType string, randIDChrs int) {
-	var wg sync.WaitGroup
-	requestsPerWorker := totalRequests / concurrencyLevel
-	extraRequests := totalRequests % concurrencyLevel
+	statsdOptions := loadtest.StatsDOptions{
+		Addr:      finalStatsDAddr,
+		Prefix:    finalStatsDPrefix,
+		DogStatsD: finalDogStatsD,
+		Tags:      parsedStatsDTags,
+	}
+	cfg := loadtest.Config{
+		Requests:         finalRequests,
+		Duration:         finalDuration,
+		Warmup:           finalWarmup,
+		DrainTimeout:     finalDrainTimeout,
+		MaxDuration:      finalMaxDuration,
+		AbortErrorRate:   abortErrorRate,
+		AbortWindow:      finalAbortWindow,
+		Concurrency:      finalConcurrency,
+		Rate:             finalRate,
+		ArrivalRate:      finalArrivalRate,
+		MaxQueue:         finalMaxQueue,
+		Spike:            spikeProfile,
+		Stages:           stageProfile,
+		ReportInterval:   finalReportInterval,
+		InterimOutPath:   finalInterimOutPath,
+		StreamPath:       finalStreamPath,
+		StatsD:           statsdOptions,
+		CurveOutputs:     finalCurveOutputs,
+		ThinkTime:        finalThinkTime,
+		ThinkJitter:      finalThinkJitter,
+		ClientMix:        clientShares,
+		ExpectStatus:     expectedStatuses,
+		BodyAssertions:   bodyAssertions,
+		AssertSample:     assertSampleRate,
+		Verb:             finalVerb,
+		JSONPath:         finalJsonPath,
+		RandIDType:       finalRandIDType,
+		SeqStart:         finalSeqStart,
+		RandIDChrs:       finalRandIDChrs,
+		DisableKeepAlive: finalDisableKeepAlive,
+		MaxIdleConns:     finalMaxIdleConns,
+		MaxConnsPerHost:  finalMaxConnsPerHost,
 
-	results := make(chan int, totalRequests)
-	statusCodeCount := make(map[int]int)
-	networkErrorCount := 0
-	startTime := time.Now()
+		Timeout:               finalTimeout,
+		Retries:               finalRetries,
+		GiveUpAfter:           finalGiveUpAfter,
+		DeadlineHeader:        finalDeadlineHeader,
+		DeadlineFormat:        finalDeadlineFormat,
+		Hedge:                 hedge,
+		ConnectTimeout:        finalConnectTimeout,
+		TLSHandshakeTimeout:   finalTLSHandshakeTimeout,
+		ResponseHeaderTimeout: finalResponseHeaderTimeout,
+		DNSDelay:              finalDNSDelay,
+		DoH:                   resolver,
+		Resolve:               resolveOverrides,
+		DNSCache:              answerCache,
+		DisableHappyEyeballs:  finalDisableHappyEyeballs,
+		IPVersion:             ipVersion,
+		Insecure:              finalInsecure,
+		InsecureHosts:         finalInsecureHosts,
+		HostHeader:            finalHostHeader,
+		SNI:                   finalSNI,
 
-	for i := 0; i < concurrencyLevel; i++ {
-		wg.Add(1)
-		go func(requests int) {
-			defer wg.Done()
-			client := &http.Client{
-				Timeout: 30 * time.Second,
-			}
+		FeedCapture: finalFeedCapture,
+		FeedSize:    finalFeedSize,
 
-			var requestBody []byte
-
-			if verb == "POST" && jsonPath != "" {
-				body, err := os.ReadFile(jsonPath)
-				if err != nil {
-					color.Red("❌ Error reading JSON file: %v", err)
-					return
-				}
-				if randIDType != "" {
-					body, err = modifyJSONBody(body, randIDType, randIDChrs)
-					if err != nil {
-						color.Red("❌ Error modifying JSON body: %v", err)
-						return
-					}
-				}
-				requestBody = body
-			}
+		ReadAfterWrite:      finalReadAfterWrite,
+		ReadAfterWriteDelay: finalReadAfterWriteDelay,
 
-			for j := 0; j < requests; j++ {
-				req, err := http.NewRequest(verb, url, bytes.NewBuffer(requestBody))
-				if err != nil {
-					color.Red("❌ Error creating request: %v", err)
-					results <- -1
-					continue
-				}
-				if verb == "POST" {
-					req.Header.Set("Content-Type", "application/json")
-				}
-				resp, err := client.Do(req)
-				if err != nil {
-					color.Red("❌ Network error: %v", err)
-					results <- -1
-					continue
-				}
-				results <- resp.StatusCode
-				resp.Body.Close()
-			}
-		}(requestsPerWorker + boolToInt(i < extraRequests))
+		Targets:        targets,
+		TargetsPath:    finalTargetsPath,
+		PostmanPath:    finalPostmanPath,
+		PostmanEnvPath: finalPostmanEnvPath,
+		CurlPath:       curlPath,
+		Generator:      generator,
+		HARPath:        finalHARPath,
+		ReadURLs:       finalReadURLs,
+		WriteURLs:      finalWriteURLs,
+		ReadWeight:     readWeight,
+		WriteWeight:    writeWeight,
+
+		MaxRedirects:      finalMaxRedirects,
+		NoFollowRedirects: finalNoFollowRedirects,
+
+		SlowThreshold: finalSlowThreshold,
+		SlowTop:       finalSlowTop,
+
+		LatencyPrecision: finalLatencyPrecision,
+		MaxLatency:       finalMaxLatency,
+
+		AcceptEncoding: finalAcceptEncoding,
+		MaxBody:        maxBodyBytes,
+		GzipBody:       finalGzipBody,
+
+		ProbeRequests:  finalProbeRequests,
+		ProbeInterval:  finalProbeInterval,
+		ProbeTolerance: finalProbeTolerance,
+		ProbeURL:       finalProbeURL,
+
+		PrimePath:     finalPrimePath,
+		PrimeInterval: finalPrimeInterval,
+
+		SetupPath:    finalSetupPath,
+		TeardownPath: finalTeardownPath,
+
+		Headers:      finalHeaders,
+		RotateAccept: finalRotateAccept,
+		RotateLocale: finalRotateLocale,
+		FormFields:   finalFormFields,
+
+		Body:        finalBody,
+		BodyFile:    finalBodyFile,
+		RawBody:     finalRawBody,
+		Seed:        finalSeed,
+		ContentType: finalContentType,
+
+		Files:           finalFiles,
+		MultipartFields: finalMultipartFields,
+
+		OpenAPIPath:    finalOpenAPIPath,
+		OpenAPISamples: finalOpenAPISamples,
+		ValidateOnly:   finalValidateOnly,
+
+		Cooldown:       finalCooldown,
+		HealthURL:      finalHealthURL,
+		HealthInterval: finalHealthInterval,
+
+		DataPath: finalDataPath,
+		DataMode: finalDataMode,
+		DataPer:  finalDataPer,
+		Cookies:  finalCookies,
+
+		RandFields:  finalRandFields,
+		Rerandomize: finalRerandomize,
+
+		FailoverURLs: finalFailoverURLs,
+		FailoverOn:   failoverPolicy,
+
+		Verbose:         finalVerbosity,
+		VerboseRequests: finalVerboseRequests,
+		RawLogPath:      finalRawLogPath,
+		ErrorLogPath:    finalErrorLogPath,
+		DecisionLogPath: finalDecisionLogPath,
+		ConnLogPath:     finalConnLogPath,
+		ReportJSONPath:  finalReportJSONPath,
+		ReportHTMLPath:  finalReportHTMLPath,
+		ResultsPath:     finalResultsPath,
+		BaselinePath:    finalSaveBaselinePath,
+		Output:          loadtest.OutputOptions{AgeRecipients: finalAgeRecipients, GPGRecipients: finalGPGRecipients, SigningKey: signingKey},
+		RedactHeaders:   finalRedactHeaders,
+		RedactFields:    finalRedactFields,
+
+		CaptureResponses: responseCapture,
+		CaptureDir:       finalCaptureDir,
+
+		NoDefaultRedaction: finalNoDefaultRedaction,
+		ResponseSchemaPath: finalResponseSchemaPath,
+		ScriptPath:         finalScriptPath,
+
+		StartAt: runStart,
+		Window:  finalWindow,
+		Region:  finalRegion,
+
+		Settings: settings,
+		Logger:   logger,
 	}
 
-	go func() {
-		wg.Wait()
-		close(results)
-	}()
+	if verifyReport != "" {
+		if !loadtest.VerifyRun(verifyReport, cfg) {
+			exit(1)
+		}
+		return
+	}
 
-	for statusCode := range results {
-		if statusCode == -1 {
-			networkErrorCount++
+	ctx, stop := interruptContext()
+	defer stop()
+	switch {
+	case monitor:
+		err := loadtest.RunMonitor(ctx, cfg, finalURL, loadtest.MonitorOptions{
+			Interval:      finalMonitorInterval,
+			MetricsListen: finalMetricsListen,
+			AlertWebhook:  finalAlertWebhook,
+			AlertAfter:    finalAlertAfter,
+			Thresholds:    thresholds,
+		})
+		if err != nil {
+			color.Red("❌ %v", err)
+			exit(1)
+		}
+	case finalValidateOnly:
+		color.Cyan("📐 Validating sample requests for %s against %s...", finalURL, finalOpenAPIPath)
+		if _, err := loadtest.RunContext(ctx, cfg); err != nil {
+			color.Red("❌ %v", err)
+			exit(1)
+		}
+	case len(sweepLevels) > 0:
+		color.Cyan("🏁 Starting the concurrency sweep for %s...", finalURL)
+		if err := loadtest.RunSweep(ctx, cfg, sweepLevels); err != nil {
+			color.Red("❌ %v", err)
+			exit(1)
+		}
+	default:
+		var summary loadtest.Result
+		if len(hosts) > 0 {
+			summary, err = loadtest.RunOnHosts(cfg, hosts, loadtest.HostsOptions{
+				Binary: finalRemoteBin,
+				Report: loadtest.ReplayOptions{ReportJSONPath: finalReportJSONPath, ReportHTMLPath: finalReportHTMLPath, SigningKey: signingKey},
+			})
 		} else {
-			statusCodeCount[statusCode]++
+			color.Cyan("🏁 Starting the load test for %s...", finalURL)
+			summary, err = loadtest.RunContext(ctx, cfg)
+		}
+		if err != nil {
+			color.Red("❌ %v", err)
+			exit(1)
+		}
+		passed := summary.FailedAssertions == 0 && summary.FailedBodyChecks == 0 && summary.SchemaFailures == 0 && summary.ScriptFailures == 0 &&
+			!summary.MaxDurationExceeded
+		if len(thresholds) > 0 {
+			passed = loadtest.CheckThresholds(thresholds, summary) && passed
+		}
+		if baseline != nil {
+			passed = loadtest.CompareBaseline(finalComparePath, *baseline, summary, cfg, tolerances) && passed
+		}
+		if !passed {
+			exit(1)
 		}
 	}
+}
 
-	totalTime := time.Since(startTime)
-
-	generateReport(totalTime, totalRequests, statusCodeCount, networkErrorCount)
+// interruptContext returns a context cancelled by the first Ctrl+C, which stops the run
+// and lets its in-flight requests drain. The default behavior is then restored, so a
+// second Ctrl+C kills the process.
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx, stop
 }
 
-// modifyJSONBody modifies the JSON body by adding a random ID to the object.
-// The ID type and length are specified by the parameters.
-func modifyJSONBody(body []byte, idType string, length int) ([]byte, error) {
-	var jsonObj map[string]interface{}
-	err := json.Unmarshal(body, &jsonObj)
+// runReportCommand implements "restclient report results.bin [more.bin...] [flags]": it
+// renders the text report of a run saved with --out, and its JSON and HTML reports when
+// asked to. Several results files are merged into one run broken down per region. It
+// returns the exit status.
+func runReportCommand(args []string) int {
+	var paths []string
+	for len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		paths, args = append(paths, args[0]), args[1:]
+	}
+	if len(paths) == 0 {
+		color.Red("❌ Usage: restclient report results.bin [more.bin...] [--report-json=report.json] [--report-html=report.html]")
+		return 2
+	}
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	reportJSONPath := fs.String("report-json", "", "📄 Write the machine-readable summary of the run to this JSON file")
+	reportHTMLPath := fs.String("report-html", "", "🖥️ Write the report of the run to this HTML file")
+	summaryOnly := fs.Bool("summary-only", false, "🤫 Only write the JSON and HTML reports, without printing the text report")
+	signKeyPath := fs.String("sign-key", "", "🔏 Sign the --report-json report with this Ed25519 private key (PEM)")
+	fs.Parse(args)
+
+	opts := loadtest.ReplayOptions{
+		ReportJSONPath: *reportJSONPath,
+		ReportHTMLPath: *reportHTMLPath,
+		SummaryOnly:    *summaryOnly,
+	}
+	if *signKeyPath != "" {
+		if *reportJSONPath == "" {
+			color.Red("❌ --sign-key requires --report-json.")
+			return 2
+		}
+		key, err := loadtest.LoadSigningKey(*signKeyPath)
+		if err != nil {
+			color.Red("❌ Error loading signing key: %v", err)
+			return 1
+		}
+		opts.SigningKey = key
+	}
+	var err error
+	if len(paths) == 1 {
+		_, err = loadtest.Replay(paths[0], opts)
+	} else {
+		_, err = loadtest.ReplayAll(paths, opts)
+	}
 	if err != nil {
-		return nil, err
+		color.Red("❌ %v", err)
+		return 1
 	}
+	return 0
+}
 
-	id := generateRandomID(idType, length)
-	jsonObj["id"] = id
+// runVerifyCommand implements "restclient verify report.json --key public.pem": it checks
+// that a JSON report written with --sign-key was signed with the private key of the given
+// public key and not modified since. It returns the exit status.
+func runVerifyCommand(args []string) int {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		color.Red("❌ Usage: restclient verify report.json --key=public.pem")
+		return 2
+	}
+	path := args[0]
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	keyPath := fs.String("key", "", "🔑 Ed25519 public key (PEM) of the key the report was signed with")
+	fs.Parse(args[1:])
+	if *keyPath == "" {
+		color.Red("❌ --key is required to verify a report.")
+		return 2
+	}
 
-	modifiedBody, err := json.Marshal(jsonObj)
+	key, err := loadtest.LoadVerifyKey(*keyPath)
 	if err != nil {
-		return nil, err
+		color.Red("❌ Error loading public key: %v", err)
+		return 1
+	}
+	report, err := loadtest.VerifyReport(path, key)
+	if err != nil {
+		color.Red("❌ %v", err)
+		return 1
+	}
+	color.Green("✅ %s is signed with key %s and unmodified (run of %s, %d requests)", path, report.Signature.KeyID,
+		report.Summary.StartedAt.Format(time.RFC3339), report.Summary.Requests)
+	return 0
+}
+
+// runRecordCommand implements "restclient record [flags]": it runs a proxy recording the
+// requests of a browser or client until interrupted, and writes them to a HAR file to be
+// replayed with --har. It returns the exit status.
+func runRecordCommand(args []string) int {
+	fs := flag.NewFlagSet("record", flag.ExitOnError)
+	listen := fs.String("listen", "127.0.0.1:8888", "🎙️ Address the recording proxy listens on")
+	out := fs.String("out", "session.har", "💾 HAR file the recorded requests are written to")
+	target := fs.String("target", "", "🎯 Forward every request to this base URL as a reverse proxy, to record clients without proxy support or HTTPS services")
+	insecure := fs.Bool("insecure", false, "🔓 Skip TLS certificate verification for the --target")
+	var hosts stringList
+	fs.Var(&hosts, "record-host", "🎙️ Only record requests to this host, as name, name:port or *.domain (repeatable)")
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		color.Red("❌ Usage: restclient record [--listen=127.0.0.1:8888] [--out=session.har] [--target=https://example.com] [--record-host=example.com]")
+		return 2
 	}
 
-	return modifiedBody, nil
+	// SIGTERM is what timeout, kill and container runtimes send, and must not lose the
+	// session either.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	err := loadtest.RunRecorder(ctx, loadtest.RecordOptions{
+		Listen:     *listen,
+		OutputPath: *out,
+		Target:     *target,
+		Hosts:      hosts,
+		Insecure:   *insecure,
+	})
+	if err != nil {
+		color.Red("❌ Error recording: %v", err)
+		return 1
+	}
+	return 0
 }
 
-// generateRandomID generates a random ID based on the specified type and length.
-// Supported types are "number" and "string".
-func generateRandomID(idType string, length int) interface{} {
-	rand.Seed(time.Now().UnixNano())
-	switch idType {
-	case "number":
-		id := rand.Intn(int(math.Pow10(length)))
-		return id
-	case "string":
-		const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-		id := make([]byte, length)
-		for i := range id {
-			id[i] = charset[rand.Intn(len(charset))]
+// runCompareCommand implements "restclient compare a b": it prints the summaries of two
+// runs side by side, each a restclient JSON report or the output of Vegeta, k6 or hey.
+// It returns the exit status.
+func runCompareCommand(args []string) int {
+	if len(args) != 2 {
+		color.Red("❌ Usage: restclient compare run-a.json run-b.json")
+		return 2
+	}
+	var runs [2]loadtest.ImportedRun
+	for i, path := range args {
+		run, err := loadtest.ImportRun(path)
+		if err != nil {
+			color.Red("❌ Error reading %s: %v", path, err)
+			return 1
 		}
-		return string(id)
-	default:
-		return nil
+		runs[i] = run
 	}
+	loadtest.CompareRuns(args[0], runs[0], args[1], runs[1])
+	return 0
 }
 
-// generateReport generates a summary report of the load test results, including
-// the total time, successful and failed requests, and the distribution of HTTP status codes.
-func generateReport(totalTime time.Duration, totalRequests int, statusCodeCount map[int]int, networkErrorCount int) {
-	color.Green("\n===== 📝 Load Test Report =====")
-	fmt.Printf("⏳ Total time: %v\n", totalTime)
-	fmt.Printf("📊 Total requests: %d\n", totalRequests)
-	color.Cyan("✅ Successful requests (HTTP 200): %d\n", statusCodeCount[200])
+// settings holds the effective value of every setting by environment variable name. The
+// getEnv helpers record it once the flag and the environment are resolved, and the run
+// hashes it into its manifest.
+var settings = make(map[string]string)
 
-	delete(statusCodeCount, 200)
+// recordSetting records the effective value of the setting name.
+func recordSetting(name, value string) {
+	settings[name] = value
+}
 
-	if len(statusCodeCount) > 0 {
-		color.Yellow("\n📉 Distribution of other HTTP status codes:")
-		for status, count := range statusCodeCount {
-			if status >= 400 {
-				color.Red("  ❌ Failed requests (HTTP %d): %d", status, count)
-			} else {
-				fmt.Printf("  - HTTP %d: %d\n", status, count)
-			}
+// flagPassed reports whether the flag name was given on the command line or its
+// environment variable env is set.
+func flagPassed(name, env string) bool {
+	if _, exists := os.LookupEnv(env); exists {
+		return true
+	}
+	passed := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			passed = true
 		}
+	})
+	return passed
+}
+
+// getEnv retrieves the value of the environment variable named by the key.
+// If the variable is not present, it returns the fallback value.
+func getEnv(key string, fallback string) string {
+	value := fallback
+	if envValue, exists := os.LookupEnv(key); exists {
+		value = envValue
 	}
+	recordSetting(key, value)
+	return value
+}
 
-	if networkErrorCount > 0 {
-		color.Red("\n❌ Network errors: %d", networkErrorCount)
+// getEnvAsBool retrieves the value of the environment variable named by the key and converts it to a boolean.
+// If the variable is not present or cannot be converted, it returns the fallback value.
+func getEnvAsBool(name string, fallback bool) bool {
+	value := fallback
+	if envValue, exists := os.LookupEnv(name); exists {
+		boolValue, err := strconv.ParseBool(envValue)
+		if err != nil {
+			color.Red("❌ Invalid value for %s in .env file: %v", name, err)
+		} else {
+			value = boolValue
+		}
 	}
+	recordSetting(name, strconv.FormatBool(value))
+	return value
+}
 
-	color.Magenta("\n⚡ Requests per second: %.2f\n", float64(totalRequests)/totalTime.Seconds())
+// getEnvAsDuration retrieves the value of the environment variable named by the key and parses it as a duration.
+// If the variable is not present or cannot be parsed, it returns the fallback value.
+func getEnvAsDuration(name string, fallback time.Duration) time.Duration {
+	value := fallback
+	if envValue, exists := os.LookupEnv(name); exists {
+		durationValue, err := time.ParseDuration(envValue)
+		if err != nil {
+			color.Red("❌ Invalid value for %s in .env file: %v", name, err)
+		} else {
+			value = durationValue
+		}
+	}
+	recordSetting(name, value.String())
+	return value
 }
 
-// boolToInt converts a boolean to an integer (1 for true, 0 for false).
-func boolToInt(b bool) int {
-	if b {
-		return 1
+// getEnvAsList retrieves the value of the environment variable named by the key as a comma-separated list.
+// If the variable is not present, it returns the fallback value.
+func getEnvAsList(name string, fallback []string) []string {
+	list := fallback
+	if value, exists := os.LookupEnv(name); exists {
+		list = loadtest.SplitList(value)
 	}
-	return 0
+	recordSetting(name, strings.Join(list, "\n"))
+	return list
 }
 
-// getEnv retrieves the value of the environment variable named by the key.
+// getEnvAsFloat retrieves the value of the environment variable named by the key and converts it to a float.
+// If the variable is not present or cannot be converted, it returns the fallback value.
+func getEnvAsFloat(name string, fallback float64) float64 {
+	value := fallback
+	if envValue, exists := os.LookupEnv(name); exists {
+		floatValue, err := strconv.ParseFloat(envValue, 64)
+		if err != nil {
+			color.Red("❌ Invalid value for %s in .env file: %v", name, err)
+		} else {
+			value = floatValue
+		}
+	}
+	recordSetting(name, strconv.FormatFloat(value, 'g', -1, 64))
+	return value
+}
+
+// getEnvAsLines retrieves the value of the environment variable named by the key as a newline-separated list.
 // If the variable is not present, it returns the fallback value.
-func getEnv(key string, fallback string) string {
-	if value, exists := os.LookupEnv(key); exists {
-		return value
+func getEnvAsLines(name string, fallback []string) []string {
+	list := fallback
+	if value, exists := os.LookupEnv(name); exists {
+		list = nil
+		for _, line := range strings.Split(value, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				list = append(list, line)
+			}
+		}
 	}
-	return fallback
+	recordSetting(name, strings.Join(list, "\n"))
+	return list
 }
 
 // getEnvAsInt retrieves the value of the environment variable named by the key and converts it to an integer.
 // If the variable is not present or cannot be converted, it returns the fallback value.
 func getEnvAsInt(name string, fallback int) int {
-	if value, exists := os.LookupEnv(name); exists {
-		intValue, err := strconv.Atoi(value)
+	value := fallback
+	if envValue, exists := os.LookupEnv(name); exists {
+		intValue, err := strconv.Atoi(envValue)
 		if err != nil {
 			color.Red("❌ Invalid value for %s in .env file: %v", name, err)
-			return fallback
+		} else {
+			value = intValue
 		}
-		return intValue
 	}
-	return fallback
+	recordSetting(name, strconv.Itoa(value))
+	return value
+}
+
+// stringList is a flag.Value collecting every occurrence of a repeatable flag.
+type stringList []string
+
+// String returns the collected values joined by commas.
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+// Set appends a value to the list.
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
 }
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// TestMain runs the command instead of the tests when RESTCLIENT_MAIN is set, so that
+// a test can start it in a child process and check its exit status.
+func TestMain(m *testing.M) {
+	if os.Getenv("RESTCLIENT_MAIN") != "" {
+		main()
+		exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runCommand runs restclient with args in dir and returns its exit status and output.
+func runCommand(t *testing.T, dir string, args ...string) (int, string) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "RESTCLIENT_MAIN=1", "NO_COLOR=1")
+	out, err := cmd.CombinedOutput()
+	var exitErr *exec.ExitError
+	switch {
+	case errors.As(err, &exitErr):
+		return exitErr.ExitCode(), string(out)
+	case err != nil:
+		t.Fatal(err)
+	}
+	return 0, string(out)
+}
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestValidateOnlyExitStatus(t *testing.T) {
+	dir := t.TempDir()
+	spec := writeFile(t, dir, "spec.json", `{"openapi": "3.0.3", "paths": {"/users": {"get": {}}}}`)
+	tests := []struct {
+		url    string
+		status int
+	}{
+		{url: "http://127.0.0.1:1/users", status: 0},
+		{url: "http://127.0.0.1:1/orders", status: 1},
+	}
+	for _, tt := range tests {
+		status, out := runCommand(t, dir, "--url", tt.url, "--openapi", spec, "--validate-only")
+		if status != tt.status {
+			t.Errorf("--validate-only of %s exited with %d, want %d:\n%s", tt.url, status, tt.status, out)
+		}
+	}
+}
+
+func TestExitStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+	dir := t.TempDir()
+	failingSetup := writeFile(t, dir, "setup.txt", "POST "+srv.URL+"/fail\n")
+
+	tests := []struct {
+		name   string
+		args   []string
+		status int
+	}{
+		{name: "passing run", args: []string{"--url", srv.URL + "/ok", "--threshold", "error_rate<1%"}, status: 0},
+		{name: "failed threshold", args: []string{"--url", srv.URL + "/fail", "--threshold", "error_rate<1%"}, status: 1},
+		{name: "failed setup", args: []string{"--url", srv.URL + "/ok", "--setup", failingSetup, "--threshold", "error_rate<1%"}, status: 1},
+		{name: "missing data file", args: []string{"--url", srv.URL + "/ok", "--data", filepath.Join(dir, "missing.csv")}, status: 1},
+		{name: "invalid threshold", args: []string{"--url", srv.URL + "/ok", "--threshold", "p99<"}, status: 2},
+		{name: "invalid flag value", args: []string{"--url", srv.URL + "/ok", "--retries", "-1"}, status: 2},
+		{name: "missing URL", args: nil, status: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append([]string{"--requests", "2", "--concurrency", "1"}, tt.args...)
+			if status, out := runCommand(t, dir, args...); status != tt.status {
+				t.Errorf("exit status %d, want %d:\n%s", status, tt.status, out)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+
+	"github.com/fatih/color"
+)
+
+// plainSymbols are the emojis spelled out in plain output. Other emojis are dropped.
+var plainSymbols = map[rune]string{
+	'❌': "[error]",
+	'⛔': "[error]",
+	'⚠': "[warn]",
+	'🚨': "[alert]",
+	'✅': "[ok]",
+}
+
+// plainText replaces the other non-ASCII symbols of the output, such as the µ of
+// durations.
+var plainText = map[rune]string{
+	'µ': "u",
+	'×': "x",
+	'→': "->",
+	'…': "...",
+	'●': "*",
+}
+
+// restoreOutput flushes the plain output and puts the standard output back, when plain
+// output is on. exit calls it, since os.Exit skips deferred calls.
+var restoreOutput = func() {}
+
+// plain is set once plainOutput is on.
+var plain bool
+
+// exit flushes the output and exits with code.
+func exit(code int) {
+	restoreOutput()
+	os.Exit(code)
+}
+
+// plainOutput turns off colors and routes the standard output through a filter that
+// removes emojis, so that logs of CI runners and cron jobs hold plain ASCII text.
+func plainOutput() {
+	color.NoColor = true
+	if plain {
+		return
+	}
+	plain = true
+	stdout := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		color.Red("❌ Error setting up plain output: %v", err)
+		return
+	}
+	os.Stdout, color.Output = w, w
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		copyPlain(stdout, r)
+	}()
+	restoreOutput = func() {
+		w.Close()
+		<-done
+		os.Stdout, color.Output = stdout, stdout
+		restoreOutput = func() {}
+	}
+}
+
+// reportsMoved is set once reportsToStderr moved the console output.
+var reportsMoved bool
+
+// reportsToStderr moves the console output, plain or not, to the standard error, so that
+// the standard output only carries the lines of --stream -.
+func reportsToStderr() {
+	if reportsMoved {
+		return
+	}
+	reportsMoved = true
+	wasPlain := plain
+	restoreOutput()
+	os.Stdout, color.Output = os.Stderr, os.Stderr
+	if wasPlain {
+		plain = false
+		plainOutput()
+	}
+}
+
+// copyPlain copies src to dst without emojis: those of plainSymbols are spelled out,
+// the others are removed with the spaces that follow them.
+func copyPlain(dst io.Writer, src io.Reader) {
+	in := bufio.NewReader(src)
+	out := bufio.NewWriter(dst)
+	// A spelled-out symbol is followed by a single space, unless it ends the line.
+	skipSpaces, space := false, false
+	for {
+		r, _, err := in.ReadRune()
+		if err != nil {
+			out.Flush()
+			return
+		}
+		switch {
+		case r == '\uFE0F' || r == '\u200D':
+			// Variation selectors and joiners belong to the emoji before them.
+		case plainSymbols[r] != "":
+			out.WriteString(plainSymbols[r])
+			skipSpaces, space = true, true
+		case isEmoji(r):
+			skipSpaces = true
+		case r == ' ' && skipSpaces:
+		default:
+			if space && r != '\n' {
+				out.WriteByte(' ')
+			}
+			skipSpaces, space = false, false
+			if text, ok := plainText[r]; ok {
+				out.WriteString(text)
+			} else {
+				out.WriteRune(r)
+			}
+		}
+		if in.Buffered() == 0 {
+			out.Flush()
+		}
+	}
+}
+
+// isEmoji reports whether r is a pictograph or a symbol commonly rendered as one.
+func isEmoji(r rune) bool {
+	return (r >= 0x1F000 && r <= 0x1FAFF) || (r >= 0x2300 && r <= 0x23FF) ||
+		(r >= 0x2600 && r <= 0x27BF) || (r >= 0x2B00 && r <= 0x2BFF) || r == 0x21AA
+}
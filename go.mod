@@ -2,10 +2,14 @@ module github.com/mayckol/rest-client
 
 go 1.22.1
 
-require github.com/fatih/color v1.17.0
+require (
+	github.com/andybalholm/brotli v1.2.5
+	github.com/fatih/color v1.17.0
+	github.com/joho/godotenv v1.5.1
+	github.com/yuin/gopher-lua v1.1.1
+)
 
 require (
-	github.com/joho/godotenv v1.5.1 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
 	golang.org/x/sys v0.24.0 // indirect
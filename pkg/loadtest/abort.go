@@ -0,0 +1,63 @@
+package loadtest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// errorRateGuard tracks the error rate over the most recent requests, so a run against
+// a broken deployment can be aborted instead of hammering it for the full duration.
+// Network errors and 4xx/5xx responses count as errors; skipped and cancelled requests
+// are ignored.
+type errorRateGuard struct {
+	threshold float64
+	window    []bool
+	next      int
+	filled    bool
+	errors    int
+}
+
+// newErrorRateGuard returns a guard tripping when more than threshold (a ratio between
+// 0 and 1) of the last window requests failed.
+func newErrorRateGuard(threshold float64, window int) *errorRateGuard {
+	return &errorRateGuard{threshold: threshold, window: make([]bool, window)}
+}
+
+// add records res and reports whether the rolling error rate exceeds the threshold. The
+// guard only trips once the window is full.
+func (g *errorRateGuard) add(res requestResult) bool {
+	if res.skipped() || res.drainCancelled {
+		return false
+	}
+	failed := res.statusCode == -1 || res.statusCode >= 400
+	if g.window[g.next] {
+		g.errors--
+	}
+	g.window[g.next] = failed
+	if failed {
+		g.errors++
+	}
+	g.next = (g.next + 1) % len(g.window)
+	if g.next == 0 {
+		g.filled = true
+	}
+	return g.filled && g.rate() > g.threshold
+}
+
+// rate returns the error rate over the window.
+func (g *errorRateGuard) rate() float64 {
+	return float64(g.errors) / float64(len(g.window))
+}
+
+// ParsePercentage parses a percentage such as "10%" or "2.5" into a ratio between 0 and 1.
+func ParsePercentage(value string) (float64, error) {
+	percent, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(value), "%")), 64)
+	if err != nil {
+		return 0, err
+	}
+	if percent <= 0 || percent > 100 {
+		return 0, fmt.Errorf("%q is not between 0 and 100%%", value)
+	}
+	return percent / 100, nil
+}
@@ -0,0 +1,131 @@
+package loadtest
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// arrivalSchedule varies the arrival rate of an open-model run over its measured phase,
+// and names the window of the report every arrival belongs to.
+type arrivalSchedule interface {
+	rateAt(offset time.Duration) float64
+	window(offset time.Duration) string
+}
+
+// arrivalPlan is the schedule of an open-model run: requests start at a constant rate, or
+// at the rates of a schedule, whether or not the previous ones have completed, on at most
+// maxVUs virtual users at once. Up to maxQueue arrivals finding them all busy wait for one
+// to be idle. requests is the number of measured requests to start, or -1 to go on until
+// the run stops.
+type arrivalPlan struct {
+	rate      float64
+	schedule  arrivalSchedule
+	maxVUs    int
+	maxQueue  int
+	requests  int
+	warmupEnd time.Time
+}
+
+// arrival is a request of an open-model run, waiting for a virtual user.
+type arrival struct {
+	request   int
+	warmup    bool
+	scheduled time.Time
+	window    string
+}
+
+// dropped returns the result of an arrival that was never sent.
+func (a arrival) dropped() requestResult {
+	return requestResult{arrivalDropped: true, warmup: a.warmup, completed: a.scheduled, window: a.window}
+}
+
+// runArrivals starts the requests of plan, each on an idle virtual user, and sends their
+// results. Virtual users are set up with newVU as the load needs them, up to maxVUs. An
+// arrival finding all of them busy waits in the queue of plan.maxQueue arrivals, or, once
+// the queue is full, is shed: it is not sent late, which would hide the slowdown the way
+// closed-loop workers do, but dropped and reported as such. Memory stays bounded however
+// far behind the target falls.
+func runArrivals(run *runControl, plan arrivalPlan, newVU func(int) (*worker, func()), send func(*worker, int, bool) requestResult, results chan<- requestResult) {
+	queue := make(chan arrival, plan.maxQueue)
+	var releases []func()
+	spawned, started := 0, 0
+	var busy atomic.Int64
+	var inFlight sync.WaitGroup
+	var generatorDone atomic.Bool
+	defer func() {
+		close(queue)
+		inFlight.Wait()
+		for _, release := range releases {
+			release()
+		}
+	}()
+
+	// serve sends the arrivals of the queue on w until the queue is closed. With a queue,
+	// the latency of an arrival includes its wait in it, since that is what a user would
+	// see.
+	serve := func(w *worker) {
+		defer inFlight.Done()
+		for a := range queue {
+			if generatorDone.Load() {
+				continue
+			}
+			if run.stopped() {
+				results <- a.dropped()
+				continue
+			}
+			busy.Add(1)
+			wait := time.Since(a.scheduled)
+			res := send(w, a.request, a.warmup)
+			busy.Add(-1)
+			if res.generatorDone {
+				generatorDone.Store(true)
+				continue
+			}
+			if plan.maxQueue > 0 && wait > 0 && !res.skipped() {
+				res.latency += wait
+			}
+			res.drained = run.stopped() && !res.drainCancelled
+			res.window = a.window
+			results <- res
+		}
+	}
+
+	next := time.Now()
+	for j, measured := 0, 0; plan.requests < 0 || measured < plan.requests; j++ {
+		run.pause(time.Until(next))
+		if run.stopped() || generatorDone.Load() {
+			return
+		}
+		a := arrival{request: j, warmup: next.Before(plan.warmupEnd), scheduled: next}
+		if !a.warmup {
+			measured++
+		}
+		rate := plan.rate
+		if plan.schedule != nil {
+			offset := a.scheduled.Sub(plan.warmupEnd)
+			rate, a.window = plan.schedule.rateAt(offset), plan.schedule.window(offset)
+		}
+		next = next.Add(time.Duration(float64(time.Second) / rate))
+
+		// A new virtual user is set up when none is idle for the arrival, so that arrivals
+		// only queue once there are maxVUs of them.
+		if spawned < plan.maxVUs && int64(started)-busy.Load() <= int64(len(queue)) {
+			w, release := newVU(spawned)
+			spawned++
+			if w != nil {
+				releases = append(releases, release)
+				started++
+				inFlight.Add(1)
+				go serve(w)
+				queue <- a
+				continue
+			}
+		}
+		select {
+		case queue <- a:
+		default:
+			results <- a.dropped()
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package loadtest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// concurrencyServer answers every request after delay and records the largest number of
+// requests it served at once.
+func concurrencyServer(delay time.Duration) (*httptest.Server, *atomic.Int64) {
+	var current, peak atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := current.Add(1)
+		defer current.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(delay)
+	}))
+	return srv, &peak
+}
+
+func TestArrivalRate(t *testing.T) {
+	tests := []struct {
+		name        string
+		delay       time.Duration
+		concurrency int
+		// minPeak is the least number of requests in flight at once: arrivals do not wait
+		// for the previous requests to complete.
+		minPeak int64
+		// maxPeak is the most: no more requests than virtual users are in flight.
+		maxPeak int64
+		dropped bool
+	}{
+		{name: "fast target", delay: 0, concurrency: 10, minPeak: 1, maxPeak: 10},
+		{name: "slow target", delay: 100 * time.Millisecond, concurrency: 50, minPeak: 5, maxPeak: 50},
+		{name: "all virtual users busy", delay: 200 * time.Millisecond, concurrency: 2, minPeak: 2, maxPeak: 2, dropped: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, peak := concurrencyServer(tt.delay)
+			defer srv.Close()
+
+			start := time.Now()
+			summary, err := New(
+				WithConfig(Config{SummaryOnly: true, ArrivalRate: 100}),
+				WithURL(srv.URL),
+				WithConcurrency(tt.concurrency),
+				WithRequests(50),
+			).Run(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			elapsed := time.Since(start)
+
+			if summary.Requests+summary.Skipped != 50 {
+				t.Errorf("Requests = %d, Skipped = %d, want 50 arrivals", summary.Requests, summary.Skipped)
+			}
+			if tt.dropped && (summary.Skipped < 30 || summary.Requests < 2) {
+				t.Errorf("Requests = %d, Skipped = %d, want most arrivals dropped while both virtual users are busy", summary.Requests, summary.Skipped)
+			}
+			if !tt.dropped && (summary.Skipped != 0 || summary.Successful != 50) {
+				t.Errorf("Successful = %d, Skipped = %d, want every arrival sent", summary.Successful, summary.Skipped)
+			}
+			if p := peak.Load(); p < tt.minPeak || p > tt.maxPeak {
+				t.Errorf("%d requests in flight at once, want %d to %d", p, tt.minPeak, tt.maxPeak)
+			}
+			// 50 arrivals at 100 per second take half a second, however long the requests take.
+			if elapsed < 450*time.Millisecond || elapsed > 450*time.Millisecond+tt.delay+time.Second {
+				t.Errorf("the run took %v, want about 500ms plus the latency of the last request", elapsed)
+			}
+		})
+	}
+}
+
+func TestMaxQueue(t *testing.T) {
+	srv, peak := concurrencyServer(200 * time.Millisecond)
+	defer srv.Close()
+
+	summary, err := New(
+		WithConfig(Config{SummaryOnly: true, ArrivalRate: 100, MaxQueue: 5}),
+		WithURL(srv.URL),
+		WithConcurrency(2),
+		WithRequests(50),
+	).Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Requests+summary.Skipped != 50 || summary.Skipped == 0 {
+		t.Errorf("Requests = %d, Skipped = %d, want 50 arrivals with the overflow of the queue shed", summary.Requests, summary.Skipped)
+	}
+	// Two virtual users send five requests in half a second, and the five queued arrivals
+	// after it.
+	if summary.Requests < 7 || summary.Successful != summary.Requests {
+		t.Errorf("Requests = %d, Successful = %d, want the queued arrivals sent", summary.Requests, summary.Successful)
+	}
+	if p := peak.Load(); p > 2 {
+		t.Errorf("%d requests in flight at once, want at most the 2 virtual users", p)
+	}
+	// A queued arrival waits for a virtual user for at least one request.
+	if summary.MaxLatencyMs < 350 {
+		t.Errorf("MaxLatencyMs = %v, want the wait in the queue counted", summary.MaxLatencyMs)
+	}
+}
@@ -0,0 +1,101 @@
+package loadtest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/fatih/color"
+)
+
+// BaselineTolerances are the regressions allowed against a baseline, as ratios: latency
+// may grow by up to latency and throughput drop by up to throughput.
+type BaselineTolerances struct {
+	Latency    float64
+	Throughput float64
+}
+
+// ReadBaseline reads a run saved with --save-baseline or --report-json.
+func ReadBaseline(path string) (Report, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return Report{}, err
+	}
+	var report Report
+	if err := json.Unmarshal(content, &report); err != nil {
+		return Report{}, err
+	}
+	if report.Summary.Requests == 0 {
+		return Report{}, errors.New("the baseline has no requests")
+	}
+	return report, nil
+}
+
+// baselineMetric is a headline number compared against the baseline. Latencies regress
+// when they grow, throughput when it drops.
+type baselineMetric struct {
+	name    string
+	value   func(Result) float64
+	latency bool
+}
+
+// baselineMetrics are the numbers compared against the baseline, in report order.
+var baselineMetrics = []baselineMetric{
+	{"avg", func(s Result) float64 { return s.AvgLatencyMs }, true},
+	{"p50", func(s Result) float64 { return s.P50LatencyMs }, true},
+	{"p90", func(s Result) float64 { return s.P90LatencyMs }, true},
+	{"p95", func(s Result) float64 { return s.P95LatencyMs }, true},
+	{"p99", func(s Result) float64 { return s.P99LatencyMs }, true},
+	{"rps", func(s Result) float64 { return s.RequestsPerSecond }, false},
+}
+
+// CompareBaseline prints the change of every metric of the current run against the
+// baseline at path and flags the ones beyond tolerances. It reports whether the run
+// has no regression.
+func CompareBaseline(path string, baseline Report, current Result, cfg Config, tolerances BaselineTolerances) bool {
+	color.Green("\n===== 📊 Baseline Comparison =====")
+	if m, err := newManifest(cfg); err == nil && baseline.Manifest.Config != "" && m.Config != baseline.Manifest.Config {
+		color.Yellow("⚠️  %s was recorded with different settings or inputs; check them with verify-run.", path)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Metric\tBaseline\tCurrent\tChange\t")
+	passed := true
+	for _, metric := range baselineMetrics {
+		was, now := metric.value(baseline.Summary), metric.value(current)
+		change := 0.0
+		if was > 0 {
+			change = (now - was) / was
+		}
+		regressed := change > tolerances.Latency
+		if !metric.latency {
+			regressed = -change > tolerances.Throughput
+		}
+		verdict := ""
+		if regressed {
+			verdict = "❌ regression"
+			passed = false
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%+.1f%%\t%s\n", metric.name, formatMetric(metric, was), formatMetric(metric, now), change*100, verdict)
+	}
+	tw.Flush()
+
+	if passed {
+		color.Cyan("✅ No regression against %s (latency tolerance %.1f%%, throughput tolerance %.1f%%)",
+			path, tolerances.Latency*100, tolerances.Throughput*100)
+	} else {
+		color.Red("❌ Regression against %s (latency tolerance %.1f%%, throughput tolerance %.1f%%)",
+			path, tolerances.Latency*100, tolerances.Throughput*100)
+	}
+	return passed
+}
+
+// formatMetric renders the value of a baseline metric.
+func formatMetric(metric baselineMetric, value float64) string {
+	if metric.latency {
+		return formatMs(value)
+	}
+	return fmt.Sprintf("%.2f", value)
+}
@@ -0,0 +1,219 @@
+package loadtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	"github.com/fatih/color"
+)
+
+// BodyAssertion is a check run against response bodies: either a substring the body must
+// contain, or a JSONPath expression such as `$.status == "ok"` the decoded body must
+// satisfy. A JSONPath without an operator only requires the field to exist.
+type BodyAssertion struct {
+	name      string
+	contains  string
+	jsonCheck bool
+	jsonPath  string
+	op        string
+	want      interface{}
+}
+
+// assertionOperators are the comparison operators of a JSONPath assertion, two-character
+// ones first so they are matched before their prefixes.
+var assertionOperators = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+// jsonPathIndex matches the bracketed segments of a JSONPath: an array index or a quoted
+// member name.
+var jsonPathIndex = regexp.MustCompile(`\[(\d+|'[^']*'|"[^"]*")\]`)
+
+// NewContainsAssertion returns an assertion requiring bodies to contain s.
+func NewContainsAssertion(s string) BodyAssertion {
+	return BodyAssertion{name: fmt.Sprintf("body contains %q", s), contains: s}
+}
+
+// ParseJSONPathAssertion parses an expression of the form <path> [<operator> <value>],
+// where the path starts with $ and the value is a JSON literal, e.g. `$.status == "ok"`,
+// `$.items[0].price > 10` or `$.data.id`.
+func ParseJSONPathAssertion(expr string) (BodyAssertion, error) {
+	a := BodyAssertion{name: strings.TrimSpace(expr), jsonCheck: true}
+	path := a.name
+	if i, op := findOperator(expr); i >= 0 {
+		path = strings.TrimSpace(expr[:i])
+		a.op = op
+		literal := strings.TrimSpace(expr[i+len(op):])
+		if err := json.Unmarshal([]byte(literal), &a.want); err != nil {
+			return BodyAssertion{}, fmt.Errorf("the value %s of %q is not a JSON literal", literal, expr)
+		}
+		if _, number := a.want.(float64); (op != "==" && op != "!=") && !number {
+			if _, text := a.want.(string); !text {
+				return BodyAssertion{}, fmt.Errorf("%s only compares numbers and strings in %q", op, expr)
+			}
+		}
+	}
+	dotted, err := dottedJSONPath(path)
+	if err != nil {
+		return BodyAssertion{}, fmt.Errorf("invalid path in %q: %v", expr, err)
+	}
+	a.jsonPath = dotted
+	return a, nil
+}
+
+// findOperator returns the position and the operator of the first comparison in expr
+// outside of quoted strings, or -1.
+func findOperator(expr string) (int, string) {
+	var quote byte
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		default:
+			for _, op := range assertionOperators {
+				if strings.HasPrefix(expr[i:], op) {
+					return i, op
+				}
+			}
+		}
+	}
+	return -1, ""
+}
+
+// dottedJSONPath converts a JSONPath such as $.data.items[0]['id'] into the dotted form
+// of lookupJSONPath, "data.items.0.id". The root $ converts to an empty path.
+func dottedJSONPath(path string) (string, error) {
+	rest, ok := strings.CutPrefix(path, "$")
+	if !ok {
+		return "", fmt.Errorf("%q does not start with $", path)
+	}
+	rest = jsonPathIndex.ReplaceAllStringFunc(rest, func(segment string) string {
+		return "." + strings.Trim(segment, `[]'"`)
+	})
+	if rest == "" {
+		return "", nil
+	}
+	if !strings.HasPrefix(rest, ".") || strings.Contains(rest, "..") || strings.ContainsAny(rest, "[]*") {
+		return "", fmt.Errorf("%q is not a supported path, expected member names and array indexes", path)
+	}
+	return rest[1:], nil
+}
+
+// checkBody runs every assertion against body and reports which ones passed, in order.
+// The body is decoded once for all JSONPath assertions.
+func checkBody(assertions []BodyAssertion, body []byte) []bool {
+	checks := make([]bool, len(assertions))
+	var doc interface{}
+	decoded, valid := false, false
+	for i, a := range assertions {
+		if !a.jsonCheck {
+			checks[i] = bytes.Contains(body, []byte(a.contains))
+			continue
+		}
+		if !decoded {
+			valid = json.Unmarshal(body, &doc) == nil
+			decoded = true
+		}
+		checks[i] = valid && a.matches(doc)
+	}
+	return checks
+}
+
+// matches reports whether the decoded body doc satisfies the JSONPath assertion.
+func (a BodyAssertion) matches(doc interface{}) bool {
+	value, ok := doc, true
+	if a.jsonPath != "" {
+		value, ok = lookupJSONPath(doc, a.jsonPath)
+	}
+	if !ok {
+		return false
+	}
+	switch a.op {
+	case "":
+		return true
+	case "==":
+		return reflect.DeepEqual(value, a.want)
+	case "!=":
+		return !reflect.DeepEqual(value, a.want)
+	}
+	cmp, comparable := compareJSON(value, a.want)
+	if !comparable {
+		return false
+	}
+	switch a.op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	default:
+		return cmp >= 0
+	}
+}
+
+// compareJSON orders two decoded JSON numbers or strings, returning -1, 0 or 1. It
+// reports false when the values are not both numbers or both strings.
+func compareJSON(a, b interface{}) (int, bool) {
+	switch x := a.(type) {
+	case float64:
+		y, ok := b.(float64)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+		return 0, true
+	case string:
+		y, ok := b.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(x, y), true
+	}
+	return 0, false
+}
+
+// bodySampler picks the responses body assertions run against. Sampled responses are
+// evenly spread: with a rate of 0.1, every tenth response is checked.
+type bodySampler struct {
+	rate  float64
+	count atomic.Int64
+}
+
+// sample reports whether the next response is checked.
+func (s *bodySampler) sample() bool {
+	n := s.count.Add(1)
+	return int64(float64(n)*s.rate) > int64(float64(n-1)*s.rate)
+}
+
+// generateBodyAssertionReport prints how many sampled responses passed and failed every
+// body assertion, given by name.
+func generateBodyAssertionReport(names []string, st *stats) {
+	color.Green("\n===== 🧪 Body Assertions =====")
+	for i, name := range names {
+		passed, failed := 0, 0
+		if i < len(st.checksPassed) {
+			passed, failed = st.checksPassed[i], st.checksFailed[i]
+		}
+		if failed > 0 {
+			color.Red("❌ %s: %d passed, %d failed", name, passed, failed)
+		} else {
+			color.Cyan("✅ %s: %d passed, %d failed", name, passed, failed)
+		}
+	}
+}
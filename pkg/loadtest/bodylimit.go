@@ -0,0 +1,95 @@
+package loadtest
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// byteUnits maps the size suffixes accepted by parseByteSize to their multiplier, longest first.
+var byteUnits = []struct {
+	suffix     string
+	multiplier int64
+}{
+	{"KIB", 1 << 10}, {"MIB", 1 << 20}, {"GIB", 1 << 30},
+	{"KB", 1000}, {"MB", 1000 * 1000}, {"GB", 1000 * 1000 * 1000},
+	{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30},
+	{"B", 1},
+}
+
+// ParseByteSize parses a size such as "512", "64KB", "1MB" or "2MiB". An empty
+// string means no limit and returns 0.
+func ParseByteSize(text string) (int64, error) {
+	value := strings.ToUpper(strings.TrimSpace(text))
+	if value == "" {
+		return 0, nil
+	}
+	multiplier := int64(1)
+	for _, unit := range byteUnits {
+		if strings.HasSuffix(value, unit.suffix) {
+			value = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix))
+			multiplier = unit.multiplier
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", text)
+	}
+	return int64(n * float64(multiplier)), nil
+}
+
+// drainBuffers holds the buffers used to discard response bodies, so draining does not
+// allocate for every request.
+var drainBuffers = sync.Pool{
+	New: func() interface{} {
+		buf := make([]byte, 32*1024)
+		return &buf
+	},
+}
+
+// drain discards everything left in r.
+func drain(r io.Reader) {
+	buf := drainBuffers.Get().(*[]byte)
+	io.CopyBuffer(io.Discard, r, *buf)
+	drainBuffers.Put(buf)
+}
+
+// cappedReader stops reading after limit bytes and reports whether the underlying
+// reader had more to give. A limit of 0 means no cap.
+type cappedReader struct {
+	r         io.Reader
+	remaining int64
+	limited   bool
+	truncated bool
+}
+
+// newCappedReader caps r at limit bytes.
+func newCappedReader(r io.Reader, limit int64) *cappedReader {
+	return &cappedReader{r: r, remaining: limit, limited: limit > 0}
+}
+
+// Read reads from the underlying reader until the cap is reached. Once it is, a single
+// byte is read to find out whether the body was cut short.
+func (c *cappedReader) Read(p []byte) (int, error) {
+	if !c.limited {
+		return c.r.Read(p)
+	}
+	if c.remaining <= 0 {
+		if !c.truncated {
+			var probe [1]byte
+			if n, _ := io.ReadFull(c.r, probe[:]); n > 0 {
+				c.truncated = true
+			}
+		}
+		return 0, io.EOF
+	}
+	if int64(len(p)) > c.remaining {
+		p = p[:c.remaining]
+	}
+	n, err := c.r.Read(p)
+	c.remaining -= int64(n)
+	return n, err
+}
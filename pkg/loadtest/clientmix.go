@@ -0,0 +1,234 @@
+package loadtest
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// clientProfile models a kind of device and network: the bandwidth of its link in both
+// directions, the latency added to every round trip, how long it keeps connections
+// alive and the User-Agent it sends.
+type clientProfile struct {
+	name        string
+	downloadBps int64
+	uploadBps   int64
+	latency     time.Duration
+	keepAlive   bool
+	idleTimeout time.Duration
+	userAgent   string
+}
+
+// clientProfiles are the presets available to --client-mix, by name. Bandwidths are in
+// bits per second and roughly follow the network presets of browser developer tools.
+var clientProfiles = map[string]clientProfile{
+	"mobile-3g": {
+		name:        "mobile-3g",
+		downloadBps: 1_600_000,
+		uploadBps:   750_000,
+		latency:     300 * time.Millisecond,
+		userAgent:   "Mozilla/5.0 (Linux; Android 10; K) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Mobile Safari/537.36",
+	},
+	"mobile-lte": {
+		name:        "mobile-lte",
+		downloadBps: 12_000_000,
+		uploadBps:   5_000_000,
+		latency:     70 * time.Millisecond,
+		keepAlive:   true,
+		idleTimeout: 15 * time.Second,
+		userAgent:   "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1",
+	},
+	"desktop-fiber": {
+		name:        "desktop-fiber",
+		downloadBps: 300_000_000,
+		uploadBps:   100_000_000,
+		latency:     5 * time.Millisecond,
+		keepAlive:   true,
+		idleTimeout: 90 * time.Second,
+		userAgent:   "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+	},
+}
+
+// clientProfileAliases are shorter names accepted for the presets.
+var clientProfileAliases = map[string]string{
+	"desktop": "desktop-fiber",
+}
+
+// ClientShare is a profile of the mix with the percentage of workers using it.
+type ClientShare struct {
+	profile clientProfile
+	percent int
+}
+
+// ParseClientMix parses a comma-separated list of profile:percentage pairs such as
+// "mobile-3g:30,desktop:70". The percentages must add up to 100.
+func ParseClientMix(value string) ([]ClientShare, error) {
+	var shares []ClientShare
+	total := 0
+	for _, item := range SplitList(value) {
+		name, percent, ok := strings.Cut(item, ":")
+		if !ok {
+			return nil, fmt.Errorf("%q is not of the form profile:percentage", item)
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		if alias, ok := clientProfileAliases[name]; ok {
+			name = alias
+		}
+		profile, ok := clientProfiles[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown client profile %q, expected one of %s", name, strings.Join(clientProfileNames(), ", "))
+		}
+		share, err := strconv.Atoi(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(percent), "%")))
+		if err != nil || share <= 0 {
+			return nil, fmt.Errorf("invalid percentage in %q", item)
+		}
+		shares = append(shares, ClientShare{profile: profile, percent: share})
+		total += share
+	}
+	if len(shares) > 0 && total != 100 {
+		return nil, fmt.Errorf("the percentages add up to %d instead of 100", total)
+	}
+	return shares, nil
+}
+
+// clientProfileNames lists the names of the presets in alphabetical order.
+func clientProfileNames() []string {
+	names := make([]string, 0, len(clientProfiles))
+	for name := range clientProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// clientMix is a profile of the mix as used by a run, with its own client so that its
+// connections are shaped and pooled apart from the other profiles.
+type clientMix struct {
+	profile clientProfile
+	percent int
+	client  *http.Client
+}
+
+// newClientMix builds a client for every share of the mix.
+func newClientMix(cfg Config, connLog *connLog, shares []ClientShare) []*clientMix {
+	mix := make([]*clientMix, 0, len(shares))
+	for _, share := range shares {
+		profile := share.profile
+		mix = append(mix, &clientMix{
+			profile: profile,
+			percent: share.percent,
+			client: &http.Client{
+				Transport:     newTransport(cfg, connLog, &profile),
+				Timeout:       cfg.Timeout,
+				CheckRedirect: newRedirectPolicy(cfg),
+			},
+		})
+	}
+	return mix
+}
+
+// mixForWorker returns the profile of the worker id out of workers. Workers are spread
+// over the profiles in order, in proportion to their percentages.
+func mixForWorker(mix []*clientMix, id, workers int) *clientMix {
+	if len(mix) == 0 {
+		return nil
+	}
+	position := (float64(id) + 0.5) * 100 / float64(workers)
+	cumulative := 0
+	for _, m := range mix {
+		cumulative += m.percent
+		if position < float64(cumulative) {
+			return m
+		}
+	}
+	return mix[len(mix)-1]
+}
+
+// wrapDial returns a dial function whose connections are shaped like the network of
+// the profile. Opening a connection costs one round trip of added latency.
+func (p *clientProfile) wrapDial(dial func(ctx context.Context, network, address string) (net.Conn, error)) func(ctx context.Context, network, address string) (net.Conn, error) {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		conn, err := dial(ctx, network, address)
+		if err != nil {
+			return nil, err
+		}
+		select {
+		case <-time.After(p.latency):
+		case <-ctx.Done():
+			conn.Close()
+			return nil, ctx.Err()
+		}
+		return &shapedConn{Conn: conn, profile: p}, nil
+	}
+}
+
+// shapedReadSize caps a single read of a shaped connection so a large response is paced
+// smoothly instead of in bursts.
+const shapedReadSize = 16 << 10
+
+// shapedConn limits the bandwidth of a connection and adds the latency of the profile
+// to every round trip: the first bytes read after a write are held back by it. Reads
+// and writes are paced independently since the transport does them from different
+// goroutines.
+type shapedConn struct {
+	net.Conn
+	profile *clientProfile
+
+	writeMu   sync.Mutex
+	writeFree time.Time
+	readFree  time.Time
+	awaiting  atomic.Bool
+}
+
+// Write sends b once the upload link has had the time to carry it.
+func (c *shapedConn) Write(b []byte) (int, error) {
+	c.writeMu.Lock()
+	c.writeFree = pace(c.writeFree, len(b), c.profile.uploadBps)
+	c.writeMu.Unlock()
+	c.awaiting.Store(true)
+	return c.Conn.Write(b)
+}
+
+// Read receives data, delayed by the round trip latency when it answers a write and
+// paced by the download bandwidth.
+func (c *shapedConn) Read(b []byte) (int, error) {
+	if c.profile.downloadBps > 0 && len(b) > shapedReadSize {
+		b = b[:shapedReadSize]
+	}
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		if c.awaiting.Swap(false) {
+			time.Sleep(c.profile.latency)
+		}
+		c.readFree = pace(c.readFree, n, c.profile.downloadBps)
+	}
+	return n, err
+}
+
+// paceGranularity is the shortest wait pace sleeps for. Shorter waits are carried over
+// to the next transfer, since sleeping for them costs more than they last.
+const paceGranularity = time.Millisecond
+
+// pace waits until a link of bps bits per second that is busy until free has carried n
+// more bytes, and returns when it is free again. A bps of 0 means an unlimited link.
+func pace(free time.Time, n int, bps int64) time.Time {
+	if bps <= 0 {
+		return free
+	}
+	now := time.Now()
+	if free.Before(now) {
+		free = now
+	}
+	free = free.Add(time.Duration(int64(n) * 8 * int64(time.Second) / bps))
+	if wait := time.Until(free); wait > paceGranularity {
+		time.Sleep(wait)
+	}
+	return free
+}
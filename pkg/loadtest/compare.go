@@ -0,0 +1,270 @@
+package loadtest
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// ImportedRun is the summary of a run of restclient or of another load testing tool,
+// converted to a Result. Metrics the tool does not report are NaN, and Successful is -1
+// when the tool does not report it.
+type ImportedRun struct {
+	Tool    string
+	Summary Result
+}
+
+// ImportRun reads the summary of a run from path, detecting its format: a restclient
+// JSON report or baseline, a Vegeta JSON report (vegeta report -type=json), a k6 summary
+// (--summary-export or handleSummary JSON) or the CSV output of hey (hey -o csv).
+func ImportRun(path string) (ImportedRun, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return ImportedRun{}, err
+	}
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(content, &doc); err != nil {
+		if bytes.HasPrefix(bytes.TrimSpace(content), []byte("response-time,")) {
+			return importHey(content)
+		}
+		return ImportedRun{}, fmt.Errorf("%s is neither JSON nor hey CSV output", path)
+	}
+	switch {
+	case doc["summary"] != nil:
+		var report Report
+		if err := json.Unmarshal(content, &report); err != nil {
+			return ImportedRun{}, err
+		}
+		return ImportedRun{Tool: "restclient", Summary: report.Summary}, nil
+	case doc["latencies"] != nil && doc["status_codes"] != nil:
+		return importVegeta(content)
+	case doc["metrics"] != nil:
+		return importK6(doc["metrics"])
+	}
+	return ImportedRun{}, fmt.Errorf("%s is not a restclient, Vegeta or k6 summary", path)
+}
+
+// vegetaReport is the part of a Vegeta JSON report that is compared. Latencies are in
+// nanoseconds and success is a ratio.
+type vegetaReport struct {
+	Latencies struct {
+		Mean  float64 `json:"mean"`
+		P50   float64 `json:"50th"`
+		P90   float64 `json:"90th"`
+		P95   float64 `json:"95th"`
+		P99   float64 `json:"99th"`
+		Max   float64 `json:"max"`
+		Total float64 `json:"total"`
+	} `json:"latencies"`
+	Earliest    time.Time      `json:"earliest"`
+	Duration    float64        `json:"duration"`
+	Requests    int            `json:"requests"`
+	Rate        float64        `json:"rate"`
+	StatusCodes map[string]int `json:"status_codes"`
+}
+
+// importVegeta converts a Vegeta JSON report. Vegeta counts network errors as status 0.
+func importVegeta(content []byte) (ImportedRun, error) {
+	var r vegetaReport
+	if err := json.Unmarshal(content, &r); err != nil {
+		return ImportedRun{}, err
+	}
+	s := Result{
+		StartedAt:         r.Earliest,
+		DurationMs:        r.Duration / 1e6,
+		Requests:          r.Requests,
+		StatusCodes:       make(map[int]int),
+		AvgLatencyMs:      r.Latencies.Mean / 1e6,
+		P50LatencyMs:      r.Latencies.P50 / 1e6,
+		P90LatencyMs:      r.Latencies.P90 / 1e6,
+		P95LatencyMs:      r.Latencies.P95 / 1e6,
+		P99LatencyMs:      r.Latencies.P99 / 1e6,
+		MaxLatencyMs:      r.Latencies.Max / 1e6,
+		RequestsPerSecond: r.Rate,
+	}
+	for code, count := range r.StatusCodes {
+		status, err := strconv.Atoi(code)
+		if err != nil {
+			continue
+		}
+		if status == 0 {
+			s.NetworkErrors += count
+			continue
+		}
+		s.StatusCodes[status] += count
+		if status >= 200 && status < 300 {
+			s.Successful += count
+		}
+	}
+	return ImportedRun{Tool: "vegeta", Summary: s}, nil
+}
+
+// importK6 converts the metrics of a k6 summary. Durations are in milliseconds, and the
+// values of a metric are nested under "values" in handleSummary output. k6 reports no
+// status codes, and no p99 by default.
+func importK6(raw json.RawMessage) (ImportedRun, error) {
+	var metrics map[string]map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &metrics); err != nil {
+		return ImportedRun{}, err
+	}
+	value := func(metric, name string) float64 {
+		values := metrics[metric]
+		if nested, ok := values["values"]; ok {
+			if err := json.Unmarshal(nested, &values); err != nil {
+				return math.NaN()
+			}
+		}
+		var v float64
+		if err := json.Unmarshal(values[name], &v); err != nil {
+			return math.NaN()
+		}
+		return v
+	}
+	if metrics["http_req_duration"] == nil {
+		return ImportedRun{}, errors.New("the k6 summary has no http_req_duration metric")
+	}
+	requests := value("http_reqs", "count")
+	failedRate := value("http_req_failed", "value")
+	if math.IsNaN(failedRate) {
+		failedRate = value("http_req_failed", "rate")
+	}
+	s := Result{
+		Requests:          int(requests),
+		Successful:        int(math.Round(requests * (1 - failedRate))),
+		AvgLatencyMs:      value("http_req_duration", "avg"),
+		P50LatencyMs:      value("http_req_duration", "med"),
+		P90LatencyMs:      value("http_req_duration", "p(90)"),
+		P95LatencyMs:      value("http_req_duration", "p(95)"),
+		P99LatencyMs:      value("http_req_duration", "p(99)"),
+		MaxLatencyMs:      value("http_req_duration", "max"),
+		RequestsPerSecond: value("http_reqs", "rate"),
+	}
+	if math.IsNaN(failedRate) {
+		s.Successful = -1
+	}
+	return ImportedRun{Tool: "k6", Summary: s}, nil
+}
+
+// importHey converts the per-request CSV output of hey, whose response times and offsets
+// are in seconds, computing the summary from the requests.
+func importHey(content []byte) (ImportedRun, error) {
+	rows, err := csv.NewReader(bytes.NewReader(content)).ReadAll()
+	if err != nil {
+		return ImportedRun{}, err
+	}
+	columns := make(map[string]int)
+	for i, name := range rows[0] {
+		columns[name] = i
+	}
+	for _, name := range []string{"response-time", "status-code", "offset"} {
+		if _, ok := columns[name]; !ok {
+			return ImportedRun{}, fmt.Errorf("the hey output has no %s column", name)
+		}
+	}
+	timeCol, statusCol, offsetCol := columns["response-time"], columns["status-code"], columns["offset"]
+
+	s := Result{StatusCodes: make(map[int]int)}
+	var latencies []float64
+	var total, end float64
+	for _, row := range rows[1:] {
+		latency, err1 := strconv.ParseFloat(row[timeCol], 64)
+		status, err2 := strconv.Atoi(row[statusCol])
+		offset, err3 := strconv.ParseFloat(row[offsetCol], 64)
+		if err := errors.Join(err1, err2, err3); err != nil {
+			return ImportedRun{}, fmt.Errorf("invalid hey row %q: %v", strings.Join(row, ","), err)
+		}
+		latencies = append(latencies, latency*1000)
+		total += latency * 1000
+		end = max(end, offset+latency)
+		s.StatusCodes[status]++
+		if status >= 200 && status < 300 {
+			s.Successful++
+		}
+	}
+	if len(latencies) == 0 {
+		return ImportedRun{}, errors.New("the hey output has no requests")
+	}
+	sort.Float64s(latencies)
+	at := func(q float64) float64 {
+		return latencies[max(int(math.Ceil(q*float64(len(latencies))))-1, 0)]
+	}
+	s.Requests = len(latencies)
+	s.DurationMs = end * 1000
+	s.AvgLatencyMs = total / float64(len(latencies))
+	s.P50LatencyMs, s.P90LatencyMs, s.P95LatencyMs, s.P99LatencyMs = at(0.50), at(0.90), at(0.95), at(0.99)
+	s.MaxLatencyMs = latencies[len(latencies)-1]
+	if end > 0 {
+		s.RequestsPerSecond = float64(s.Requests) / end
+	}
+	return ImportedRun{Tool: "hey", Summary: s}, nil
+}
+
+// comparedMetric is a headline number shown side by side for two runs.
+type comparedMetric struct {
+	name   string
+	value  func(Result) float64
+	format func(float64) string
+}
+
+// comparedMetrics are the numbers CompareRuns shows, in order.
+var comparedMetrics = []comparedMetric{
+	{"requests", func(s Result) float64 { return float64(s.Requests) }, func(v float64) string { return strconv.Itoa(int(v)) }},
+	{"success", successRate, func(v float64) string { return fmt.Sprintf("%.2f%%", v) }},
+	{"rps", func(s Result) float64 { return s.RequestsPerSecond }, func(v float64) string { return fmt.Sprintf("%.2f", v) }},
+	{"avg", func(s Result) float64 { return s.AvgLatencyMs }, formatMs},
+	{"p50", func(s Result) float64 { return s.P50LatencyMs }, formatMs},
+	{"p90", func(s Result) float64 { return s.P90LatencyMs }, formatMs},
+	{"p95", func(s Result) float64 { return s.P95LatencyMs }, formatMs},
+	{"p99", func(s Result) float64 { return s.P99LatencyMs }, formatMs},
+	{"max", func(s Result) float64 { return s.MaxLatencyMs }, formatMs},
+}
+
+// successRate returns the percentage of the requests of s with a 2xx response, or NaN
+// when it is unknown.
+func successRate(s Result) float64 {
+	if s.Requests == 0 || s.Successful < 0 {
+		return math.NaN()
+	}
+	return float64(s.Successful) * 100 / float64(s.Requests)
+}
+
+// CompareRuns prints the headline numbers of the runs a and b, read from pathA and
+// pathB, side by side with the change from a to b. Metrics one of the tools does not
+// report are shown as "-".
+func CompareRuns(pathA string, a ImportedRun, pathB string, b ImportedRun) {
+	color.Green("\n===== ⚖️  Run Comparison =====")
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Metric\t%s (%s)\t%s (%s)\tChange\t\n", filepath.Base(pathA), a.Tool, filepath.Base(pathB), b.Tool)
+	for _, metric := range comparedMetrics {
+		was, now := metric.value(a.Summary), metric.value(b.Summary)
+		change := "-"
+		if !math.IsNaN(was) && !math.IsNaN(now) && was != 0 {
+			change = fmt.Sprintf("%+.1f%%", (now-was)*100/was)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t\n", metric.name, formatCompared(metric, was), formatCompared(metric, now), change)
+	}
+	tw.Flush()
+	if a.Tool != b.Tool {
+		color.Yellow("⚠️  Tools measure latency from different points and compute percentiles differently; expect small gaps.")
+	}
+}
+
+// formatCompared renders the value of a compared metric, or "-" when it is unknown.
+func formatCompared(metric comparedMetric, value float64) string {
+	if math.IsNaN(value) {
+		return "-"
+	}
+	return metric.format(value)
+}
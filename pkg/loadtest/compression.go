@@ -0,0 +1,74 @@
+package loadtest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+// Read reads from the underlying reader and counts the bytes returned.
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// responseReader reads a response body, counting both the bytes received on the
+// wire and the bytes after decoding the Content-Encoding.
+type responseReader struct {
+	wire    *countingReader
+	decoded *countingReader
+}
+
+// newResponseReader wraps the body of resp. When decode is true, gzip and brotli
+// encoded bodies are decompressed while reading; this is needed whenever the
+// Accept-Encoding header is set explicitly, because net/http then leaves the body
+// compressed.
+func newResponseReader(resp *http.Response, decode bool) *responseReader {
+	wire := &countingReader{r: resp.Body}
+	var body io.Reader = wire
+	if decode {
+		switch strings.ToLower(resp.Header.Get("Content-Encoding")) {
+		case "gzip":
+			if gz, err := gzip.NewReader(wire); err == nil {
+				body = gz
+			}
+		case "br":
+			body = brotli.NewReader(wire)
+		}
+	}
+	return &responseReader{wire: wire, decoded: &countingReader{r: body}}
+}
+
+// Read reads decoded bytes from the response body.
+func (r *responseReader) Read(p []byte) (int, error) {
+	return r.decoded.Read(p)
+}
+
+// counts returns the number of bytes read on the wire and after decoding.
+func (r *responseReader) counts() (int64, int64) {
+	return r.wire.n, r.decoded.n
+}
+
+// gzipBody compresses a request body.
+func gzipBody(body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(body); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,131 @@
+package loadtest
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Connection lifecycle events written to the connection log.
+const (
+	connOpen  = "open"
+	connReuse = "reuse"
+	connClose = "close"
+	connError = "error"
+)
+
+// connEvent is a single connection lifecycle event as written to the connection log.
+type connEvent struct {
+	Time       time.Time `json:"time"`
+	Event      string    `json:"event"`
+	ConnID     int64     `json:"conn_id,omitempty"`
+	Address    string    `json:"address,omitempty"`
+	LocalAddr  string    `json:"local_addr,omitempty"`
+	RemoteAddr string    `json:"remote_addr,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Requests   int64     `json:"requests,omitempty"`
+	LifetimeMs float64   `json:"lifetime_ms,omitempty"`
+}
+
+// connLog records connection lifecycle events as NDJSON. Events come from the transport
+// and the workers, so writes are serialized.
+type connLog struct {
+	mu     sync.Mutex
+	log    *ndjsonLog
+	closed bool
+	nextID atomic.Int64
+}
+
+// newConnLog creates the connection log file at path.
+func newConnLog(path string, opts OutputOptions, logger *slog.Logger) (*connLog, error) {
+	log, err := newNDJSONLog(path, opts, logger)
+	if err != nil {
+		return nil, err
+	}
+	return &connLog{log: log}, nil
+}
+
+// write appends ev to the log, dropping events that arrive after it was closed.
+func (l *connLog) write(ev connEvent) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return
+	}
+	ev.Time = time.Now()
+	l.log.write(ev)
+}
+
+// wrapDial returns a dial function that logs the outcome of every dial and tracks the
+// connections it opens so their reuse and close are logged too.
+func (l *connLog) wrapDial(dial func(ctx context.Context, network, address string) (net.Conn, error)) func(ctx context.Context, network, address string) (net.Conn, error) {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		conn, err := dial(ctx, network, address)
+		if err != nil {
+			l.write(connEvent{Event: connError, Address: address, Error: err.Error()})
+			return nil, err
+		}
+		tc := &trackedConn{Conn: conn, log: l, id: l.nextID.Add(1), opened: time.Now()}
+		l.write(connEvent{
+			Event:      connOpen,
+			ConnID:     tc.id,
+			Address:    address,
+			LocalAddr:  conn.LocalAddr().String(),
+			RemoteAddr: conn.RemoteAddr().String(),
+		})
+		return tc, nil
+	}
+}
+
+// used counts a request sent on conn, logging a reuse event when the connection was
+// already open.
+func (l *connLog) used(conn net.Conn, reused bool) {
+	if nc, ok := conn.(interface{ NetConn() net.Conn }); ok {
+		conn = nc.NetConn()
+	}
+	tc, ok := conn.(*trackedConn)
+	if !ok {
+		return
+	}
+	tc.requests.Add(1)
+	if !reused {
+		return
+	}
+	l.write(connEvent{Event: connReuse, ConnID: tc.id, RemoteAddr: tc.RemoteAddr().String()})
+}
+
+// close flushes and closes the log file.
+func (l *connLog) close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.closed = true
+	return l.log.close()
+}
+
+// trackedConn is a connection opened through connLog.wrapDial.
+type trackedConn struct {
+	net.Conn
+	log      *connLog
+	id       int64
+	opened   time.Time
+	requests atomic.Int64
+	once     sync.Once
+}
+
+// Close closes the connection and logs its lifetime and the number of requests it served.
+func (c *trackedConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(func() {
+		c.log.write(connEvent{
+			Event:      connClose,
+			ConnID:     c.id,
+			RemoteAddr: c.RemoteAddr().String(),
+			Requests:   c.requests.Load(),
+			LifetimeMs: float64(time.Since(c.opened).Microseconds()) / 1000,
+		})
+	})
+	return err
+}
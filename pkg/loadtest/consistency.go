@@ -0,0 +1,108 @@
+package loadtest
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// readBack is the outcome of the GET reading back the entity a write just created, in
+// the value captured from the write response.
+type readBack struct {
+	status  int
+	latency time.Duration
+}
+
+// notFound reports whether the entity was not visible yet.
+func (r *readBack) notFound() bool {
+	return r.status == http.StatusNotFound
+}
+
+// readAfterWrite waits --read-after-write-delay, then reads back the entity identified by
+// value, the {{feed}} of the --read-after-write URL. It returns nil when the run stopped
+// while waiting or the request could not be built.
+func (e *engine) readAfterWrite(value string, w *worker) *readBack {
+	e.run.pause(e.cfg.ReadAfterWriteDelay)
+	if e.run.stopped() {
+		return nil
+	}
+	scope := newRenderScope(e.feed, w.row, w.random)
+	scope.feedValue, scope.feedTaken = value, true
+	p, _, ok := e.prepareScoped(target{method: http.MethodGet, url: e.cfg.ReadAfterWrite}, w, scope)
+	if !ok {
+		return nil
+	}
+	res := e.send(p, p.url)
+	if res.drainCancelled || res.abandoned {
+		return nil
+	}
+	return &readBack{status: res.statusCode, latency: res.latency}
+}
+
+// readBackStats aggregates the read-backs of a run. Their latencies are kept apart for
+// entities that were found and those that were not found yet.
+type readBackStats struct {
+	checks   int
+	notFound int
+	errors   int
+	found    *latencyHistogram
+	missing  *latencyHistogram
+}
+
+// add records the read-back of a measured result, if it has one, with latencies in the
+// range lr.
+func (s *readBackStats) add(lr histogramRange, res requestResult) {
+	r := res.readBack
+	if r == nil {
+		return
+	}
+	if s.found == nil {
+		s.found, s.missing = newLatencyHistogram(lr), newLatencyHistogram(lr)
+	}
+	s.checks++
+	switch {
+	case r.notFound():
+		s.notFound++
+		s.missing.record(r.latency)
+	case r.status == -1 || r.status >= 300:
+		s.errors++
+	default:
+		s.found.record(r.latency)
+	}
+}
+
+// generateReadAfterWriteReport shows how often an entity read back right after its write
+// was not found yet, a measure of the replication lag of the target under write load.
+func generateReadAfterWriteReport(url string, delay time.Duration, s readBackStats) {
+	color.Green("\n===== 🔁 Read-Your-Writes =====")
+	fmt.Printf("🔗 Read back with GET %s, %v after each write\n", url, delay)
+	if s.checks == 0 {
+		fmt.Println("No write response carried the --feed-capture value, so nothing was read back.")
+		return
+	}
+	fmt.Printf("🔁 Read-backs: %d\n", s.checks)
+	if s.notFound == 0 && s.errors == 0 {
+		color.Cyan("✅ Every written entity was found when read back.")
+	} else {
+		found := s.checks - s.notFound - s.errors
+		fmt.Printf("✅ Found: %d (%.2f%%)\n", found, float64(found)*100/float64(s.checks))
+	}
+	if s.notFound > 0 {
+		color.Red("👻 Not found yet (404): %d (%.2f%%)", s.notFound, float64(s.notFound)*100/float64(s.checks))
+	}
+	if s.errors > 0 {
+		color.Red("❌ Other failures: %d", s.errors)
+	}
+	printReadBackLatencies("Found", s.found)
+	printReadBackLatencies("Not found yet", s.missing)
+}
+
+// printReadBackLatencies prints the latency percentiles of a kind of read-back.
+func printReadBackLatencies(kind string, h *latencyHistogram) {
+	if h.count() == 0 {
+		return
+	}
+	fmt.Printf("⏱️  %s: p50 %v, p95 %v, p99 %v, max %v\n", kind, h.percentile(0.50), h.percentile(0.95), h.percentile(0.99), h.percentile(1))
+}
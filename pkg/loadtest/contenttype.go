@@ -0,0 +1,94 @@
+package loadtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Content types detected for request bodies.
+const (
+	contentTypeJSON = "application/json"
+	contentTypeXML  = "application/xml"
+	contentTypeForm = "application/x-www-form-urlencoded"
+)
+
+// templateActions matches the template actions of a body, replaced by a placeholder
+// before its shape is looked at.
+var templateActions = regexp.MustCompile(`{{.*?}}`)
+
+// detectContentType guesses the Content-Type of a body file from its extension and,
+// failing that, from its first bytes; binary data is application/octet-stream. The body
+// may still be an unrendered template, so only its shape is looked at.
+func detectContentType(path string, body []byte) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return contentTypeJSON
+	case ".xml":
+		return contentTypeXML
+	case ".form":
+		return contentTypeForm
+	}
+	trimmed := bytes.TrimSpace(templateActions.ReplaceAll(body, []byte("x")))
+	switch {
+	case len(trimmed) == 0:
+		return contentTypeJSON
+	case trimmed[0] == '{' || trimmed[0] == '[':
+		return contentTypeJSON
+	case trimmed[0] == '<':
+		return contentTypeXML
+	case looksLikeForm(trimmed):
+		return contentTypeForm
+	}
+	if byExt := mime.TypeByExtension(filepath.Ext(path)); byExt != "" {
+		return byExt
+	}
+	return http.DetectContentType(body)
+}
+
+// looksLikeForm reports whether body is a single line of key=value pairs.
+func looksLikeForm(body []byte) bool {
+	if bytes.ContainsAny(body, " \t\r\n") || !bytes.Contains(body, []byte("=")) {
+		return false
+	}
+	_, err := url.ParseQuery(string(body))
+	return err == nil
+}
+
+// bodyMatchesType reports whether a rendered body is consistent with the declared
+// Content-Type. Only JSON, XML and form bodies are checked; anything else matches.
+func bodyMatchesType(contentType string, body []byte) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch {
+	case isJSONType(mediaType):
+		return json.Valid(body)
+	case mediaType == contentTypeXML || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml"):
+		return bytes.HasPrefix(bytes.TrimSpace(body), []byte("<"))
+	case mediaType == contentTypeForm:
+		return looksLikeForm(bytes.TrimSpace(body))
+	}
+	return true
+}
+
+// isJSONType reports whether contentType is a JSON media type.
+func isJSONType(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType == contentTypeJSON || strings.HasSuffix(mediaType, "+json")
+}
+
+// isTextType reports whether contentType is a textual format that may contain template
+// actions. Other bodies, such as images or protobuf messages, are sent byte for byte.
+func isTextType(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return strings.HasPrefix(mediaType, "text/") || isJSONType(mediaType) ||
+		mediaType == contentTypeXML || strings.HasSuffix(mediaType, "+xml") ||
+		mediaType == contentTypeForm
+}
@@ -0,0 +1,21 @@
+package loadtest
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+)
+
+// Cookie jar scopes: a jar of its own for every virtual user (worker), one jar shared by
+// all of them, or no jar, sending only the cookies set as headers.
+const (
+	CookiesPerVU  = "vu"
+	CookiesShared = "shared"
+	CookiesOff    = "off"
+)
+
+// newCookieJar returns an empty cookie jar. Without a public suffix list, cookies set for
+// a parent domain are only sent back to the host that set them.
+func newCookieJar() http.CookieJar {
+	jar, _ := cookiejar.New(nil)
+	return jar
+}
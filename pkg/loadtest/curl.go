@@ -0,0 +1,358 @@
+package loadtest
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// curlIgnoredOptions are curl options without an effect on the request sent, or whose
+// effect the run already has, such as following redirects.
+var curlIgnoredOptions = map[string]bool{
+	"-s": true, "--silent": true, "-S": true, "--show-error": true, "-v": true, "--verbose": true,
+	"-i": true, "--include": true, "-L": true, "--location": true, "--compressed": true,
+	"-f": true, "--fail": true, "-#": true, "--progress-bar": true, "--http1.1": true, "--http2": true,
+	"-N": true, "--no-buffer": true,
+}
+
+// curlIgnoredArgOptions are ignored curl options taking an argument.
+var curlIgnoredArgOptions = map[string]bool{
+	"-o": true, "--output": true, "-w": true, "--write-out": true, "-m": true, "--max-time": true,
+	"--connect-timeout": true, "--retry": true, "-x": true, "--proxy": true,
+}
+
+// curlArgOptions are the supported curl options taking an argument, by short and long name.
+var curlArgOptions = map[string]string{
+	"-X": "--request", "-H": "--header", "-d": "--data", "-u": "--user", "-A": "--user-agent",
+	"-e": "--referer", "-b": "--cookie", "-F": "--form",
+	"--request": "--request", "--header": "--header", "--data": "--data", "--data-raw": "--data-raw",
+	"--data-binary": "--data-binary", "--data-ascii": "--data", "--data-urlencode": "--data-urlencode",
+	"--json": "--json", "--user": "--user", "--user-agent": "--user-agent", "--referer": "--referer",
+	"--cookie": "--cookie", "--url": "--url", "--form": "--form",
+}
+
+// IsCurlCommand reports whether a --from-curl value is a curl command rather than the
+// path of a file of them.
+func IsCurlCommand(spec string) bool {
+	spec = strings.TrimSpace(spec)
+	return spec == "curl" || strings.HasPrefix(spec, "curl ") || strings.HasPrefix(spec, "curl\t")
+}
+
+// LoadCurl converts curl commands into targets sharing the traffic evenly: spec is either a
+// single command, such as one copied from the network tab of a browser, or the path of a
+// file of commands, one per line or continued over lines ending with a backslash. The
+// method, URL, headers, basic authentication, cookies and data options of the commands are
+// kept; options affecting only the output of curl are ignored. @file data is read
+// relative to the file of commands.
+func LoadCurl(spec string, rawBodies bool) ([]WeightedTarget, error) {
+	text, dir := spec, ""
+	if !IsCurlCommand(spec) {
+		content, err := os.ReadFile(spec)
+		if err != nil {
+			return nil, err
+		}
+		text, dir = string(content), filepath.Dir(spec)
+	}
+	commands, err := splitShellCommands(text)
+	if err != nil {
+		return nil, err
+	}
+	var targets []WeightedTarget
+	for i, words := range commands {
+		t, err := parseCurl(words, dir, rawBodies)
+		if err != nil {
+			if dir == "" {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%s: command %d: %w", spec, i+1, err)
+		}
+		targets = append(targets, t)
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("%s: no curl command", spec)
+	}
+	return targets, nil
+}
+
+// splitShortOptions splits combined short options, as in -sSL, into one word each. The
+// first one taking an argument ends the group, the rest of the word being its argument,
+// as in -sXPOST.
+func splitShortOptions(word string) []string {
+	var split []string
+	for i := 1; i < len(word); i++ {
+		option := "-" + word[i:i+1]
+		split = append(split, option)
+		if curlArgOptions[option] != "" || curlIgnoredArgOptions[option] {
+			if i+1 < len(word) {
+				split = append(split, word[i+1:])
+			}
+			break
+		}
+	}
+	return split
+}
+
+// parseCurl converts the words of a curl command into a target.
+func parseCurl(words []string, dir string, rawBodies bool) (WeightedTarget, error) {
+	if words[0] != "curl" {
+		return WeightedTarget{}, fmt.Errorf("expected a curl command, got %q", words[0])
+	}
+	var method, rawURL, contentType string
+	var headerLines, data []string
+	get, head, insecure := false, false, false
+	for i := 1; i < len(words); i++ {
+		if word := words[i]; len(word) > 2 && word[0] == '-' && word[1] != '-' {
+			words = append(words[:i:i], append(splitShortOptions(word), words[i+1:]...)...)
+		}
+		option, value := words[i], ""
+		long := curlArgOptions[option]
+		if long == "" && !curlIgnoredArgOptions[option] {
+			switch {
+			case option == "-G" || option == "--get":
+				get = true
+			case option == "-I" || option == "--head":
+				head = true
+			case option == "-k" || option == "--insecure":
+				insecure = true
+			case curlIgnoredOptions[option]:
+			case strings.HasPrefix(option, "-"):
+				return WeightedTarget{}, fmt.Errorf("unsupported curl option %s", option)
+			case rawURL != "":
+				return WeightedTarget{}, fmt.Errorf("several URLs in one command: %s and %s", rawURL, option)
+			default:
+				rawURL = option
+			}
+			continue
+		}
+		if i+1 == len(words) {
+			return WeightedTarget{}, fmt.Errorf("curl option %s requires an argument", option)
+		}
+		i++
+		value = words[i]
+		switch long {
+		case "":
+		case "--request":
+			method = strings.ToUpper(value)
+		case "--header":
+			headerLines = append(headerLines, value)
+		case "--data", "--data-raw", "--data-binary", "--json":
+			if strings.HasPrefix(value, "@") && long != "--data-raw" {
+				content, err := os.ReadFile(curlPath(dir, value[1:]))
+				if err != nil {
+					return WeightedTarget{}, err
+				}
+				value = string(content)
+				if long == "--data" {
+					// Like curl, -d drops the line breaks of the file.
+					value = strings.NewReplacer("\r", "", "\n", "").Replace(value)
+				}
+			}
+			data = append(data, value)
+			if long == "--json" {
+				contentType = "application/json"
+				headerLines = append(headerLines, "Accept: application/json")
+			}
+		case "--data-urlencode":
+			name, content, ok := strings.Cut(value, "=")
+			if !ok {
+				data = append(data, url.QueryEscape(value))
+			} else if name == "" {
+				data = append(data, url.QueryEscape(content))
+			} else {
+				data = append(data, name+"="+url.QueryEscape(content))
+			}
+		case "--user":
+			headerLines = append(headerLines, "Authorization: Basic "+base64.StdEncoding.EncodeToString([]byte(value)))
+		case "--user-agent":
+			headerLines = append(headerLines, "User-Agent: "+value)
+		case "--referer":
+			headerLines = append(headerLines, "Referer: "+value)
+		case "--cookie":
+			if !strings.Contains(value, "=") {
+				return WeightedTarget{}, fmt.Errorf("cookie files are not supported, pass the cookies as name=value")
+			}
+			headerLines = append(headerLines, "Cookie: "+value)
+		case "--url":
+			rawURL = value
+		case "--form":
+			return WeightedTarget{}, fmt.Errorf("multipart forms (%s) are not supported, use --file and --form-field", option)
+		}
+	}
+	if rawURL == "" {
+		return WeightedTarget{}, fmt.Errorf("the curl command has no URL")
+	}
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "http://" + rawURL
+	}
+
+	body := strings.Join(data, "&")
+	switch {
+	case method != "":
+	case head:
+		method = "HEAD"
+	case len(data) > 0 && !get:
+		method = "POST"
+	default:
+		method = "GET"
+	}
+	if get && len(data) > 0 {
+		separator := "?"
+		if strings.Contains(rawURL, "?") {
+			separator = "&"
+		}
+		rawURL, data = rawURL+separator+body, nil
+	}
+
+	t := WeightedTarget{target: target{name: method + " " + rawURL, method: method, url: rawURL}, weight: 1}
+	t.options.insecure = insecure
+	if len(data) > 0 {
+		for _, line := range headerLines {
+			if name, value, ok := strings.Cut(line, ":"); ok && strings.EqualFold(strings.TrimSpace(name), "Content-Type") {
+				contentType = strings.TrimSpace(value)
+			}
+		}
+		if contentType == "" {
+			contentType = contentTypeForm
+		}
+		if err := t.setBody(t.name, []byte(body), contentType, rawBodies); err != nil {
+			return WeightedTarget{}, err
+		}
+	}
+	headers, err := parseHeaders(headerLines)
+	if err != nil {
+		return WeightedTarget{}, err
+	}
+	t.headers = headers
+	return t, nil
+}
+
+// curlPath returns the path of an @file argument, relative to dir.
+func curlPath(dir, path string) string {
+	if dir == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// splitShellCommands splits text into commands and their words the way a POSIX shell
+// does for simple commands: commands end at unquoted line breaks or semicolons, and
+// single quotes, double quotes, $'...' strings and backslashes are honored, including
+// backslashes continuing a command on the next line. Comments start with an unquoted #.
+func splitShellCommands(text string) ([][]string, error) {
+	var commands [][]string
+	var words []string
+	var word strings.Builder
+	inWord := false
+	endWord := func() {
+		if inWord {
+			words = append(words, word.String())
+			word.Reset()
+			inWord = false
+		}
+	}
+	endCommand := func() {
+		endWord()
+		if len(words) > 0 {
+			commands = append(commands, words)
+			words = nil
+		}
+	}
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case c == '\\':
+			i++
+			if i == len(runes) {
+				break
+			}
+			if runes[i] == '\n' {
+				continue
+			}
+			if runes[i] == '\r' && i+1 < len(runes) && runes[i+1] == '\n' {
+				i++
+				continue
+			}
+			word.WriteRune(runes[i])
+			inWord = true
+		case c == '\'':
+			end := indexRune(runes, i+1, '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated single quote")
+			}
+			word.WriteString(string(runes[i+1 : end]))
+			i, inWord = end, true
+		case c == '$' && i+1 < len(runes) && runes[i+1] == '\'':
+			i += 2
+			for ; i < len(runes) && runes[i] != '\''; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+					word.WriteString(ansiEscape(runes[i]))
+					continue
+				}
+				word.WriteRune(runes[i])
+			}
+			if i == len(runes) {
+				return nil, fmt.Errorf("unterminated $' quote")
+			}
+			inWord = true
+		case c == '"':
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) && strings.ContainsRune("$`\"\\\n", runes[i+1]) {
+					i++
+					if runes[i] == '\n' {
+						continue
+					}
+				}
+				word.WriteRune(runes[i])
+			}
+			if i == len(runes) {
+				return nil, fmt.Errorf("unterminated double quote")
+			}
+			inWord = true
+		case c == '#' && !inWord:
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			endCommand()
+		case c == '\n' || c == ';':
+			endCommand()
+		case c == ' ' || c == '\t' || c == '\r':
+			endWord()
+		default:
+			word.WriteRune(c)
+			inWord = true
+		}
+	}
+	endCommand()
+	return commands, nil
+}
+
+// indexRune returns the index of the first r in runes from start, or -1.
+func indexRune(runes []rune, start int, r rune) int {
+	for i := start; i < len(runes); i++ {
+		if runes[i] == r {
+			return i
+		}
+	}
+	return -1
+}
+
+// ansiEscape returns the character a backslash escape of a $'...' string stands for.
+func ansiEscape(c rune) string {
+	switch c {
+	case 'n':
+		return "\n"
+	case 't':
+		return "\t"
+	case 'r':
+		return "\r"
+	case '0':
+		return "\x00"
+	}
+	return string(c)
+}
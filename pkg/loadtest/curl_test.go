@@ -0,0 +1,173 @@
+package loadtest
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadCurl(t *testing.T) {
+	tests := []struct {
+		command  string
+		method   string
+		url      string
+		headers  map[string]string
+		body     string
+		bodyType string
+		insecure bool
+	}{
+		{
+			command: "curl https://api.example.com/items",
+			method:  "GET",
+			url:     "https://api.example.com/items",
+		},
+		{
+			command: "curl example.com/health",
+			method:  "GET",
+			url:     "http://example.com/health",
+		},
+		{
+			command:  `curl -X POST https://api.example.com/items -H 'Authorization: Bearer t' --json '{"name":"a"}'`,
+			method:   "POST",
+			url:      "https://api.example.com/items",
+			headers:  map[string]string{"Authorization": "Bearer t", "Accept": "application/json"},
+			body:     `{"name":"a"}`,
+			bodyType: "application/json",
+		},
+		{
+			command:  "curl https://example.com/form -d a=1 -d b=2",
+			method:   "POST",
+			url:      "https://example.com/form",
+			body:     "a=1&b=2",
+			bodyType: contentTypeForm,
+		},
+		{
+			command: "curl -G https://example.com/search -d q=go --data-urlencode 'tag=a b'",
+			method:  "GET",
+			url:     "https://example.com/search?q=go&tag=a+b",
+		},
+		{
+			command: "curl -I https://example.com/",
+			method:  "HEAD",
+			url:     "https://example.com/",
+		},
+		{
+			command: "curl -u user:pass -A agent/1 -e https://ref.example.com -b 'a=1' https://example.com/",
+			method:  "GET",
+			url:     "https://example.com/",
+			headers: map[string]string{
+				"Authorization": "Basic dXNlcjpwYXNz", "User-Agent": "agent/1",
+				"Referer": "https://ref.example.com", "Cookie": "a=1",
+			},
+		},
+		{
+			command: "curl -sSL -o /dev/null -w '%{http_code}' --compressed https://example.com/",
+			method:  "GET",
+			url:     "https://example.com/",
+		},
+		{
+			command:  "curl -sXPOST https://example.com/items -d x=1",
+			method:   "POST",
+			url:      "https://example.com/items",
+			body:     "x=1",
+			bodyType: contentTypeForm,
+		},
+		{
+			command: "curl -sXDELETE https://example.com/items/1",
+			method:  "DELETE",
+			url:     "https://example.com/items/1",
+		},
+		{
+			command: "curl -sSX PUT https://example.com/items/1 -Hx-trace:1",
+			method:  "PUT",
+			url:     "https://example.com/items/1",
+			headers: map[string]string{"x-trace": "1"},
+		},
+		{
+			command:  "curl -sk https://self-signed.example.com/",
+			method:   "GET",
+			url:      "https://self-signed.example.com/",
+			insecure: true,
+		},
+		{
+			command:  "curl --insecure https://self-signed.example.com/",
+			method:   "GET",
+			url:      "https://self-signed.example.com/",
+			insecure: true,
+		},
+	}
+	for _, tt := range tests {
+		targets, err := LoadCurl(tt.command, false)
+		if err != nil {
+			t.Errorf("LoadCurl(%q) error = %v", tt.command, err)
+			continue
+		}
+		if len(targets) != 1 {
+			t.Errorf("LoadCurl(%q) returned %d targets, want 1", tt.command, len(targets))
+			continue
+		}
+		got := targets[0]
+		if got.method != tt.method || got.url != tt.url {
+			t.Errorf("LoadCurl(%q) = %s %s, want %s %s", tt.command, got.method, got.url, tt.method, tt.url)
+		}
+		headers := make(map[string]string)
+		for _, h := range got.headers {
+			headers[h.name] = string(h.value.raw)
+		}
+		if len(headers) > 0 || len(tt.headers) > 0 {
+			if !reflect.DeepEqual(headers, tt.headers) {
+				t.Errorf("LoadCurl(%q) headers = %v, want %v", tt.command, headers, tt.headers)
+			}
+		}
+		var body string
+		if got.body != nil {
+			body = string(got.body.raw)
+		}
+		if body != tt.body || (tt.body != "" && got.bodyType != tt.bodyType) {
+			t.Errorf("LoadCurl(%q) body = %q (%s), want %q (%s)", tt.command, body, got.bodyType, tt.body, tt.bodyType)
+		}
+		if got.options.insecure != tt.insecure {
+			t.Errorf("LoadCurl(%q) insecure = %v, want %v", tt.command, got.options.insecure, tt.insecure)
+		}
+	}
+}
+
+func TestLoadCurlErrors(t *testing.T) {
+	tests := []struct {
+		command string
+		err     string
+	}{
+		{"curl", "no URL"},
+		{"curl -sZ https://example.com/", "unsupported curl option -Z"},
+		{"curl --frobnicate https://example.com/", "unsupported curl option --frobnicate"},
+		{"curl https://example.com/ -H", "requires an argument"},
+		{"curl https://a.example.com/ https://b.example.com/", "several URLs"},
+		{"curl -F file=@a.txt https://example.com/", "multipart forms"},
+		{"curl -b cookies.txt https://example.com/", "cookie files are not supported"},
+	}
+	for _, tt := range tests {
+		_, err := LoadCurl(tt.command, false)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("LoadCurl(%q) error = %v, want one containing %q", tt.command, err, tt.err)
+		}
+	}
+}
+
+func TestSplitShortOptions(t *testing.T) {
+	tests := []struct {
+		word string
+		want []string
+	}{
+		{"-sSL", []string{"-s", "-S", "-L"}},
+		{"-XPOST", []string{"-X", "POST"}},
+		{"-sXPOST", []string{"-s", "-X", "POST"}},
+		{"-sX", []string{"-s", "-X"}},
+		{"-d@body.json", []string{"-d", "@body.json"}},
+		{"-so/dev/null", []string{"-s", "-o", "/dev/null"}},
+	}
+	for _, tt := range tests {
+		if got := splitShortOptions(tt.word); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitShortOptions(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package loadtest
+
+import (
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"strconv"
+	"strings"
+)
+
+// findKnee returns the index of the saturation knee of a sweep: the level after which
+// adding concurrency stops paying off in throughput. It uses the Kneedle method on the
+// concurrency/RPS curve, normalized to the unit square, and picks the level farthest
+// above the diagonal. It returns -1 with fewer than three levels or when throughput
+// never flattens.
+func findKnee(results []sweepLevel) int {
+	if len(results) < 3 {
+		return -1
+	}
+	minC, maxC := float64(results[0].concurrency), float64(results[0].concurrency)
+	minR, maxR := results[0].summary.RequestsPerSecond, results[0].summary.RequestsPerSecond
+	for _, level := range results {
+		minC, maxC = min(minC, float64(level.concurrency)), max(maxC, float64(level.concurrency))
+		minR, maxR = min(minR, level.summary.RequestsPerSecond), max(maxR, level.summary.RequestsPerSecond)
+	}
+	if maxC == minC || maxR == minR {
+		return -1
+	}
+	knee, best := -1, 0.0
+	for i, level := range results {
+		x := (float64(level.concurrency) - minC) / (maxC - minC)
+		y := (level.summary.RequestsPerSecond - minR) / (maxR - minR)
+		if d := y - x; d > best {
+			knee, best = i, d
+		}
+	}
+	return knee
+}
+
+// writeCurve writes the throughput/latency curve of a sweep to a CSV or an HTML file,
+// chosen by extension.
+func writeCurve(path string, opts OutputOptions, results []sweepLevel, knee int) error {
+	out, err := createOutput(path, opts)
+	if err != nil {
+		return err
+	}
+	if strings.HasSuffix(strings.ToLower(path), ".html") {
+		err = curveTemplate.Execute(out, newCurveChart(results, knee))
+	} else {
+		err = writeCurveCSV(csv.NewWriter(out), results, knee)
+	}
+	if err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// writeCurveCSV writes one row per level of a sweep.
+func writeCurveCSV(w *csv.Writer, results []sweepLevel, knee int) error {
+	w.Write([]string{"concurrency", "requests", "failed", "rps", "p50_ms", "p90_ms", "p95_ms", "p99_ms", "max_ms", "knee"})
+	for i, level := range results {
+		s := level.summary
+		w.Write([]string{
+			strconv.Itoa(level.concurrency), strconv.Itoa(s.Requests), strconv.Itoa(s.failed()),
+			formatFloat(s.RequestsPerSecond), formatFloat(s.P50LatencyMs), formatFloat(s.P90LatencyMs),
+			formatFloat(s.P95LatencyMs), formatFloat(s.P99LatencyMs), formatFloat(s.MaxLatencyMs),
+			strconv.FormatBool(i == knee),
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// formatFloat formats a measurement for CSV output.
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', 3, 64)
+}
+
+// Dimensions of the HTML chart, in SVG units.
+const (
+	chartWidth  = 800
+	chartHeight = 450
+	chartMargin = 60
+)
+
+// curveChart is the data of the HTML chart: p50 and p99 latency against throughput,
+// one point per level.
+type curveChart struct {
+	Width, Height int
+	Left, Right   float64
+	Top, Bottom   float64
+	P50, P99      string
+	Points        []curvePoint
+	Knee          *curvePoint
+	XTicks        []chartTick
+	YTicks        []chartTick
+}
+
+// curvePoint is a level of the sweep placed on the chart.
+type curvePoint struct {
+	X, Y50, Y99 float64
+
+	Concurrency int
+	RPS         string
+	P50, P99    string
+	Knee        bool
+}
+
+// chartTick is an axis label.
+type chartTick struct {
+	Position float64
+	Label    string
+}
+
+// newCurveChart scales the levels of a sweep to the chart area.
+func newCurveChart(results []sweepLevel, knee int) curveChart {
+	c := curveChart{
+		Width: chartWidth, Height: chartHeight,
+		Left: chartMargin, Right: chartWidth - chartMargin/2,
+		Top: chartMargin / 2, Bottom: chartHeight - chartMargin,
+	}
+	maxRPS, maxLatency := 1.0, 1.0
+	for _, level := range results {
+		maxRPS = max(maxRPS, level.summary.RequestsPerSecond)
+		maxLatency = max(maxLatency, level.summary.P99LatencyMs)
+	}
+	maxRPS *= 1.05
+	maxLatency *= 1.1
+	x := func(rps float64) float64 { return c.Left + rps/maxRPS*(c.Right-c.Left) }
+	y := func(ms float64) float64 { return c.Bottom - ms/maxLatency*(c.Bottom-c.Top) }
+
+	var p50, p99 []string
+	for i, level := range results {
+		s := level.summary
+		point := curvePoint{
+			X:           x(s.RequestsPerSecond),
+			Y50:         y(s.P50LatencyMs),
+			Y99:         y(s.P99LatencyMs),
+			Concurrency: level.concurrency,
+			RPS:         fmt.Sprintf("%.2f", s.RequestsPerSecond),
+			P50:         formatMs(s.P50LatencyMs),
+			P99:         formatMs(s.P99LatencyMs),
+			Knee:        i == knee,
+		}
+		c.Points = append(c.Points, point)
+		p50 = append(p50, fmt.Sprintf("%.1f,%.1f", point.X, point.Y50))
+		p99 = append(p99, fmt.Sprintf("%.1f,%.1f", point.X, point.Y99))
+	}
+	if knee >= 0 {
+		c.Knee = &c.Points[knee]
+	}
+	c.P50, c.P99 = strings.Join(p50, " "), strings.Join(p99, " ")
+	for i := 0; i <= 5; i++ {
+		rps := maxRPS * float64(i) / 5
+		ms := maxLatency * float64(i) / 5
+		c.XTicks = append(c.XTicks, chartTick{Position: x(rps), Label: fmt.Sprintf("%.0f", rps)})
+		c.YTicks = append(c.YTicks, chartTick{Position: y(ms), Label: formatMs(ms)})
+	}
+	return c
+}
+
+// curveTemplate renders a self-contained HTML page with an SVG chart of the curve.
+var curveTemplate = template.Must(template.New("curve").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Latency vs throughput</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+svg text { font-size: 12px; }
+.p50 { stroke: #1f77b4; fill: none; stroke-width: 2; }
+.p99 { stroke: #ff7f0e; fill: none; stroke-width: 2; }
+.axis { stroke: #333; }
+.grid { stroke: #ddd; }
+</style>
+</head>
+<body>
+<h1>Latency vs throughput</h1>
+<svg width="{{.Width}}" height="{{.Height}}" viewBox="0 0 {{.Width}} {{.Height}}">
+{{range .YTicks}}<line class="grid" x1="{{$.Left}}" x2="{{$.Right}}" y1="{{.Position}}" y2="{{.Position}}"/>
+<text x="{{$.Left}}" y="{{.Position}}" dx="-6" dy="4" text-anchor="end">{{.Label}}</text>
+{{end}}{{range .XTicks}}<text x="{{.Position}}" y="{{$.Bottom}}" dy="18" text-anchor="middle">{{.Label}}</text>
+{{end}}<line class="axis" x1="{{.Left}}" x2="{{.Right}}" y1="{{.Bottom}}" y2="{{.Bottom}}"/>
+<line class="axis" x1="{{.Left}}" x2="{{.Left}}" y1="{{.Top}}" y2="{{.Bottom}}"/>
+<text x="{{.Right}}" y="{{.Bottom}}" dy="40" text-anchor="end">Requests per second</text>
+<polyline class="p50" points="{{.P50}}"/>
+<polyline class="p99" points="{{.P99}}"/>
+{{range .Points}}<circle cx="{{.X}}" cy="{{.Y50}}" r="4" fill="#1f77b4"><title>concurrency {{.Concurrency}}: {{.RPS}} RPS, p50 {{.P50}}</title></circle>
+<circle cx="{{.X}}" cy="{{.Y99}}" r="4" fill="#ff7f0e"><title>concurrency {{.Concurrency}}: {{.RPS}} RPS, p99 {{.P99}}</title></circle>
+{{end}}{{with .Knee}}<circle cx="{{.X}}" cy="{{.Y99}}" r="9" fill="none" stroke="#d62728" stroke-width="3"/>
+<text x="{{.X}}" y="{{.Y99}}" dy="-14" text-anchor="middle" fill="#d62728">saturation knee</text>
+{{end}}<text x="{{.Right}}" y="{{.Top}}" text-anchor="end"><tspan fill="#1f77b4">● p50</tspan> <tspan fill="#ff7f0e">● p99</tspan></text>
+</svg>
+<table border="1" cellpadding="4" cellspacing="0">
+<tr><th>Concurrency</th><th>RPS</th><th>p50</th><th>p99</th><th></th></tr>
+{{range .Points}}<tr><td>{{.Concurrency}}</td><td>{{.RPS}}</td><td>{{.P50}}</td><td>{{.P99}}</td><td>{{if .Knee}}saturation knee{{end}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
@@ -0,0 +1,133 @@
+package loadtest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Data feed consumption modes.
+const (
+	DataModeSequential = "sequential"
+	DataModeRandom     = "random"
+	DataModeOnce       = "once"
+)
+
+// Data feed scopes: a new row for every request or one row per virtual user (worker).
+const (
+	DataPerRequest = "request"
+	DataPerVU      = "vu"
+)
+
+// errDataExhausted is returned when every row of a data feed in "once" mode has been used.
+var errDataExhausted = errors.New("data feed is exhausted")
+
+// dataFeed hands out the rows of a CSV or JSONL file to requests. Rows are exposed to
+// templates as the template data, so a column named email is available as {{.email}}.
+type dataFeed struct {
+	mu   sync.Mutex
+	rows []map[string]string
+	mode string
+	next int
+
+	random *randomStream
+}
+
+// loadDataFeed reads a CSV (with a header row) or JSONL file, chosen by extension.
+// In random mode rows are drawn from random.
+func loadDataFeed(path, mode string, random *randomStream) (*dataFeed, error) {
+	switch mode {
+	case DataModeSequential, DataModeRandom, DataModeOnce:
+	default:
+		return nil, fmt.Errorf("unknown data mode %q, expected sequential, random or once", mode)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []map[string]string
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jsonl", ".ndjson":
+		rows, err = parseJSONLRows(content)
+	default:
+		rows, err = parseCSVRows(content)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%s contains no rows", path)
+	}
+	return &dataFeed{rows: rows, mode: mode, random: random}, nil
+}
+
+// parseCSVRows parses CSV content whose first record holds the column names.
+func parseCSVRows(content []byte) ([]map[string]string, error) {
+	records, err := csv.NewReader(bytes.NewReader(content)).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	columns := records[0]
+	rows := make([]map[string]string, 0, len(records)-1)
+	for _, record := range records[1:] {
+		row := make(map[string]string, len(columns))
+		for i, column := range columns {
+			if i < len(record) {
+				row[column] = record[i]
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// parseJSONLRows parses one JSON object per line, rendering every value as a string.
+func parseJSONLRows(content []byte) ([]map[string]string, error) {
+	var rows []map[string]string
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var object map[string]interface{}
+		if err := json.Unmarshal([]byte(text), &object); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		row := make(map[string]string, len(object))
+		for key, value := range object {
+			row[key] = stringifyJSONValue(value)
+		}
+		rows = append(rows, row)
+	}
+	return rows, scanner.Err()
+}
+
+// take returns the next row according to the consumption mode.
+func (d *dataFeed) take() (map[string]string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	switch d.mode {
+	case DataModeRandom:
+		return d.rows[d.random.Intn(len(d.rows))], nil
+	case DataModeOnce:
+		if d.next >= len(d.rows) {
+			return nil, errDataExhausted
+		}
+	}
+	row := d.rows[d.next%len(d.rows)]
+	d.next++
+	return row, nil
+}
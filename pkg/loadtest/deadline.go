@@ -0,0 +1,64 @@
+package loadtest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// grpcTimeoutUnits are the units of a grpc-timeout value, from the most precise.
+var grpcTimeoutUnits = []struct {
+	unit   time.Duration
+	suffix string
+}{
+	{time.Nanosecond, "n"},
+	{time.Microsecond, "u"},
+	{time.Millisecond, "m"},
+	{time.Second, "S"},
+	{time.Minute, "M"},
+	{time.Hour, "H"},
+}
+
+// deadline returns how long the client waits for a request to t: its timeout, shortened
+// by --client-gives-up-after, or 0 without a limit.
+func (e *engine) deadline(t target) time.Duration {
+	deadline := e.cfg.Timeout
+	if t.options.timeout > 0 {
+		deadline = t.options.timeout
+	}
+	if e.cfg.GiveUpAfter > 0 && (deadline <= 0 || e.cfg.GiveUpAfter < deadline) {
+		deadline = e.cfg.GiveUpAfter
+	}
+	return deadline
+}
+
+// deadlineFormat returns the format of the --deadline-header value: the one set, or the
+// gRPC format for a grpc-timeout header and milliseconds for any other.
+func (e *engine) deadlineFormat() string {
+	switch {
+	case e.cfg.DeadlineFormat != "":
+		return e.cfg.DeadlineFormat
+	case strings.EqualFold(e.cfg.DeadlineHeader, "grpc-timeout"):
+		return "grpc"
+	}
+	return "ms"
+}
+
+// formatDeadline formats d as milliseconds, seconds or a grpc-timeout value, which takes
+// at most 8 digits and the unit keeping d the most precise.
+func formatDeadline(d time.Duration, format string) string {
+	switch format {
+	case "s":
+		return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+	case "grpc":
+		for _, u := range grpcTimeoutUnits {
+			// Rounded up, so the server never gets less time than the client waits.
+			if value := (d + u.unit - 1) / u.unit; value <= 99999999 {
+				return fmt.Sprintf("%d%s", value, u.suffix)
+			}
+		}
+		return "99999999H"
+	}
+	return strconv.FormatInt(d.Milliseconds(), 10)
+}
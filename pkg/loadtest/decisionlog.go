@@ -0,0 +1,94 @@
+package loadtest
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// targetDecision records how the target of a single request was picked, as written to
+// the decision log. Every random draw is a step, so the log shows which branch of the
+// read/write ratio and of the target weights each request took.
+type targetDecision struct {
+	Time    time.Time      `json:"time"`
+	Worker  int            `json:"worker"`
+	Request int            `json:"request"`
+	Warmup  bool           `json:"warmup,omitempty"`
+	Steps   []decisionStep `json:"steps,omitempty"`
+	Target  string         `json:"target"`
+	Share   float64        `json:"share"`
+}
+
+// decisionStep is a single weighted draw: Draw was drawn out of Of, landing in the
+// branch Picked of weight Weight.
+type decisionStep struct {
+	Choice string `json:"choice"`
+	Draw   int    `json:"draw"`
+	Of     int    `json:"of"`
+	Picked string `json:"picked"`
+	Weight int    `json:"weight"`
+}
+
+// step records a draw of d, when d is not nil, and scales the configured share of the
+// target by the weight of the branch.
+func (d *targetDecision) step(choice string, draw, of int, picked string, weight int) {
+	if d == nil {
+		return
+	}
+	d.Steps = append(d.Steps, decisionStep{Choice: choice, Draw: draw, Of: of, Picked: picked, Weight: weight})
+	d.Share *= float64(weight) / float64(of)
+}
+
+// pick records the target a decision ended on.
+func (d *targetDecision) pick(t target) {
+	if d != nil {
+		d.Target = targetLabel(t)
+	}
+}
+
+// targetLabel names a target in the decision log and the selection report.
+func targetLabel(t target) string {
+	if t.name != "" {
+		return t.name
+	}
+	return t.method + " " + t.url
+}
+
+// selectionMix compares the realized share of every target with its configured share.
+type selectionMix struct {
+	total  int
+	counts map[string]int
+	shares map[string]float64
+}
+
+// newSelectionMix returns an empty selection mix.
+func newSelectionMix() *selectionMix {
+	return &selectionMix{counts: make(map[string]int), shares: make(map[string]float64)}
+}
+
+// add counts the target of d.
+func (m *selectionMix) add(d *targetDecision) {
+	m.total++
+	m.counts[d.Target]++
+	m.shares[d.Target] = d.Share
+}
+
+// generateSelectionReport prints how often every target was picked against its
+// configured share.
+func generateSelectionReport(m *selectionMix) {
+	if m.total == 0 {
+		return
+	}
+	color.Green("\n===== 🎲 Target Selection =====")
+	targets := make([]string, 0, len(m.counts))
+	for t := range m.counts {
+		targets = append(targets, t)
+	}
+	sort.Strings(targets)
+	for _, t := range targets {
+		fmt.Printf("🔹 %s: %d requests (%.1f%%), configured %.1f%%\n",
+			t, m.counts[t], float64(m.counts[t])*100/float64(m.total), m.shares[t]*100)
+	}
+}
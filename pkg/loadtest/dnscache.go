@@ -0,0 +1,168 @@
+package loadtest
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// DNSCache keeps the addresses of target names for new connections, instead of
+// resolving them for every connection: pinned for the whole run, or re-resolved once
+// their answer is older than the refresh interval. Lookups still go through the resolver
+// of the run, such as DoH, and show up in the DNS phase of the request that made them.
+type DNSCache struct {
+	refresh time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*dnsCacheEntry
+	hits    atomic.Int64
+}
+
+// dnsCacheEntry is the cached answer for a name. Its mutex is held while the name is
+// resolved, so concurrent connections wait for a single lookup.
+type dnsCacheEntry struct {
+	mu       sync.Mutex
+	addrs    []string
+	resolved time.Time
+}
+
+// ParseDNSCache parses the --dns-cache value: "pin" to resolve every name once, or the
+// interval after which names are resolved again, such as 30s.
+func ParseDNSCache(value string) (*DNSCache, error) {
+	if value == "" {
+		return nil, nil
+	}
+	cache := &DNSCache{entries: make(map[string]*dnsCacheEntry)}
+	if value == "pin" {
+		return cache, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return nil, fmt.Errorf("%q is neither pin nor a positive duration such as 30s", value)
+	}
+	cache.refresh = d
+	return cache, nil
+}
+
+// lookup returns the addresses of host, from the cache while they are fresh. Lookups
+// report to the httptrace hooks of ctx like the lookups of the dialer do.
+func (c *DNSCache) lookup(ctx context.Context, resolver *net.Resolver, host string) ([]string, error) {
+	key := strings.ToLower(host)
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	if !ok {
+		entry = &dnsCacheEntry{}
+		c.entries[key] = entry
+	}
+	c.mu.Unlock()
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	if entry.addrs != nil && (c.refresh == 0 || time.Since(entry.resolved) < c.refresh) {
+		c.hits.Add(1)
+		return entry.addrs, nil
+	}
+	trace := httptrace.ContextClientTrace(ctx)
+	if trace != nil && trace.DNSStart != nil {
+		trace.DNSStart(httptrace.DNSStartInfo{Host: host})
+	}
+	addrs, err := resolver.LookupHost(ctx, host)
+	if trace != nil && trace.DNSDone != nil {
+		info := httptrace.DNSDoneInfo{Err: err}
+		for _, addr := range addrs {
+			info.Addrs = append(info.Addrs, net.IPAddr{IP: net.ParseIP(addr)})
+		}
+		trace.DNSDone(info)
+	}
+	if err != nil {
+		return nil, err
+	}
+	entry.addrs, entry.resolved = addrs, time.Now()
+	return addrs, nil
+}
+
+// wrapDial resolves the names dialed with resolver through the cache, and dials their
+// addresses in order until one connects. Addresses of another family than the network
+// asks for are skipped.
+func (c *DNSCache) wrapDial(dial func(ctx context.Context, network, address string) (net.Conn, error), resolver *net.Resolver) func(ctx context.Context, network, address string) (net.Conn, error) {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil || net.ParseIP(host) != nil {
+			return dial(ctx, network, address)
+		}
+		addrs, err := c.lookup(ctx, resolver, host)
+		if err != nil {
+			return nil, err
+		}
+		err = fmt.Errorf("no %s address for %s", network, host)
+		for _, addr := range addrs {
+			ip := net.ParseIP(addr)
+			if (network == "tcp4" && ip.To4() == nil) || (network == "tcp6" && ip.To4() != nil) {
+				continue
+			}
+			var conn net.Conn
+			if conn, err = dial(ctx, network, net.JoinHostPort(addr, port)); err == nil {
+				return conn, nil
+			}
+		}
+		return nil, err
+	}
+}
+
+// dnsStats counts the DNS lookups of the requests of a run and their duration. Lookups
+// shared by concurrent dials are counted once.
+type dnsStats struct {
+	lookups  atomic.Int64
+	failures atomic.Int64
+	elapsed  atomic.Int64
+	max      atomic.Int64
+}
+
+// add records a lookup that took d.
+func (s *dnsStats) add(d time.Duration, err error) {
+	s.lookups.Add(1)
+	if err != nil {
+		s.failures.Add(1)
+	}
+	s.elapsed.Add(int64(d))
+	for {
+		max := s.max.Load()
+		if int64(d) <= max || s.max.CompareAndSwap(max, int64(d)) {
+			return
+		}
+	}
+}
+
+// generateDNSReport prints how many DNS lookups the requests made and how long they took,
+// and how often connections used a cached answer instead.
+func generateDNSReport(s *dnsStats, cache *DNSCache) {
+	lookups := s.lookups.Load()
+	if lookups == 0 && cache == nil {
+		return
+	}
+	avg := time.Duration(0)
+	if lookups > 0 {
+		avg = time.Duration(s.elapsed.Load() / lookups)
+	}
+	fmt.Printf("\n🔎 DNS lookups: %d, avg %v, max %v\n", lookups, avg, time.Duration(s.max.Load()))
+	if failures := s.failures.Load(); failures > 0 {
+		color.Red("❌ Failed DNS lookups: %d", failures)
+	}
+	switch {
+	case cache == nil:
+	case cache.refresh == 0:
+		fmt.Printf("📌 DNS answers pinned for the run, reused by %d new connections\n", cache.hits.Load())
+	default:
+		fmt.Printf("📌 DNS answers cached for %v, reused by %d new connections\n", cache.refresh, cache.hits.Load())
+	}
+}
@@ -0,0 +1,55 @@
+package loadtest
+
+import "time"
+
+// errorRecord is a failed request as written to the error log.
+type errorRecord struct {
+	Time      time.Time `json:"time"`
+	Worker    int       `json:"worker"`
+	Method    string    `json:"method"`
+	URL       string    `json:"url"`
+	Status    int       `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	LatencyMs float64   `json:"latency_ms"`
+	Body      string    `json:"body,omitempty"`
+	Warmup    bool      `json:"warmup,omitempty"`
+}
+
+// failedStatus reports whether a response with status counts as a failed request for the
+// error log: any status outside of expected, or outside of 2xx without expected statuses.
+func failedStatus(status int, expected []int) bool {
+	if len(expected) == 0 {
+		return status < 200 || status >= 300
+	}
+	for _, s := range expected {
+		if status == s {
+			return false
+		}
+	}
+	return true
+}
+
+// newErrorRecord returns the error log record of res, or nil when res did not fail. Results
+// that are not requests, such as those skipped on an empty feed, are never logged.
+func newErrorRecord(res requestResult, expected []int) *errorRecord {
+	switch {
+	case res.abandoned:
+	case res.statusCode == -1:
+		if res.errorText == "" {
+			res.errorText = "the request could not be built"
+		}
+	case res.statusCode <= 0 || !failedStatus(res.statusCode, expected):
+		return nil
+	}
+	return &errorRecord{
+		Time:      res.completed,
+		Worker:    res.worker,
+		Method:    res.method,
+		URL:       res.url,
+		Status:    res.statusCode,
+		Error:     res.errorText,
+		LatencyMs: float64(res.latency) / float64(time.Millisecond),
+		Body:      res.errorBody,
+		Warmup:    res.warmup,
+	}
+}
@@ -0,0 +1,75 @@
+package loadtest
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// ParseExpectedStatuses parses a comma-separated list of HTTP status codes such as
+// "200,201".
+func ParseExpectedStatuses(value string) ([]int, error) {
+	var statuses []int
+	for _, item := range SplitList(value) {
+		status, err := strconv.Atoi(item)
+		if err != nil || status < 100 || status > 599 {
+			return nil, fmt.Errorf("invalid status code %q", item)
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// unexpectedStatus reports whether res is a response whose status is not one of
+// expected. Requests that got no response are network errors, not failed assertions.
+func unexpectedStatus(res requestResult, expected []int) bool {
+	if len(expected) == 0 || res.statusCode <= 0 {
+		return false
+	}
+	for _, status := range expected {
+		if res.statusCode == status {
+			return false
+		}
+	}
+	return true
+}
+
+// generateAssertionReport prints how many responses failed the --expect-status assertion
+// or the expected statuses of their target.
+func generateAssertionReport(expected []int, byTarget map[string][]int, total int, st *stats) {
+	var rules []string
+	if len(expected) > 0 {
+		rules = append(rules, formatStatuses(expected))
+	}
+	targets := make([]string, 0, len(byTarget))
+	for name := range byTarget {
+		targets = append(targets, name)
+	}
+	sort.Strings(targets)
+	for _, name := range targets {
+		rules = append(rules, name+": "+formatStatuses(byTarget[name]))
+	}
+	rate := 0.0
+	if total > 0 {
+		rate = float64(st.unexpectedStatusCount) * 100 / float64(total)
+	}
+	message := fmt.Sprintf("\n🧪 Failed status assertions (expected %s): %d (%.2f%% of requests)",
+		strings.Join(rules, "; "), st.unexpectedStatusCount, rate)
+	if st.unexpectedStatusCount > 0 {
+		color.Red("%s", message)
+	} else {
+		color.Cyan("%s", message)
+	}
+}
+
+// formatStatuses returns statuses as a comma-separated list.
+func formatStatuses(statuses []int) string {
+	codes := make([]string, len(statuses))
+	for i, status := range statuses {
+		codes[i] = strconv.Itoa(status)
+	}
+	return strings.Join(codes, ", ")
+}
@@ -0,0 +1,99 @@
+package loadtest
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// FailoverPolicy is the set of outcomes that make the client fail over to the next
+// base URL, mirroring the client-side failover logic of an SDK.
+type FailoverPolicy struct {
+	network     bool
+	serverError bool
+	statuses    map[int]bool
+}
+
+// ParseFailoverPolicy parses a comma-separated list of conditions: "network" for requests
+// that got no response, "5xx" for any server error, or individual status codes like 429.
+func ParseFailoverPolicy(spec string) (FailoverPolicy, error) {
+	policy := FailoverPolicy{statuses: make(map[int]bool)}
+	for _, condition := range strings.Split(spec, ",") {
+		condition = strings.ToLower(strings.TrimSpace(condition))
+		switch condition {
+		case "":
+		case "network":
+			policy.network = true
+		case "5xx":
+			policy.serverError = true
+		default:
+			status, err := strconv.Atoi(condition)
+			if err != nil || status < 100 || status > 599 {
+				return FailoverPolicy{}, fmt.Errorf("unknown failover condition %q", condition)
+			}
+			policy.statuses[status] = true
+		}
+	}
+	return policy, nil
+}
+
+// triggers reports whether a response with the given status code (-1 for network errors)
+// should fail over.
+func (p FailoverPolicy) triggers(statusCode int) bool {
+	switch {
+	case statusCode == -1:
+		return p.network
+	case statusCode >= 500 && p.serverError:
+		return true
+	default:
+		return p.statuses[statusCode]
+	}
+}
+
+// rebaseURL replaces the scheme and host of rawURL with the ones of base, keeping the
+// path and query.
+func rebaseURL(rawURL, base string) (string, error) {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	b, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	target.Scheme, target.Host = b.Scheme, b.Host
+	return target.String(), nil
+}
+
+// baseOf returns the scheme and host of rawURL.
+func baseOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// generateFailoverReport prints how often failover was triggered, which base URLs served
+// the requests and the latency added by failed attempts.
+func generateFailoverReport(st *stats) {
+	color.Green("\n===== 🔀 Failover =====")
+	fmt.Printf("🔁 Requests that failed over: %d (%d extra attempts)\n", st.failedOverCount, st.failoverAttempts)
+	if st.failedOverCount > 0 {
+		fmt.Printf("⏱️  Added latency: %v total, %v per failed-over request\n",
+			st.failoverDelay, st.failoverDelay/time.Duration(st.failedOverCount))
+	}
+	bases := make([]string, 0, len(st.servedBy))
+	for b := range st.servedBy {
+		bases = append(bases, b)
+	}
+	sort.Strings(bases)
+	for _, b := range bases {
+		fmt.Printf("  - served by %s: %d\n", b, st.servedBy[b])
+	}
+}
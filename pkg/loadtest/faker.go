@@ -0,0 +1,129 @@
+package loadtest
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Word lists the faker template functions draw from.
+var (
+	fakerFirstNames = []string{"James", "Mary", "Robert", "Patricia", "John", "Jennifer", "Michael", "Linda", "David", "Elizabeth", "William", "Barbara", "Richard", "Susan", "Joseph", "Jessica", "Thomas", "Sarah", "Carlos", "Ana", "Lucas", "Sofia", "Mateus", "Yuki", "Wei", "Fatima", "Omar", "Priya", "Olga", "Lars"}
+	fakerLastNames  = []string{"Smith", "Johnson", "Williams", "Brown", "Jones", "Garcia", "Miller", "Davis", "Rodriguez", "Martinez", "Hernandez", "Lopez", "Gonzalez", "Wilson", "Anderson", "Thomas", "Taylor", "Moore", "Jackson", "Martin", "Silva", "Santos", "Oliveira", "Tanaka", "Chen", "Khan", "Ivanova", "Nielsen", "Müller", "Rossi"}
+	fakerStreets    = []string{"Main St", "Oak Ave", "Pine Rd", "Maple Dr", "Cedar Ln", "Elm St", "Lake View", "Hill Rd", "Park Ave", "Sunset Blvd", "River Rd", "Church St"}
+	fakerCities     = []string{"Springfield", "Riverside", "Franklin", "Greenville", "Bristol", "Clinton", "Fairview", "Salem", "Madison", "Georgetown", "Arlington", "Ashland"}
+	fakerCountries  = []string{"United States", "Brazil", "Canada", "Germany", "Japan", "India", "Portugal", "France", "Mexico", "Australia", "Italy", "Spain"}
+	fakerDomains    = []string{"example.com", "example.org", "example.net", "mail.test", "inbox.test"}
+	fakerCompanies  = []string{"Acme", "Globex", "Initech", "Umbrella", "Hooli", "Stark", "Wayne", "Wonka", "Cyberdyne", "Soylent"}
+	fakerSuffixes   = []string{"Inc", "LLC", "Ltd", "Group", "Corp"}
+	fakerLorem      = strings.Fields("lorem ipsum dolor sit amet consectetur adipiscing elit sed do eiusmod tempor incididunt ut labore et dolore magna aliqua enim ad minim veniam quis nostrud exercitation ullamco laboris nisi aliquip ex ea commodo consequat")
+)
+
+// fakerFuncs returns the template functions generating realistic fake data from r.
+func fakerFuncs(r *randomStream) map[string]interface{} {
+	return map[string]interface{}{
+		"firstName": r.fakeFirstName,
+		"lastName":  r.fakeLastName,
+		"name":      r.fakeName,
+		"username":  r.fakeUsername,
+		"email":     r.fakeEmail,
+		"phone":     r.fakePhone,
+		"street":    r.fakeStreet,
+		"city":      r.fakeCity,
+		"country":   r.fakeCountry,
+		"zip":       r.fakeZip,
+		"address":   r.fakeAddress,
+		"company":   r.fakeCompany,
+		"lorem":     r.fakeLorem,
+		"sentence":  r.fakeSentence,
+		"paragraph": r.fakeParagraph,
+	}
+}
+
+// pick returns a random element of list.
+func (r *randomStream) pick(list []string) string {
+	return list[r.Intn(len(list))]
+}
+
+// fakeFirstName returns a random first name.
+func (r *randomStream) fakeFirstName() string {
+	return r.pick(fakerFirstNames)
+}
+
+// fakeLastName returns a random last name.
+func (r *randomStream) fakeLastName() string {
+	return r.pick(fakerLastNames)
+}
+
+// fakeName returns a random full name.
+func (r *randomStream) fakeName() string {
+	return r.fakeFirstName() + " " + r.fakeLastName()
+}
+
+// fakeUsername returns a random, likely unique username.
+func (r *randomStream) fakeUsername() string {
+	return strings.ToLower(r.fakeFirstName()) + fmt.Sprintf(".%s%d", strings.ToLower(r.fakeLastName()), r.Intn(10000))
+}
+
+// fakeEmail returns a random, likely unique email address on a reserved domain.
+func (r *randomStream) fakeEmail() string {
+	return r.fakeUsername() + "@" + r.pick(fakerDomains)
+}
+
+// fakePhone returns a random phone number in E.164 format.
+func (r *randomStream) fakePhone() string {
+	return fmt.Sprintf("+1%03d555%04d", 200+r.Intn(800), r.Intn(10000))
+}
+
+// fakeStreet returns a random street address line.
+func (r *randomStream) fakeStreet() string {
+	return fmt.Sprintf("%d %s", 1+r.Intn(9999), r.pick(fakerStreets))
+}
+
+// fakeCity returns a random city name.
+func (r *randomStream) fakeCity() string {
+	return r.pick(fakerCities)
+}
+
+// fakeCountry returns a random country name.
+func (r *randomStream) fakeCountry() string {
+	return r.pick(fakerCountries)
+}
+
+// fakeZip returns a random five digit postal code.
+func (r *randomStream) fakeZip() string {
+	return fmt.Sprintf("%05d", r.Intn(100000))
+}
+
+// fakeAddress returns a random single-line postal address.
+func (r *randomStream) fakeAddress() string {
+	return fmt.Sprintf("%s, %s %s, %s", r.fakeStreet(), r.fakeCity(), r.fakeZip(), r.fakeCountry())
+}
+
+// fakeCompany returns a random company name.
+func (r *randomStream) fakeCompany() string {
+	return r.pick(fakerCompanies) + " " + r.pick(fakerSuffixes)
+}
+
+// fakeLorem returns the given number of random lorem ipsum words.
+func (r *randomStream) fakeLorem(words int) string {
+	list := make([]string, words)
+	for i := range list {
+		list[i] = r.pick(fakerLorem)
+	}
+	return strings.Join(list, " ")
+}
+
+// fakeSentence returns a random lorem ipsum sentence.
+func (r *randomStream) fakeSentence() string {
+	sentence := r.fakeLorem(6 + r.Intn(8))
+	return strings.ToUpper(sentence[:1]) + sentence[1:] + "."
+}
+
+// fakeParagraph returns a random lorem ipsum paragraph.
+func (r *randomStream) fakeParagraph() string {
+	sentences := make([]string, 3+r.Intn(4))
+	for i := range sentences {
+		sentences[i] = r.fakeSentence()
+	}
+	return strings.Join(sentences, " ")
+}
@@ -0,0 +1,171 @@
+package loadtest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// remoteResultsFD is the file descriptor a remote run writes its results to. The remote
+// shell points it at the standard output of ssh, while the text report of the run goes
+// to the standard error.
+const remoteResultsFD = 3
+
+// HostsOptions configures a run fanned out over SSH with RunOnHosts.
+type HostsOptions struct {
+	// Binary is the command starting restclient on the hosts.
+	Binary string
+	// Report controls the reports of the merged run.
+	Report ReplayOptions
+}
+
+// ReadHosts reads a hosts file: one SSH destination per line, such as user@host or
+// ssh://user@host:2222. Blank lines and lines starting with # are skipped.
+func ReadHosts(path string) ([]string, error) {
+	hosts, err := readLines(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("%s lists no host", path)
+	}
+	return hosts, nil
+}
+
+// RunOnHosts runs the load test of cfg on every host at once, over SSH, and reports the
+// merged run broken down per host. No agent runs on the hosts: restclient is started
+// there with the settings of cfg in its environment, streams its results back on the
+// standard output of ssh, and exits. The input files of cfg, such as bodies and data
+// files, must exist at the same paths on the hosts.
+//
+// A host whose results cannot be read is left out of the report; RunOnHosts fails when
+// none can be read.
+func RunOnHosts(cfg Config, hosts []string, opts HostsOptions) (Result, error) {
+	dir, err := os.MkdirTemp("", "restclient-hosts-")
+	if err != nil {
+		return Result{}, err
+	}
+	defer os.RemoveAll(dir)
+
+	color.Cyan("🛰️  Starting the load test on %d hosts over SSH...", len(hosts))
+	paths := make([]string, len(hosts))
+	errs := make([]error, len(hosts))
+	var wg sync.WaitGroup
+	for i, host := range hosts {
+		paths[i] = filepath.Join(dir, fmt.Sprintf("%d.bin", i))
+		wg.Add(1)
+		go func(i int, host string) {
+			defer wg.Done()
+			errs[i] = runOnHost(cfg, host, opts.Binary, paths[i])
+		}(i, host)
+	}
+	wg.Wait()
+
+	var received, from []string
+	for i, host := range hosts {
+		if errs[i] != nil {
+			cfg.log().Error("Remote run failed", "host", host, "error", errs[i])
+			continue
+		}
+		color.Cyan("✅ %s: results received", host)
+		received, from = append(received, paths[i]), append(from, host)
+	}
+	if len(received) == 0 {
+		return Result{}, errors.New("no host returned its results")
+	}
+	return replayAll("results streamed from "+strings.Join(from, ", "), received, opts.Report)
+}
+
+// runOnHost runs the load test of cfg on host and saves the results it streams back to
+// path. The run is labeled with the name of the host, so the merged report breaks it
+// down per host. The exit status of the remote run is not an error as long as its
+// results are complete, since it also reflects failed assertions.
+func runOnHost(cfg Config, host, binary, path string) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	var stderr bytes.Buffer
+	cmd := exec.Command("ssh", sshArgs(cfg, host, binary)...)
+	cmd.Stdout = out
+	cmd.Stderr = &stderr
+	runErr := cmd.Run()
+
+	if _, _, err := readResults(path, func(requestResult) {}); err != nil {
+		if runErr != nil {
+			return fmt.Errorf("%v: %s", runErr, lastLine(stderr.String()))
+		}
+		return err
+	}
+	return nil
+}
+
+// sshArgs returns the arguments of ssh running the load test of cfg on host. The host
+// follows "--", so that a host starting with "-" is not taken for an option.
+func sshArgs(cfg Config, host, binary string) []string {
+	return []string{"-o", "BatchMode=yes", "--", host, remoteCommand(cfg, host, binary)}
+}
+
+// remoteSettings are left out of the manifest but still apply to remote runs: the
+// redaction rules decide what the results they stream back contain.
+var remoteSettings = map[string]bool{
+	"REDACT_HEADERS":       true,
+	"REDACT_FIELDS":        true,
+	"NO_DEFAULT_REDACTION": true,
+}
+
+// remoteCommand returns the shell command running the load test of cfg on host: the
+// settings of cfg as environment variables of binary, but for those about where results
+// are written, with the results going to remoteResultsFD.
+func remoteCommand(cfg Config, host, binary string) string {
+	env := map[string]string{
+		"OUT":    fmt.Sprintf("/dev/fd/%d", remoteResultsFD),
+		"REGION": host,
+	}
+	for name, value := range cfg.Settings {
+		if !outputSettings[name] || remoteSettings[name] {
+			env[name] = value
+		}
+	}
+	if !cfg.StartAt.IsZero() {
+		// Sent as an absolute time, so that every host starts at the same instant whatever
+		// its time zone.
+		env["START_AT"] = cfg.StartAt.Format(time.RFC3339)
+	}
+	if cfg.Window > 0 {
+		env["WINDOW"] = cfg.Window.String()
+	}
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	args := []string{"env"}
+	for _, name := range names {
+		args = append(args, shellQuote(name+"="+env[name]))
+	}
+	args = append(args, binary)
+	return fmt.Sprintf("%s %d>&1 1>&2", strings.Join(args, " "), remoteResultsFD)
+}
+
+// shellQuote quotes s as a single word for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// lastLine returns the last non-empty line of s, which holds the reason a command failed.
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return lines[len(lines)-1]
+}
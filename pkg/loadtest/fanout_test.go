@@ -0,0 +1,47 @@
+package loadtest
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSSHArgs(t *testing.T) {
+	cfg := Config{
+		Settings: map[string]string{
+			"URL":                  "https://api.example.com/",
+			"HEADERS":              "X-Name: it's",
+			"REDACT_HEADERS":       "X-Token",
+			"REDACT_FIELDS":        "card.number\nitems[*].token",
+			"NO_DEFAULT_REDACTION": "true",
+			"RAW_LOG":              "raw.ndjson",
+			"HOSTS":                "hosts.txt",
+			"REPORT_JSON":          "report.json",
+		},
+		StartAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.FixedZone("CET", 3600)),
+		Window:  time.Hour,
+	}
+	got := sshArgs(cfg, "-oProxyCommand=x", "/opt/restclient")
+	want := []string{
+		"-o", "BatchMode=yes", "--", "-oProxyCommand=x",
+		`env 'HEADERS=X-Name: it'\''s' 'NO_DEFAULT_REDACTION=true' 'OUT=/dev/fd/3' 'REDACT_FIELDS=card.number
+items[*].token' 'REDACT_HEADERS=X-Token' 'REGION=-oProxyCommand=x' 'START_AT=2026-01-02T03:04:05+01:00' 'URL=https://api.example.com/' 'WINDOW=1h0m0s' /opt/restclient 3>&1 1>&2`,
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("sshArgs() =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{in: "", want: "''"},
+		{in: "a b", want: "'a b'"},
+		{in: "it's", want: `'it'\''s'`},
+		{in: "$HOME `id`", want: "'$HOME `id`'"},
+	}
+	for _, tt := range tests {
+		if got := shellQuote(tt.in); got != tt.want {
+			t.Errorf("shellQuote(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
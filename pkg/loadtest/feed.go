@@ -0,0 +1,114 @@
+package loadtest
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// feedPool is a bounded, concurrency-safe pool of values captured from responses,
+// e.g. the IDs returned by POST requests. Once the pool is full the oldest value
+// is overwritten, so consumers keep targeting recently created entities.
+type feedPool struct {
+	mu       sync.Mutex
+	values   []string
+	next     int
+	size     int
+	captured int
+	random   *randomStream
+}
+
+// newFeedPool returns an empty pool holding at most size values, picking values with random.
+func newFeedPool(size int, random *randomStream) *feedPool {
+	if size < 1 {
+		size = 1
+	}
+	return &feedPool{size: size, random: random}
+}
+
+// put adds a value to the pool, evicting the oldest one when the pool is full.
+func (p *feedPool) put(value string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.captured++
+	if len(p.values) < p.size {
+		p.values = append(p.values, value)
+		return
+	}
+	p.values[p.next] = value
+	p.next = (p.next + 1) % p.size
+}
+
+// take returns a random value from the pool, or false if the pool is empty.
+// Values are not removed, so several readers may target the same entity.
+func (p *feedPool) take() (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.values) == 0 {
+		return "", false
+	}
+	return p.values[p.random.Intn(len(p.values))], true
+}
+
+// capturedCount returns how many values have been captured during the run.
+func (p *feedPool) capturedCount() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.captured
+}
+
+// capture extracts the field at path from a JSON response body, adds it to the pool and
+// returns it. Bodies that are not JSON or do not contain the field are ignored.
+func (p *feedPool) capture(body []byte, path string) (string, bool) {
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return "", false
+	}
+	value, ok := lookupJSONPath(doc, path)
+	if !ok {
+		return "", false
+	}
+	captured := stringifyJSONValue(value)
+	p.put(captured)
+	return captured, true
+}
+
+// lookupJSONPath walks a decoded JSON document following a dotted path such as
+// "data.items.0.id", where numeric segments index into arrays.
+func lookupJSONPath(doc interface{}, path string) (interface{}, bool) {
+	current := doc
+	for _, key := range strings.Split(path, ".") {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, ok := node[key]
+			if !ok {
+				return nil, false
+			}
+			current = value
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, false
+			}
+			current = node[index]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// stringifyJSONValue renders a decoded JSON value in a form suitable for
+// substitution into URLs and bodies.
+func stringifyJSONValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		encoded, _ := json.Marshal(v)
+		return string(encoded)
+	}
+}
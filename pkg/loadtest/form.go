@@ -0,0 +1,50 @@
+package loadtest
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// formField is a single key of an application/x-www-form-urlencoded body. The value is
+// rendered as a template for every request.
+type formField struct {
+	key   string
+	value *templateSource
+}
+
+// parseFormFields parses "key=value" form field definitions.
+func parseFormFields(definitions []string) ([]formField, error) {
+	fields := make([]formField, 0, len(definitions))
+	for _, definition := range definitions {
+		key, value, ok := strings.Cut(definition, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid form field %q, expected key=value", definition)
+		}
+		source, err := newTemplateSource(key, []byte(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid template in form field %s: %w", key, err)
+		}
+		fields = append(fields, formField{key: key, value: source})
+	}
+	return fields, nil
+}
+
+// renderForm renders the form fields and encodes them as a URL-encoded body. Repeated
+// keys are kept in order.
+func renderForm(fields []formField, scope *renderScope) ([]byte, error) {
+	var b strings.Builder
+	for i, field := range fields {
+		value, err := field.value.render(scope)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			b.WriteByte('&')
+		}
+		b.WriteString(url.QueryEscape(field.key))
+		b.WriteByte('=')
+		b.WriteString(url.QueryEscape(string(value)))
+	}
+	return []byte(b.String()), nil
+}
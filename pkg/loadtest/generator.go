@@ -0,0 +1,137 @@
+package loadtest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// RequestGenerator produces the requests of a run. Next is called by every worker for
+// each request it sends, so it must be safe for concurrent use; ctx is done once the run
+// stops. Next returns io.EOF when it has no more requests, which ends the run once every
+// worker got it.
+//
+// The default generator picks a target of the Config for every request and renders its
+// URL, headers and body templates. A custom generator replaces it for workloads those
+// cannot describe: its requests are sent without the headers and body of the Config,
+// while the host header, client mix, header rotation, deadline header, before_request
+// hook, compression, timeouts, hedging, failover, assertions and reports apply to them
+// as to any other.
+type RequestGenerator interface {
+	Next(ctx context.Context) (*http.Request, error)
+}
+
+// targetGenerator is the default RequestGenerator, sending the targets of the Config.
+// It renders a request for the worker of the context of Next, and returns it with the
+// prepared request in its context, so that the engine sends it as rendered.
+type targetGenerator struct {
+	e *engine
+}
+
+// generatorRequest is what Next of the default generator needs to know about the
+// request it renders: the worker sending it and where its target selection is logged.
+type generatorRequest struct {
+	w *worker
+	d *targetDecision
+}
+
+type generatorRequestKey struct{}
+type preparedRequestKey struct{}
+
+// unsentRequest is the error of the default generator when a request cannot be sent,
+// e.g. because the feed pool is empty. Its result says why.
+type unsentRequest struct {
+	res requestResult
+}
+
+func (u *unsentRequest) Error() string {
+	return "the request could not be rendered"
+}
+
+// Next renders the next request of the worker of ctx.
+func (g *targetGenerator) Next(ctx context.Context) (*http.Request, error) {
+	r, ok := ctx.Value(generatorRequestKey{}).(generatorRequest)
+	if !ok {
+		return nil, errors.New("the default generator only renders requests for the workers of a run")
+	}
+	p, res, ok := g.e.prepareRequest(g.e.pickTarget(r.w.targets, r.d), r.w)
+	if !ok {
+		return nil, &unsentRequest{res: res}
+	}
+	ctx = context.WithValue(ctx, preparedRequestKey{}, p)
+	if p.request != nil {
+		return p.request.WithContext(ctx), nil
+	}
+	req, err := http.NewRequestWithContext(ctx, p.target.method, p.url, bytes.NewReader(p.body))
+	if err != nil {
+		g.e.cfg.log().Error("Error creating request", "url", p.url, "error", err)
+		return nil, &unsentRequest{res: requestResult{class: p.target.class, method: p.target.method, url: p.url, statusCode: -1}}
+	}
+	req.Header = p.header
+	return req, nil
+}
+
+// sendNext sends the next request of the generator of the run on w. The result is
+// marked generatorDone, and must not be counted, when the generator has no more
+// requests or the run stopped while it was waiting for one.
+func (e *engine) sendNext(w *worker, d *targetDecision) requestResult {
+	ctx := e.run.stop
+	req, err := e.generator.Next(context.WithValue(ctx, generatorRequestKey{}, generatorRequest{w: w, d: d}))
+	var unsent *unsentRequest
+	switch {
+	case errors.As(err, &unsent):
+		return unsent.res
+	case errors.Is(err, io.EOF) || (err != nil && ctx.Err() != nil):
+		return requestResult{generatorDone: true}
+	case err != nil:
+		e.cfg.log().Error("Error generating request", "error", err)
+		return requestResult{statusCode: -1}
+	}
+	if p, ok := req.Context().Value(preparedRequestKey{}).(*preparedRequest); ok {
+		return e.sendPrepared(p)
+	}
+	p, err := prepareGenerated(req)
+	if err != nil {
+		e.cfg.log().Error("Error reading generated request body", "url", req.URL.String(), "error", err)
+		return requestResult{method: req.Method, url: req.URL.String(), statusCode: -1}
+	}
+	if !e.finishPrepared(p, w) {
+		return requestResult{method: p.target.method, url: p.url, statusCode: -1}
+	}
+	return e.sendPrepared(p)
+}
+
+// prepareGenerated turns a generated request into a prepared request, reading its body
+// so that it can be sent again by hedging and failover.
+func prepareGenerated(req *http.Request) (*preparedRequest, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	method := req.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	url := req.URL.String()
+	p := &preparedRequest{
+		target:    target{method: method, url: url, withBody: len(body) > 0},
+		url:       url,
+		body:      body,
+		plainBody: body,
+		header:    req.Header.Clone(),
+	}
+	if p.header == nil {
+		p.header = make(http.Header)
+	}
+	if req.Host != "" && req.Host != req.URL.Host {
+		p.host = req.Host
+	}
+	return p, nil
+}
@@ -0,0 +1,89 @@
+package loadtest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingGenerator generates n POST requests to base, numbered in their path and body,
+// then io.EOF, and fails the requests listed in fail.
+type countingGenerator struct {
+	base string
+	n    int64
+	fail map[int64]bool
+	next atomic.Int64
+}
+
+func (g *countingGenerator) Next(ctx context.Context) (*http.Request, error) {
+	i := g.next.Add(1)
+	if i > g.n {
+		return nil, io.EOF
+	}
+	if g.fail[i] {
+		return nil, fmt.Errorf("request %d cannot be generated", i)
+	}
+	return http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/items/%d", g.base, i), strings.NewReader(fmt.Sprint(i)))
+}
+
+func TestCustomGenerator(t *testing.T) {
+	var mu sync.Mutex
+	var sent []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		sent = append(sent, fmt.Sprintf("%s %s %s host=%s config=%q", r.Method, r.URL.Path, body, r.Host, r.Header.Get("X-Config")))
+	}))
+	defer srv.Close()
+
+	summary, err := New(
+		WithConfig(Config{SummaryOnly: true, HostHeader: "api.example.com"}),
+		WithGenerator(&countingGenerator{base: srv.URL, n: 5, fail: map[int64]bool{3: true}}),
+		WithHeader("X-Config", "1"),
+		WithBody("config body"),
+		WithConcurrency(2),
+		WithDuration(time.Minute),
+	).Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Requests != 5 || summary.Successful != 4 || summary.NetworkErrors != 1 {
+		t.Errorf("Requests = %d, Successful = %d, NetworkErrors = %d, want 5, 4 and the generator error", summary.Requests, summary.Successful, summary.NetworkErrors)
+	}
+	slices.Sort(sent)
+	want := []string{
+		`POST /items/1 1 host=api.example.com config=""`,
+		`POST /items/2 2 host=api.example.com config=""`,
+		`POST /items/4 4 host=api.example.com config=""`,
+		`POST /items/5 5 host=api.example.com config=""`,
+	}
+	if !slices.Equal(sent, want) {
+		t.Errorf("sent %q, want %q", sent, want)
+	}
+}
+
+func TestDefaultGeneratorOutsideRun(t *testing.T) {
+	g := &targetGenerator{}
+	if _, err := g.Next(context.Background()); err == nil {
+		t.Error("Next() error = nil, want an error outside the workers of a run")
+	}
+}
+
+func TestRunWithoutTargets(t *testing.T) {
+	if _, err := New(WithRequests(1)).Run(context.Background()); err == nil || !strings.Contains(err.Error(), "WithGenerator") {
+		t.Errorf("Run() error = %v, want an error naming WithURL and WithGenerator", err)
+	}
+	summary, err := New(WithGenerator(&countingGenerator{}), WithDuration(time.Minute)).Run(context.Background())
+	if err != nil || summary.Requests != 0 {
+		t.Errorf("Run() = %d requests, %v, want a run of an empty generator to end without requests", summary.Requests, err)
+	}
+}
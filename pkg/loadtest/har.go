@@ -0,0 +1,207 @@
+package loadtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// harFile is the part of a HAR file (HTTP Archive, as exported by browsers) that is
+// replayed.
+type harFile struct {
+	Log struct {
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+// harEntry is a recorded request of a HAR file.
+type harEntry struct {
+	StartedDateTime time.Time `json:"startedDateTime"`
+	Request         struct {
+		Method   string         `json:"method"`
+		URL      string         `json:"url"`
+		Headers  []harNameValue `json:"headers"`
+		PostData *struct {
+			MimeType string         `json:"mimeType"`
+			Text     string         `json:"text"`
+			Params   []harNameValue `json:"params"`
+		} `json:"postData"`
+	} `json:"request"`
+}
+
+// harNameValue is a header or form parameter of a HAR request.
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// harSkippedHeaders are recorded headers that are not replayed: HTTP/2 pseudo-headers
+// are dropped by prefix, and these are set by the client for the connection it uses.
+var harSkippedHeaders = map[string]bool{
+	"host":              true,
+	"content-length":    true,
+	"connection":        true,
+	"keep-alive":        true,
+	"transfer-encoding": true,
+	"accept-encoding":   true,
+}
+
+// harRequest is a request of a HAR file ready to be replayed.
+type harRequest struct {
+	method string
+	url    string
+	header http.Header
+	host   string
+	body   []byte
+	offset time.Duration
+}
+
+// HARReplay is a RequestGenerator replaying the requests of a HAR file in the order they
+// were recorded. It cycles through them until the run ends, or, with the original
+// timing, sends every request at its offset from the first one and ends the run after
+// the last.
+type HARReplay struct {
+	requests []harRequest
+	timing   bool
+
+	mu    sync.Mutex
+	next  int
+	start time.Time
+}
+
+// LoadHAR reads the requests of the HAR file at path, keeping their methods, headers and
+// bodies. Requests to other schemes than HTTP and HTTPS, such as data: URLs, are
+// skipped. With timing, the requests are replayed at their original pace.
+func LoadHAR(path string, timing bool) (*HARReplay, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var har harFile
+	if err := json.Unmarshal(content, &har); err != nil {
+		return nil, fmt.Errorf("%s is not a HAR file: %v", path, err)
+	}
+	entries := har.Log.Entries
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].StartedDateTime.Before(entries[j].StartedDateTime) })
+
+	replay := &HARReplay{timing: timing}
+	for i, entry := range entries {
+		r, ok, err := newHARRequest(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%s: entry %d: %w", path, i+1, err)
+		}
+		if !ok {
+			continue
+		}
+		if len(replay.requests) > 0 {
+			r.offset = entry.StartedDateTime.Sub(entries[0].StartedDateTime)
+		}
+		replay.requests = append(replay.requests, r)
+	}
+	if len(replay.requests) == 0 {
+		return nil, fmt.Errorf("%s has no HTTP request to replay", path)
+	}
+	return replay, nil
+}
+
+// newHARRequest converts a HAR entry, or returns false when it is not an HTTP request.
+func newHARRequest(entry harEntry) (harRequest, bool, error) {
+	parsed, err := url.Parse(entry.Request.URL)
+	if err != nil {
+		return harRequest{}, false, err
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return harRequest{}, false, nil
+	}
+	r := harRequest{method: strings.ToUpper(entry.Request.Method), url: entry.Request.URL, header: make(http.Header)}
+	if r.method == "" {
+		r.method = http.MethodGet
+	}
+	for _, h := range entry.Request.Headers {
+		name := strings.ToLower(h.Name)
+		switch {
+		case name == ":authority":
+			r.host = h.Value
+		case strings.HasPrefix(name, ":") || harSkippedHeaders[name]:
+		default:
+			r.header.Add(h.Name, h.Value)
+		}
+	}
+	if data := entry.Request.PostData; data != nil {
+		r.body = []byte(data.Text)
+		if data.Text == "" && len(data.Params) > 0 {
+			form := url.Values{}
+			for _, p := range data.Params {
+				form.Add(p.Name, p.Value)
+			}
+			r.body = []byte(form.Encode())
+		}
+		if r.header.Get("Content-Type") == "" && data.MimeType != "" {
+			r.header.Set("Content-Type", data.MimeType)
+		}
+	}
+	if r.host == parsed.Host {
+		r.host = ""
+	}
+	return r, true, nil
+}
+
+// Len returns the number of requests of the HAR file.
+func (h *HARReplay) Len() int {
+	return len(h.requests)
+}
+
+// Duration returns the time between the first and the last recorded request.
+func (h *HARReplay) Duration() time.Duration {
+	return h.requests[len(h.requests)-1].offset
+}
+
+// Next returns the next recorded request. With the original timing, it waits until the
+// offset of the request from the first call, and returns io.EOF after the last one.
+func (h *HARReplay) Next(ctx context.Context) (*http.Request, error) {
+	h.mu.Lock()
+	if h.start.IsZero() {
+		h.start = time.Now()
+	}
+	if h.timing && h.next == len(h.requests) {
+		h.mu.Unlock()
+		return nil, io.EOF
+	}
+	r := h.requests[h.next%len(h.requests)]
+	h.next++
+	if !h.timing {
+		h.next %= len(h.requests)
+	}
+	at := h.start.Add(r.offset)
+	h.mu.Unlock()
+
+	if h.timing {
+		if wait := time.Until(at); wait > 0 {
+			timer := time.NewTimer(wait)
+			defer timer.Stop()
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, r.method, r.url, bytes.NewReader(r.body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header = r.header.Clone()
+	if r.host != "" {
+		req.Host = r.host
+	}
+	return req, nil
+}
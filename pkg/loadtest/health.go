@@ -0,0 +1,151 @@
+package loadtest
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// Phases of a run as seen by the health monitor.
+const (
+	phaseWarmup   = "warmup"
+	phaseLoad     = "load"
+	phaseCooldown = "cooldown"
+)
+
+// healthSample is a single health check result, taken at offset since the load started.
+// A statusCode of -1 means the check failed before a response was received.
+type healthSample struct {
+	offset     time.Duration
+	phase      string
+	statusCode int
+	latency    time.Duration
+}
+
+// healthMonitor samples a health URL on a side channel, independent from the load,
+// so the report can show how the target behaves during the load and the cooldown.
+type healthMonitor struct {
+	client   *http.Client
+	url      string
+	interval time.Duration
+	start    time.Time
+
+	mu      sync.Mutex
+	phase   string
+	samples []healthSample
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// startHealthMonitor starts sampling url every interval until stopped.
+func startHealthMonitor(url string, interval, timeout time.Duration) *healthMonitor {
+	m := &healthMonitor{
+		client:   &http.Client{Timeout: timeout},
+		url:      url,
+		interval: interval,
+		start:    time.Now(),
+		phase:    phaseLoad,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go m.run()
+	return m
+}
+
+// run takes a sample immediately and then once per interval.
+func (m *healthMonitor) run() {
+	defer close(m.done)
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	for {
+		m.sample()
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sample performs a single health check.
+func (m *healthMonitor) sample() {
+	m.mu.Lock()
+	phase := m.phase
+	m.mu.Unlock()
+
+	s := healthSample{offset: time.Since(m.start), phase: phase, statusCode: -1}
+	start := time.Now()
+	resp, err := m.client.Get(m.url)
+	if err == nil {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		s.statusCode = resp.StatusCode
+	}
+	s.latency = time.Since(start)
+
+	m.mu.Lock()
+	m.samples = append(m.samples, s)
+	m.mu.Unlock()
+}
+
+// setPhase changes the phase recorded for the following samples.
+func (m *healthMonitor) setPhase(phase string) {
+	m.mu.Lock()
+	m.phase = phase
+	m.mu.Unlock()
+}
+
+// close stops sampling and waits for an in-flight check to finish.
+func (m *healthMonitor) close() {
+	close(m.stop)
+	<-m.done
+}
+
+// recoveryTime returns how long into the cooldown the health checks became and stayed
+// successful, or false if they never did.
+func (m *healthMonitor) recoveryTime() (time.Duration, bool) {
+	var cooldownStart, recovered time.Duration
+	found := false
+	for _, s := range m.samples {
+		if s.phase != phaseCooldown {
+			continue
+		}
+		if cooldownStart == 0 {
+			cooldownStart = s.offset
+		}
+		healthy := s.statusCode >= 200 && s.statusCode < 300
+		if healthy && !found {
+			recovered, found = s.offset, true
+		} else if !healthy {
+			found = false
+		}
+	}
+	return recovered - cooldownStart, found
+}
+
+// generateHealthReport prints the health timeline covering the load and the cooldown.
+func generateHealthReport(m *healthMonitor) {
+	color.Green("\n===== 💓 Health Timeline (%s) =====", m.url)
+	for _, s := range m.samples {
+		status := fmt.Sprintf("HTTP %d", s.statusCode)
+		if s.statusCode == -1 {
+			status = "error"
+		}
+		line := fmt.Sprintf("  t+%-8v [%-8s] %-8s %v", s.offset.Round(100*time.Millisecond), s.phase, status, s.latency.Round(time.Microsecond))
+		if s.statusCode >= 200 && s.statusCode < 300 {
+			fmt.Println(line)
+		} else {
+			color.Red(line)
+		}
+	}
+	if recovery, ok := m.recoveryTime(); ok {
+		color.Cyan("✅ Health checks recovered %v into the cooldown.", recovery.Round(time.Millisecond))
+	} else if len(m.samples) > 0 && m.samples[len(m.samples)-1].phase == phaseCooldown {
+		color.Red("⚠️  Health checks did not recover during the cooldown.")
+	}
+}
@@ -0,0 +1,172 @@
+package loadtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// errHedgeLost is the cancellation cause of the slower request of a hedged pair.
+var errHedgeLost = errors.New("the other request of the hedged pair answered first")
+
+// Adaptive hedge delays are the chosen percentile of the latencies of the last
+// hedgeWindow requests, recomputed every hedgeRefresh requests. No hedge is sent until
+// hedgeMinSamples latencies are known.
+const (
+	hedgeWindow     = 1000
+	hedgeRefresh    = 50
+	hedgeMinSamples = 20
+)
+
+// HedgePolicy decides when a duplicate of a request still waiting for its response is
+// sent: after a fixed delay, or after a percentile of the recent latencies, the way
+// hedging clients usually pick it.
+type HedgePolicy struct {
+	fixed    time.Duration
+	quantile float64
+
+	mu        sync.Mutex
+	latencies []time.Duration
+	next      int
+	observed  int
+	current   time.Duration
+}
+
+// ParseHedgeAfter parses a hedge delay: a duration such as "50ms" or a percentile of
+// the recent latencies such as "p95".
+func ParseHedgeAfter(value string) (*HedgePolicy, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if percentile, ok := strings.CutPrefix(value, "p"); ok {
+		q, err := strconv.ParseFloat(percentile, 64)
+		if err != nil || q <= 0 || q >= 100 {
+			return nil, fmt.Errorf("%q is not a percentile between p0 and p100", value)
+		}
+		return &HedgePolicy{quantile: q / 100}, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return nil, fmt.Errorf("%q is neither a positive duration nor a percentile such as p95", value)
+	}
+	return &HedgePolicy{fixed: d}, nil
+}
+
+// delay returns how long to wait for a response before hedging, or 0 when not enough
+// latencies are known yet to hedge after a percentile.
+func (h *HedgePolicy) delay() time.Duration {
+	if h.fixed > 0 {
+		return h.fixed
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.current
+}
+
+// observe records the latency of a request for adaptive delays.
+func (h *HedgePolicy) observe(latency time.Duration) {
+	if h.fixed > 0 {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.latencies) < hedgeWindow {
+		h.latencies = append(h.latencies, latency)
+	} else {
+		h.latencies[h.next] = latency
+		h.next = (h.next + 1) % hedgeWindow
+	}
+	h.observed++
+	if len(h.latencies) >= hedgeMinSamples && (h.current == 0 || h.observed%hedgeRefresh == 0) {
+		sorted := append([]time.Duration(nil), h.latencies...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		h.current = sorted[min(int(h.quantile*float64(len(sorted))), len(sorted)-1)]
+	}
+}
+
+// describe renders the policy for the report.
+func (h *HedgePolicy) describe() string {
+	if h.fixed > 0 {
+		return fmt.Sprintf("after %v", h.fixed)
+	}
+	return fmt.Sprintf("after p%s of the recent latencies (last %v)",
+		strconv.FormatFloat(h.quantile*100, 'f', -1, 64), h.delay())
+}
+
+// hedgeOutcome is the result of one request of a hedged pair.
+type hedgeOutcome struct {
+	res   requestResult
+	hedge bool
+}
+
+// hedgedAttempt sends p to url and, when no response came back within the hedge delay,
+// a duplicate of it. The first response is used and the other request is cancelled; a
+// network error only wins when both requests failed. The latency is counted from the
+// first request, as the caller waited for it.
+func (e *engine) hedgedAttempt(ctx context.Context, p *preparedRequest, url string) requestResult {
+	delay := e.hedge.delay()
+	if delay <= 0 {
+		res := e.attempt(ctx, p, url)
+		e.hedge.observe(res.latency)
+		return res
+	}
+
+	start := time.Now()
+	primaryCtx, cancelPrimary := context.WithCancelCause(ctx)
+	defer cancelPrimary(nil)
+	hedgeCtx, cancelHedge := context.WithCancelCause(ctx)
+	defer cancelHedge(nil)
+	outcomes := make(chan hedgeOutcome, 2)
+	go func() { outcomes <- hedgeOutcome{res: e.attempt(primaryCtx, p, url)} }()
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case first := <-outcomes:
+		e.hedge.observe(first.res.latency)
+		return first.res
+	case <-timer.C:
+	}
+
+	go func() { outcomes <- hedgeOutcome{res: e.attempt(hedgeCtx, p, url), hedge: true} }()
+	first := <-outcomes
+	if first.res.statusCode == -1 {
+		if second := <-outcomes; second.res.statusCode != -1 {
+			first = second
+		}
+	} else if first.hedge {
+		cancelPrimary(errHedgeLost)
+		<-outcomes
+	} else {
+		cancelHedge(errHedgeLost)
+		<-outcomes
+	}
+
+	res := first.res
+	res.latency = time.Since(start)
+	res.hedged = true
+	res.hedgeWon = first.hedge
+	e.hedge.observe(res.latency)
+	return res
+}
+
+// generateHedgeReport prints how often requests were hedged, how often the hedge
+// answered first and the extra load it put on the target.
+func generateHedgeReport(policy string, total int, st *stats) {
+	color.Green("\n===== 🪞 Hedged Requests =====")
+	rate := 0.0
+	if total > 0 {
+		rate = float64(st.hedgedCount) * 100 / float64(total)
+	}
+	fmt.Printf("⏱️  Hedging %s\n", policy)
+	fmt.Printf("🪞 Hedged requests: %d (%.2f%% of requests)\n", st.hedgedCount, rate)
+	fmt.Printf("🏁 Answered first by the hedge: %d\n", st.hedgeWins)
+	fmt.Printf("🗑️  Wasted requests (duplicates whose response was discarded): %d (+%.2f%% load on the target)\n", st.hedgedCount, rate)
+}
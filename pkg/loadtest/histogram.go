@@ -0,0 +1,125 @@
+package loadtest
+
+import (
+	"math"
+	"math/bits"
+	"time"
+)
+
+// histogramRange is the range and precision latency histograms record with: latencies
+// are kept to Digits significant decimal digits up to Highest, and longer ones are
+// recorded as Highest. Its fields are exported to be saved in results files.
+type histogramRange struct {
+	Highest time.Duration
+	Digits  int
+}
+
+// defaultLatencyRange is the range of the latency histograms of a run without
+// --max-latency and --latency-precision, and of results files that do not record theirs.
+var defaultLatencyRange = histogramRange{Highest: time.Hour, Digits: 3}
+
+// latencyHistogram is a High Dynamic Range histogram of latencies in nanoseconds. Values
+// are grouped in buckets covering powers of two, each split into enough sub-buckets to
+// keep the configured number of significant digits, so percentiles are accurate to
+// that precision while memory only grows with the logarithm of the range, not with the
+// number of requests. Counts are allocated up to the highest latency seen.
+type latencyHistogram struct {
+	subBucketHalfCountMagnitude uint
+	subBucketHalfCount          int
+	subBucketMask               uint64
+	highest                     int64
+
+	counts  []int64
+	total   int64
+	max     int64
+	clamped int64
+}
+
+// newLatencyHistogram returns an empty histogram with the range r.
+func newLatencyHistogram(r histogramRange) *latencyHistogram {
+	// Sub-buckets must tell apart values differing by one unit of the last significant
+	// digit at the top of a bucket, where they are the furthest apart.
+	largestSingleUnit := 2 * int64(math.Pow10(r.Digits))
+	subBucketCountMagnitude := uint(bits.Len64(uint64(largestSingleUnit - 1)))
+	subBucketCount := 1 << subBucketCountMagnitude
+	return &latencyHistogram{
+		subBucketHalfCountMagnitude: subBucketCountMagnitude - 1,
+		subBucketHalfCount:          subBucketCount / 2,
+		subBucketMask:               uint64(subBucketCount - 1),
+		highest:                     int64(r.Highest),
+	}
+}
+
+// record adds a latency to the histogram.
+func (h *latencyHistogram) record(d time.Duration) {
+	v := max(int64(d), 0)
+	if v > h.highest {
+		v = h.highest
+		h.clamped++
+	}
+	i := h.countsIndex(v)
+	if i >= len(h.counts) {
+		h.counts = append(h.counts, make([]int64, i+1-len(h.counts))...)
+	}
+	h.counts[i]++
+	h.total++
+	h.max = max(h.max, v)
+}
+
+// countsIndex returns the index of the counter of v.
+func (h *latencyHistogram) countsIndex(v int64) int {
+	bucket := bits.Len64(uint64(v)|h.subBucketMask) - int(h.subBucketHalfCountMagnitude+1)
+	subBucket := int(v >> uint(bucket))
+	return (bucket+1)<<h.subBucketHalfCountMagnitude + subBucket - h.subBucketHalfCount
+}
+
+// highestEquivalentValue returns the largest value counted by the counter at index i.
+func (h *latencyHistogram) highestEquivalentValue(i int) int64 {
+	bucket := i>>h.subBucketHalfCountMagnitude - 1
+	subBucket := i&(h.subBucketHalfCount-1) + h.subBucketHalfCount
+	if bucket < 0 {
+		subBucket -= h.subBucketHalfCount
+		bucket = 0
+	}
+	return int64(subBucket+1)<<uint(bucket) - 1
+}
+
+// highestTrackable returns the highest latency the histogram records.
+func (h *latencyHistogram) highestTrackable() time.Duration {
+	return time.Duration(h.highest)
+}
+
+// merge adds the latencies recorded in other, a histogram with the same range.
+func (h *latencyHistogram) merge(other *latencyHistogram) {
+	if len(other.counts) > len(h.counts) {
+		h.counts = append(h.counts, make([]int64, len(other.counts)-len(h.counts))...)
+	}
+	for i, count := range other.counts {
+		h.counts[i] += count
+	}
+	h.total += other.total
+	h.clamped += other.clamped
+	h.max = max(h.max, other.max)
+}
+
+// count returns the number of latencies recorded.
+func (h *latencyHistogram) count() int64 {
+	return h.total
+}
+
+// percentile returns the latency under which the fraction q of the recorded latencies
+// fall, to the precision of the histogram. It never exceeds the largest latency seen.
+func (h *latencyHistogram) percentile(q float64) time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+	target := max(int64(math.Ceil(q*float64(h.total))), 1)
+	var seen int64
+	for i, count := range h.counts {
+		seen += count
+		if seen >= target {
+			return time.Duration(min(h.highestEquivalentValue(i), h.max))
+		}
+	}
+	return time.Duration(h.max)
+}
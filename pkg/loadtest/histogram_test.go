@@ -0,0 +1,78 @@
+package loadtest
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLatencyHistogramPercentile(t *testing.T) {
+	uniform := make([]time.Duration, 1000)
+	for i := range uniform {
+		uniform[i] = time.Duration(i+1) * time.Millisecond
+	}
+	tests := []struct {
+		name      string
+		latencies []time.Duration
+		q         float64
+		want      time.Duration
+	}{
+		{"empty", nil, 0.5, 0},
+		{"single", []time.Duration{42 * time.Millisecond}, 0.99, 42 * time.Millisecond},
+		{"p50", uniform, 0.50, 500 * time.Millisecond},
+		{"p90", uniform, 0.90, 900 * time.Millisecond},
+		{"p99", uniform, 0.99, 990 * time.Millisecond},
+		{"max", uniform, 1, 1000 * time.Millisecond},
+		{"min", uniform, 0, time.Millisecond},
+		{"sub-microsecond", []time.Duration{500, 700, 900}, 0.5, 700},
+	}
+	for _, tt := range tests {
+		h := newLatencyHistogram(histogramRange{Highest: time.Hour, Digits: 3})
+		for _, d := range tt.latencies {
+			h.record(d)
+		}
+		got := h.percentile(tt.q)
+		// Values are kept to 3 significant digits.
+		if diff := got - tt.want; diff < -tt.want/1000 || diff > tt.want/1000 {
+			t.Errorf("%s: percentile(%v) = %v, want %v within 0.1%%", tt.name, tt.q, got, tt.want)
+		}
+	}
+}
+
+func TestLatencyHistogramClamp(t *testing.T) {
+	h := newLatencyHistogram(histogramRange{Highest: time.Second, Digits: 2})
+	h.record(10 * time.Millisecond)
+	h.record(time.Minute)
+	h.record(-time.Millisecond)
+	if h.count() != 3 || h.clamped != 1 {
+		t.Fatalf("count() = %d, clamped = %d, want 3 and 1", h.count(), h.clamped)
+	}
+	if got := h.percentile(1); got != time.Second {
+		t.Errorf("percentile(1) = %v, want the highest trackable latency %v", got, time.Second)
+	}
+	if got := h.percentile(0); got != 0 {
+		t.Errorf("percentile(0) = %v, want 0 for a negative latency", got)
+	}
+}
+
+func TestLatencyHistogramMerge(t *testing.T) {
+	r := histogramRange{Highest: time.Hour, Digits: 3}
+	a, b, all := newLatencyHistogram(r), newLatencyHistogram(r), newLatencyHistogram(r)
+	for i := 1; i <= 200; i++ {
+		d := time.Duration(i) * time.Millisecond
+		if i%3 == 0 {
+			a.record(d)
+		} else {
+			b.record(d)
+		}
+		all.record(d)
+	}
+	a.merge(b)
+	if a.count() != all.count() || a.max != all.max {
+		t.Fatalf("merged count %d, max %v, want %d and %v", a.count(), a.max, all.count(), all.max)
+	}
+	for _, q := range []float64{0, 0.5, 0.9, 0.99, 1} {
+		if got, want := a.percentile(q), all.percentile(q); got != want {
+			t.Errorf("merged percentile(%v) = %v, want %v", q, got, want)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package loadtest
+
+import (
+	"fmt"
+	"html/template"
+	"sort"
+	"strings"
+	"time"
+)
+
+// htmlReport is the data of the HTML report of a run.
+type htmlReport struct {
+	StartedAt   string
+	Duration    string
+	Summary     Result
+	Latencies   []htmlRow
+	StatusCodes []htmlRow
+	Breakdowns  []htmlBreakdown
+	Series      []htmlSeries
+}
+
+// htmlRow is a labelled value of a table of the HTML report.
+type htmlRow struct {
+	Label string
+	Value string
+}
+
+// Dimensions of the over-time charts of the HTML report, in SVG units.
+const (
+	seriesWidth  = 720
+	seriesHeight = 160
+)
+
+// htmlSeries is an over-time chart of the HTML report: a polyline of one value per
+// second of the run, scaled so that the largest value reaches the top.
+type htmlSeries struct {
+	Title   string
+	Peak    string
+	Seconds int
+	Width   int
+	Height  int
+	Points  string
+}
+
+// newHTMLSeries returns the chart of value over the points of a timeline.
+func newHTMLSeries(title string, points []TimelinePoint, value func(TimelinePoint) float64, format func(float64) string) htmlSeries {
+	peak := 0.0
+	for _, p := range points {
+		peak = max(peak, value(p))
+	}
+	s := htmlSeries{Title: title, Peak: format(peak), Seconds: len(points), Width: seriesWidth, Height: seriesHeight}
+	coords := make([]string, 0, len(points))
+	for i, p := range points {
+		x := float64(seriesWidth) * (float64(i) + 0.5) / float64(len(points))
+		y := float64(seriesHeight)
+		if peak > 0 {
+			y -= value(p) / peak * float64(seriesHeight-10)
+		}
+		coords = append(coords, fmt.Sprintf("%.1f,%.1f", x, y))
+	}
+	s.Points = strings.Join(coords, " ")
+	return s
+}
+
+// htmlBreakdown is a per-key table of the HTML report, such as the results per target.
+type htmlBreakdown struct {
+	Title string
+	Rows  []htmlBreakdownRow
+}
+
+// htmlBreakdownRow is the line of a key of a breakdown.
+type htmlBreakdownRow struct {
+	Key           string
+	Requests      int
+	Successful    int
+	NetworkErrors int
+	AvgLatency    time.Duration
+	P99Latency    time.Duration
+}
+
+// writeHTMLReport writes a self-contained HTML page with the summary of a run and its
+// breakdowns to path, encrypted when recipients are configured.
+func writeHTMLReport(path string, opts OutputOptions, summary Result, measured *tally) error {
+	out, err := createOutput(path, opts)
+	if err != nil {
+		return err
+	}
+	if err := reportTemplate.Execute(out, newHTMLReport(summary, measured)); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// newHTMLReport lays out the summary and the breakdowns of a run for the template.
+func newHTMLReport(summary Result, measured *tally) htmlReport {
+	r := htmlReport{
+		StartedAt: summary.StartedAt.Format(time.RFC1123),
+		Duration:  time.Duration(summary.DurationMs * float64(time.Millisecond)).Round(time.Millisecond).String(),
+		Summary:   summary,
+	}
+	for _, latency := range []struct {
+		label string
+		ms    float64
+	}{
+		{"avg", summary.AvgLatencyMs}, {"p50", summary.P50LatencyMs}, {"p90", summary.P90LatencyMs},
+		{"p95", summary.P95LatencyMs}, {"p99", summary.P99LatencyMs}, {"max", summary.MaxLatencyMs},
+	} {
+		r.Latencies = append(r.Latencies, htmlRow{Label: latency.label, Value: formatMs(latency.ms)})
+	}
+	codes := make([]int, 0, len(summary.StatusCodes))
+	for code := range summary.StatusCodes {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		r.StatusCodes = append(r.StatusCodes, htmlRow{Label: fmt.Sprintf("HTTP %d", code), Value: fmt.Sprint(summary.StatusCodes[code])})
+	}
+	for _, breakdown := range []struct {
+		title string
+		byKey map[string]*stats
+	}{
+		{"Per class", measured.byClass}, {"Per target", measured.byTarget}, {"Per client profile", measured.byProfile},
+	} {
+		if len(breakdown.byKey) == 0 {
+			continue
+		}
+		r.Breakdowns = append(r.Breakdowns, newHTMLBreakdown(breakdown.title, breakdown.byKey))
+	}
+	if points := measured.timeline.points(time.Duration(summary.DurationMs * float64(time.Millisecond))); len(points) > 1 {
+		r.Series = []htmlSeries{
+			newHTMLSeries("Requests per second", points, func(p TimelinePoint) float64 { return p.RPS },
+				func(rps float64) string { return fmt.Sprintf("%.2f", rps) }),
+			newHTMLSeries("p95 latency", points, func(p TimelinePoint) float64 { return p.P95LatencyMs }, formatMs),
+		}
+	}
+	return r
+}
+
+// newHTMLBreakdown returns the table of a breakdown, in alphabetical key order.
+func newHTMLBreakdown(title string, byKey map[string]*stats) htmlBreakdown {
+	keys := make([]string, 0, len(byKey))
+	for key := range byKey {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	b := htmlBreakdown{Title: title}
+	for _, key := range keys {
+		st := byKey[key]
+		b.Rows = append(b.Rows, htmlBreakdownRow{
+			Key:           key,
+			Requests:      st.sentCount(),
+			Successful:    st.successCount(),
+			NetworkErrors: st.networkErrorCount,
+			AvgLatency:    st.averageLatency(),
+			P99Latency:    st.percentile(0.99),
+		})
+	}
+	return b
+}
+
+// reportTemplate renders the HTML report of a run.
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Load test report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+th, td { border: 1px solid #ccc; padding: 4px 10px; text-align: left; }
+.bad { color: #d62728; }
+.axis { stroke: #333; }
+.series { stroke: #1f77b4; fill: none; stroke-width: 2; }
+</style>
+</head>
+<body>
+<h1>Load test report</h1>
+<p>Started {{.StartedAt}}, measured for {{.Duration}}.{{if .Summary.Interrupted}} <span class="bad">The run was interrupted.</span>{{end}}{{with .Summary.Aborted}} <span class="bad">The run was aborted: {{.}}</span>{{end}}</p>
+<h2>Summary</h2>
+<table>
+<tr><th>Requests</th><td>{{.Summary.Requests}}</td></tr>
+<tr><th>Successful (2xx)</th><td>{{.Summary.Successful}}</td></tr>
+<tr><th>Network errors</th><td{{if .Summary.NetworkErrors}} class="bad"{{end}}>{{.Summary.NetworkErrors}}</td></tr>
+{{if .Summary.Skipped}}<tr><th>Skipped</th><td>{{.Summary.Skipped}}</td></tr>
+{{end}}{{if .Summary.Abandoned}}<tr><th>Abandoned</th><td>{{.Summary.Abandoned}}</td></tr>
+{{end}}{{if .Summary.FailedAssertions}}<tr><th>Failed status assertions</th><td class="bad">{{.Summary.FailedAssertions}}</td></tr>
+{{end}}{{if .Summary.FailedBodyChecks}}<tr><th>Failed body checks</th><td class="bad">{{.Summary.FailedBodyChecks}}</td></tr>
+{{end}}{{if .Summary.SchemaFailures}}<tr><th>Schema failures</th><td class="bad">{{.Summary.SchemaFailures}}</td></tr>
+{{end}}{{if .Summary.ScriptFailures}}<tr><th>Script failures</th><td class="bad">{{.Summary.ScriptFailures}}</td></tr>
+{{end}}<tr><th>Requests per second</th><td>{{printf "%.2f" .Summary.RequestsPerSecond}}</td></tr>
+</table>
+<h2>Latency</h2>
+<table>
+<tr>{{range .Latencies}}<th>{{.Label}}</th>{{end}}</tr>
+<tr>{{range .Latencies}}<td>{{.Value}}</td>{{end}}</tr>
+</table>
+{{if .StatusCodes}}<h2>Status codes</h2>
+<table>
+{{range .StatusCodes}}<tr><th>{{.Label}}</th><td>{{.Value}}</td></tr>
+{{end}}</table>
+{{end}}{{range .Series}}<h2>{{.Title}} over time</h2>
+<p>Peak {{.Peak}} over {{.Seconds}} seconds.</p>
+<svg width="{{.Width}}" height="{{.Height}}" viewBox="0 0 {{.Width}} {{.Height}}">
+<line class="axis" x1="0" x2="{{.Width}}" y1="{{.Height}}" y2="{{.Height}}"/>
+<polyline class="series" points="{{.Points}}"/>
+</svg>
+{{end}}{{range .Breakdowns}}<h2>{{.Title}}</h2>
+<table>
+<tr><th></th><th>Requests</th><th>Successful (2xx)</th><th>Network errors</th><th>avg</th><th>p99</th></tr>
+{{range .Rows}}<tr><td>{{.Key}}</td><td>{{.Requests}}</td><td>{{.Successful}}</td><td>{{.NetworkErrors}}</td><td>{{.AvgLatency}}</td><td>{{.P99Latency}}</td></tr>
+{{end}}</table>
+{{end}}</body>
+</html>
+`))
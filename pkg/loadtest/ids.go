@@ -0,0 +1,80 @@
+package loadtest
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// sequence backs the "seq" ID type. A run shares one between all its workers so every
+// generated value is unique within the run.
+type sequence struct {
+	last atomic.Int64
+}
+
+// reset makes the next sequence ID equal to start.
+func (s *sequence) reset(start int64) {
+	s.last.Store(start - 1)
+}
+
+// next returns the next value of the sequence.
+func (s *sequence) next() int64 {
+	return s.last.Add(1)
+}
+
+// crockfordAlphabet is the base32 alphabet used by ULIDs.
+const crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// newUUID returns a random (version 4) UUID drawing its random bits from r.
+func newUUID(r io.Reader) string {
+	var u [16]byte
+	io.ReadFull(r, u[:])
+	u[6] = (u[6] & 0x0f) | 0x40
+	u[8] = (u[8] & 0x3f) | 0x80
+	return formatUUID(u)
+}
+
+// newUUIDv7 returns a time-ordered (version 7) UUID: a 48-bit Unix millisecond
+// timestamp followed by random bits.
+func newUUIDv7(r io.Reader) string {
+	var u [16]byte
+	io.ReadFull(r, u[6:])
+	putUnixMillis(u[:6], time.Now())
+	u[6] = (u[6] & 0x0f) | 0x70
+	u[8] = (u[8] & 0x3f) | 0x80
+	return formatUUID(u)
+}
+
+// newULID returns a ULID: a 48-bit Unix millisecond timestamp and 80 random bits,
+// encoded as 26 Crockford base32 characters.
+func newULID(r io.Reader) string {
+	var u [16]byte
+	io.ReadFull(r, u[6:])
+	putUnixMillis(u[:6], time.Now())
+
+	hi := binary.BigEndian.Uint64(u[:8])
+	lo := binary.BigEndian.Uint64(u[8:])
+	out := make([]byte, 26)
+	for i := 25; i >= 0; i-- {
+		out[i] = crockfordAlphabet[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out)
+}
+
+// putUnixMillis writes the Unix millisecond timestamp of t as 48 big-endian bits.
+func putUnixMillis(dst []byte, t time.Time) {
+	ms := uint64(t.UnixMilli())
+	for i := 5; i >= 0; i-- {
+		dst[i] = byte(ms)
+		ms >>= 8
+	}
+}
+
+// formatUUID renders u in the canonical 8-4-4-4-12 form.
+func formatUUID(u [16]byte) string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
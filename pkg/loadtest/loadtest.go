@@ -0,0 +1,1286 @@
+// Package loadtest is the load generator of the restclient command, for Go programs and
+// test suites that embed it. A Config describes a run, mirroring the command line flags,
+// and Run executes it and returns its Result, printing the same reports as the command
+// unless SummaryOnly is set.
+package loadtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// Config holds the settings of a single load test run. Its fields mirror the flags of
+// the restclient command; values parsed from a flag, such as Hedge or ClientMix, come
+// from the Parse function of their type.
+type Config struct {
+	Requests         int
+	Duration         time.Duration
+	Warmup           time.Duration
+	DrainTimeout     time.Duration
+	MaxDuration      time.Duration
+	AbortErrorRate   float64
+	AbortWindow      int
+	Concurrency      int
+	Rate             float64
+	ArrivalRate      float64
+	MaxQueue         int
+	Spike            *SpikeProfile
+	Stages           *StageProfile
+	ReportInterval   time.Duration
+	InterimOutPath   string
+	StreamPath       string
+	StatsD           StatsDOptions
+	SummaryOnly      bool
+	CurveOutputs     []string
+	ThinkTime        time.Duration
+	ThinkJitter      time.Duration
+	ClientMix        []ClientShare
+	ExpectStatus     []int
+	BodyAssertions   []BodyAssertion
+	AssertSample     float64
+	Verb             string
+	JSONPath         string
+	RandIDType       string
+	SeqStart         int64
+	RandIDChrs       int
+	DisableKeepAlive bool
+	MaxIdleConns     int
+	MaxConnsPerHost  int
+
+	Timeout               time.Duration
+	Retries               int
+	GiveUpAfter           time.Duration
+	DeadlineHeader        string
+	DeadlineFormat        string
+	Hedge                 *HedgePolicy
+	ConnectTimeout        time.Duration
+	TLSHandshakeTimeout   time.Duration
+	ResponseHeaderTimeout time.Duration
+	DNSDelay              time.Duration
+	DoH                   *DoHResolver
+	Resolve               ResolveOverrides
+	DNSCache              *DNSCache
+	DisableHappyEyeballs  bool
+	IPVersion             int
+	Insecure              bool
+	InsecureHosts         []string
+	HostHeader            string
+	SNI                   string
+
+	FeedCapture string
+	FeedSize    int
+
+	ReadAfterWrite      string
+	ReadAfterWriteDelay time.Duration
+
+	Targets        []WeightedTarget
+	TargetsPath    string
+	PostmanPath    string
+	PostmanEnvPath string
+	CurlPath       string
+	Generator      RequestGenerator
+	HARPath        string
+	ReadURLs       []string
+	WriteURLs      []string
+	ReadWeight     int
+	WriteWeight    int
+
+	MaxRedirects      int
+	NoFollowRedirects bool
+
+	SlowThreshold time.Duration
+	SlowTop       int
+
+	LatencyPrecision int
+	MaxLatency       time.Duration
+
+	AcceptEncoding string
+	MaxBody        int64
+	GzipBody       bool
+
+	ProbeRequests  int
+	ProbeInterval  time.Duration
+	ProbeTolerance float64
+	ProbeURL       string
+
+	PrimePath     string
+	PrimeInterval time.Duration
+
+	SetupPath    string
+	TeardownPath string
+
+	Headers      []string
+	RotateAccept []string
+	RotateLocale []string
+	FormFields   []string
+
+	Body        string
+	BodyFile    string
+	RawBody     bool
+	Seed        int64
+	ContentType string
+
+	Files           []string
+	MultipartFields []string
+
+	OpenAPIPath    string
+	OpenAPISamples int
+	ValidateOnly   bool
+
+	Cooldown       time.Duration
+	HealthURL      string
+	HealthInterval time.Duration
+
+	DataPath string
+	DataMode string
+	DataPer  string
+	Cookies  string
+
+	RandFields  []string
+	Rerandomize bool
+
+	FailoverURLs []string
+	FailoverOn   FailoverPolicy
+
+	Verbose         int
+	VerboseRequests int
+	RawLogPath      string
+	ErrorLogPath    string
+	DecisionLogPath string
+	ConnLogPath     string
+	ReportJSONPath  string
+	ReportHTMLPath  string
+	ResultsPath     string
+	BaselinePath    string
+	Output          OutputOptions
+	RedactHeaders   []string
+	RedactFields    []string
+
+	CaptureResponses *ResponseCapture
+	CaptureDir       string
+
+	NoDefaultRedaction bool
+	ResponseSchemaPath string
+	ScriptPath         string
+
+	StartAt time.Time
+	Window  time.Duration
+	Region  string
+
+	// Settings holds the effective value of every setting by environment variable name,
+	// such as the restclient command records them, for the manifest of the run.
+	Settings map[string]string
+
+	// Logger receives the diagnostics of the run, e.g. a JSON logger feeding a log
+	// pipeline. Nil means the console at the info level.
+	Logger *slog.Logger
+
+	// steps shares the setup and teardown steps among the checks of RunMonitor.
+	steps *stepSession
+}
+
+// withDefaults returns cfg with the settings that cannot be zero, and were left unset,
+// set to the defaults of the restclient command.
+func (cfg Config) withDefaults() Config {
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 10
+	}
+	if cfg.Requests <= 0 && cfg.Duration <= 0 {
+		cfg.Requests = 100
+	}
+	if cfg.Verb == "" {
+		cfg.Verb = http.MethodGet
+	}
+	if cfg.ReadWeight == 0 && cfg.WriteWeight == 0 {
+		cfg.ReadWeight, cfg.WriteWeight = 1, 1
+	}
+	if cfg.FeedSize <= 0 {
+		cfg.FeedSize = 1000
+	}
+	if cfg.DataMode == "" {
+		cfg.DataMode = DataModeSequential
+	}
+	if cfg.DataPer == "" {
+		cfg.DataPer = DataPerRequest
+	}
+	if cfg.Cookies == "" {
+		cfg.Cookies = CookiesPerVU
+	}
+	if cfg.CaptureResponses != nil && cfg.CaptureDir == "" {
+		cfg.CaptureDir = "captures"
+	}
+	if cfg.HealthInterval <= 0 {
+		cfg.HealthInterval = 5 * time.Second
+	}
+	if cfg.LatencyPrecision <= 0 {
+		cfg.LatencyPrecision = 3
+	}
+	if cfg.MaxLatency <= 0 {
+		cfg.MaxLatency = time.Hour
+	}
+	return cfg
+}
+
+// requestResult describes the outcome of a single request. The class is the workload class
+// ("read" or "write") of the target it was sent to, or empty for single URL runs, and
+// the profile is the --client-mix profile of the worker that sent it, and the window is the
+// --spike window or --steps stage it was started in.
+// A statusCode of -1 means the request failed before a response was received.
+// A feedMiss means the request needed a value from the feed pool while it was empty
+// and was therefore not sent; a dataExhausted means the same for the rows of a data
+// feed consumed in "once" mode; an arrivalDropped means an arrival of an open-model run
+// found every virtual user busy. A generatorDone result is not a request: the
+// RequestGenerator of the run has no more of them. The remaining fields describe the request and response
+// in enough detail to follow up on outliers.
+type requestResult struct {
+	class          string
+	target         string
+	profile        string
+	region         string
+	statusCode     int
+	latency        time.Duration
+	completed      time.Time
+	reused         bool
+	feedMiss       bool
+	dataExhausted  bool
+	arrivalDropped bool
+	generatorDone  bool
+	window         string
+	bodyTruncated  bool
+
+	drained        bool
+	drainCancelled bool
+	warmup         bool
+	abandoned      bool
+	hedged         bool
+	hedgeWon       bool
+
+	unexpectedStatus bool
+	checks           []bool
+	schemaChecked    bool
+	schemaProblems   []string
+	scriptChecked    bool
+	scriptFailure    string
+
+	method        string
+	url           string
+	remoteAddr    string
+	contentType   string
+	contentLength int64
+	timing        requestTiming
+	exchange      *exchangeRecord
+	pairSampled   bool
+	worker        int
+	errorText     string
+	errorBody     string
+	decision      *targetDecision
+
+	connectAttempts map[string]int
+	rotated         map[string]string
+
+	servedBy      string
+	failovers     int
+	failoverDelay time.Duration
+	retries       int
+	retryDelay    time.Duration
+	expectStatus  []int
+
+	captured string
+	readBack *readBack
+
+	responseHeader http.Header
+	responseBody   []byte
+
+	bodyBytes            int64
+	bodyBytesWire        int64
+	responseBytesWire    int64
+	responseBytesDecoded int64
+	headerBytesSent      int64
+	headerBytesReceived  int64
+}
+
+// skipped reports whether the request of r was not sent at all.
+func (r requestResult) skipped() bool {
+	return r.feedMiss || r.dataExhausted || r.arrivalDropped
+}
+
+// worker holds the state of a single worker. Random field values are generated once
+// per worker and location and injected into every body it sends; the row is set
+// when data rows are assigned per virtual user. Template data and target picks draw
+// from separate random streams, so changing the targets does not change the data. The
+// jar holds the cookies of the virtual user, unless the run shares or disables them.
+// Virtual users are numbered from 1, and their iteration counts the requests they sent
+// so far; requests sent outside of the load, such as setup steps, have a vu of 0.
+type worker struct {
+	body         *templateSource
+	randomValues map[string]interface{}
+	row          map[string]string
+	random       *randomStream
+	targets      *randomStream
+	mix          *clientMix
+	script       *scriptState
+	jar          http.CookieJar
+	vu           int
+	iteration    int
+	// dataExhausted is set when no data row was left for the virtual user.
+	dataExhausted bool
+}
+
+// engine holds the state shared by all workers of a run.
+type engine struct {
+	cfg        Config
+	client     *http.Client
+	feed       *feedPool
+	data       *dataFeed
+	headers    []header
+	randFields []randField
+	form       []formField
+	redactor   *redactor
+
+	multipartFields []formField
+	multipartFiles  []multipartFile
+
+	bodyType      string
+	bodyTypeCheck sync.Once
+
+	rotations []*headerRotation
+
+	failoverBases []string
+	totalWeight   int
+
+	random *randomStreams
+	run    *runControl
+	pacer  *pacer
+
+	connLog  *connLog
+	mix      []*clientMix
+	dns      *dnsStats
+	capture  *responseCapture
+	errorLog *ndjsonLog
+	verbose  *verboseDump
+
+	assertSampler  *bodySampler
+	responseSchema *responseSchema
+	script         *script
+	hedge          *HedgePolicy
+
+	urlsMu   sync.Mutex
+	urls     map[string]*templateSource
+	requests *requestCache
+	// generator is cfg.Generator, or the default generator sending the targets.
+	generator RequestGenerator
+
+	timeoutOverrides bool
+
+	vars map[string]string
+}
+
+// Run starts the load test with the specified parameters.
+// It uses a goroutine for each worker, sending concurrent requests to the target URL.
+// All workers share a single client so that connections can be reused across them.
+// It returns the summary of the run, or an error when the run could not be started.
+// With ValidateOnly, it returns an empty summary once the sample requests are valid.
+func Run(cfg Config) (Result, error) {
+	return RunContext(context.Background(), cfg)
+}
+
+// RunContext is Run with a context: cancelling ctx interrupts the run, which stops
+// issuing requests and drains the ones in flight, and before a scheduled start it cancels
+// the run. The package installs no signal handler; to stop a run on Ctrl+C, cancel ctx on
+// os.Interrupt, e.g. with signal.NotifyContext.
+func RunContext(ctx context.Context, cfg Config) (Result, error) {
+	cfg = cfg.withDefaults()
+	logger := cfg.log()
+	if cfg.Generator != nil && cfg.ProbeRequests > 0 && cfg.ProbeURL == "" {
+		return Result{}, errors.New("probing a run with a request generator needs a probe URL")
+	}
+	var wg sync.WaitGroup
+	requestsPerWorker := cfg.Requests / cfg.Concurrency
+	extraRequests := cfg.Requests % cfg.Concurrency
+	bufferSize := cfg.Requests
+	if cfg.Duration > 0 {
+		requestsPerWorker, extraRequests = -1, 0
+		bufferSize = cfg.Concurrency
+	}
+
+	results := make(chan requestResult, bufferSize)
+	measured := newTally(histogramRange{Highest: cfg.MaxLatency, Digits: cfg.LatencyPrecision}, cfg.SlowThreshold, cfg.SlowTop)
+	st := measured.all
+
+	headers, err := parseHeaders(cfg.Headers)
+	if err != nil {
+		return Result{}, err
+	}
+
+	var runManifest Manifest
+	if cfg.ReportJSONPath != "" || cfg.BaselinePath != "" || cfg.ResultsPath != "" {
+		runManifest, err = newManifest(cfg)
+		if err != nil {
+			return Result{}, fmt.Errorf("error hashing run inputs: %w", err)
+		}
+	}
+
+	form, err := parseFormFields(cfg.FormFields)
+	if err != nil {
+		return Result{}, err
+	}
+	multipartFields, err := parseFormFields(cfg.MultipartFields)
+	if err != nil {
+		return Result{}, err
+	}
+	multipartFiles, err := parseMultipartFiles(cfg.Files)
+	if err != nil {
+		return Result{}, fmt.Errorf("invalid file: %w", err)
+	}
+
+	var randFields []randField
+	if cfg.RandIDType != "" {
+		randFields = append(randFields, randField{
+			spec:   "id",
+			path:   []pathSegment{{key: "id"}},
+			idType: cfg.RandIDType,
+			length: cfg.RandIDChrs,
+		})
+	}
+	for _, spec := range cfg.RandFields {
+		field, err := parseRandField(spec)
+		if err != nil {
+			return Result{}, err
+		}
+		randFields = append(randFields, field)
+	}
+
+	redact, err := newRedactor(cfg.RedactHeaders, cfg.RedactFields, !cfg.NoDefaultRedaction)
+	if err != nil {
+		return Result{}, fmt.Errorf("invalid redaction rule: %w", err)
+	}
+
+	random := newRandomStreams(cfg.Seed)
+	random.seq.reset(cfg.SeqStart)
+
+	var data *dataFeed
+	if cfg.DataPath != "" {
+		data, err = loadDataFeed(cfg.DataPath, cfg.DataMode, random.stream("data"))
+		if err != nil {
+			return Result{}, fmt.Errorf("error loading data file: %w", err)
+		}
+	}
+
+	var primeURLs []string
+	if cfg.PrimePath != "" {
+		primeURLs, err = readLines(cfg.PrimePath)
+		if err != nil {
+			return Result{}, fmt.Errorf("error reading priming file: %w", err)
+		}
+	}
+
+	setup, teardown, err := loadSteps(cfg)
+	if err != nil {
+		return Result{}, err
+	}
+
+	var connLog *connLog
+	if cfg.ConnLogPath != "" {
+		connLog, err = newConnLog(cfg.ConnLogPath, cfg.Output, logger)
+		if err != nil {
+			return Result{}, fmt.Errorf("error creating connection log: %w", err)
+		}
+	}
+
+	e := &engine{
+		cfg: cfg,
+		client: &http.Client{
+			Transport:     newTransport(cfg, connLog, nil),
+			Timeout:       cfg.Timeout,
+			CheckRedirect: newRedirectPolicy(cfg),
+		},
+		feed:    newFeedPool(cfg.FeedSize, random.stream("feed")),
+		data:    data,
+		headers: headers,
+		urls:    make(map[string]*templateSource),
+
+		randFields: randFields,
+		form:       form,
+
+		multipartFields: multipartFields,
+		multipartFiles:  multipartFiles,
+
+		redactor: redact,
+
+		failoverBases: cfg.FailoverURLs,
+
+		connLog: connLog,
+		mix:     newClientMix(cfg, connLog, cfg.ClientMix),
+		dns:     &dnsStats{},
+		verbose: newVerboseDump(cfg.Verbose, cfg.VerboseRequests),
+		vars:    make(map[string]string),
+
+		assertSampler: &bodySampler{rate: cfg.AssertSample},
+		hedge:         cfg.Hedge,
+
+		random: random,
+		pacer:  newPacer(cfg.Rate),
+	}
+	if cfg.Cookies == CookiesShared {
+		jar := newCookieJar()
+		e.client.Jar = jar
+		for _, m := range e.mix {
+			m.client.Jar = jar
+		}
+	}
+	if hasTimeoutOverride(cfg) {
+		e.timeoutOverrides = true
+		e.client.Timeout = 0
+		for _, m := range e.mix {
+			m.client.Timeout = 0
+		}
+	}
+	if len(cfg.RotateAccept) > 0 {
+		e.rotations = append(e.rotations, newHeaderRotation("Accept", cfg.RotateAccept))
+	}
+	if len(cfg.RotateLocale) > 0 {
+		e.rotations = append(e.rotations, newHeaderRotation("Accept-Language", cfg.RotateLocale))
+	}
+
+	var body *templateSource
+	bodyPath := cfg.BodyFile
+	if bodyPath == "" && cfg.JSONPath != "" && (cfg.Verb == "POST" || len(cfg.WriteURLs) > 0) {
+		bodyPath = cfg.JSONPath
+	}
+	var rawBody []byte
+	if cfg.Body != "" {
+		rawBody = []byte(cfg.Body)
+	} else if bodyPath != "" {
+		rawBody, err = os.ReadFile(bodyPath)
+		if err != nil {
+			return Result{}, fmt.Errorf("error reading body file: %w", err)
+		}
+	}
+	if rawBody != nil {
+		e.bodyType = detectContentType(bodyPath, rawBody)
+		if cfg.ContentType != "" {
+			e.bodyType = cfg.ContentType
+		}
+		if cfg.RawBody || !isTextType(e.bodyType) {
+			body = newRawSource(rawBody)
+		} else {
+			name := bodyPath
+			if name == "" {
+				name = "body"
+			}
+			body, err = newTemplateSource(name, rawBody)
+			if err != nil {
+				return Result{}, fmt.Errorf("error parsing body template: %w", err)
+			}
+		}
+	}
+
+	for _, t := range cfg.Targets {
+		e.totalWeight += t.weight
+	}
+
+	if cfg.ResponseSchemaPath != "" {
+		e.responseSchema, err = loadResponseSchema(cfg.ResponseSchemaPath)
+		if err != nil {
+			return Result{}, fmt.Errorf("error loading response schema: %w", err)
+		}
+	}
+
+	if cfg.ScriptPath != "" {
+		e.script, err = loadScript(cfg.ScriptPath)
+		if err != nil {
+			return Result{}, fmt.Errorf("error loading script: %w", err)
+		}
+	}
+	e.requests = newRequestCache(e)
+	e.generator = cfg.Generator
+	if e.generator == nil {
+		e.generator = &targetGenerator{e: e}
+	}
+
+	if cfg.OpenAPIPath != "" {
+		validator, err := loadOpenAPISpec(cfg.OpenAPIPath)
+		if err != nil {
+			return Result{}, fmt.Errorf("error loading OpenAPI spec: %w", err)
+		}
+		if !e.validateSamples(validator, body, cfg.OpenAPISamples) {
+			return Result{}, errors.New("fix the scenario or the spec before running the load test")
+		}
+		if cfg.ValidateOnly {
+			return Result{}, nil
+		}
+	}
+
+	window := newSchedule(cfg, time.Now())
+	var planned time.Duration
+	if cfg.Duration > 0 {
+		planned = cfg.Warmup + cfg.Duration + cfg.Cooldown
+	}
+	if err := window.wait(ctx, planned); err != nil {
+		return Result{}, err
+	}
+	// --max-duration bounds everything from here, in-flight requests included.
+	var errMaxDuration error
+	releaseDeadline := func() {}
+	if cfg.MaxDuration > 0 {
+		errMaxDuration = fmt.Errorf("the run was still going after --max-duration of %v", cfg.MaxDuration)
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, cfg.MaxDuration, errMaxDuration)
+		stopAlert := context.AfterFunc(ctx, func() {
+			if context.Cause(ctx) == errMaxDuration {
+				logger.Error("Aborting the run", "reason", errMaxDuration)
+			}
+		})
+		releaseDeadline = func() {
+			stopAlert()
+			cancel()
+		}
+		defer releaseDeadline()
+	}
+	overtime := func() bool { return errMaxDuration != nil && context.Cause(ctx) == errMaxDuration }
+
+	if session := cfg.steps; session != nil {
+		switch session.phase {
+		case sessionSetup:
+			if err := e.runSetup(ctx, setup); err != nil {
+				return Result{}, err
+			}
+			session.vars = e.vars
+			return Result{}, nil
+		case sessionTeardown:
+			e.vars = maps.Clone(session.vars)
+			e.runTeardown(teardown)
+			return Result{}, nil
+		}
+		e.vars = maps.Clone(session.vars)
+	} else if len(setup) > 0 {
+		if err := e.runSetup(ctx, setup); err != nil {
+			return Result{}, err
+		}
+	}
+
+	var rawLog *ndjsonLog
+	if cfg.RawLogPath != "" {
+		rawLog, err = newNDJSONLog(cfg.RawLogPath, cfg.Output, logger)
+		if err != nil {
+			return Result{}, fmt.Errorf("error creating raw log: %w", err)
+		}
+	}
+
+	if cfg.ErrorLogPath != "" {
+		e.errorLog, err = newNDJSONLog(cfg.ErrorLogPath, cfg.Output, logger)
+		if err != nil {
+			return Result{}, fmt.Errorf("error creating error log: %w", err)
+		}
+	}
+
+	var interimOut *ndjsonLog
+	if cfg.InterimOutPath != "" {
+		interimOut, err = newNDJSONLog(cfg.InterimOutPath, cfg.Output, logger)
+		if err != nil {
+			return Result{}, fmt.Errorf("error creating interim report file: %w", err)
+		}
+	}
+
+	var streamOut *ndjsonLog
+	switch cfg.StreamPath {
+	case "":
+	case "-":
+		streamOut = startNDJSONLog("the standard output", stdoutStream{standardOutput}, logger)
+	default:
+		streamOut, err = newNDJSONLog(cfg.StreamPath, cfg.Output, logger)
+		if err != nil {
+			return Result{}, fmt.Errorf("error creating stream file: %w", err)
+		}
+	}
+
+	statsd, err := newStatsDClient(cfg.StatsD)
+	if err != nil {
+		return Result{}, fmt.Errorf("error connecting to StatsD: %w", err)
+	}
+
+	var decisions *ndjsonLog
+	if cfg.DecisionLogPath != "" {
+		decisions, err = newNDJSONLog(cfg.DecisionLogPath, cfg.Output, logger)
+		if err != nil {
+			return Result{}, fmt.Errorf("error creating decision log: %w", err)
+		}
+	}
+	selection := newSelectionMix()
+
+	if cfg.CaptureResponses != nil {
+		e.capture, err = newResponseCapture(cfg.CaptureResponses, cfg.CaptureDir, cfg.Output, random.stream("capture"), logger)
+		if err != nil {
+			return Result{}, fmt.Errorf("error creating capture directory: %w", err)
+		}
+	}
+
+	var samples *resultsWriter
+	if cfg.ResultsPath != "" {
+		samples, err = newResultsWriter(cfg.ResultsPath, cfg.Output, runManifest)
+		if err != nil {
+			return Result{}, fmt.Errorf("error creating results file: %w", err)
+		}
+	}
+
+	if len(primeURLs) > 0 {
+		e.runPriming(ctx, primeURLs)
+	}
+
+	var baseline probeResult
+	if cfg.ProbeRequests > 0 && !overtime() {
+		if cfg.ProbeURL == "" {
+			cfg.ProbeURL = defaultProbeURL(cfg)
+		}
+		logger.Info("Probing for a baseline", "url", cfg.ProbeURL)
+		baseline = e.runProbe(cfg.ProbeURL)
+	}
+
+	var health *healthMonitor
+	var warmupTimer *time.Timer
+	if cfg.HealthURL != "" {
+		health = startHealthMonitor(cfg.HealthURL, cfg.HealthInterval, cfg.Timeout)
+		if cfg.Warmup > 0 {
+			health.setPhase(phaseWarmup)
+			warmupTimer = time.AfterFunc(cfg.Warmup, func() { health.setPhase(phaseLoad) })
+		}
+	}
+
+	startTime := time.Now()
+	warmupEnd := startTime.Add(cfg.Warmup)
+	measured.timeline.start = warmupEnd
+
+	runDuration := cfg.Duration
+	if runDuration > 0 {
+		runDuration += cfg.Warmup
+	}
+	run := newRunControl(ctx, runDuration, cfg.DrainTimeout, logger)
+	e.run = run
+	defer window.enforce(run)()
+
+	// newVU sets up the virtual user of worker id, or returns nil when its script could
+	// not be started. Its release function closes its script state.
+	newVU := func(id int) (*worker, func()) {
+		w := &worker{
+			body:         body,
+			randomValues: make(map[string]interface{}),
+			random:       random.stream(fmt.Sprintf("worker/%d/data", id)),
+			targets:      random.stream(fmt.Sprintf("worker/%d/targets", id)),
+			mix:          mixForWorker(e.mix, id, cfg.Concurrency),
+			vu:           id + 1,
+		}
+		if cfg.Cookies == CookiesPerVU {
+			w.jar = newCookieJar()
+		}
+		release := func() {}
+		if e.script != nil {
+			state, err := e.script.newState()
+			if err != nil {
+				logger.Error("Error starting script", "worker", id, "error", err)
+				return nil, nil
+			}
+			w.script = state
+			release = state.close
+		}
+		if data != nil && cfg.DataPer == DataPerVU {
+			row, err := data.take()
+			if err != nil {
+				w.dataExhausted = true
+			}
+			w.row = row
+		}
+		return w, release
+	}
+
+	send := func(w *worker, request int, warmup bool) requestResult {
+		id := w.vu - 1
+		if w.dataExhausted {
+			return requestResult{dataExhausted: true, warmup: warmup}
+		}
+		var d *targetDecision
+		if decisions != nil && cfg.Generator == nil {
+			d = &targetDecision{Time: time.Now(), Worker: id, Request: request, Warmup: warmup}
+		}
+		res := e.sendNext(w, d)
+		if w.script != nil && w.script.after != nil && res.statusCode != 0 {
+			res.scriptChecked = true
+			res.scriptFailure = w.script.afterResponse(res)
+		}
+		res.responseHeader, res.responseBody = nil, nil
+		if cfg.ReadAfterWrite != "" && res.captured != "" && sendsBody(res.method) {
+			res.readBack = e.readAfterWrite(res.captured, w)
+		}
+		res.completed = time.Now()
+		res.warmup = warmup
+		res.decision = d
+		res.worker = id
+		w.iteration++
+		return res
+	}
+
+	var schedule arrivalSchedule
+	switch {
+	case cfg.Spike != nil:
+		schedule = cfg.Spike
+	case cfg.Stages != nil && cfg.Stages.Rates():
+		schedule = cfg.Stages
+	}
+	// Concurrency stages run closed-loop workers, activated as the stages need them.
+	var stages *StageProfile
+	if cfg.Stages != nil && !cfg.Stages.Rates() {
+		stages = cfg.Stages
+	}
+	openModel := cfg.ArrivalRate > 0 || schedule != nil
+	if openModel {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			plan := arrivalPlan{rate: cfg.ArrivalRate, schedule: schedule, maxVUs: cfg.Concurrency, maxQueue: cfg.MaxQueue, requests: cfg.Requests, warmupEnd: warmupEnd}
+			if cfg.Duration > 0 {
+				plan.requests = -1
+			}
+			runArrivals(run, plan, newVU, send, results)
+		}()
+	}
+	for i := 0; !openModel && i < cfg.Concurrency; i++ {
+		wg.Add(1)
+		go func(id, requests int) {
+			defer wg.Done()
+
+			w, release := newVU(id)
+			if w == nil {
+				return
+			}
+			defer release()
+			think := random.stream(fmt.Sprintf("worker/%d/think", id))
+			if w.dataExhausted {
+				for j := 0; j < requests; j++ {
+					results <- requestResult{dataExhausted: true}
+				}
+				return
+			}
+
+			for j := 0; time.Now().Before(warmupEnd) && !run.stopped(); j++ {
+				if stages != nil && id >= stages.stages[0].concurrency {
+					// Workers of the later stages join after the warm-up.
+					break
+				}
+				e.pacer.wait(run)
+				if run.stopped() {
+					break
+				}
+				res := send(w, j, true)
+				if res.generatorDone {
+					break
+				}
+				results <- res
+				if res.dataExhausted {
+					break
+				}
+				run.pause(thinkTime(think, cfg.ThinkTime, cfg.ThinkJitter))
+			}
+
+			for j := 0; requests < 0 || j < requests; j++ {
+				window := ""
+				if stages != nil {
+					if !stages.waitActive(run, id, warmupEnd) {
+						return
+					}
+					window = stages.window(time.Since(warmupEnd))
+				}
+				e.pacer.wait(run)
+				if run.stopped() {
+					return
+				}
+				res := send(w, j, false)
+				if res.generatorDone {
+					return
+				}
+				res.window = window
+				res.drained = run.stopped() && !res.drainCancelled
+				results <- res
+				if res.dataExhausted {
+					for j++; j < requests; j++ {
+						results <- requestResult{class: res.class, dataExhausted: true}
+					}
+				}
+				if requests < 0 || j+1 < requests {
+					run.pause(thinkTime(think, cfg.ThinkTime, cfg.ThinkJitter))
+				}
+			}
+		}(i, requestsPerWorker+boolToInt(i < extraRequests))
+	}
+
+	var interrupted bool
+	go func() {
+		wg.Wait()
+		interrupted = run.interrupted()
+		run.finish()
+		close(results)
+	}()
+
+	errorsLogged := 0
+	var guard *errorRateGuard
+	if cfg.AbortErrorRate > 0 {
+		guard = newErrorRateGuard(cfg.AbortErrorRate, cfg.AbortWindow)
+	}
+
+	interim := newInterimReporter(cfg.ReportInterval, startTime, interimOut, st.latencyRange)
+	stream := newStatsStream(startTime, streamOut, st.latencyRange)
+
+collect:
+	for {
+		var res requestResult
+		select {
+		case r, ok := <-results:
+			if !ok {
+				break collect
+			}
+			res = r
+		case now := <-interim.tick():
+			interim.report(now, measured.all)
+			continue
+		case now := <-stream.tick():
+			stream.write(now, measured.all)
+			continue
+		case <-statsd.tick():
+			statsd.flush()
+			continue
+		}
+		if guard != nil && guard.add(res) && run.aborted() == nil {
+			run.abort(fmt.Errorf("the error rate over the last %d requests reached %.1f%%, above %.1f%%",
+				cfg.AbortWindow, guard.rate()*100, cfg.AbortErrorRate*100))
+			logger.Error("Aborting the run", "reason", run.aborted())
+		}
+		if rawLog != nil && res.exchange != nil {
+			if res.pairSampled {
+				rawLog.write(res.exchange.truncated())
+			} else {
+				rawLog.write(res.exchange)
+			}
+		}
+		if decisions != nil && res.decision != nil {
+			decisions.write(res.decision)
+			if !res.warmup {
+				selection.add(res.decision)
+			}
+		}
+		if e.capture != nil && res.pairSampled && res.exchange != nil {
+			e.capture.add(res.exchange)
+		}
+		if e.errorLog != nil {
+			if rec := newErrorRecord(res, expectedStatusesOf(res, cfg.ExpectStatus)); rec != nil {
+				e.errorLog.write(rec)
+				errorsLogged++
+			}
+		}
+		if res.warmup {
+			st.warmupCount++
+			continue
+		}
+		res.unexpectedStatus = unexpectedStatus(res, expectedStatusesOf(res, cfg.ExpectStatus))
+		measured.add(res)
+		interim.add(res)
+		stream.add(res)
+		statsd.add(res)
+		if samples != nil {
+			if err := samples.write(res); err != nil {
+				logger.Error("Error writing results", "error", err)
+			}
+		}
+	}
+	if err := interim.stop(); err != nil {
+		logger.Error("Error writing interim reports", "error", err)
+	}
+	if err := stream.stop(); err != nil {
+		logger.Error("Error writing stream", "error", err)
+	}
+	if err := statsd.stop(); err != nil {
+		logger.Warn("Error sending metrics to StatsD", "error", err)
+	}
+	if rawLog != nil {
+		if err := rawLog.close(); err != nil {
+			logger.Error("Error writing raw log", "error", err)
+		}
+	}
+	if decisions != nil {
+		if err := decisions.close(); err != nil {
+			logger.Error("Error writing decision log", "error", err)
+		}
+	}
+	if e.errorLog != nil {
+		if err := e.errorLog.close(); err != nil {
+			logger.Error("Error writing error log", "error", err)
+		} else if errorsLogged > 0 {
+			logger.Warn("Failed requests were written to the error log", "count", errorsLogged, "path", cfg.ErrorLogPath)
+		}
+	}
+	if e.capture != nil {
+		if err := e.capture.close(); err != nil {
+			logger.Error("Error saving captured responses", "error", err)
+		}
+	}
+
+	totalTime := max(time.Since(warmupEnd), 0)
+	e.run = nil
+	if warmupTimer != nil {
+		warmupTimer.Stop()
+	}
+
+	if cfg.Cooldown > 0 && !overtime() {
+		logger.Info("Cooling down", "duration", cfg.Cooldown)
+		if health != nil {
+			health.setPhase(phaseCooldown)
+		}
+		select {
+		case <-time.After(cfg.Cooldown):
+		case <-ctx.Done():
+		}
+	}
+	if health != nil {
+		health.close()
+	}
+
+	var afterLoad probeResult
+	if cfg.ProbeRequests > 0 && !overtime() {
+		logger.Info("Probing after the load", "url", cfg.ProbeURL)
+		afterLoad = e.runProbe(cfg.ProbeURL)
+	}
+	if len(teardown) > 0 && cfg.steps == nil {
+		// Teardown steps clean up after the load, so they run even after an interrupt.
+		e.runTeardown(teardown)
+	}
+
+	if connLog != nil {
+		e.client.CloseIdleConnections()
+		for _, m := range e.mix {
+			m.client.CloseIdleConnections()
+		}
+		if err := connLog.close(); err != nil {
+			logger.Error("Error closing connection log", "error", err)
+		}
+	}
+
+	summary := newReportSummary(startTime, totalTime, st)
+	summary.Interrupted = interrupted
+	if err := run.aborted(); err != nil {
+		summary.Aborted = err.Error()
+	} else if overtime() {
+		summary.Aborted = errMaxDuration.Error()
+	}
+	summary.MaxDurationExceeded = overtime()
+	releaseDeadline()
+	if cfg.ReportJSONPath != "" {
+		report := Report{Manifest: runManifest, Summary: summary, Timeline: measured.timeline.points(totalTime)}
+		if err := writeJSONReport(cfg.ReportJSONPath, cfg.Output, report); err != nil {
+			logger.Error("Error writing JSON report", "path", cfg.ReportJSONPath, "error", err)
+		}
+	}
+	if cfg.BaselinePath != "" {
+		// Baselines are read back by --compare, so they are never encrypted; like the
+		// JSON report, they only hold hashes of the settings.
+		report := Report{Manifest: runManifest, Summary: summary}
+		if err := writeJSONReport(cfg.BaselinePath, OutputOptions{}, report); err != nil {
+			logger.Error("Error saving baseline", "path", cfg.BaselinePath, "error", err)
+		}
+	}
+	if cfg.ReportHTMLPath != "" {
+		if err := writeHTMLReport(cfg.ReportHTMLPath, cfg.Output, summary, measured); err != nil {
+			logger.Error("Error writing HTML report", "path", cfg.ReportHTMLPath, "error", err)
+		}
+	}
+	reporting := newReportSettings(cfg, e)
+	if samples != nil {
+		footer := resultsFooter{
+			StartedAt:    startTime,
+			MeasuredFrom: warmupEnd,
+			Duration:     totalTime,
+			Warmup:       st.warmupCount,
+			FeedCaptured: e.feed.capturedCount(),
+			Interrupted:  summary.Interrupted,
+			Aborted:      summary.Aborted,
+			Region:       cfg.Region,
+			Settings:     reporting,
+
+			MaxDurationExceeded: summary.MaxDurationExceeded,
+		}
+		if err := samples.close(footer); err != nil {
+			logger.Error("Error closing results file", "error", err)
+		}
+	}
+	if cfg.SummaryOnly {
+		return summary, nil
+	}
+
+	printReports(reporting, totalTime, measured, e.feed.capturedCount(), summary.Aborted)
+	generateSelectionReport(selection)
+	if cfg.DoH != nil {
+		generateDoHReport(cfg.DoH)
+	}
+	generateDNSReport(e.dns, cfg.DNSCache)
+	if health != nil {
+		generateHealthReport(health)
+	}
+	if cfg.ProbeRequests > 0 {
+		generateProbeReport(baseline, afterLoad, cfg.ProbeTolerance)
+	}
+	return summary, nil
+}
+
+// defaultProbeURL returns the URL probed when none is configured: the first target.
+func defaultProbeURL(cfg Config) string {
+	switch {
+	case len(cfg.Targets) > 0:
+		return cfg.Targets[0].url
+	case len(cfg.ReadURLs) > 0:
+		return cfg.ReadURLs[0]
+	default:
+		return cfg.WriteURLs[0]
+	}
+}
+
+// printReports prints the text report of a run from its measured results, either right
+// after the run or from a results file.
+func printReports(reporting reportSettings, totalTime time.Duration, measured *tally, feedCaptured int, aborted string) {
+	st := measured.all
+	// generateReport consumes the status code counts, so the total is taken first. Skipped
+	// requests were never sent, so they are reported on their own lines instead.
+	total := st.sentCount()
+	generateReport(totalTime, total, st, feedCaptured, reporting.MaxQueue)
+	generateStatusReport(st)
+	generateTimingReport(st)
+	generateThroughputReport(totalTime, st)
+	if reporting.GiveUpAfter > 0 {
+		generateAbandonmentReport(reporting.GiveUpAfter, total, st)
+	}
+	if reporting.Hedge != "" {
+		generateHedgeReport(reporting.Hedge, total, st)
+	}
+	if len(reporting.ExpectStatus) > 0 || len(reporting.TargetExpectStatus) > 0 {
+		generateAssertionReport(reporting.ExpectStatus, reporting.TargetExpectStatus, total, st)
+	}
+	if st.retriedCount > 0 {
+		generateRetryReport(st)
+	}
+	if len(reporting.BodyAssertions) > 0 {
+		generateBodyAssertionReport(reporting.BodyAssertions, st)
+	}
+	if reporting.ResponseSchema != "" {
+		generateSchemaReport(reporting.ResponseSchema, st)
+	}
+	if reporting.Script != "" && st.scriptChecked > 0 {
+		generateScriptReport(reporting.Script, st)
+	}
+	if aborted != "" {
+		color.Red("\n🚨 The run was aborted early: %s", aborted)
+	}
+	generateClassReport(measured.byClass)
+	generateTargetReport(measured.byTarget)
+	generateProfileReport(measured.byProfile)
+	generateRegionReport(measured.byRegion)
+	if len(reporting.Stages) > 0 {
+		generateStageReport(reporting.Stages, measured.byWindow)
+	} else {
+		generateSpikeReport(measured.byWindow)
+	}
+	rotations := make([]*headerRotation, len(reporting.Rotations))
+	for i, rotation := range reporting.Rotations {
+		rotations[i] = newHeaderRotation(rotation.Header, rotation.Values)
+	}
+	generateRotationReport(rotations, measured.rotated)
+	if reporting.Compression {
+		generateCompressionReport(st)
+	}
+	if reporting.Failover {
+		generateFailoverReport(st)
+	}
+	if reporting.ReadAfterWrite != "" {
+		generateReadAfterWriteReport(reporting.ReadAfterWrite, reporting.ReadAfterWriteDelay, measured.readBacks)
+	}
+	generateSlowReport(measured.slow)
+}
+
+// generateReport generates a summary report of the load test results, including
+// the total time, successful and failed requests, and the distribution of HTTP status codes.
+func generateReport(totalTime time.Duration, totalRequests int, st *stats, feedCaptured, maxQueue int) {
+	color.Green("\n===== 📝 Load Test Report =====")
+	fmt.Printf("⏳ Total time: %v\n", totalTime)
+	fmt.Printf("📊 Total requests: %d\n", totalRequests)
+	if st.warmupCount > 0 {
+		fmt.Printf("🔥 Warm-up requests left out of the report: %d\n", st.warmupCount)
+	}
+	color.Cyan("✅ Successful requests (HTTP 200): %d\n", st.statusCodeCount[200])
+
+	if st.latencies.count() > 0 {
+		fmt.Printf("⏱️  Latency: avg %v, p50 %v, p90 %v, p95 %v, p99 %v, max %v\n", st.averageLatency(),
+			st.percentile(0.50), st.percentile(0.90), st.percentile(0.95), st.percentile(0.99), st.percentile(1))
+	}
+	if st.latencies.clamped > 0 {
+		color.Yellow("📏 Latencies over --max-latency, recorded as %v: %d", st.latencies.highestTrackable(), st.latencies.clamped)
+	}
+
+	delete(st.statusCodeCount, 200)
+
+	if len(st.statusCodeCount) > 0 {
+		color.Yellow("\n📉 Distribution of other HTTP status codes:")
+		for status, count := range st.statusCodeCount {
+			if status >= 400 {
+				color.Red("  ❌ Failed requests (HTTP %d): %d", status, count)
+			} else {
+				fmt.Printf("  - HTTP %d: %d\n", status, count)
+			}
+		}
+	}
+
+	if st.networkErrorCount > 0 {
+		color.Red("\n❌ Network errors: %d", st.networkErrorCount)
+	}
+
+	fmt.Printf("\n🔁 Requests on reused connections: %d\n", st.reusedConnCount)
+	fmt.Printf("🆕 Requests on new connections: %d\n", st.newConnCount)
+	generateFamilyReport(st)
+
+	if st.drainedCount > 0 || st.drainCancelledCount > 0 {
+		fmt.Printf("\n⏹️  In-flight requests at the end of the run: %d completed while draining, %d cancelled after the drain timeout\n",
+			st.drainedCount, st.drainCancelledCount)
+	}
+
+	if st.truncatedCount > 0 {
+		color.Yellow("\n✂️  Response bodies truncated at --max-body: %d", st.truncatedCount)
+	}
+
+	if st.dataExhaustedCount > 0 {
+		color.Yellow("\n⏭️  Requests not sent because the data file was exhausted: %d", st.dataExhaustedCount)
+	}
+
+	switch {
+	case st.droppedCount > 0 && maxQueue > 0:
+		color.Yellow("\n⏭️  Arrivals shed because all --concurrency virtual users were busy and %d were queued: %d", maxQueue, st.droppedCount)
+	case st.droppedCount > 0:
+		color.Yellow("\n⏭️  Arrivals dropped because all --concurrency virtual users were busy: %d", st.droppedCount)
+	}
+
+	if feedCaptured > 0 || st.feedMissCount > 0 {
+		fmt.Printf("\n🧺 Values captured into the feed pool: %d\n", feedCaptured)
+		color.Yellow("⏭️  Requests skipped because the feed pool was empty: %d", st.feedMissCount)
+	}
+
+	color.Magenta("\n⚡ Requests per second: %.2f\n", requestsPerSecond(totalRequests, totalTime))
+}
+
+// boolToInt converts a boolean to an integer (1 for true, 0 for false).
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
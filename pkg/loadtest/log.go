@@ -0,0 +1,110 @@
+package loadtest
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/fatih/color"
+)
+
+// defaultLogger receives the diagnostics of the runs that set no Logger.
+var defaultLogger = NewConsoleLogger(slog.LevelInfo)
+
+// log returns the logger receiving the diagnostics of a run of cfg: errors, warnings and
+// the progress of the run. Reports are not diagnostics and are always printed to the
+// standard output.
+func (cfg Config) log() *slog.Logger {
+	if cfg.Logger != nil {
+		return cfg.Logger
+	}
+	return defaultLogger
+}
+
+// NewConsoleLogger returns the default logger of the engine: records of level and above
+// are printed to the console like the reports, in the color of their level.
+func NewConsoleLogger(level slog.Level) *slog.Logger {
+	return slog.New(&consoleHandler{level: level, mu: &sync.Mutex{}})
+}
+
+// consoleHandler prints a record on a single line: its message, then its error attribute
+// after a colon and its other attributes as key=value pairs.
+type consoleHandler struct {
+	level slog.Level
+	attrs []slog.Attr
+	mu    *sync.Mutex
+}
+
+// Enabled reports whether records of level are printed.
+func (h *consoleHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+// Handle prints r.
+func (h *consoleHandler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+	var errText string
+	var pairs []string
+	add := func(a slog.Attr) bool {
+		if a.Key == "error" {
+			errText = a.Value.String()
+			return true
+		}
+		value := a.Value.String()
+		if strings.ContainsAny(value, " \t\"") {
+			value = strconv.Quote(value)
+		}
+		pairs = append(pairs, a.Key+"="+value)
+		return true
+	}
+	for _, a := range h.attrs {
+		add(a)
+	}
+	r.Attrs(add)
+
+	out := color.New(color.FgCyan)
+	switch {
+	case r.Level >= slog.LevelError:
+		out = color.New(color.FgRed)
+		b.WriteString("❌ ")
+	case r.Level >= slog.LevelWarn:
+		out = color.New(color.FgYellow)
+		b.WriteString("⚠️  ")
+	case r.Level < slog.LevelInfo:
+		out = color.New(color.Reset)
+	}
+	b.WriteString(r.Message)
+	if errText != "" {
+		b.WriteString(": " + errText)
+	}
+	for _, pair := range pairs {
+		b.WriteString(" " + pair)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := out.Fprintln(color.Output, b.String())
+	return err
+}
+
+// WithAttrs returns a handler adding attrs to every record.
+func (h *consoleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &consoleHandler{level: h.level, attrs: append(append([]slog.Attr{}, h.attrs...), attrs...), mu: h.mu}
+}
+
+// WithGroup returns h: the console does not show groups.
+func (h *consoleHandler) WithGroup(string) slog.Handler {
+	return h
+}
+
+// ParseLogLevel parses the --log-level value: debug, info, warn or error.
+func ParseLogLevel(value string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(value)); err != nil {
+		return 0, fmt.Errorf("%q is not debug, info, warn or error", value)
+	}
+	return level, nil
+}
@@ -0,0 +1,261 @@
+package loadtest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// Manifest identifies the inputs of a run: the tool version, the effective settings and
+// the content of every file read. It is written with the JSON report so a published
+// result can later be checked against the inputs it claims to come from. Setting values
+// are only stored as hashes since headers and bodies often carry credentials.
+type Manifest struct {
+	Tool     string            `json:"tool"`
+	Go       string            `json:"go"`
+	Config   string            `json:"config"`
+	Settings map[string]string `json:"settings"`
+	Files    map[string]string `json:"files"`
+}
+
+// outputSettings only change when the run happens or where and how results are written,
+// not what is sent, so they are left out of the manifest.
+var outputSettings = map[string]bool{
+	"RAW_LOG":              true,
+	"CONN_LOG":             true,
+	"DECISION_LOG":         true,
+	"ERROR_LOG":            true,
+	"REPORT_INTERVAL":      true,
+	"INTERIM_OUT":          true,
+	"STREAM":               true,
+	"STATSD":               true,
+	"STATSD_PREFIX":        true,
+	"DOGSTATSD":            true,
+	"STATSD_TAGS":          true,
+	"CAPTURE_RESPONSES":    true,
+	"CAPTURE_DIR":          true,
+	"VERBOSE":              true,
+	"VERBOSE_REQUESTS":     true,
+	"NO_COLOR":             true,
+	"LOG_FORMAT":           true,
+	"LOG_LEVEL":            true,
+	"PLAIN":                true,
+	"REPORT_JSON":          true,
+	"SIGN_KEY":             true,
+	"REPORT_HTML":          true,
+	"OUT":                  true,
+	"SAVE_BASELINE":        true,
+	"COMPARE":              true,
+	"LATENCY_TOLERANCE":    true,
+	"THROUGHPUT_TOLERANCE": true,
+	"CURVE_OUT":            true,
+	"THRESHOLDS":           true,
+	"ENCRYPT_AGE":          true,
+	"ENCRYPT_GPG":          true,
+	"REDACT_HEADERS":       true,
+	"REDACT_FIELDS":        true,
+	"NO_DEFAULT_REDACTION": true,
+	"START_AT":             true,
+	"WINDOW":               true,
+	"REGION":               true,
+	"HOSTS":                true,
+	"REMOTE_BIN":           true,
+}
+
+// newManifest builds the manifest of a run of cfg from its settings and the current
+// content of its input files.
+func newManifest(cfg Config) (Manifest, error) {
+	m := Manifest{
+		Tool:     toolVersion(),
+		Go:       runtime.Version(),
+		Settings: make(map[string]string),
+		Files:    make(map[string]string),
+	}
+	names := make([]string, 0, len(cfg.Settings))
+	for name := range cfg.Settings {
+		if !outputSettings[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	config := sha256.New()
+	for _, name := range names {
+		fmt.Fprintf(config, "%s=%q\n", name, cfg.Settings[name])
+		m.Settings[name] = hashBytes([]byte(cfg.Settings[name]))
+	}
+	m.Config = "sha256:" + hex.EncodeToString(config.Sum(nil))
+
+	for _, path := range inputFiles(cfg) {
+		sum, err := hashFile(path)
+		if err != nil {
+			return Manifest{}, err
+		}
+		m.Files[path] = sum
+	}
+	return m, nil
+}
+
+// inputFiles lists the files a run of cfg reads requests from.
+func inputFiles(cfg Config) []string {
+	paths := []string{cfg.JSONPath, cfg.BodyFile, cfg.TargetsPath, cfg.PostmanPath, cfg.PostmanEnvPath, cfg.CurlPath, cfg.OpenAPIPath, cfg.HARPath, cfg.DataPath, cfg.PrimePath, cfg.SetupPath, cfg.TeardownPath, cfg.ScriptPath}
+	for _, t := range cfg.Targets {
+		paths = append(paths, t.bodyPath)
+	}
+	for _, definition := range cfg.Files {
+		_, path, _ := strings.Cut(definition, "=@")
+		paths = append(paths, path)
+	}
+
+	var files []string
+	seen := make(map[string]bool)
+	for _, path := range paths {
+		if path != "" && !seen[path] {
+			seen[path] = true
+			files = append(files, path)
+		}
+	}
+	return files
+}
+
+// hashBytes returns the SHA-256 of b in the "sha256:<hex>" form used by the manifest.
+func hashBytes(b []byte) string {
+	sum := sha256.Sum256(b)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// hashFile returns the SHA-256 of the content of the file at path.
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// toolVersion returns the module version of the binary with its VCS revision, when the
+// build recorded them.
+func toolVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	version := info.Main.Version
+	for _, setting := range info.Settings {
+		switch {
+		case setting.Key == "vcs.revision":
+			version += " " + setting.Value
+		case setting.Key == "vcs.modified" && setting.Value == "true":
+			version += " (modified)"
+		}
+	}
+	return version
+}
+
+// VerifyRun checks that the manifest of the JSON report at path matches a run of cfg
+// and prints every difference. It reports whether the report and cfg match.
+func VerifyRun(path string, cfg Config) bool {
+	color.Green("\n===== 🔏 Run Verification =====")
+	recorded, err := readManifest(path)
+	if err != nil {
+		color.Red("❌ Error reading report: %v", err)
+		return false
+	}
+	current, err := newManifest(cfg)
+	if err != nil {
+		color.Red("❌ Error hashing run inputs: %v", err)
+		return false
+	}
+
+	mismatches := compareManifests(recorded, current)
+	if len(mismatches) == 0 {
+		color.Green("✅ %s matches the given configuration and inputs", path)
+		if cfg.Settings["SEED"] == "0" {
+			color.Yellow("⚠️  The run was not seeded, so its random data cannot be reproduced.")
+		}
+		return true
+	}
+	color.Red("❌ %s does not match the given configuration and inputs:", path)
+	for _, mismatch := range mismatches {
+		color.Red("  - %s", mismatch)
+	}
+	return false
+}
+
+// readManifest reads the manifest of the JSON report at path.
+func readManifest(path string) (Manifest, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return Manifest{}, err
+	}
+	var report struct {
+		Manifest *Manifest `json:"manifest"`
+	}
+	if err := json.Unmarshal(content, &report); err != nil {
+		return Manifest{}, err
+	}
+	if report.Manifest == nil {
+		return Manifest{}, errors.New("the report has no manifest")
+	}
+	return *report.Manifest, nil
+}
+
+// compareManifests describes every difference between a recorded and a current manifest.
+func compareManifests(recorded, current Manifest) []string {
+	var mismatches []string
+	if recorded.Tool != current.Tool {
+		mismatches = append(mismatches, fmt.Sprintf("tool version: report %q, current %q", recorded.Tool, current.Tool))
+	}
+	if recorded.Go != current.Go {
+		mismatches = append(mismatches, fmt.Sprintf("Go version: report %q, current %q", recorded.Go, current.Go))
+	}
+	mismatches = append(mismatches, compareHashes("setting", recorded.Settings, current.Settings)...)
+	mismatches = append(mismatches, compareHashes("file", recorded.Files, current.Files)...)
+	if len(mismatches) == 0 && recorded.Config != current.Config {
+		mismatches = append(mismatches, "config hash differs although every setting matches")
+	}
+	return mismatches
+}
+
+// compareHashes describes the differences between two maps of hashes, in name order.
+func compareHashes(kind string, recorded, current map[string]string) []string {
+	names := make([]string, 0, len(recorded)+len(current))
+	for name := range recorded {
+		names = append(names, name)
+	}
+	for name := range current {
+		if _, ok := recorded[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var mismatches []string
+	for _, name := range names {
+		want, inReport := recorded[name]
+		got, inCurrent := current[name]
+		switch {
+		case !inReport:
+			mismatches = append(mismatches, fmt.Sprintf("%s %s is not in the report", kind, name))
+		case !inCurrent:
+			mismatches = append(mismatches, fmt.Sprintf("%s %s is in the report but not in the given inputs", kind, name))
+		case want != got:
+			mismatches = append(mismatches, fmt.Sprintf("%s %s differs", kind, name))
+		}
+	}
+	return mismatches
+}
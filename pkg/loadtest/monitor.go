@@ -0,0 +1,247 @@
+package loadtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// MonitorOptions configures "restclient monitor".
+type MonitorOptions struct {
+	Interval      time.Duration
+	MetricsListen string
+	AlertWebhook  string
+	AlertAfter    int
+	Thresholds    []Threshold
+}
+
+// monitorState is the record of the checks of a monitor, exported as metrics.
+type monitorState struct {
+	mu          sync.Mutex
+	checks      int
+	failed      int
+	consecutive int
+	alerting    bool
+	lastCheck   time.Time
+	last        Result
+}
+
+// monitorAlert is the JSON payload posted to --alert-webhook when the monitored scenario
+// goes down or recovers.
+type monitorAlert struct {
+	Status              string    `json:"status"`
+	Target              string    `json:"target"`
+	Time                time.Time `json:"time"`
+	Reason              string    `json:"reason,omitempty"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	Checks              int       `json:"checks"`
+	FailedChecks        int       `json:"failed_checks"`
+}
+
+// RunMonitor runs the scenario of cfg as a synthetic check once per interval until ctx
+// is done. Every check is a short run whose outcome is logged on one line; after
+// alertAfter consecutive failed checks an alert is raised, and cleared by the next
+// passing check. The setup steps of cfg are sent once before the first check, whose
+// variables every check reads, and the teardown steps once after the last one. It
+// returns an error when the monitor could not start.
+func RunMonitor(ctx context.Context, cfg Config, target string, opts MonitorOptions) error {
+	cfg.SummaryOnly = true
+	state := &monitorState{}
+	if opts.MetricsListen != "" {
+		listener, err := net.Listen("tcp", opts.MetricsListen)
+		if err != nil {
+			return fmt.Errorf("error serving metrics: %w", err)
+		}
+		mux := http.NewServeMux()
+		mux.HandleFunc("/metrics", state.serveMetrics)
+		go http.Serve(listener, mux)
+		color.Cyan("📡 Serving metrics on http://%s/metrics", listener.Addr())
+	}
+
+	session := &stepSession{phase: sessionSetup}
+	cfg.steps = session
+	if cfg.SetupPath != "" {
+		if _, err := RunContext(ctx, cfg); err != nil {
+			return err
+		}
+	}
+	session.phase = sessionChecks
+
+	color.Cyan("🛰️  Monitoring %s every %v, press Ctrl+C to stop...", target, opts.Interval)
+checks:
+	for {
+		start := time.Now()
+		summary, err := RunContext(ctx, cfg)
+		if summary.Interrupted {
+			break
+		}
+		state.record(cfg, target, summary, monitorFailure(summary, err, opts.Thresholds), opts)
+
+		select {
+		case <-ctx.Done():
+			break checks
+		case <-time.After(time.Until(start.Add(opts.Interval))):
+		}
+	}
+
+	if cfg.TeardownPath != "" {
+		// Teardown steps clean up after the checks, so they run even after an interrupt.
+		session.phase = sessionTeardown
+		if _, err := RunContext(context.WithoutCancel(ctx), cfg); err != nil {
+			cfg.log().Error("Teardown failed", "error", err)
+		}
+	}
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	color.Green("\n===== 🛰️  Monitor Summary =====")
+	fmt.Printf("🔁 Checks: %d, failed: %d, uptime %.2f%%\n", state.checks, state.failed, state.uptime()*100)
+	return nil
+}
+
+// monitorFailure returns why a check failed, or an empty string when it passed.
+func monitorFailure(summary Result, err error, thresholds []Threshold) string {
+	if err != nil {
+		return fmt.Sprintf("the check could not run: %v", err)
+	}
+	var reasons []string
+	if failed := summary.failed(); failed > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d of %d requests failed", failed, summary.Requests))
+	}
+	if summary.Abandoned > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d requests abandoned", summary.Abandoned))
+	}
+	if summary.FailedAssertions > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d failed status assertions", summary.FailedAssertions))
+	}
+	if summary.FailedBodyChecks > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d failed body checks", summary.FailedBodyChecks))
+	}
+	if summary.SchemaFailures > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d schema failures", summary.SchemaFailures))
+	}
+	if summary.ScriptFailures > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d script failures", summary.ScriptFailures))
+	}
+	for _, t := range thresholds {
+		if actual, rendered := t.measure(summary); !t.passes(actual) {
+			reasons = append(reasons, fmt.Sprintf("%s (actual %s)", t.expr, rendered))
+		}
+	}
+	return strings.Join(reasons, ", ")
+}
+
+// record logs the outcome of a check, updates the metrics and raises or clears the alert.
+func (s *monitorState) record(cfg Config, target string, summary Result, reason string, opts MonitorOptions) {
+	s.mu.Lock()
+	s.checks++
+	s.lastCheck = time.Now()
+	s.last = summary
+	if reason != "" {
+		s.failed++
+		s.consecutive++
+	} else {
+		s.consecutive = 0
+	}
+	alert := monitorAlert{
+		Target:              target,
+		Time:                s.lastCheck.UTC(),
+		Reason:              reason,
+		ConsecutiveFailures: s.consecutive,
+		Checks:              s.checks,
+		FailedChecks:        s.failed,
+	}
+	switch {
+	case reason != "" && !s.alerting && s.consecutive >= opts.AlertAfter:
+		s.alerting = true
+		alert.Status = "down"
+	case reason == "" && s.alerting:
+		s.alerting = false
+		alert.Status = "recovered"
+	}
+	s.mu.Unlock()
+
+	stamp := alert.Time.Local().Format(time.TimeOnly)
+	if reason != "" {
+		color.Red("❌ %s check %d failed: %s", stamp, alert.Checks, reason)
+	} else {
+		fmt.Printf("✅ %s check %d passed: %d requests, avg %s, max %s\n", stamp, alert.Checks,
+			summary.Requests, formatMs(summary.AvgLatencyMs), formatMs(summary.MaxLatencyMs))
+	}
+	switch alert.Status {
+	case "down":
+		color.Red("🚨 ALERT: %s is failing (%d consecutive failed checks)", target, alert.ConsecutiveFailures)
+	case "recovered":
+		color.Green("💚 RECOVERED: %s passes its checks again", target)
+	default:
+		return
+	}
+	if opts.AlertWebhook != "" {
+		if err := postAlert(opts.AlertWebhook, cfg.Timeout, alert); err != nil {
+			cfg.log().Error("Error sending alert", "webhook", opts.AlertWebhook, "error", err)
+		}
+	}
+}
+
+// postAlert posts alert as JSON to the webhook url.
+func postAlert(url string, timeout time.Duration, alert monitorAlert) error {
+	payload, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("the webhook answered HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// uptime returns the fraction of passed checks. The caller holds s.mu.
+func (s *monitorState) uptime() float64 {
+	if s.checks == 0 {
+		return 0
+	}
+	return float64(s.checks-s.failed) / float64(s.checks)
+}
+
+// serveMetrics exports the state of the monitor in the Prometheus text format.
+func (s *monitorState) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	up := 0
+	if s.checks > 0 && s.consecutive == 0 {
+		up = 1
+	}
+	fmt.Fprintf(w, "# HELP restclient_monitor_up Whether the last check passed.\n# TYPE restclient_monitor_up gauge\nrestclient_monitor_up %d\n", up)
+	fmt.Fprintf(w, "# HELP restclient_monitor_checks_total Checks run.\n# TYPE restclient_monitor_checks_total counter\nrestclient_monitor_checks_total %d\n", s.checks)
+	fmt.Fprintf(w, "# HELP restclient_monitor_failed_checks_total Checks that failed.\n# TYPE restclient_monitor_failed_checks_total counter\nrestclient_monitor_failed_checks_total %d\n", s.failed)
+	fmt.Fprintf(w, "# HELP restclient_monitor_uptime_ratio Fraction of the checks that passed.\n# TYPE restclient_monitor_uptime_ratio gauge\nrestclient_monitor_uptime_ratio %g\n", s.uptime())
+	if s.checks == 0 {
+		return
+	}
+	fmt.Fprintf(w, "# HELP restclient_monitor_last_check_timestamp_seconds Time of the last check.\n# TYPE restclient_monitor_last_check_timestamp_seconds gauge\nrestclient_monitor_last_check_timestamp_seconds %d\n", s.lastCheck.Unix())
+	fmt.Fprintf(w, "# HELP restclient_monitor_latency_seconds Latency of the requests of the last check.\n# TYPE restclient_monitor_latency_seconds gauge\n")
+	for _, stat := range []struct {
+		name string
+		ms   float64
+	}{
+		{"avg", s.last.AvgLatencyMs}, {"p50", s.last.P50LatencyMs}, {"p95", s.last.P95LatencyMs},
+		{"p99", s.last.P99LatencyMs}, {"max", s.last.MaxLatencyMs},
+	} {
+		fmt.Fprintf(w, "restclient_monitor_latency_seconds{stat=%q} %g\n", stat.name, stat.ms/1000)
+	}
+}
@@ -0,0 +1,101 @@
+package loadtest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunMonitorSendsStepsOnce(t *testing.T) {
+	var mu sync.Mutex
+	paths := make(map[string]int)
+	var tokens []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		paths[r.URL.Path]++
+		switch r.URL.Path {
+		case "/login":
+			w.Write([]byte(`{"token": "t1"}`))
+		case "/check":
+			tokens = append(tokens, r.Header.Get("Authorization"))
+		}
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	setup := filepath.Join(dir, "setup.txt")
+	teardown := filepath.Join(dir, "teardown.txt")
+	if err := os.WriteFile(setup, []byte("POST "+srv.URL+"/login capture=token:token\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(teardown, []byte("DELETE "+srv.URL+"/logout\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	targets, err := ParseTargets([]string{srv.URL + "/check"}, "GET")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := Config{
+		Targets:      targets,
+		Requests:     1,
+		Concurrency:  1,
+		Headers:      []string{`Authorization: Bearer {{var "token"}}`},
+		SetupPath:    setup,
+		TeardownPath: teardown,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 250*time.Millisecond)
+	defer cancel()
+	if err := RunMonitor(ctx, cfg, srv.URL, MonitorOptions{Interval: 50 * time.Millisecond, AlertAfter: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if paths["/login"] != 1 || paths["/logout"] != 1 {
+		t.Errorf("setup sent %d times and teardown %d times, want once each", paths["/login"], paths["/logout"])
+	}
+	if paths["/check"] < 2 {
+		t.Errorf("%d checks, want several", paths["/check"])
+	}
+	for i, token := range tokens {
+		if token != "Bearer t1" {
+			t.Errorf("check %d sent Authorization %q, want the token captured by the setup", i, token)
+		}
+	}
+}
+
+func TestRunMonitorFailedSetup(t *testing.T) {
+	var checks atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		checks.Add(1)
+	}))
+	defer srv.Close()
+
+	setup := filepath.Join(t.TempDir(), "setup.txt")
+	if err := os.WriteFile(setup, []byte("POST "+srv.URL+"/login\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	targets, err := ParseTargets([]string{srv.URL + "/check"}, "GET")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := Config{Targets: targets, Requests: 1, Concurrency: 1, SetupPath: setup}
+	if err := RunMonitor(context.Background(), cfg, srv.URL, MonitorOptions{Interval: time.Second, AlertAfter: 1}); err == nil {
+		t.Error("RunMonitor() error = nil, want the failed setup step")
+	}
+	if n := checks.Load(); n != 0 {
+		t.Errorf("%d checks sent after a failed setup, want none", n)
+	}
+}
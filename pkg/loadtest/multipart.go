@@ -0,0 +1,162 @@
+package loadtest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// multipartFile is a file sent as a part of a multipart/form-data body.
+type multipartFile struct {
+	field string
+	path  string
+	size  int64
+}
+
+// parseMultipartFiles parses "field=@path" file definitions and checks that every file exists.
+func parseMultipartFiles(definitions []string) ([]multipartFile, error) {
+	files := make([]multipartFile, 0, len(definitions))
+	for _, definition := range definitions {
+		field, path, ok := strings.Cut(definition, "=@")
+		if !ok || field == "" || path == "" {
+			return nil, fmt.Errorf("invalid file %q, expected field=@path", definition)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if info.IsDir() {
+			return nil, fmt.Errorf("file %s is a directory", path)
+		}
+		files = append(files, multipartFile{field: field, path: path, size: info.Size()})
+	}
+	return files, nil
+}
+
+// multipartPayload is a rendered multipart/form-data body. Files are streamed from disk
+// every time the body is opened instead of being kept in memory.
+type multipartPayload struct {
+	boundary string
+	fields   [][2]string
+	files    []multipartFile
+	length   int64
+}
+
+// newMultipartPayload renders the form fields of a multipart body for a single request.
+func newMultipartPayload(fields []formField, files []multipartFile, scope *renderScope) (*multipartPayload, error) {
+	p := &multipartPayload{boundary: randomBoundary(), files: files}
+	for _, field := range fields {
+		value, err := field.value.render(scope)
+		if err != nil {
+			return nil, err
+		}
+		p.fields = append(p.fields, [2]string{field.key, string(value)})
+	}
+	p.length = p.contentLength()
+	return p, nil
+}
+
+// contentType returns the Content-Type header of the body, including its boundary.
+func (p *multipartPayload) contentType() string {
+	return "multipart/form-data; boundary=" + p.boundary
+}
+
+// contentLength returns the exact size of the body: the multipart framing written with
+// empty files plus the size of every file.
+func (p *multipartPayload) contentLength() int64 {
+	counter := &countingWriter{}
+	p.write(counter, false)
+	length := counter.n
+	for _, file := range p.files {
+		length += file.size
+	}
+	return length
+}
+
+// open returns a reader streaming the body. Files are read while the request is sent.
+func (p *multipartPayload) open() io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(p.write(pw, true))
+	}()
+	return pr
+}
+
+// write writes the body to w, with the file contents when withFiles is true.
+func (p *multipartPayload) write(w io.Writer, withFiles bool) error {
+	mw := multipart.NewWriter(w)
+	if err := mw.SetBoundary(p.boundary); err != nil {
+		return err
+	}
+	for _, field := range p.fields {
+		if err := mw.WriteField(field[0], field[1]); err != nil {
+			return err
+		}
+	}
+	for _, file := range p.files {
+		part, err := mw.CreatePart(fileHeader(file))
+		if err != nil {
+			return err
+		}
+		if withFiles {
+			if err := copyFile(part, file.path); err != nil {
+				return err
+			}
+		}
+	}
+	return mw.Close()
+}
+
+// fileHeader returns the part header of a file, typed from its extension.
+func fileHeader(file multipartFile) textproto.MIMEHeader {
+	contentType := mime.TypeByExtension(filepath.Ext(file.path))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+		escapeQuotes(file.field), escapeQuotes(filepath.Base(file.path))))
+	h.Set("Content-Type", contentType)
+	return h
+}
+
+// copyFile streams the file at path to w.
+func copyFile(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// escapeQuotes escapes a Content-Disposition parameter value the way mime/multipart does.
+func escapeQuotes(s string) string {
+	return strings.NewReplacer("\\", "\\\\", `"`, "\\\"").Replace(s)
+}
+
+// randomBoundary returns a random multipart boundary.
+func randomBoundary() string {
+	var buf [24]byte
+	rand.Read(buf[:])
+	return hex.EncodeToString(buf[:])
+}
+
+// countingWriter counts the bytes written to it and discards them.
+type countingWriter struct {
+	n int64
+}
+
+// Write counts p.
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
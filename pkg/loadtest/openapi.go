@@ -0,0 +1,751 @@
+package loadtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"mime"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+)
+
+// openAPISpec is the subset of an OpenAPI 3 document needed to validate and generate
+// requests.
+type openAPISpec struct {
+	OpenAPI    string                     `json:"openapi"`
+	Servers    []openAPIServer            `json:"servers"`
+	Paths      map[string]openAPIPathItem `json:"paths"`
+	Components openAPIComponents          `json:"components"`
+}
+
+// openAPIServer is a server the paths of a spec are relative to.
+type openAPIServer struct {
+	URL       string `json:"url"`
+	Variables map[string]struct {
+		Default string `json:"default"`
+	} `json:"variables"`
+}
+
+// url returns the URL of the server, with its variables set to their defaults.
+func (s openAPIServer) url() string {
+	u := s.URL
+	for name, variable := range s.Variables {
+		u = strings.ReplaceAll(u, "{"+name+"}", variable.Default)
+	}
+	return u
+}
+
+// openAPIPathItem holds the operations defined on a path template.
+type openAPIPathItem struct {
+	Parameters []*openAPIParameter `json:"parameters"`
+	Get        *openAPIOperation   `json:"get"`
+	Put        *openAPIOperation   `json:"put"`
+	Post       *openAPIOperation   `json:"post"`
+	Delete     *openAPIOperation   `json:"delete"`
+	Options    *openAPIOperation   `json:"options"`
+	Head       *openAPIOperation   `json:"head"`
+	Patch      *openAPIOperation   `json:"patch"`
+	Trace      *openAPIOperation   `json:"trace"`
+}
+
+// operations returns the operations of the path item by method.
+func (p openAPIPathItem) operations() map[string]*openAPIOperation {
+	ops := make(map[string]*openAPIOperation)
+	for method, op := range map[string]*openAPIOperation{
+		"GET": p.Get, "PUT": p.Put, "POST": p.Post, "DELETE": p.Delete,
+		"OPTIONS": p.Options, "HEAD": p.Head, "PATCH": p.Patch, "TRACE": p.Trace,
+	} {
+		if op != nil {
+			ops[method] = op
+		}
+	}
+	return ops
+}
+
+// openAPIOperation is a single method on a path.
+type openAPIOperation struct {
+	OperationID string              `json:"operationId"`
+	Parameters  []*openAPIParameter `json:"parameters"`
+	RequestBody *openAPIRequestBody `json:"requestBody"`
+}
+
+// openAPIParameter is a path, query or header parameter.
+type openAPIParameter struct {
+	Ref      string         `json:"$ref"`
+	Name     string         `json:"name"`
+	In       string         `json:"in"`
+	Required bool           `json:"required"`
+	Schema   *openAPISchema `json:"schema"`
+	Example  interface{}    `json:"example"`
+}
+
+// openAPIRequestBody lists the accepted media types of a request body.
+type openAPIRequestBody struct {
+	Ref      string                      `json:"$ref"`
+	Required bool                        `json:"required"`
+	Content  map[string]openAPIMediaType `json:"content"`
+}
+
+// openAPIMediaType is the schema of a body of one media type.
+type openAPIMediaType struct {
+	Schema  *openAPISchema `json:"schema"`
+	Example interface{}    `json:"example"`
+}
+
+// openAPIComponents holds the definitions local $ref values point to.
+type openAPIComponents struct {
+	Schemas       map[string]*openAPISchema      `json:"schemas"`
+	Parameters    map[string]*openAPIParameter   `json:"parameters"`
+	RequestBodies map[string]*openAPIRequestBody `json:"requestBodies"`
+}
+
+// openAPISchema is the subset of JSON Schema checked against rendered requests.
+type openAPISchema struct {
+	Ref                  string                    `json:"$ref"`
+	Type                 schemaTypes               `json:"type"`
+	Nullable             bool                      `json:"nullable"`
+	Format               string                    `json:"format"`
+	Enum                 []interface{}             `json:"enum"`
+	Required             []string                  `json:"required"`
+	Properties           map[string]*openAPISchema `json:"properties"`
+	AdditionalProperties *additionalProperties     `json:"additionalProperties"`
+	Items                *openAPISchema            `json:"items"`
+	MinLength            *int                      `json:"minLength"`
+	MaxLength            *int                      `json:"maxLength"`
+	Pattern              string                    `json:"pattern"`
+	Minimum              *float64                  `json:"minimum"`
+	Maximum              *float64                  `json:"maximum"`
+	MinItems             *int                      `json:"minItems"`
+	MaxItems             *int                      `json:"maxItems"`
+	AllOf                []*openAPISchema          `json:"allOf"`
+	AnyOf                []*openAPISchema          `json:"anyOf"`
+	OneOf                []*openAPISchema          `json:"oneOf"`
+	Example              interface{}               `json:"example"`
+	Default              interface{}               `json:"default"`
+}
+
+// schemaTypes is the type of a schema, a single name in OpenAPI 3.0 and possibly a list
+// in OpenAPI 3.1.
+type schemaTypes []string
+
+// UnmarshalJSON accepts both "type": "string" and "type": ["string", "null"].
+func (t *schemaTypes) UnmarshalJSON(b []byte) error {
+	var single string
+	if err := json.Unmarshal(b, &single); err == nil {
+		*t = schemaTypes{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(b, &list); err != nil {
+		return err
+	}
+	*t = list
+	return nil
+}
+
+// additionalProperties is either a boolean or the schema of the properties not listed
+// in properties.
+type additionalProperties struct {
+	allowed bool
+	schema  *openAPISchema
+}
+
+// UnmarshalJSON accepts a boolean or a schema.
+func (a *additionalProperties) UnmarshalJSON(b []byte) error {
+	if err := json.Unmarshal(b, &a.allowed); err == nil {
+		return nil
+	}
+	a.allowed = true
+	return json.Unmarshal(b, &a.schema)
+}
+
+// openAPIValidator checks rendered requests against a spec.
+type openAPIValidator struct {
+	spec      *openAPISpec
+	basePaths []string
+	patterns  map[string]*regexp.Regexp
+
+	// definitions are the schemas of a plain JSON Schema document, by $ref.
+	definitions map[string]*openAPISchema
+}
+
+// loadOpenAPISpec reads an OpenAPI 3 document in JSON.
+func loadOpenAPISpec(path string) (*openAPIValidator, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return nil, fmt.Errorf("%s: YAML specs are not supported, convert the spec to JSON first", path)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	spec := &openAPISpec{}
+	if err := json.Unmarshal(content, spec); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if !strings.HasPrefix(spec.OpenAPI, "3.") {
+		return nil, fmt.Errorf("%s: only OpenAPI 3 documents are supported", path)
+	}
+
+	v := &openAPIValidator{spec: spec, patterns: make(map[string]*regexp.Regexp)}
+	for _, server := range spec.Servers {
+		u, err := url.Parse(server.url())
+		if err != nil || strings.Contains(server.url(), "{") {
+			continue
+		}
+		v.basePaths = append(v.basePaths, strings.TrimSuffix(u.Path, "/"))
+	}
+	if len(v.basePaths) == 0 {
+		v.basePaths = []string{""}
+	}
+	return v, nil
+}
+
+// validate returns the problems found in a rendered request, each one prefixed by the
+// location it applies to.
+func (v *openAPIValidator) validate(p *preparedRequest) []string {
+	u, err := url.Parse(p.url)
+	if err != nil {
+		return []string{fmt.Sprintf("invalid URL: %v", err)}
+	}
+	template, item, pathValues, ok := v.findPath(u.Path)
+	if !ok {
+		return []string{fmt.Sprintf("no path of the spec matches %s", u.Path)}
+	}
+	ops := item.operations()
+	op := ops[p.target.method]
+	if op == nil {
+		allowed := make([]string, 0, len(ops))
+		for method := range ops {
+			allowed = append(allowed, method)
+		}
+		sort.Strings(allowed)
+		return []string{fmt.Sprintf("%s is not defined on %s, the spec allows %s", p.target.method, template, strings.Join(allowed, ", "))}
+	}
+
+	var problems []string
+	query := u.Query()
+	for _, param := range v.parameters(item, op) {
+		var values []string
+		switch param.In {
+		case "path":
+			values = []string{pathValues[param.Name]}
+		case "query":
+			values = query[param.Name]
+		case "header":
+			values = p.header.Values(param.Name)
+		default:
+			continue
+		}
+		location := fmt.Sprintf("%s parameter %s", param.In, param.Name)
+		if len(values) == 0 {
+			if param.Required {
+				problems = append(problems, location+": required parameter is missing")
+			}
+			continue
+		}
+		problems = append(problems, v.validateStrings(param.Schema, values, location)...)
+	}
+	return append(problems, v.validateBody(op.RequestBody, p)...)
+}
+
+// findPath returns the path template matching path, preferring templates with the most
+// literal segments, together with the values of its path parameters.
+func (v *openAPIValidator) findPath(path string) (string, openAPIPathItem, map[string]string, bool) {
+	var best string
+	var bestValues map[string]string
+	bestScore := -1
+	for _, base := range v.basePaths {
+		if !strings.HasPrefix(path, base) {
+			continue
+		}
+		rest := strings.TrimPrefix(path, base)
+		if rest != "" && !strings.HasPrefix(rest, "/") {
+			continue
+		}
+		for template := range v.spec.Paths {
+			values, score, ok := matchPathTemplate(template, rest)
+			if ok && (score > bestScore || score == bestScore && template < best) {
+				best, bestValues, bestScore = template, values, score
+			}
+		}
+	}
+	if bestScore < 0 {
+		return "", openAPIPathItem{}, nil, false
+	}
+	return best, v.spec.Paths[best], bestValues, true
+}
+
+// matchPathTemplate matches path against a template such as /users/{id}. The score is
+// the number of literal segments.
+func matchPathTemplate(template, path string) (map[string]string, int, bool) {
+	templateSegments := strings.Split(strings.Trim(template, "/"), "/")
+	pathSegments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(templateSegments) != len(pathSegments) {
+		return nil, 0, false
+	}
+	values := make(map[string]string)
+	score := 0
+	for i, segment := range templateSegments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			if pathSegments[i] == "" {
+				return nil, 0, false
+			}
+			value, err := url.PathUnescape(pathSegments[i])
+			if err != nil {
+				value = pathSegments[i]
+			}
+			values[segment[1:len(segment)-1]] = value
+			continue
+		}
+		if segment != pathSegments[i] {
+			return nil, 0, false
+		}
+		score++
+	}
+	return values, score, true
+}
+
+// parameters returns the parameters of an operation, including the ones inherited from
+// its path item unless the operation overrides them.
+func (v *openAPIValidator) parameters(item openAPIPathItem, op *openAPIOperation) []*openAPIParameter {
+	var params []*openAPIParameter
+	seen := make(map[string]bool)
+	for _, list := range [][]*openAPIParameter{op.Parameters, item.Parameters} {
+		for _, param := range list {
+			param = v.resolveParameter(param)
+			if param == nil || seen[param.In+" "+param.Name] {
+				continue
+			}
+			seen[param.In+" "+param.Name] = true
+			params = append(params, param)
+		}
+	}
+	return params
+}
+
+// validateBody checks the Content-Type and the content of the request body.
+func (v *openAPIValidator) validateBody(body *openAPIRequestBody, p *preparedRequest) []string {
+	body = v.resolveRequestBody(body)
+	hasBody := len(p.plainBody) > 0 || p.multipart != nil
+	switch {
+	case body == nil && hasBody:
+		return []string{"body: the spec defines no request body but one is sent"}
+	case body == nil:
+		return nil
+	case !hasBody && body.Required:
+		return []string{"body: the request body is required"}
+	case !hasBody:
+		return nil
+	}
+
+	contentType := p.header.Get("Content-Type")
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return []string{fmt.Sprintf("body: invalid Content-Type %q", contentType)}
+	}
+	media, ok := matchMediaType(body.Content, mediaType)
+	if !ok {
+		accepted := make([]string, 0, len(body.Content))
+		for name := range body.Content {
+			accepted = append(accepted, name)
+		}
+		sort.Strings(accepted)
+		return []string{fmt.Sprintf("body: Content-Type %s is not accepted, the spec allows %s", mediaType, strings.Join(accepted, ", "))}
+	}
+	if media.Schema == nil {
+		return nil
+	}
+
+	switch {
+	case p.multipart != nil:
+		fields := make(url.Values)
+		for _, field := range p.multipart.fields {
+			fields.Add(field[0], field[1])
+		}
+		for _, file := range p.multipart.files {
+			fields.Add(file.field, "")
+		}
+		return v.validateFields(media.Schema, fields)
+	case isJSONType(mediaType):
+		var value interface{}
+		if err := json.Unmarshal(p.plainBody, &value); err != nil {
+			return []string{fmt.Sprintf("body: not valid JSON: %v", err)}
+		}
+		var problems []string
+		v.validateValue(media.Schema, value, "body", &problems)
+		return problems
+	case mediaType == contentTypeForm:
+		fields, err := url.ParseQuery(string(p.plainBody))
+		if err != nil {
+			return []string{fmt.Sprintf("body: not a valid form: %v", err)}
+		}
+		return v.validateFields(media.Schema, fields)
+	}
+	return nil
+}
+
+// matchMediaType finds the content entry for mediaType, falling back to type/* and */*.
+func matchMediaType(content map[string]openAPIMediaType, mediaType string) (openAPIMediaType, bool) {
+	major, _, _ := strings.Cut(mediaType, "/")
+	for _, candidate := range []string{mediaType, major + "/*", "*/*"} {
+		for name, media := range content {
+			if parsed, _, err := mime.ParseMediaType(name); err == nil && parsed == candidate {
+				return media, true
+			}
+		}
+	}
+	return openAPIMediaType{}, false
+}
+
+// validateFields checks form or multipart fields against an object schema, converting
+// every value to the type of its property.
+func (v *openAPIValidator) validateFields(s *openAPISchema, fields url.Values) []string {
+	s = v.resolveSchema(s)
+	object := make(map[string]interface{}, len(fields))
+	for name, values := range fields {
+		var property *openAPISchema
+		if s != nil {
+			property = s.Properties[name]
+		}
+		object[name] = v.coerce(property, values)
+	}
+	var problems []string
+	v.validateValue(s, object, "body", &problems)
+	return problems
+}
+
+// validateStrings checks the string values of a parameter against its schema.
+func (v *openAPIValidator) validateStrings(s *openAPISchema, values []string, location string) []string {
+	var problems []string
+	v.validateValue(s, v.coerce(s, values), location, &problems)
+	return problems
+}
+
+// coerce converts string values to the type of s. Values that cannot be converted are
+// kept as strings so the type check reports them.
+func (v *openAPIValidator) coerce(s *openAPISchema, values []string) interface{} {
+	s = v.resolveSchema(s)
+	if s != nil && s.Type.has("array") {
+		if len(values) == 1 {
+			values = strings.Split(values[0], ",")
+		}
+		items := make([]interface{}, len(values))
+		for i, value := range values {
+			items[i] = v.coerce(s.Items, []string{value})
+		}
+		return items
+	}
+	value := values[0]
+	if s == nil {
+		return value
+	}
+	switch {
+	case s.Type.has("integer") || s.Type.has("number"):
+		if n, err := strconv.ParseFloat(value, 64); err == nil {
+			return n
+		}
+	case s.Type.has("boolean"):
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
+
+// validateValue checks a decoded JSON value against s and appends the problems found,
+// located by path (e.g. body.items[0].qty), to problems.
+func (v *openAPIValidator) validateValue(s *openAPISchema, value interface{}, path string, problems *[]string) {
+	s = v.resolveSchema(s)
+	if s == nil {
+		return
+	}
+	for _, sub := range s.AllOf {
+		v.validateValue(sub, value, path, problems)
+	}
+	if len(s.AnyOf) > 0 && v.countMatches(s.AnyOf, value, path) == 0 {
+		*problems = append(*problems, fmt.Sprintf("%s: does not match any of the anyOf schemas", path))
+	}
+	if len(s.OneOf) > 0 {
+		if n := v.countMatches(s.OneOf, value, path); n != 1 {
+			*problems = append(*problems, fmt.Sprintf("%s: matches %d of the oneOf schemas instead of exactly one", path, n))
+		}
+	}
+
+	if value == nil {
+		if len(s.Type) > 0 && !s.Nullable && !s.Type.has("null") {
+			*problems = append(*problems, fmt.Sprintf("%s: expected %s, got null", path, strings.Join(s.Type, " or ")))
+		}
+		return
+	}
+	if actual := jsonType(value); len(s.Type) > 0 && !s.Type.has(actual) && !(actual == "integer" && s.Type.has("number")) {
+		*problems = append(*problems, fmt.Sprintf("%s: expected %s, got %s", path, strings.Join(s.Type, " or "), actual))
+		return
+	}
+	if len(s.Enum) > 0 && !containsValue(s.Enum, value) {
+		*problems = append(*problems, fmt.Sprintf("%s: not one of the allowed values %v", path, s.Enum))
+	}
+
+	switch value := value.(type) {
+	case string:
+		v.validateString(s, value, path, problems)
+	case float64:
+		if s.Minimum != nil && value < *s.Minimum {
+			*problems = append(*problems, fmt.Sprintf("%s: below the minimum %v", path, *s.Minimum))
+		}
+		if s.Maximum != nil && value > *s.Maximum {
+			*problems = append(*problems, fmt.Sprintf("%s: above the maximum %v", path, *s.Maximum))
+		}
+	case []interface{}:
+		if s.MinItems != nil && len(value) < *s.MinItems {
+			*problems = append(*problems, fmt.Sprintf("%s: fewer than %d items", path, *s.MinItems))
+		}
+		if s.MaxItems != nil && len(value) > *s.MaxItems {
+			*problems = append(*problems, fmt.Sprintf("%s: more than %d items", path, *s.MaxItems))
+		}
+		for i, item := range value {
+			v.validateValue(s.Items, item, fmt.Sprintf("%s[%d]", path, i), problems)
+		}
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := value[name]; !ok {
+				*problems = append(*problems, fmt.Sprintf("%s.%s: required property is missing", path, name))
+			}
+		}
+		names := make([]string, 0, len(value))
+		for name := range value {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if property, ok := s.Properties[name]; ok {
+				v.validateValue(property, value[name], path+"."+name, problems)
+				continue
+			}
+			if extra := s.AdditionalProperties; extra != nil {
+				if !extra.allowed {
+					*problems = append(*problems, fmt.Sprintf("%s.%s: property is not defined in the spec", path, name))
+				} else {
+					v.validateValue(extra.schema, value[name], path+"."+name, problems)
+				}
+			}
+		}
+	}
+}
+
+// validateString checks the length, pattern and well-known formats of a string. Problems
+// leave the value out so the same problem found in several samples is reported once.
+func (v *openAPIValidator) validateString(s *openAPISchema, value, path string, problems *[]string) {
+	length := utf8.RuneCountInString(value)
+	if s.MinLength != nil && length < *s.MinLength {
+		*problems = append(*problems, fmt.Sprintf("%s: shorter than %d characters", path, *s.MinLength))
+	}
+	if s.MaxLength != nil && length > *s.MaxLength {
+		*problems = append(*problems, fmt.Sprintf("%s: longer than %d characters", path, *s.MaxLength))
+	}
+	if s.Pattern != "" {
+		pattern, ok := v.patterns[s.Pattern]
+		if !ok {
+			pattern, _ = regexp.Compile(s.Pattern)
+			v.patterns[s.Pattern] = pattern
+		}
+		if pattern != nil && !pattern.MatchString(value) {
+			*problems = append(*problems, fmt.Sprintf("%s: does not match the pattern %s", path, s.Pattern))
+		}
+	}
+	if !matchesFormat(s.Format, value) {
+		*problems = append(*problems, fmt.Sprintf("%s: not a valid %s", path, s.Format))
+	}
+}
+
+// uuidPattern matches the textual form of a UUID.
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// matchesFormat checks the string formats most often used by APIs; other formats are
+// not checked.
+func matchesFormat(format, value string) bool {
+	switch format {
+	case "uuid":
+		return uuidPattern.MatchString(value)
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, value)
+		return err == nil
+	case "date":
+		_, err := time.Parse("2006-01-02", value)
+		return err == nil
+	case "email":
+		local, domain, ok := strings.Cut(value, "@")
+		return ok && local != "" && strings.Contains(domain, ".")
+	}
+	return true
+}
+
+// countMatches returns how many of schemas value matches.
+func (v *openAPIValidator) countMatches(schemas []*openAPISchema, value interface{}, path string) int {
+	matches := 0
+	for _, s := range schemas {
+		var problems []string
+		v.validateValue(s, value, path, &problems)
+		if len(problems) == 0 {
+			matches++
+		}
+	}
+	return matches
+}
+
+// jsonType returns the JSON Schema type of a decoded JSON value.
+func jsonType(value interface{}) string {
+	switch value := value.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		if value == math.Trunc(value) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return "null"
+}
+
+// has reports whether the schema type includes name.
+func (t schemaTypes) has(name string) bool {
+	for _, typ := range t {
+		if typ == name {
+			return true
+		}
+	}
+	return false
+}
+
+// containsValue reports whether value is one of values.
+func containsValue(values []interface{}, value interface{}) bool {
+	for _, candidate := range values {
+		if reflect.DeepEqual(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveSchema follows local $ref values of a schema.
+func (v *openAPIValidator) resolveSchema(s *openAPISchema) *openAPISchema {
+	for depth := 0; s != nil && s.Ref != ""; depth++ {
+		if depth > 32 {
+			return nil
+		}
+		if definition, ok := v.definitions[s.Ref]; ok {
+			s = definition
+			continue
+		}
+		name, ok := strings.CutPrefix(s.Ref, "#/components/schemas/")
+		if !ok {
+			return nil
+		}
+		s = v.spec.Components.Schemas[name]
+	}
+	return s
+}
+
+// resolveParameter follows a local $ref of a parameter.
+func (v *openAPIValidator) resolveParameter(p *openAPIParameter) *openAPIParameter {
+	if p != nil && p.Ref != "" {
+		name, ok := strings.CutPrefix(p.Ref, "#/components/parameters/")
+		if !ok {
+			return nil
+		}
+		return v.spec.Components.Parameters[name]
+	}
+	return p
+}
+
+// resolveRequestBody follows a local $ref of a request body.
+func (v *openAPIValidator) resolveRequestBody(b *openAPIRequestBody) *openAPIRequestBody {
+	if b != nil && b.Ref != "" {
+		name, ok := strings.CutPrefix(b.Ref, "#/components/requestBodies/")
+		if !ok {
+			return nil
+		}
+		return v.spec.Components.RequestBodies[name]
+	}
+	return b
+}
+
+// validateSamples renders samples requests per target the way workers do, without sending
+// them, and checks them against the spec. Rows of the data file are read without being
+// consumed and the ID sequence and header rotations are rewound afterwards, so the run
+// that follows is not affected. It reports whether every sample matched.
+func (e *engine) validateSamples(v *openAPIValidator, body *templateSource, samples int) bool {
+	color.Green("\n===== 📐 OpenAPI Validation =====")
+	w := &worker{
+		body:         body,
+		randomValues: make(map[string]interface{}),
+		random:       e.random.stream("openapi"),
+	}
+
+	var order []string
+	counts := make(map[string]int)
+	record := func(problem string) {
+		if counts[problem] == 0 {
+			order = append(order, problem)
+		}
+		counts[problem]++
+	}
+	validated, skipped := 0, 0
+	for _, t := range e.allTargets() {
+		for i := 0; i < samples; i++ {
+			if e.data != nil {
+				w.row = e.data.rows[(validated+skipped)%len(e.data.rows)]
+			}
+			p, res, ok := e.prepareRequest(t, w)
+			switch {
+			case !ok && res.feedMiss:
+				skipped++
+				continue
+			case !ok:
+				record(fmt.Sprintf("%s %s: the request could not be rendered", t.method, t.url))
+			default:
+				location := t.method + " " + p.url
+				if u, err := url.Parse(p.url); err == nil {
+					location = t.method + " " + u.Path
+				}
+				for _, problem := range v.validate(p) {
+					record(location + ": " + problem)
+				}
+			}
+			validated++
+		}
+	}
+	e.random.seq.reset(e.cfg.SeqStart)
+	for _, rotation := range e.rotations {
+		rotation.next.Store(0)
+	}
+
+	if skipped > 0 {
+		color.Yellow("⏭️  Sample requests not validated because they read from the empty feed pool: %d", skipped)
+	}
+	if len(order) == 0 {
+		color.Cyan("✅ %d sample requests match %s", validated, e.cfg.OpenAPIPath)
+		return true
+	}
+	color.Red("❌ Sample requests do not match %s (%d rendered):", e.cfg.OpenAPIPath, validated)
+	for _, problem := range order {
+		color.Red("  - %s (%d samples)", problem, counts[problem])
+	}
+	return false
+}
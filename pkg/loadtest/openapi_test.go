@@ -0,0 +1,260 @@
+package loadtest
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testSpec = `{
+  "openapi": "3.0.3",
+  "servers": [{"url": "https://api.example.com/v1"}],
+  "paths": {
+    "/users/{id}": {
+      "parameters": [{"name": "id", "in": "path", "required": true, "schema": {"type": "integer"}}],
+      "get": {
+        "parameters": [{"name": "fields", "in": "query", "required": true, "schema": {"type": "string"}}]
+      },
+      "delete": {}
+    },
+    "/users/me": {
+      "get": {}
+    },
+    "/users": {
+      "post": {
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/User"}}}
+        }
+      }
+    }
+  },
+  "components": {
+    "schemas": {
+      "User": {
+        "type": "object",
+        "required": ["email"],
+        "properties": {
+          "email": {"type": "string", "format": "email"},
+          "age": {"type": "integer", "minimum": 0},
+          "role": {"type": "string", "enum": ["admin", "user"]}
+        }
+      }
+    }
+  }
+}`
+
+func writeSpec(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadOpenAPISpecErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{name: "spec.yaml", content: "openapi: 3.0.0", err: "YAML specs are not supported"},
+		{name: "spec.json", content: `{"swagger": "2.0"}`, err: "only OpenAPI 3 documents are supported"},
+		{name: "spec.json", content: `{"openapi": `, err: "parsing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.content, func(t *testing.T) {
+			_, err := loadOpenAPISpec(writeSpec(t, tt.name, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("loadOpenAPISpec() error = %v, want it to contain %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestOpenAPIValidate(t *testing.T) {
+	v, err := loadOpenAPISpec(writeSpec(t, "spec.json", testSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	jsonHeader := http.Header{"Content-Type": {"application/json"}}
+	tests := []struct {
+		name     string
+		method   string
+		url      string
+		header   http.Header
+		body     string
+		problems []string
+	}{
+		{
+			name:   "valid path and query parameters",
+			method: "GET",
+			url:    "https://api.example.com/v1/users/42?fields=name",
+		},
+		{
+			name:   "literal path preferred over a template",
+			method: "GET",
+			url:    "https://api.example.com/v1/users/me",
+		},
+		{
+			name:     "unknown path",
+			method:   "GET",
+			url:      "https://api.example.com/v1/orders",
+			problems: []string{"no path of the spec matches /v1/orders"},
+		},
+		{
+			name:     "path outside the server base path",
+			method:   "GET",
+			url:      "https://api.example.com/users/42",
+			problems: []string{"no path of the spec matches /users/42"},
+		},
+		{
+			name:     "method not defined",
+			method:   "PUT",
+			url:      "https://api.example.com/v1/users/42",
+			problems: []string{"PUT is not defined on /users/{id}, the spec allows DELETE, GET"},
+		},
+		{
+			name:     "missing required query parameter",
+			method:   "GET",
+			url:      "https://api.example.com/v1/users/42",
+			problems: []string{"query parameter fields: required parameter is missing"},
+		},
+		{
+			name:     "path parameter of the wrong type",
+			method:   "DELETE",
+			url:      "https://api.example.com/v1/users/abc",
+			problems: []string{"path parameter id: expected integer, got string"},
+		},
+		{
+			name:   "valid body",
+			method: "POST",
+			url:    "https://api.example.com/v1/users",
+			header: jsonHeader,
+			body:   `{"email":"a@example.com","age":30,"role":"admin"}`,
+		},
+		{
+			name:     "missing required body",
+			method:   "POST",
+			url:      "https://api.example.com/v1/users",
+			problems: []string{"body: the request body is required"},
+		},
+		{
+			name:     "body on an operation without one",
+			method:   "DELETE",
+			url:      "https://api.example.com/v1/users/42",
+			header:   jsonHeader,
+			body:     `{}`,
+			problems: []string{"body: the spec defines no request body but one is sent"},
+		},
+		{
+			name:     "unaccepted content type",
+			method:   "POST",
+			url:      "https://api.example.com/v1/users",
+			header:   http.Header{"Content-Type": {"text/plain"}},
+			body:     "hello",
+			problems: []string{"body: Content-Type text/plain is not accepted, the spec allows application/json"},
+		},
+		{
+			name:   "invalid body fields",
+			method: "POST",
+			url:    "https://api.example.com/v1/users",
+			header: jsonHeader,
+			body:   `{"email":"nope","age":-1,"role":"root"}`,
+			problems: []string{
+				"body.age: below the minimum 0",
+				"body.email: not a valid email",
+				"body.role: not one of the allowed values [admin user]",
+			},
+		},
+		{
+			name:     "missing required property",
+			method:   "POST",
+			url:      "https://api.example.com/v1/users",
+			header:   jsonHeader,
+			body:     `{"age":"thirty"}`,
+			problems: []string{"body.email: required property is missing", "body.age: expected integer, got string"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := tt.header
+			if header == nil {
+				header = http.Header{}
+			}
+			p := &preparedRequest{
+				target:    target{method: tt.method},
+				url:       tt.url,
+				header:    header,
+				plainBody: []byte(tt.body),
+			}
+			if problems := v.validate(p); !reflect.DeepEqual(problems, tt.problems) {
+				t.Errorf("validate() = %q, want %q", problems, tt.problems)
+			}
+		})
+	}
+}
+
+func TestMatchPathTemplate(t *testing.T) {
+	tests := []struct {
+		template string
+		path     string
+		values   map[string]string
+		score    int
+		ok       bool
+	}{
+		{template: "/users", path: "/users", values: map[string]string{}, score: 1, ok: true},
+		{template: "/users/{id}", path: "/users/42", values: map[string]string{"id": "42"}, score: 1, ok: true},
+		{template: "/users/{id}", path: "/users/a%20b", values: map[string]string{"id": "a b"}, score: 1, ok: true},
+		{template: "/users/{id}/orders/{order}", path: "/users/1/orders/2/", values: map[string]string{"id": "1", "order": "2"}, score: 2, ok: true},
+		{template: "/users/{id}", path: "/users", ok: false},
+		{template: "/users/{id}", path: "/users/1/orders", ok: false},
+		{template: "/users/{id}", path: "/orders/1", ok: false},
+		{template: "/users/{id}/orders", path: "/users//orders", ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.template+" "+tt.path, func(t *testing.T) {
+			values, score, ok := matchPathTemplate(tt.template, tt.path)
+			if ok != tt.ok {
+				t.Fatalf("matchPathTemplate() ok = %v, want %v", ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			if score != tt.score || !reflect.DeepEqual(values, tt.values) {
+				t.Errorf("matchPathTemplate() = %v, %d, want %v, %d", values, score, tt.values, tt.score)
+			}
+		})
+	}
+}
+
+func TestMatchesFormat(t *testing.T) {
+	tests := []struct {
+		format string
+		value  string
+		want   bool
+	}{
+		{format: "uuid", value: "123e4567-e89b-12d3-a456-426614174000", want: true},
+		{format: "uuid", value: "123e4567e89b12d3a456426614174000", want: false},
+		{format: "date-time", value: "2024-05-01T10:00:00Z", want: true},
+		{format: "date-time", value: "2024-05-01 10:00:00", want: false},
+		{format: "date", value: "2024-05-01", want: true},
+		{format: "date", value: "2024-13-01", want: false},
+		{format: "email", value: "a@example.com", want: true},
+		{format: "email", value: "@example.com", want: false},
+		{format: "email", value: "a@localhost", want: false},
+		{format: "hostname", value: "anything", want: true},
+		{format: "", value: "anything", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format+" "+tt.value, func(t *testing.T) {
+			if got := matchesFormat(tt.format, tt.value); got != tt.want {
+				t.Errorf("matchesFormat(%q, %q) = %v, want %v", tt.format, tt.value, got, tt.want)
+			}
+		})
+	}
+}
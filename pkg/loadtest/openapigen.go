@@ -0,0 +1,381 @@
+package loadtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// openAPIMethods are the methods of the operations of a path, in the order their targets
+// are generated.
+var openAPIMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS", "TRACE"}
+
+// openAPIMaxDepth bounds the nesting of generated bodies, so recursive schemas end.
+const openAPIMaxDepth = 8
+
+// fakerFieldNames maps field and parameter names, lowercased and without separators, to
+// the faker template functions producing realistic values for them.
+var fakerFieldNames = map[string]string{
+	"email": "email", "emailaddress": "email",
+	"firstname": "firstName", "givenname": "firstName",
+	"lastname": "lastName", "surname": "lastName", "familyname": "lastName",
+	"name": "name", "fullname": "name", "displayname": "name",
+	"username": "username", "login": "username", "nickname": "username",
+	"phone": "phone", "phonenumber": "phone", "mobile": "phone", "telephone": "phone",
+	"street": "street", "streetaddress": "street", "address1": "street",
+	"address": "address",
+	"city":    "city", "town": "city",
+	"country": "country",
+	"zip":     "zip", "zipcode": "zip", "postalcode": "zip", "postcode": "zip",
+	"company": "company", "organization": "company", "organisation": "company",
+	"description": "sentence", "comment": "sentence", "summary": "sentence", "bio": "paragraph",
+	"title": "lorem 3",
+}
+
+// openAPIValue is a generated value: a template pipeline rendered for every request, or
+// the fixed value an example of the spec gives.
+type openAPIValue struct {
+	pipeline string
+	// text reports whether the pipeline renders a string, quoted in JSON bodies.
+	text  bool
+	fixed interface{}
+}
+
+// GenerateOpenAPITargets generates a target for every operation of the OpenAPI 3 spec at
+// path whose operationId is listed in operations, or for every operation when operations
+// is just "all", so a service can be load tested from its spec alone. Paths are appended
+// to server, or to the first absolute server URL of the spec when server is empty.
+//
+// Path parameters, required query and header parameters and JSON or URL-encoded bodies
+// are filled in from the examples, defaults and enums of the spec, and otherwise with
+// template helpers matching the schemas: faker functions for fields such as email or
+// city, uuid and now for the uuid and date-time formats, and randInt or randString within
+// the bounds of the schema. The targets share the traffic evenly.
+func GenerateOpenAPITargets(path, server string, operations []string) ([]WeightedTarget, error) {
+	v, err := loadOpenAPISpec(path)
+	if err != nil {
+		return nil, err
+	}
+	base := strings.TrimSuffix(server, "/")
+	if base == "" {
+		for _, s := range v.spec.Servers {
+			if u, err := url.Parse(s.url()); err == nil && u.Scheme != "" && u.Host != "" {
+				base = strings.TrimSuffix(s.url(), "/")
+				break
+			}
+		}
+		if base == "" {
+			return nil, fmt.Errorf("%s has no absolute server URL, set one with --openapi-server", path)
+		}
+	}
+
+	all := len(operations) == 1 && operations[0] == "all"
+	wanted := make(map[string]bool)
+	for _, id := range operations {
+		wanted[id] = true
+	}
+	templates := make([]string, 0, len(v.spec.Paths))
+	for template := range v.spec.Paths {
+		templates = append(templates, template)
+	}
+	sort.Strings(templates)
+
+	var targets []WeightedTarget
+	for _, template := range templates {
+		item := v.spec.Paths[template]
+		ops := item.operations()
+		for _, method := range openAPIMethods {
+			op := ops[method]
+			if op == nil || !all && !wanted[op.OperationID] {
+				continue
+			}
+			delete(wanted, op.OperationID)
+			t, err := v.generateTarget(base, template, method, item, op)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s %s: %w", path, method, template, err)
+			}
+			targets = append(targets, t)
+		}
+	}
+	if !all && len(wanted) > 0 {
+		missing := make([]string, 0, len(wanted))
+		for id := range wanted {
+			missing = append(missing, id)
+		}
+		sort.Strings(missing)
+		return nil, fmt.Errorf("%s has no operation with operationId %s", path, strings.Join(missing, ", "))
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("%s defines no operation", path)
+	}
+	return targets, nil
+}
+
+// generateTarget returns the target of an operation, named after its operationId.
+func (v *openAPIValidator) generateTarget(base, template, method string, item openAPIPathItem, op *openAPIOperation) (WeightedTarget, error) {
+	name := op.OperationID
+	if name == "" {
+		name = method + " " + template
+	}
+	path := template
+	var query, headerLines []string
+	for _, p := range v.parameters(item, op) {
+		value := v.parameterValue(p)
+		switch p.In {
+		case "path":
+			path = strings.ReplaceAll(path, "{"+p.Name+"}", value.escaped(url.PathEscape, "pathEscape"))
+		case "query":
+			if p.Required || p.Example != nil {
+				query = append(query, url.QueryEscape(p.Name)+"="+value.escaped(url.QueryEscape, "queryEscape"))
+			}
+		case "header":
+			if p.Required {
+				headerLines = append(headerLines, p.Name+": "+value.escaped(func(s string) string { return s }, ""))
+			}
+		}
+	}
+	u := base + path
+	if len(query) > 0 {
+		u += "?" + strings.Join(query, "&")
+	}
+	t := WeightedTarget{target: target{name: name, method: method, url: u}, weight: 1}
+
+	if body := v.resolveRequestBody(op.RequestBody); body != nil {
+		mediaType, content, ok := openAPIBodyType(body.Content)
+		switch {
+		case ok:
+			var text string
+			if mediaType == contentTypeForm {
+				text = v.formTemplate(content.Schema)
+			} else if content.Example != nil {
+				example, err := json.Marshal(content.Example)
+				if err != nil {
+					return WeightedTarget{}, err
+				}
+				text = string(example)
+			} else {
+				text = v.jsonTemplate(content.Schema, "", 0)
+			}
+			if err := t.setBody(name, []byte(text), mediaType, false); err != nil {
+				return WeightedTarget{}, fmt.Errorf("generated body: %w", err)
+			}
+		case body.Required:
+			return WeightedTarget{}, fmt.Errorf("a JSON or form body is required, but the operation accepts neither")
+		}
+	}
+	headers, err := parseHeaders(headerLines)
+	if err != nil {
+		return WeightedTarget{}, err
+	}
+	t.headers = headers
+	return t, nil
+}
+
+// openAPIBodyType picks the media type bodies are generated for: JSON, else URL-encoded
+// forms.
+func openAPIBodyType(content map[string]openAPIMediaType) (string, openAPIMediaType, bool) {
+	types := make([]string, 0, len(content))
+	for mediaType := range content {
+		types = append(types, mediaType)
+	}
+	sort.Strings(types)
+	for _, form := range []bool{false, true} {
+		for _, mediaType := range types {
+			if form && mediaType == contentTypeForm || !form && isJSONType(mediaType) && !strings.Contains(mediaType, "*") {
+				return mediaType, content[mediaType], true
+			}
+		}
+	}
+	return "", openAPIMediaType{}, false
+}
+
+// parameterValue returns the value generated for a parameter.
+func (v *openAPIValidator) parameterValue(p *openAPIParameter) openAPIValue {
+	if p.Example != nil {
+		return openAPIValue{fixed: p.Example}
+	}
+	return v.scalarValue(p.Schema, p.Name)
+}
+
+// scalarValue returns the value generated for a string, number or boolean schema, or for
+// a field named name without a schema.
+func (v *openAPIValidator) scalarValue(s *openAPISchema, name string) openAPIValue {
+	s = v.resolveSchema(s)
+	if s == nil {
+		s = &openAPISchema{}
+	}
+	switch {
+	case s.Example != nil:
+		return openAPIValue{fixed: s.Example}
+	case s.Default != nil:
+		return openAPIValue{fixed: s.Default}
+	case len(s.Enum) > 0:
+		return openAPIValue{fixed: s.Enum[0]}
+	case len(s.OneOf) > 0:
+		return v.scalarValue(s.OneOf[0], name)
+	case len(s.AnyOf) > 0:
+		return v.scalarValue(s.AnyOf[0], name)
+	case len(s.AllOf) > 0:
+		return v.scalarValue(s.AllOf[0], name)
+	case s.Type.has("integer") || s.Type.has("number"):
+		min, max := 1, 1000
+		if s.Minimum != nil {
+			min = clampInt(math.Ceil(*s.Minimum))
+			max = min + 999
+		}
+		if s.Maximum != nil {
+			max = clampInt(math.Floor(*s.Maximum))
+		}
+		if max < min {
+			max = min
+		}
+		return openAPIValue{pipeline: fmt.Sprintf("randInt %d %d", min, max)}
+	case s.Type.has("boolean"):
+		return openAPIValue{fixed: true}
+	}
+
+	switch s.Format {
+	case "uuid":
+		return openAPIValue{pipeline: "uuid", text: true}
+	case "date-time":
+		return openAPIValue{pipeline: "now", text: true}
+	case "date":
+		return openAPIValue{pipeline: "slice now 0 10", text: true}
+	case "email":
+		return openAPIValue{pipeline: "email", text: true}
+	case "uri", "url":
+		return openAPIValue{pipeline: `printf "https://example.com/%s" (randString 8)`, text: true}
+	}
+	key := strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(name))
+	if fn, ok := fakerFieldNames[key]; ok && s.MaxLength == nil && s.Pattern == "" {
+		return openAPIValue{pipeline: fn, text: true}
+	}
+	length := 10
+	if s.MinLength != nil && *s.MinLength > length {
+		length = *s.MinLength
+	}
+	if s.MaxLength != nil && *s.MaxLength < length {
+		length = *s.MaxLength
+	}
+	return openAPIValue{pipeline: fmt.Sprintf("randString %d", length), text: true}
+}
+
+// clampInt converts f to an int, bounding it to the range of the ints randInt accepts.
+func clampInt(f float64) int {
+	return int(math.Max(math.Min(f, math.MaxInt32), math.MinInt32))
+}
+
+// escaped returns the value as template text inside a URL or header, escaped with fn, or
+// the template function named escape for generated values.
+func (val openAPIValue) escaped(fn func(string) string, escape string) string {
+	if val.pipeline == "" {
+		return fn(fmt.Sprint(val.fixed))
+	}
+	if escape == "" {
+		return "{{" + val.pipeline + "}}"
+	}
+	return "{{" + val.pipeline + " | print | " + escape + "}}"
+}
+
+// json returns the value as template text inside a JSON body.
+func (val openAPIValue) json() string {
+	if val.pipeline == "" {
+		encoded, err := json.Marshal(val.fixed)
+		if err != nil {
+			return "null"
+		}
+		return string(encoded)
+	}
+	if val.text {
+		return `"{{` + val.pipeline + `}}"`
+	}
+	return "{{" + val.pipeline + "}}"
+}
+
+// jsonTemplate returns the template of a JSON body matching s, with every property of its
+// objects set. name is the name of the field holding the value.
+func (v *openAPIValidator) jsonTemplate(s *openAPISchema, name string, depth int) string {
+	s = v.resolveSchema(s)
+	if s == nil || depth > openAPIMaxDepth {
+		return "null"
+	}
+	switch {
+	case s.Example != nil || s.Default != nil || len(s.Enum) > 0:
+		return v.scalarValue(s, name).json()
+	case len(s.OneOf) > 0:
+		return v.jsonTemplate(s.OneOf[0], name, depth+1)
+	case len(s.AnyOf) > 0:
+		return v.jsonTemplate(s.AnyOf[0], name, depth+1)
+	}
+	properties := v.objectProperties(s, depth)
+	switch {
+	case s.Type.has("object") || len(s.Type) == 0 && len(properties) > 0:
+		names := make([]string, 0, len(properties))
+		for property := range properties {
+			names = append(names, property)
+		}
+		sort.Strings(names)
+		fields := make([]string, len(names))
+		for i, property := range names {
+			key, _ := json.Marshal(property)
+			fields[i] = string(key) + ":" + v.jsonTemplate(properties[property], property, depth+1)
+		}
+		return "{" + strings.Join(fields, ",") + "}"
+	case s.Type.has("array"):
+		count := 1
+		if s.MinItems != nil && *s.MinItems > count {
+			count = *s.MinItems
+		}
+		items := make([]string, count)
+		for i := range items {
+			items[i] = v.jsonTemplate(s.Items, name, depth+1)
+		}
+		return "[" + strings.Join(items, ",") + "]"
+	case len(s.AllOf) > 0:
+		return v.jsonTemplate(s.AllOf[0], name, depth+1)
+	}
+	return v.scalarValue(s, name).json()
+}
+
+// objectProperties returns the properties of s, including those of the schemas it
+// combines with allOf.
+func (v *openAPIValidator) objectProperties(s *openAPISchema, depth int) map[string]*openAPISchema {
+	properties := make(map[string]*openAPISchema)
+	for name, property := range s.Properties {
+		properties[name] = property
+	}
+	if depth > openAPIMaxDepth {
+		return properties
+	}
+	for _, part := range s.AllOf {
+		if part = v.resolveSchema(part); part != nil {
+			for name, property := range v.objectProperties(part, depth+1) {
+				properties[name] = property
+			}
+		}
+	}
+	return properties
+}
+
+// formTemplate returns the template of a URL-encoded body with a field for every
+// property of s.
+func (v *openAPIValidator) formTemplate(s *openAPISchema) string {
+	s = v.resolveSchema(s)
+	if s == nil {
+		return ""
+	}
+	properties := v.objectProperties(s, 0)
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fields := make([]string, len(names))
+	for i, name := range names {
+		fields[i] = url.QueryEscape(name) + "=" + v.scalarValue(properties[name], name).escaped(url.QueryEscape, "queryEscape")
+	}
+	return strings.Join(fields, "&")
+}
@@ -0,0 +1,109 @@
+package loadtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Option sets up a LoadTest built by New.
+type Option func(*LoadTest)
+
+// LoadTest is a run built from options, for programs that only need a few settings
+// rather than the whole Config.
+type LoadTest struct {
+	cfg  Config
+	urls []string
+}
+
+// New returns a load test with opts applied in order. Unlike a Config given to Run, it
+// prints no report by default: its Result is returned by Run.
+func New(opts ...Option) *LoadTest {
+	t := &LoadTest{cfg: Config{SummaryOnly: true}}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// Run runs the load test until every request was sent, the duration elapsed or ctx is
+// done, and returns its Result, or an error when the run could not start.
+func (t *LoadTest) Run(ctx context.Context) (Result, error) {
+	cfg := t.cfg.withDefaults()
+	if len(t.urls) > 0 {
+		targets, err := ParseTargets(t.urls, cfg.Verb)
+		if err != nil {
+			return Result{}, err
+		}
+		cfg.Targets = append(cfg.Targets, targets...)
+	}
+	if cfg.Generator == nil && len(cfg.Targets) == 0 && len(cfg.ReadURLs) == 0 && len(cfg.WriteURLs) == 0 {
+		return Result{}, errors.New("no URL to load, use WithURL or WithGenerator")
+	}
+	return RunContext(ctx, cfg)
+}
+
+// WithConfig starts from cfg, for the settings no option covers. Options given before it
+// are overridden.
+func WithConfig(cfg Config) Option {
+	return func(t *LoadTest) { t.cfg = cfg }
+}
+
+// WithURL adds a target, given as a --url value of the form [weight:][METHOD ]URL.
+// Several targets share the traffic according to their weights.
+func WithURL(url string) Option {
+	return func(t *LoadTest) { t.urls = append(t.urls, url) }
+}
+
+// WithGenerator sends the requests of g instead of those of the targets.
+func WithGenerator(g RequestGenerator) Option {
+	return func(t *LoadTest) { t.cfg.Generator = g }
+}
+
+// WithMethod sets the method of the targets that do not give one (default: GET).
+func WithMethod(method string) Option {
+	return func(t *LoadTest) { t.cfg.Verb = method }
+}
+
+// WithHeader adds a header to every request. Its value may be a body template.
+func WithHeader(name, value string) Option {
+	return func(t *LoadTest) { t.cfg.Headers = append(t.cfg.Headers, fmt.Sprintf("%s: %s", name, value)) }
+}
+
+// WithBody sets the body of the requests, rendered as a template unless its content type
+// is binary.
+func WithBody(body string) Option {
+	return func(t *LoadTest) { t.cfg.Body = body }
+}
+
+// WithConcurrency sets the number of workers sending requests (default: 10).
+func WithConcurrency(workers int) Option {
+	return func(t *LoadTest) { t.cfg.Concurrency = workers }
+}
+
+// WithRequests sets the number of requests to send (default: 100).
+func WithRequests(requests int) Option {
+	return func(t *LoadTest) { t.cfg.Requests = requests }
+}
+
+// WithDuration runs the load test for d instead of a number of requests.
+func WithDuration(d time.Duration) Option {
+	return func(t *LoadTest) { t.cfg.Duration = d }
+}
+
+// WithRate caps the requests of all workers together at perSecond requests per second.
+func WithRate(perSecond float64) Option {
+	return func(t *LoadTest) { t.cfg.Rate = perSecond }
+}
+
+// WithTimeout sets the timeout of every request.
+func WithTimeout(d time.Duration) Option {
+	return func(t *LoadTest) { t.cfg.Timeout = d }
+}
+
+// WithLogger sends the diagnostics of the run to l instead of the console.
+func WithLogger(l *slog.Logger) Option {
+	return func(t *LoadTest) { t.cfg.Logger = l }
+}
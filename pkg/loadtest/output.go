@@ -0,0 +1,76 @@
+package loadtest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+)
+
+// OutputOptions controls how files written by the tool are protected.
+type OutputOptions struct {
+	AgeRecipients []string
+	GPGRecipients []string
+	// SigningKey signs the JSON report, when set.
+	SigningKey *SigningKey
+}
+
+// encrypted reports whether output files are encrypted.
+func (o OutputOptions) encrypted() bool {
+	return len(o.AgeRecipients) > 0 || len(o.GPGRecipients) > 0
+}
+
+// createOutput creates the file at path. When recipients are configured the content is
+// piped through the age or gpg command line tool, so nothing is stored in plain text.
+func createOutput(path string, opts OutputOptions) (io.WriteCloser, error) {
+	if len(opts.AgeRecipients) > 0 && len(opts.GPGRecipients) > 0 {
+		return nil, errors.New("age and gpg recipients cannot be combined")
+	}
+	if !opts.encrypted() {
+		return os.Create(path)
+	}
+
+	var cmd *exec.Cmd
+	if len(opts.AgeRecipients) > 0 {
+		args := []string{"--encrypt", "--output", path}
+		for _, recipient := range opts.AgeRecipients {
+			args = append(args, "--recipient", recipient)
+		}
+		cmd = exec.Command("age", args...)
+	} else {
+		args := []string{"--batch", "--yes", "--trust-model", "always", "--encrypt", "--output", path}
+		for _, recipient := range opts.GPGRecipients {
+			args = append(args, "--recipient", recipient)
+		}
+		cmd = exec.Command("gpg", args...)
+	}
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("starting %s: %w", cmd.Path, err)
+	}
+	return &encryptedOutput{stdin: stdin, cmd: cmd}, nil
+}
+
+// encryptedOutput writes to the standard input of an encryption command.
+type encryptedOutput struct {
+	stdin io.WriteCloser
+	cmd   *exec.Cmd
+}
+
+// Write passes p to the encryption command.
+func (o *encryptedOutput) Write(p []byte) (int, error) {
+	return o.stdin.Write(p)
+}
+
+// Close flushes the input and waits for the encryption command to finish writing the file.
+func (o *encryptedOutput) Close() error {
+	if err := o.stdin.Close(); err != nil {
+		return err
+	}
+	return o.cmd.Wait()
+}
@@ -0,0 +1,158 @@
+package loadtest
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// targetOptions are the settings a target overrides for its own requests, such as a
+// report export endpoint that legitimately takes longer than the others. They are given
+// as key=value words after the URL of a --url value or of a targets file line:
+//
+//	GET http://example.com/export timeout=120s retries=0 expect=200,202
+type targetOptions struct {
+	// timeout replaces --timeout when positive.
+	timeout time.Duration
+	// retries replaces --retries when retriesSet.
+	retries    int
+	retriesSet bool
+	// expectStatus replaces --expect-status when not empty.
+	expectStatus []int
+	// captures are the variables a setup or teardown step captures from its response.
+	captures []stepCapture
+	// insecure skips the TLS verification of the host of the target, as curl -k does.
+	insecure bool
+}
+
+// parseTargetOptions parses the key=value options following the URL of a target.
+func parseTargetOptions(words []string) (targetOptions, error) {
+	var opts targetOptions
+	for _, word := range words {
+		key, value, ok := strings.Cut(word, "=")
+		if !ok {
+			return targetOptions{}, fmt.Errorf("expected key=value after the URL, got %q", word)
+		}
+		var err error
+		switch key {
+		case "timeout":
+			opts.timeout, err = time.ParseDuration(value)
+			if err == nil && opts.timeout <= 0 {
+				err = fmt.Errorf("must be positive")
+			}
+		case "retries":
+			opts.retries, err = strconv.Atoi(value)
+			if err == nil && opts.retries < 0 {
+				err = fmt.Errorf("cannot be negative")
+			}
+			opts.retriesSet = true
+		case "expect":
+			opts.expectStatus, err = ParseExpectedStatuses(value)
+		case "capture":
+			var c stepCapture
+			c, err = parseStepCapture(value)
+			opts.captures = append(opts.captures, c)
+		default:
+			return targetOptions{}, fmt.Errorf("unknown target option %q, expected timeout, retries, expect or capture", key)
+		}
+		if err != nil {
+			return targetOptions{}, fmt.Errorf("invalid target option %s: %v", key, err)
+		}
+	}
+	return opts, nil
+}
+
+// hasTimeoutOverride reports whether a target of cfg overrides the timeout.
+func hasTimeoutOverride(cfg Config) bool {
+	for _, t := range cfg.Targets {
+		if t.options.timeout > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// withTimeout bounds ctx by the timeout of t. It is only needed when a target overrides
+// the timeout: the clients then have no timeout of their own, since it would cut the
+// slower targets short, and every request gets its timeout from its context instead.
+func (e *engine) withTimeout(ctx context.Context, t target) (context.Context, context.CancelFunc) {
+	if !e.timeoutOverrides {
+		return ctx, func() {}
+	}
+	timeout := e.cfg.Timeout
+	if t.options.timeout > 0 {
+		timeout = t.options.timeout
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// retries returns how many times a failed request to t is sent again.
+func (e *engine) retries(t target) int {
+	if t.options.retriesSet {
+		return t.options.retries
+	}
+	return e.cfg.Retries
+}
+
+// expectedStatuses returns the statuses a response from t is expected to have, or nil
+// when any status is accepted.
+func (e *engine) expectedStatuses(t target) []int {
+	if len(t.options.expectStatus) > 0 {
+		return t.options.expectStatus
+	}
+	return e.cfg.ExpectStatus
+}
+
+// expectedStatusesOf returns the statuses res is expected to have: those of its target,
+// or expected, those of the run.
+func expectedStatusesOf(res requestResult, expected []int) []int {
+	if len(res.expectStatus) > 0 {
+		return res.expectStatus
+	}
+	return expected
+}
+
+// shouldRetry reports whether res failed in a way worth sending the request again: a
+// network error, a status that is not expected or, without expected statuses, a 5xx.
+// Requests the client gave up on, and those of a stopped run, are not retried.
+func (e *engine) shouldRetry(t target, res requestResult) bool {
+	if res.drainCancelled || res.abandoned || (e.run != nil && e.run.stopped()) {
+		return false
+	}
+	if res.statusCode == -1 {
+		return true
+	}
+	if expected := e.expectedStatuses(t); len(expected) > 0 {
+		return unexpectedStatus(res, expected)
+	}
+	return res.statusCode >= 500
+}
+
+// sendWithRetries sends p to url, and again up to the retries of its target while the
+// request fails. The result is that of the last attempt, with the latency of the
+// failed ones added to its own.
+func (e *engine) sendWithRetries(p *preparedRequest, url string) requestResult {
+	res := e.send(p, url)
+	var retryDelay time.Duration
+	for i := 0; i < e.retries(p.target) && e.shouldRetry(p.target, res); i++ {
+		retryDelay += res.latency
+		next := e.send(p, url)
+		next.retries = res.retries + 1
+		next.retryDelay = retryDelay
+		next.latency += retryDelay
+		res = next
+	}
+	return res
+}
+
+// generateRetryReport prints how many requests were retried and the latency the failed
+// attempts added to them.
+func generateRetryReport(st *stats) {
+	color.Green("\n===== 🔂 Retries =====")
+	fmt.Printf("🔂 Requests retried: %d (%d retries)\n", st.retriedCount, st.retryAttempts)
+	fmt.Printf("⏱️  Added latency: %v total, %v per retried request\n", st.retryDelay, st.retryDelay/time.Duration(st.retriedCount))
+}
@@ -0,0 +1,41 @@
+package loadtest
+
+import (
+	"sync"
+	"time"
+)
+
+// pacer spreads the requests of all workers evenly to hold a constant request rate.
+// Every request reserves the next free slot, so workers that fall behind do not
+// burst to catch up.
+type pacer struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// newPacer returns a pacer holding rate requests per second, or nil for an unbounded
+// rate.
+func newPacer(rate float64) *pacer {
+	if rate <= 0 {
+		return nil
+	}
+	return &pacer{interval: time.Duration(float64(time.Second) / rate)}
+}
+
+// wait blocks until the next slot of p, returning early when the run stops. A nil pacer
+// never waits.
+func (p *pacer) wait(run *runControl) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	now := time.Now()
+	slot := p.next
+	if slot.Before(now) {
+		slot = now
+	}
+	p.next = slot.Add(p.interval)
+	p.mu.Unlock()
+	run.pause(time.Until(slot))
+}
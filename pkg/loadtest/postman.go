@@ -0,0 +1,324 @@
+package loadtest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// postmanCollection is the part of a Postman collection (format v2.1) that is loaded.
+type postmanCollection struct {
+	Item     []postmanItem     `json:"item"`
+	Variable []postmanKeyValue `json:"variable"`
+	Auth     *postmanAuth      `json:"auth"`
+}
+
+// postmanItem is a request of a collection, or a folder of them.
+type postmanItem struct {
+	Name    string          `json:"name"`
+	Item    []postmanItem   `json:"item"`
+	Request *postmanRequest `json:"request"`
+	Auth    *postmanAuth    `json:"auth"`
+}
+
+// postmanRequest is a request of a collection.
+type postmanRequest struct {
+	Method string            `json:"method"`
+	Header []postmanKeyValue `json:"header"`
+	URL    json.RawMessage   `json:"url"`
+	Auth   *postmanAuth      `json:"auth"`
+	Body   *struct {
+		Mode       string            `json:"mode"`
+		Raw        string            `json:"raw"`
+		URLEncoded []postmanKeyValue `json:"urlencoded"`
+		Options    struct {
+			Raw struct {
+				Language string `json:"language"`
+			} `json:"raw"`
+		} `json:"options"`
+	} `json:"body"`
+}
+
+// postmanKeyValue is a header, form field or variable of a collection or environment.
+type postmanKeyValue struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Disabled bool   `json:"disabled"`
+	Enabled  *bool  `json:"enabled"`
+}
+
+// active reports whether the entry is enabled: collections mark disabled entries, and
+// environments enabled ones.
+func (kv postmanKeyValue) active() bool {
+	return !kv.Disabled && (kv.Enabled == nil || *kv.Enabled)
+}
+
+// postmanAuth is the authorization of a request, folder or collection. Its parameters
+// are given as key/value lists per type.
+type postmanAuth struct {
+	Type   string            `json:"type"`
+	Bearer []postmanKeyValue `json:"bearer"`
+	Basic  []postmanKeyValue `json:"basic"`
+	APIKey []postmanKeyValue `json:"apikey"`
+}
+
+// param returns the value of the parameter key of the auth type.
+func (a *postmanAuth) param(params []postmanKeyValue, key string) string {
+	for _, p := range params {
+		if p.Key == key {
+			return p.Value
+		}
+	}
+	return ""
+}
+
+// postmanDynamicVariables maps the dynamic variables of Postman to the template helpers
+// producing the same kind of value for every request.
+var postmanDynamicVariables = map[string]string{
+	"$guid":         "{{uuid}}",
+	"$randomUUID":   "{{uuid}}",
+	"$timestamp":    "{{timestamp}}",
+	"$isoTimestamp": "{{now}}",
+	"$randomInt":    "{{randInt 0 1000}}",
+}
+
+// postmanVariable matches a {{variable}} reference.
+var postmanVariable = regexp.MustCompile(`{{\s*([^{}\s]+)\s*}}`)
+
+// LoadPostmanCollection converts the requests of a Postman collection (format v2.1) into
+// targets sharing the traffic evenly, named after their folders and names. Variables are
+// resolved from the environment file at envPath, when given, then from the variables of
+// the collection; Postman dynamic variables such as {{$guid}} become the matching
+// template helpers. Inherited bearer, basic and API key authorizations are applied.
+// Raw and URL-encoded bodies are supported; pre-request and test scripts are ignored.
+func LoadPostmanCollection(path, envPath string, rawBodies bool) ([]WeightedTarget, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var collection postmanCollection
+	if err := json.Unmarshal(content, &collection); err != nil {
+		return nil, fmt.Errorf("%s is not a Postman collection: %v", path, err)
+	}
+	variables := make(map[string]string)
+	for _, v := range collection.Variable {
+		if v.active() {
+			variables[v.Key] = v.Value
+		}
+	}
+	if envPath != "" {
+		content, err := os.ReadFile(envPath)
+		if err != nil {
+			return nil, err
+		}
+		var env struct {
+			Values []postmanKeyValue `json:"values"`
+		}
+		if err := json.Unmarshal(content, &env); err != nil {
+			return nil, fmt.Errorf("%s is not a Postman environment: %v", envPath, err)
+		}
+		for _, v := range env.Values {
+			if v.active() {
+				variables[v.Key] = v.Value
+			}
+		}
+	}
+
+	p := &postmanLoader{variables: variables, rawBodies: rawBodies}
+	if err := p.load(collection.Item, "", collection.Auth); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(p.targets) == 0 {
+		return nil, fmt.Errorf("%s has no request", path)
+	}
+	return p.targets, nil
+}
+
+// postmanLoader converts the items of a collection into targets.
+type postmanLoader struct {
+	variables map[string]string
+	rawBodies bool
+	targets   []WeightedTarget
+}
+
+// load converts items, found in the folder named prefix with the authorization auth.
+func (p *postmanLoader) load(items []postmanItem, prefix string, auth *postmanAuth) error {
+	for _, item := range items {
+		name := prefix + item.Name
+		itemAuth := auth
+		if item.Auth != nil {
+			itemAuth = item.Auth
+		}
+		if item.Request == nil {
+			if err := p.load(item.Item, name+"/", itemAuth); err != nil {
+				return err
+			}
+			continue
+		}
+		if item.Request.Auth != nil {
+			itemAuth = item.Request.Auth
+		}
+		t, err := p.convert(name, item.Request, itemAuth)
+		if err != nil {
+			return fmt.Errorf("request %q: %w", name, err)
+		}
+		p.targets = append(p.targets, t)
+	}
+	return nil
+}
+
+// convert returns the target of a request of the collection.
+func (p *postmanLoader) convert(name string, req *postmanRequest, auth *postmanAuth) (WeightedTarget, error) {
+	method := strings.ToUpper(req.Method)
+	if method == "" {
+		method = "GET"
+	}
+	rawURL, err := postmanURL(req.URL)
+	if err != nil {
+		return WeightedTarget{}, err
+	}
+	if rawURL, err = p.resolve(rawURL); err != nil {
+		return WeightedTarget{}, err
+	}
+	t := WeightedTarget{target: target{name: name, method: method, url: rawURL}, weight: 1}
+
+	var headerLines []string
+	for _, h := range req.Header {
+		if !h.active() {
+			continue
+		}
+		value, err := p.resolve(h.Value)
+		if err != nil {
+			return WeightedTarget{}, err
+		}
+		headerLines = append(headerLines, h.Key+": "+value)
+	}
+	authLine, err := p.authHeader(auth)
+	if err != nil {
+		return WeightedTarget{}, err
+	}
+	if authLine != "" {
+		headerLines = append(headerLines, authLine)
+	}
+
+	if body := req.Body; body != nil {
+		var text, contentType string
+		switch body.Mode {
+		case "raw":
+			if text, err = p.resolve(body.Raw); err != nil {
+				return WeightedTarget{}, err
+			}
+			if body.Options.Raw.Language == "json" {
+				contentType = "application/json"
+			}
+		case "urlencoded":
+			form := url.Values{}
+			for _, field := range body.URLEncoded {
+				if !field.active() {
+					continue
+				}
+				value, err := p.resolve(field.Value)
+				if err != nil {
+					return WeightedTarget{}, err
+				}
+				form.Add(field.Key, value)
+			}
+			text, contentType = form.Encode(), contentTypeForm
+		case "", "none":
+		default:
+			return WeightedTarget{}, fmt.Errorf("%s bodies are not supported", body.Mode)
+		}
+		if text != "" {
+			if err := t.setBody(name, []byte(text), contentType, p.rawBodies); err != nil {
+				return WeightedTarget{}, err
+			}
+		}
+	}
+	t.headers, err = parseHeaders(headerLines)
+	if err != nil {
+		return WeightedTarget{}, err
+	}
+	return t, nil
+}
+
+// authHeader returns the header line of a bearer, basic or API key authorization, or an
+// empty string without one.
+func (p *postmanLoader) authHeader(auth *postmanAuth) (string, error) {
+	if auth == nil {
+		return "", nil
+	}
+	var line string
+	switch auth.Type {
+	case "bearer":
+		line = "Authorization: Bearer " + auth.param(auth.Bearer, "token")
+	case "basic":
+		credentials := auth.param(auth.Basic, "username") + ":" + auth.param(auth.Basic, "password")
+		resolved, err := p.resolve(credentials)
+		if err != nil {
+			return "", err
+		}
+		return "Authorization: Basic " + base64.StdEncoding.EncodeToString([]byte(resolved)), nil
+	case "apikey":
+		if auth.param(auth.APIKey, "in") == "query" {
+			return "", fmt.Errorf("API keys in the query are not supported, add the key to the URL")
+		}
+		line = auth.param(auth.APIKey, "key") + ": " + auth.param(auth.APIKey, "value")
+	case "noauth", "":
+		return "", nil
+	default:
+		return "", fmt.Errorf("%s authorization is not supported", auth.Type)
+	}
+	return p.resolve(line)
+}
+
+// resolve replaces the {{variable}} references of s, including references in the values
+// of variables. Dynamic variables become template helpers, and other references must be
+// defined.
+func (p *postmanLoader) resolve(s string) (string, error) {
+	var missing string
+	for depth := 0; depth < 10 && postmanVariable.MatchString(s); depth++ {
+		replaced := postmanVariable.ReplaceAllStringFunc(s, func(ref string) string {
+			name := postmanVariable.FindStringSubmatch(ref)[1]
+			if helper, ok := postmanDynamicVariables[name]; ok {
+				return "\x00" + helper[2:len(helper)-2] + "\x01"
+			}
+			if value, ok := p.variables[name]; ok {
+				return value
+			}
+			if missing == "" {
+				missing = name
+			}
+			return ref
+		})
+		if missing != "" {
+			return "", fmt.Errorf("undefined variable %q", missing)
+		}
+		if replaced == s {
+			break
+		}
+		s = replaced
+	}
+	// Template actions are restored once variables are resolved, so they are not taken
+	// for variables.
+	return strings.NewReplacer("\x00", "{{", "\x01", "}}").Replace(s), nil
+}
+
+// postmanURL returns the URL of a request, given either as a string or as an object with
+// its raw form.
+func postmanURL(raw json.RawMessage) (string, error) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s, nil
+	}
+	var u struct {
+		Raw string `json:"raw"`
+	}
+	if err := json.Unmarshal(raw, &u); err != nil || u.Raw == "" {
+		return "", fmt.Errorf("the request has no URL")
+	}
+	return u.Raw, nil
+}
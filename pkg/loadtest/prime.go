@@ -0,0 +1,63 @@
+package loadtest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"time"
+)
+
+// readLines reads the items of a list file such as a priming file, one per line. Blank
+// lines and lines starting with # are skipped.
+func readLines(path string) ([]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var urls []string
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" && !strings.HasPrefix(line, "#") {
+			urls = append(urls, line)
+		}
+	}
+	return urls, scanner.Err()
+}
+
+// runPriming sends one GET request to every URL, in order, waiting cfg.PrimeInterval
+// between them, so caches on the way are warm before the measured phase. Priming requests
+// are left out of the report; failed ones are reported as they happen. It stops early when
+// ctx is done.
+func (e *engine) runPriming(ctx context.Context, urls []string) {
+	e.cfg.log().Info("Priming URLs", "count", len(urls))
+	w := &worker{randomValues: make(map[string]interface{}), random: e.random.stream("prime")}
+	failed := 0
+	for i, url := range urls {
+		if i > 0 {
+			select {
+			case <-time.After(e.cfg.PrimeInterval):
+			case <-ctx.Done():
+			}
+		}
+		if ctx.Err() != nil {
+			e.cfg.log().Warn("Priming stopped", "primed", i, "count", len(urls))
+			return
+		}
+		res := e.sendRequest(target{method: "GET", url: url}, w)
+		switch {
+		case res.statusCode == -1:
+			// sendRequest already reported the network error.
+			failed++
+		case res.statusCode >= 400:
+			failed++
+			e.cfg.log().Warn("Priming returned an error status", "url", url, "status", res.statusCode)
+		}
+	}
+	if failed > 0 {
+		e.cfg.log().Warn("Primed URLs with failures", "count", len(urls), "failed", failed)
+		return
+	}
+	e.cfg.log().Info("Primed URLs", "count", len(urls))
+}
@@ -0,0 +1,69 @@
+package loadtest
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// probeResult holds the outcome of a verification probe: a short, sequential,
+// low-rate series of requests used to compare the target before and after the load.
+type probeResult struct {
+	latencies []time.Duration
+	errors    int
+}
+
+// runProbe sends cfg.ProbeRequests sequential GET requests to url, waiting
+// cfg.ProbeInterval between them. Responses with a 5xx status count as errors.
+func (e *engine) runProbe(url string) probeResult {
+	var probe probeResult
+	w := &worker{randomValues: make(map[string]interface{}), random: e.random.stream("probe")}
+	for i := 0; i < e.cfg.ProbeRequests; i++ {
+		if i > 0 {
+			time.Sleep(e.cfg.ProbeInterval)
+		}
+		res := e.sendRequest(target{method: "GET", url: url}, w)
+		if res.statusCode == -1 || res.statusCode >= 500 {
+			probe.errors++
+			continue
+		}
+		probe.latencies = append(probe.latencies, res.latency)
+	}
+	return probe
+}
+
+// median returns the median latency of the successful probe requests.
+func (p probeResult) median() time.Duration {
+	if len(p.latencies) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), p.latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[len(sorted)/2]
+}
+
+// recovered reports whether the post-load probe is back to the baseline: no more
+// errors than before and a median latency within tolerance times the baseline median.
+func (p probeResult) recovered(baseline probeResult, tolerance float64) bool {
+	if len(p.latencies) == 0 || p.errors > baseline.errors {
+		return false
+	}
+	return float64(p.median()) <= float64(baseline.median())*tolerance
+}
+
+// generateProbeReport compares the pre-load baseline probe with the post-load probe.
+func generateProbeReport(baseline, after probeResult, tolerance float64) {
+	color.Green("\n===== 🩺 Recovery Probe =====")
+	fmt.Printf("📏 Baseline (before load): median %v, %d errors\n", baseline.median(), baseline.errors)
+	fmt.Printf("📏 After load: median %v, %d errors\n", after.median(), after.errors)
+	if baseline.median() > 0 && after.median() > 0 {
+		fmt.Printf("📐 Latency ratio: %.2fx (tolerance %.2fx)\n", float64(after.median())/float64(baseline.median()), tolerance)
+	}
+	if after.recovered(baseline, tolerance) {
+		color.Cyan("✅ The target recovered after the load phase.")
+	} else {
+		color.Red("⚠️  The target has not recovered to its baseline after the load phase.")
+	}
+}
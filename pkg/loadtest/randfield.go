@@ -0,0 +1,130 @@
+package loadtest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// pathSegment is one step of a field path: an object key, an array index or the
+// [*] wildcard matching every element of an array.
+type pathSegment struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// parseFieldPath parses a dotted or JSONPath-like expression such as "user.id",
+// "$.items[0].id" or "items[*].sku".
+func parseFieldPath(path string) ([]pathSegment, error) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	if path == "" {
+		return nil, fmt.Errorf("empty field path")
+	}
+	var segments []pathSegment
+	for _, part := range strings.Split(path, ".") {
+		key, rest, _ := strings.Cut(part, "[")
+		if key != "" {
+			segments = append(segments, pathSegment{key: key})
+		}
+		for rest != "" {
+			inner, after, ok := strings.Cut(rest, "]")
+			if !ok {
+				return nil, fmt.Errorf("unterminated [ in %q", path)
+			}
+			switch {
+			case inner == "*":
+				segments = append(segments, pathSegment{wildcard: true})
+			default:
+				index, err := strconv.Atoi(inner)
+				if err != nil || index < 0 {
+					return nil, fmt.Errorf("invalid array index %q in %q", inner, path)
+				}
+				segments = append(segments, pathSegment{index: index, isIndex: true})
+			}
+			rest = strings.TrimPrefix(after, "[")
+		}
+		if key == "" && !strings.Contains(part, "[") {
+			return nil, fmt.Errorf("empty segment in %q", path)
+		}
+	}
+	return segments, nil
+}
+
+// randField is a rule assigning a random value of the given type and length to the
+// JSON field(s) matched by path.
+type randField struct {
+	spec   string
+	path   []pathSegment
+	idType string
+	length int
+}
+
+// defaultRandFieldLength is the length used when a random field rule omits it.
+const defaultRandFieldLength = 10
+
+// parseRandField parses a rule of the form path=type[:length], e.g. "user.id=string:12"
+// or "user.ref=uuid".
+func parseRandField(spec string) (randField, error) {
+	path, kind, ok := strings.Cut(spec, "=")
+	if !ok {
+		return randField{}, fmt.Errorf("invalid random field %q, expected path=type:length", spec)
+	}
+	idType, lengthText, hasLength := strings.Cut(kind, ":")
+	length := defaultRandFieldLength
+	if hasLength {
+		var err error
+		length, err = strconv.Atoi(lengthText)
+		if err != nil || length < 1 {
+			return randField{}, fmt.Errorf("invalid length in random field %q", spec)
+		}
+	}
+	segments, err := parseFieldPath(path)
+	if err != nil {
+		return randField{}, err
+	}
+	return randField{spec: spec, path: segments, idType: idType, length: length}, nil
+}
+
+// setAtPath sets every location matched by segments inside node to the value returned by
+// value, which receives the concrete path of the location (e.g. "items.2.id"). Missing
+// object keys are created; array positions that do not exist are skipped.
+func setAtPath(node interface{}, segments []pathSegment, at string, value func(at string) interface{}) interface{} {
+	if len(segments) == 0 {
+		return value(at)
+	}
+	segment, rest := segments[0], segments[1:]
+	switch {
+	case segment.wildcard || segment.isIndex:
+		array, ok := node.([]interface{})
+		if !ok {
+			return node
+		}
+		for i := range array {
+			if segment.isIndex && i != segment.index {
+				continue
+			}
+			array[i] = setAtPath(array[i], rest, joinPath(at, strconv.Itoa(i)), value)
+		}
+		return array
+	default:
+		object, ok := node.(map[string]interface{})
+		if !ok {
+			if node != nil {
+				return node
+			}
+			object = make(map[string]interface{})
+		}
+		object[segment.key] = setAtPath(object[segment.key], rest, joinPath(at, segment.key), value)
+		return object
+	}
+}
+
+// joinPath appends a segment to a concrete dotted path.
+func joinPath(at, segment string) string {
+	if at == "" {
+		return segment
+	}
+	return at + "." + segment
+}
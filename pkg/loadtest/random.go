@@ -0,0 +1,48 @@
+package loadtest
+
+import (
+	cryptorand "crypto/rand"
+	"encoding/binary"
+	"hash/fnv"
+	"io"
+	"math/rand"
+)
+
+// randomStream is a source of random values owned by a single component, such as one
+// worker's template data. It is not safe for concurrent use.
+type randomStream struct {
+	*rand.Rand
+	// ids is where the random bits of UUIDs and ULIDs come from: the stream itself when
+	// the run is seeded, crypto/rand otherwise.
+	ids io.Reader
+	// seq is the sequence of the run, shared by all its streams.
+	seq *sequence
+}
+
+// randomStreams derives the random streams of a run. With a seed, every stream is a
+// deterministic function of the seed and its label only, so streams are isolated from
+// each other: adding a target or a worker does not shift the values drawn elsewhere.
+type randomStreams struct {
+	seed   int64
+	seeded bool
+	seq    sequence
+}
+
+// newRandomStreams returns the stream factory for seed; 0 means unseeded.
+func newRandomStreams(seed int64) *randomStreams {
+	return &randomStreams{seed: seed, seeded: seed != 0}
+}
+
+// stream returns the stream for label, e.g. "worker/3/data" or "feed".
+func (s *randomStreams) stream(label string) *randomStream {
+	if !s.seeded {
+		var buf [8]byte
+		cryptorand.Read(buf[:])
+		return &randomStream{Rand: rand.New(rand.NewSource(int64(binary.LittleEndian.Uint64(buf[:])))), ids: cryptorand.Reader, seq: &s.seq}
+	}
+	h := fnv.New64a()
+	binary.Write(h, binary.LittleEndian, s.seed)
+	io.WriteString(h, label)
+	r := rand.New(rand.NewSource(int64(h.Sum64())))
+	return &randomStream{Rand: r, ids: r, seq: &s.seq}
+}
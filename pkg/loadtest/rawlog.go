@@ -0,0 +1,118 @@
+package loadtest
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+// rawLogBodyLimit is the maximum number of body bytes kept per request and response in the raw log.
+const rawLogBodyLimit = 4096
+
+// exchangeRecord is a single request/response pair as written to the raw log.
+type exchangeRecord struct {
+	Time            time.Time           `json:"time"`
+	Method          string              `json:"method"`
+	URL             string              `json:"url"`
+	RequestHeaders  map[string][]string `json:"request_headers,omitempty"`
+	RequestBody     string              `json:"request_body,omitempty"`
+	Status          int                 `json:"status"`
+	Error           string              `json:"error,omitempty"`
+	LatencyMs       float64             `json:"latency_ms"`
+	ResponseHeaders map[string][]string `json:"response_headers,omitempty"`
+	ResponseBody    string              `json:"response_body,omitempty"`
+}
+
+// truncateBody returns at most rawLogBodyLimit bytes of body as a string.
+func truncateBody(body []byte) string {
+	if len(body) > rawLogBodyLimit {
+		return string(body[:rawLogBodyLimit]) + "…"
+	}
+	return string(body)
+}
+
+// truncated returns a copy of r with its bodies truncated like those of the raw log.
+func (r *exchangeRecord) truncated() *exchangeRecord {
+	short := *r
+	short.RequestBody = truncateBody([]byte(r.RequestBody))
+	short.ResponseBody = truncateBody([]byte(r.ResponseBody))
+	return &short
+}
+
+// ndjsonQueueSize is the number of documents an NDJSON log buffers while the disk lags
+// behind. Documents beyond it are dropped rather than slowing the run down.
+const ndjsonQueueSize = 8192
+
+// ndjsonLog writes one JSON document per line. Documents are encoded and written by a
+// goroutine of the log through a buffer, so that a busy run does not wait for the disk:
+// when the queue is full, documents are dropped and counted instead. Documents must not
+// be modified once written.
+type ndjsonLog struct {
+	path    string
+	out     io.WriteCloser
+	queue   chan interface{}
+	done    chan struct{}
+	dropped atomic.Int64
+	err     error
+	logger  *slog.Logger
+}
+
+// newNDJSONLog creates the log file at path, warning through logger about dropped documents.
+func newNDJSONLog(path string, opts OutputOptions, logger *slog.Logger) (*ndjsonLog, error) {
+	out, err := createOutput(path, opts)
+	if err != nil {
+		return nil, err
+	}
+	return startNDJSONLog(path, out, logger), nil
+}
+
+// startNDJSONLog starts a log writing to out, named path in its warnings.
+func startNDJSONLog(path string, out io.WriteCloser, logger *slog.Logger) *ndjsonLog {
+	l := &ndjsonLog{path: path, out: out, queue: make(chan interface{}, ndjsonQueueSize), done: make(chan struct{}), logger: logger}
+	go l.drain()
+	return l
+}
+
+// drain encodes the queued documents until the log is closed. The buffer is flushed
+// whenever the queue is empty, so the file keeps up with a quiet run. After an error,
+// documents are discarded and the error is returned by close.
+func (l *ndjsonLog) drain() {
+	defer close(l.done)
+	buf := bufio.NewWriterSize(l.out, 64*1024)
+	enc := json.NewEncoder(buf)
+	for v := range l.queue {
+		if l.err == nil {
+			l.err = enc.Encode(v)
+		}
+		if len(l.queue) == 0 && l.err == nil {
+			l.err = buf.Flush()
+		}
+	}
+	if l.err == nil {
+		l.err = buf.Flush()
+	}
+}
+
+// write queues v to be appended to the log, or drops it when the queue is full.
+func (l *ndjsonLog) write(v interface{}) {
+	select {
+	case l.queue <- v:
+	default:
+		l.dropped.Add(1)
+	}
+}
+
+// close writes the queued documents, closes the log file and warns about the documents
+// that were dropped. It must not be called concurrently with write.
+func (l *ndjsonLog) close() error {
+	close(l.queue)
+	<-l.done
+	if dropped := l.dropped.Load(); dropped > 0 {
+		l.logger.Warn("Records were dropped because writing could not keep up with the run", "path", l.path, "dropped", dropped)
+	}
+	return errors.Join(l.err, l.out.Close())
+}
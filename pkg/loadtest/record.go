@@ -0,0 +1,375 @@
+package loadtest
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// RecordOptions configures "restclient record".
+type RecordOptions struct {
+	Listen     string
+	OutputPath string
+	Target     string
+	Hosts      []string
+	Insecure   bool
+	// Logger receives the errors of the proxy. Nil means the console at the info level.
+	Logger *slog.Logger
+}
+
+// recordedHAR is the HAR file written by the recorder. It holds the fields other tools
+// require next to the ones replayed by LoadHAR.
+type recordedHAR struct {
+	Log struct {
+		Version string `json:"version"`
+		Creator struct {
+			Name    string `json:"name"`
+			Version string `json:"version"`
+		} `json:"creator"`
+		Entries []recordedEntry `json:"entries"`
+	} `json:"log"`
+}
+
+// recordedEntry is a request recorded by the proxy, with the status of its response.
+type recordedEntry struct {
+	StartedDateTime time.Time        `json:"startedDateTime"`
+	Time            float64          `json:"time"`
+	Request         recordedRequest  `json:"request"`
+	Response        recordedResponse `json:"response"`
+	Cache           struct{}         `json:"cache"`
+	Timings         struct {
+		Send    float64 `json:"send"`
+		Wait    float64 `json:"wait"`
+		Receive float64 `json:"receive"`
+	} `json:"timings"`
+}
+
+// recordedRequest is the request of a recorded entry.
+type recordedRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	Cookies     []harNameValue `json:"cookies"`
+	PostData    *struct {
+		MimeType string `json:"mimeType"`
+		Text     string `json:"text"`
+	} `json:"postData,omitempty"`
+	HeadersSize int `json:"headersSize"`
+	BodySize    int `json:"bodySize"`
+}
+
+// recordedResponse is the response of a recorded entry. Its body is not kept, as it is
+// not needed to replay the request.
+type recordedResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Headers     []harNameValue `json:"headers"`
+	Cookies     []harNameValue `json:"cookies"`
+	Content     struct {
+		Size     int64  `json:"size"`
+		MimeType string `json:"mimeType"`
+	} `json:"content"`
+	RedirectURL string `json:"redirectURL"`
+	HeadersSize int    `json:"headersSize"`
+	BodySize    int64  `json:"bodySize"`
+}
+
+// recorder is an HTTP proxy recording the requests going through it.
+type recorder struct {
+	target *url.URL
+	hosts  []string
+	proxy  *httputil.ReverseProxy
+
+	mu       sync.Mutex
+	entries  []recordedEntry
+	tunneled map[string]bool
+}
+
+// recordedEntryKey is the context key of the entry a forwarded request is recorded in.
+type recordedEntryKey struct{}
+
+// RunRecorder runs an HTTP proxy on opts.Listen until ctx is done, then writes the
+// requests that went through it to the HAR file at opts.OutputPath, ready to be replayed
+// with --har. Clients use it as their HTTP proxy; HTTPS requests are tunneled without
+// being recorded, as their content is encrypted. With opts.Target, the proxy is a
+// reverse proxy instead, forwarding every request to that base URL, so clients that
+// cannot use a proxy and HTTPS services can be recorded too. With opts.Hosts, only the
+// requests to these hosts are recorded. opts.Insecure skips the verification of the TLS
+// certificate of the target.
+func RunRecorder(ctx context.Context, opts RecordOptions) error {
+	logger := opts.Logger
+	if logger == nil {
+		logger = defaultLogger
+	}
+	r := &recorder{hosts: opts.Hosts, tunneled: make(map[string]bool)}
+	if opts.Target != "" {
+		target, err := url.Parse(opts.Target)
+		if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+			return fmt.Errorf("invalid target %q, expected an http or https URL", opts.Target)
+		}
+		r.target = target
+	}
+	r.proxy = &httputil.ReverseProxy{
+		// Requests are sent as the client made them, the director only routes them.
+		Director: r.route,
+		Transport: &http.Transport{
+			ForceAttemptHTTP2:   true,
+			MaxIdleConnsPerHost: 100,
+			TLSClientConfig:     &tls.Config{InsecureSkipVerify: opts.Insecure},
+		},
+		ModifyResponse: r.recordResponse,
+		ErrorHandler: func(w http.ResponseWriter, req *http.Request, err error) {
+			logger.Error("Error forwarding request", "method", req.Method, "url", req.URL.String(), "error", err)
+			w.WriteHeader(http.StatusBadGateway)
+		},
+	}
+
+	listener, err := net.Listen("tcp", opts.Listen)
+	if err != nil {
+		return err
+	}
+	server := &http.Server{Handler: r}
+	go server.Serve(listener)
+	if r.target != nil {
+		color.Cyan("🔴 Recording requests to http://%s, forwarded to %s, press Ctrl+C to stop...", listener.Addr(), r.target)
+	} else {
+		color.Cyan("🔴 Recording through the HTTP proxy http://%s, press Ctrl+C to stop...", listener.Addr())
+	}
+
+	<-ctx.Done()
+	shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	server.Shutdown(shutdown)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.entries) == 0 {
+		return fmt.Errorf("no request was recorded")
+	}
+	if err := r.writeHAR(opts.OutputPath); err != nil {
+		return err
+	}
+	color.Green("\n💾 Recorded %d requests to %s, replay them with --har=%s", len(r.entries), opts.OutputPath, opts.OutputPath)
+	return nil
+}
+
+// ServeHTTP proxies a request, recording it unless it is an HTTPS tunnel or to a host
+// that is not recorded.
+func (r *recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method == http.MethodConnect {
+		r.tunnel(w, req)
+		return
+	}
+	if r.target == nil && !req.URL.IsAbs() {
+		http.Error(w, "restclient record is an HTTP proxy: configure it as the proxy of the client, or pass --target", http.StatusBadRequest)
+		return
+	}
+	started := time.Now()
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	entry := r.newEntry(req, body, started)
+	if len(r.hosts) > 0 && !matchesHost(r.hosts, entry.url()) {
+		r.proxy.ServeHTTP(w, req)
+		return
+	}
+
+	r.proxy.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), recordedEntryKey{}, entry)))
+	if entry.Response.Status == 0 {
+		return
+	}
+	entry.Time = milliseconds(time.Since(started))
+	entry.Timings.Receive = entry.Time - entry.Timings.Wait
+	r.mu.Lock()
+	r.entries = append(r.entries, *entry)
+	r.mu.Unlock()
+	fmt.Printf("⏺️  %s %s → %d\n", entry.Request.Method, entry.Request.URL, entry.Response.Status)
+}
+
+// route points the outgoing request at the target of a reverse proxy; a forward proxy
+// request already carries its absolute URL.
+func (r *recorder) route(req *http.Request) {
+	if r.target != nil {
+		req.URL.Scheme = r.target.Scheme
+		req.URL.Host = r.target.Host
+		req.URL.Path = strings.TrimSuffix(r.target.Path, "/") + req.URL.Path
+		req.URL.RawPath = ""
+		req.Host = r.target.Host
+	}
+	req.Header.Del("Proxy-Connection")
+	req.Header.Del("Proxy-Authorization")
+	// The proxy stays out of the requests it records.
+	req.Header["X-Forwarded-For"] = nil
+	if _, ok := req.Header["User-Agent"]; !ok {
+		// Keep the client from adding the Go user agent to requests sent without one.
+		req.Header.Set("User-Agent", "")
+	}
+}
+
+// newEntry returns the recorded entry of a request, before it is forwarded.
+func (r *recorder) newEntry(req *http.Request, body []byte, started time.Time) *recordedEntry {
+	u := *req.URL
+	host := req.Host
+	if r.target != nil {
+		u.Scheme, u.Host = r.target.Scheme, r.target.Host
+		u.Path = strings.TrimSuffix(r.target.Path, "/") + u.Path
+		u.RawPath = ""
+		host = r.target.Host
+	}
+	entry := &recordedEntry{StartedDateTime: started}
+	entry.Request = recordedRequest{
+		Method:      req.Method,
+		URL:         u.String(),
+		HTTPVersion: req.Proto,
+		Headers:     []harNameValue{},
+		QueryString: []harNameValue{},
+		Cookies:     []harNameValue{},
+		HeadersSize: -1,
+		BodySize:    len(body),
+	}
+	if host != "" {
+		entry.Request.Headers = append(entry.Request.Headers, harNameValue{Name: "Host", Value: host})
+	}
+	for _, name := range sortedHeaderNames(req.Header) {
+		if strings.HasPrefix(strings.ToLower(name), "proxy-") {
+			continue
+		}
+		for _, value := range req.Header[name] {
+			entry.Request.Headers = append(entry.Request.Headers, harNameValue{Name: name, Value: value})
+		}
+	}
+	for name, values := range u.Query() {
+		for _, value := range values {
+			entry.Request.QueryString = append(entry.Request.QueryString, harNameValue{Name: name, Value: value})
+		}
+	}
+	for _, c := range req.Cookies() {
+		entry.Request.Cookies = append(entry.Request.Cookies, harNameValue{Name: c.Name, Value: c.Value})
+	}
+	if len(body) > 0 {
+		entry.Request.PostData = &struct {
+			MimeType string `json:"mimeType"`
+			Text     string `json:"text"`
+		}{MimeType: req.Header.Get("Content-Type"), Text: string(body)}
+	}
+	return entry
+}
+
+// recordResponse records the status and headers of the response to a recorded request.
+func (r *recorder) recordResponse(resp *http.Response) error {
+	entry, ok := resp.Request.Context().Value(recordedEntryKey{}).(*recordedEntry)
+	if !ok {
+		return nil
+	}
+	entry.Timings.Wait = milliseconds(time.Since(entry.StartedDateTime))
+	entry.Response = recordedResponse{
+		Status:      resp.StatusCode,
+		StatusText:  strings.TrimSpace(strings.TrimPrefix(resp.Status, fmt.Sprint(resp.StatusCode))),
+		HTTPVersion: resp.Proto,
+		Headers:     []harNameValue{},
+		Cookies:     []harNameValue{},
+		RedirectURL: resp.Header.Get("Location"),
+		HeadersSize: -1,
+		BodySize:    resp.ContentLength,
+	}
+	for _, name := range sortedHeaderNames(resp.Header) {
+		for _, value := range resp.Header[name] {
+			entry.Response.Headers = append(entry.Response.Headers, harNameValue{Name: name, Value: value})
+		}
+	}
+	for _, c := range resp.Cookies() {
+		entry.Response.Cookies = append(entry.Response.Cookies, harNameValue{Name: c.Name, Value: c.Value})
+	}
+	entry.Response.Content.Size = resp.ContentLength
+	entry.Response.Content.MimeType = resp.Header.Get("Content-Type")
+	return nil
+}
+
+// tunnel relays an HTTPS connection to its host without recording it, and tells once per
+// host that its requests are not recorded.
+func (r *recorder) tunnel(w http.ResponseWriter, req *http.Request) {
+	upstream, err := net.DialTimeout("tcp", req.Host, 10*time.Second)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		upstream.Close()
+		http.Error(w, "tunneling is not supported", http.StatusInternalServerError)
+		return
+	}
+	client, buffered, err := hijacker.Hijack()
+	if err != nil {
+		upstream.Close()
+		return
+	}
+	r.mu.Lock()
+	if !r.tunneled[req.Host] {
+		r.tunneled[req.Host] = true
+		color.Yellow("🔒 HTTPS requests to %s are tunneled but not recorded, record them with --target=https://%s", req.Host, strings.TrimSuffix(req.Host, ":443"))
+	}
+	r.mu.Unlock()
+
+	client.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n"))
+	go func() {
+		io.Copy(upstream, buffered)
+		upstream.Close()
+	}()
+	io.Copy(client, upstream)
+	client.Close()
+}
+
+// writeHAR writes the recorded entries, in the order they were sent, to a HAR file.
+func (r *recorder) writeHAR(path string) error {
+	var har recordedHAR
+	har.Log.Version = "1.2"
+	har.Log.Creator.Name = "restclient"
+	har.Log.Creator.Version = toolVersion()
+	har.Log.Entries = r.entries
+	sort.SliceStable(har.Log.Entries, func(i, j int) bool {
+		return har.Log.Entries[i].StartedDateTime.Before(har.Log.Entries[j].StartedDateTime)
+	})
+	content, err := json.MarshalIndent(har, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0o644)
+}
+
+// url returns the URL the entry was sent to.
+func (e *recordedEntry) url() *url.URL {
+	u, _ := url.Parse(e.Request.URL)
+	return u
+}
+
+// sortedHeaderNames returns the names of header in alphabetical order.
+func sortedHeaderNames(header http.Header) []string {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,102 @@
+package loadtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// redactedValue replaces redacted values in everything written to disk.
+const redactedValue = "[REDACTED]"
+
+// defaultRedactedHeaders are redacted unless default redaction is disabled, since they
+// carry credentials in almost every API.
+var defaultRedactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+
+// redactor removes sensitive values from records before they are written to disk or exported.
+type redactor struct {
+	headers map[string]bool
+	fields  [][]pathSegment
+}
+
+// newRedactor returns a redactor for the given header names (case-insensitive) and JSON
+// field paths. The default credential headers are included when withDefaults is true.
+func newRedactor(headers, fields []string, withDefaults bool) (*redactor, error) {
+	r := &redactor{headers: make(map[string]bool, len(headers))}
+	if withDefaults {
+		headers = append(append([]string(nil), defaultRedactedHeaders...), headers...)
+	}
+	for _, name := range headers {
+		r.headers[http.CanonicalHeaderKey(strings.TrimSpace(name))] = true
+	}
+	for _, field := range fields {
+		segments, err := parseFieldPath(field)
+		if err != nil {
+			return nil, err
+		}
+		r.fields = append(r.fields, segments)
+	}
+	return r, nil
+}
+
+// redactHeaders returns a copy of h with the values of redacted headers replaced.
+func (r *redactor) redactHeaders(h http.Header) map[string][]string {
+	if len(h) == 0 {
+		return nil
+	}
+	out := make(map[string][]string, len(h))
+	for name, values := range h {
+		if r.headers[http.CanonicalHeaderKey(name)] {
+			out[name] = []string{redactedValue}
+			continue
+		}
+		out[name] = append([]string(nil), values...)
+	}
+	return out
+}
+
+// redactBody replaces the redacted fields of a JSON body. Bodies that are not JSON,
+// or when no field rules are configured, are returned unchanged.
+func (r *redactor) redactBody(body []byte) []byte {
+	if len(r.fields) == 0 || len(body) == 0 {
+		return body
+	}
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return body
+	}
+	for _, segments := range r.fields {
+		doc = replaceAtPath(doc, segments, redactedValue)
+	}
+	redacted, err := json.Marshal(doc)
+	if err != nil {
+		return body
+	}
+	return redacted
+}
+
+// replaceAtPath replaces every existing location matched by segments inside node with value.
+// Unlike setAtPath it never creates missing keys.
+func replaceAtPath(node interface{}, segments []pathSegment, value interface{}) interface{} {
+	if len(segments) == 0 {
+		return value
+	}
+	segment, rest := segments[0], segments[1:]
+	switch typed := node.(type) {
+	case []interface{}:
+		if !segment.wildcard && !segment.isIndex {
+			return node
+		}
+		for i := range typed {
+			if segment.isIndex && i != segment.index {
+				continue
+			}
+			typed[i] = replaceAtPath(typed[i], rest, value)
+		}
+	case map[string]interface{}:
+		if child, ok := typed[segment.key]; ok && !segment.wildcard && !segment.isIndex {
+			typed[segment.key] = replaceAtPath(child, rest, value)
+		}
+	}
+	return node
+}
@@ -0,0 +1,112 @@
+package loadtest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// ReplayAll renders the reports of several runs saved with Config.ResultsPath as one run,
+// such as the runs of load generators spread over several regions. The merged run spans
+// from the first start to the last end, and its results are broken down per region: the
+// Config.Region of each run, or the name of its file when it has none.
+func ReplayAll(paths []string, opts ReplayOptions) (Result, error) {
+	return replayAll(strings.Join(paths, ", "), paths, opts)
+}
+
+// replayAll is ReplayAll with the results files described as source in the report.
+func replayAll(source string, paths []string, opts ReplayOptions) (Result, error) {
+	var header resultsHeader
+	var footer resultsFooter
+	var results []requestResult
+	var end time.Time
+	regions := make(map[string]bool)
+	for i, path := range paths {
+		first := len(results)
+		h, f, err := readResults(path, func(res requestResult) { results = append(results, res) })
+		if err != nil {
+			return Result{}, fmt.Errorf("error reading results: %w", err)
+		}
+		region := f.Region
+		if region == "" {
+			region = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		}
+		if regions[region] {
+			color.Yellow("⚠️  Several results files are labeled %s, their results are reported together.", region)
+		}
+		regions[region] = true
+		for j := first; j < len(results); j++ {
+			results[j].region = region
+		}
+		if i == 0 {
+			header, footer, end = h, f, f.MeasuredFrom.Add(f.Duration)
+			continue
+		}
+		if h.Manifest.Config != header.Manifest.Config {
+			color.Yellow("⚠️  %s comes from a run with other settings than %s.", path, paths[0])
+		}
+		footer = mergeFooters(footer, f)
+		if runEnd := f.MeasuredFrom.Add(f.Duration); runEnd.After(end) {
+			end = runEnd
+		}
+	}
+	footer.Duration = end.Sub(footer.MeasuredFrom)
+	footer.Region = ""
+	return replay(source, header, footer, results, opts)
+}
+
+// mergeFooters returns the footer of a run made of the runs of a and b, but for its
+// duration. The report settings of a are kept.
+func mergeFooters(a, b resultsFooter) resultsFooter {
+	if b.StartedAt.Before(a.StartedAt) {
+		a.StartedAt = b.StartedAt
+	}
+	if b.MeasuredFrom.Before(a.MeasuredFrom) {
+		a.MeasuredFrom = b.MeasuredFrom
+	}
+	a.Warmup += b.Warmup
+	a.FeedCaptured += b.FeedCaptured
+	a.Interrupted = a.Interrupted || b.Interrupted
+	a.MaxDurationExceeded = a.MaxDurationExceeded || b.MaxDurationExceeded
+	if a.Aborted == "" {
+		a.Aborted = b.Aborted
+	}
+	return a
+}
+
+// generateRegionReport prints the request count, error rate and latency percentiles of
+// every region of a merged run. Nothing is printed unless results come from several
+// regions.
+func generateRegionReport(regionStats map[string]*stats) {
+	if len(regionStats) < 2 {
+		return
+	}
+	regions := make([]string, 0, len(regionStats))
+	for region := range regionStats {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+
+	color.Green("\n===== 🌍 Per-region Breakdown =====")
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "Region\tRequests\tErrors\tp50\tp95\tp99\tMax\t")
+	worst, worstP99 := "", 0.0
+	for _, region := range regions {
+		s := newReportSummary(time.Time{}, 0, regionStats[region])
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\t%s\t\n", region, s.Requests, formatErrorRate(s),
+			formatMs(s.P50LatencyMs), formatMs(s.P95LatencyMs), formatMs(s.P99LatencyMs), formatMs(s.MaxLatencyMs))
+		if s.P99LatencyMs > worstP99 {
+			worst, worstP99 = region, s.P99LatencyMs
+		}
+	}
+	tw.Flush()
+	if worst != "" {
+		color.Yellow("🐢 Slowest region at p99: %s (%s)", worst, formatMs(worstP99))
+	}
+}
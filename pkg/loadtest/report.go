@@ -0,0 +1,148 @@
+package loadtest
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Report is the machine-readable report written with --report-json.
+type Report struct {
+	Manifest Manifest        `json:"manifest"`
+	Summary  Result          `json:"summary"`
+	Timeline []TimelinePoint `json:"timeline,omitempty"`
+	// Signature is set when the report was written with a signing key.
+	Signature *ReportSignature `json:"signature,omitempty"`
+}
+
+// Result holds the headline numbers of a run.
+type Result struct {
+	StartedAt         time.Time   `json:"started_at"`
+	DurationMs        float64     `json:"duration_ms"`
+	Requests          int         `json:"requests"`
+	Successful        int         `json:"successful"`
+	NetworkErrors     int         `json:"network_errors"`
+	Skipped           int         `json:"skipped"`
+	WarmupRequests    int         `json:"warmup_requests"`
+	FailedAssertions  int         `json:"failed_assertions"`
+	FailedBodyChecks  int         `json:"failed_body_checks"`
+	SchemaFailures    int         `json:"schema_failures"`
+	ScriptFailures    int         `json:"script_failures"`
+	Abandoned         int         `json:"abandoned"`
+	StatusCodes       map[int]int `json:"status_codes"`
+	AvgLatencyMs      float64     `json:"avg_latency_ms"`
+	P50LatencyMs      float64     `json:"p50_latency_ms"`
+	P90LatencyMs      float64     `json:"p90_latency_ms"`
+	P95LatencyMs      float64     `json:"p95_latency_ms"`
+	P99LatencyMs      float64     `json:"p99_latency_ms"`
+	MaxLatencyMs      float64     `json:"max_latency_ms"`
+	RequestsPerSecond float64     `json:"requests_per_second"`
+	Aborted           string      `json:"aborted,omitempty"`
+	Interrupted       bool        `json:"interrupted,omitempty"`
+	// MaxDurationExceeded is set when the run was aborted at its --max-duration.
+	MaxDurationExceeded bool `json:"max_duration_exceeded,omitempty"`
+	// StatusClasses counts the responses per status class, such as "2xx" and "5xx".
+	StatusClasses map[string]int `json:"status_classes,omitempty"`
+	// StatusLatencies holds the latency percentiles of the responses per status code.
+	StatusLatencies map[int]LatencyPercentiles `json:"status_latencies,omitempty"`
+	// Phases holds the percentiles of the DNS, connect, TLS, TTFB and transfer phases of
+	// the requests, each over the requests that went through the phase.
+	Phases map[string]LatencyPercentiles `json:"phases,omitempty"`
+	// BytesSent and BytesReceived are the sizes of the requests and responses, headers
+	// included, with their rates and distributions.
+	BytesSent     int64           `json:"bytes_sent"`
+	BytesReceived int64           `json:"bytes_received"`
+	SentMBps      float64         `json:"sent_mb_per_second"`
+	ReceivedMBps  float64         `json:"received_mb_per_second"`
+	RequestSizes  SizePercentiles `json:"request_sizes"`
+	ResponseSizes SizePercentiles `json:"response_sizes"`
+}
+
+// requestsPerSecond returns the rate of n requests over d, or 0 when no time was
+// measured, such as for a run interrupted during its warm-up.
+func requestsPerSecond(n int, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return float64(n) / d.Seconds()
+}
+
+// newReportSummary summarizes a run that started at startTime and took totalTime.
+func newReportSummary(startTime time.Time, totalTime time.Duration, st *stats) Result {
+	statusCodes := make(map[int]int, len(st.statusCodeCount))
+	for status, count := range st.statusCodeCount {
+		statusCodes[status] = count
+	}
+	failedChecks := 0
+	for _, failed := range st.checksFailed {
+		failedChecks += failed
+	}
+	return Result{
+		StartedAt:         startTime.UTC(),
+		DurationMs:        milliseconds(totalTime),
+		Requests:          st.sentCount(),
+		Successful:        st.successCount(),
+		NetworkErrors:     st.networkErrorCount,
+		Skipped:           st.skippedCount(),
+		WarmupRequests:    st.warmupCount,
+		FailedAssertions:  st.unexpectedStatusCount,
+		FailedBodyChecks:  failedChecks,
+		SchemaFailures:    st.schemaFailed,
+		ScriptFailures:    st.scriptFailed,
+		Abandoned:         st.abandonedCount,
+		StatusCodes:       statusCodes,
+		StatusClasses:     st.statusClassCounts(),
+		StatusLatencies:   st.statusLatencies(),
+		Phases:            st.phaseLatencies(),
+		BytesSent:         st.bytesSent,
+		BytesReceived:     st.bytesReceived,
+		SentMBps:          megabytesPerSecond(st.bytesSent, totalTime),
+		ReceivedMBps:      megabytesPerSecond(st.bytesReceived, totalTime),
+		RequestSizes:      st.requestSizes.percentiles(),
+		ResponseSizes:     st.responseSizes.percentiles(),
+		AvgLatencyMs:      milliseconds(st.averageLatency()),
+		P50LatencyMs:      milliseconds(st.percentile(0.50)),
+		P90LatencyMs:      milliseconds(st.percentile(0.90)),
+		P95LatencyMs:      milliseconds(st.percentile(0.95)),
+		P99LatencyMs:      milliseconds(st.percentile(0.99)),
+		MaxLatencyMs:      milliseconds(st.percentile(1)),
+		RequestsPerSecond: requestsPerSecond(st.sentCount(), totalTime),
+	}
+}
+
+// failed returns the number of requests that failed with a network error or a 4xx/5xx
+// response.
+func (s Result) failed() int {
+	failed := s.NetworkErrors
+	for status, count := range s.StatusCodes {
+		if status >= 400 {
+			failed += count
+		}
+	}
+	return failed
+}
+
+// milliseconds returns d in fractional milliseconds.
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// writeJSONReport writes report to path, signed when a signing key is configured and
+// encrypted when recipients are.
+func writeJSONReport(path string, opts OutputOptions, report Report) error {
+	if opts.SigningKey != nil {
+		if err := opts.SigningKey.sign(&report); err != nil {
+			return err
+		}
+	}
+	out, err := createOutput(path, opts)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,52 @@
+package loadtest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRequestsPerSecond(t *testing.T) {
+	tests := []struct {
+		n    int
+		d    time.Duration
+		want float64
+	}{
+		{n: 100, d: 2 * time.Second, want: 50},
+		{n: 5, d: 500 * time.Millisecond, want: 10},
+		{n: 0, d: time.Second, want: 0},
+		{n: 10, d: 0, want: 0},
+		{n: 0, d: 0, want: 0},
+	}
+	for _, tt := range tests {
+		if got := requestsPerSecond(tt.n, tt.d); got != tt.want {
+			t.Errorf("requestsPerSecond(%d, %v) = %v, want %v", tt.n, tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestRunInterruptedDuringWarmup(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	summary, err := New(
+		WithConfig(Config{SummaryOnly: true, Warmup: time.Minute}),
+		WithURL(srv.URL),
+		WithConcurrency(2),
+		WithDuration(time.Minute),
+	).Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Requests != 0 || summary.RequestsPerSecond != 0 {
+		t.Errorf("Requests = %d, RequestsPerSecond = %v, want 0 for a run stopped during its warm-up", summary.Requests, summary.RequestsPerSecond)
+	}
+	if _, err := json.Marshal(summary); err != nil {
+		t.Errorf("the summary cannot be written as JSON: %v", err)
+	}
+}
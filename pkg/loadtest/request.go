@@ -0,0 +1,578 @@
+package loadtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"time"
+)
+
+// preparedRequest is a fully rendered request that can be sent one or more times,
+// e.g. once per failover target.
+type preparedRequest struct {
+	target    target
+	url       string
+	body      []byte
+	plainBody []byte
+	header    http.Header
+	host      string
+	multipart *multipartPayload
+	rotated   map[string]string
+	mix       *clientMix
+	jar       http.CookieJar
+	// request is the request built once for a static target, see requestCache.
+	request *http.Request
+}
+
+// sendRequest renders and performs a single request to t.
+func (e *engine) sendRequest(t target, w *worker) requestResult {
+	p, res, ok := e.prepareRequest(t, w)
+	if !ok {
+		return res
+	}
+	return e.sendPrepared(p)
+}
+
+// sendPrepared performs a prepared request. When failover targets are configured and the
+// response matches the failover policy, the same request is sent to the next base URL in
+// order and the extra time is accounted as failover delay.
+func (e *engine) sendPrepared(p *preparedRequest) requestResult {
+	res := e.sendWithRetries(p, p.url)
+	if len(e.failoverBases) == 0 || !e.cfg.FailoverOn.triggers(res.statusCode) {
+		return res
+	}
+
+	var failoverDelay time.Duration
+	for _, base := range e.failoverBases {
+		failoverDelay += res.latency
+		url, err := rebaseURL(p.url, base)
+		if err != nil {
+			e.cfg.log().Error("Error building failover URL", "error", err)
+			break
+		}
+		next := e.sendWithRetries(p, url)
+		next.failovers = res.failovers + 1
+		next.failoverDelay = failoverDelay
+		next.latency += failoverDelay
+		res = next
+		if !e.cfg.FailoverOn.triggers(res.statusCode) {
+			break
+		}
+	}
+	return res
+}
+
+// prepareRequest renders the URL, body and headers of a request. When the request cannot
+// be sent, the returned result describes why and ok is false.
+func (e *engine) prepareRequest(t target, w *worker) (*preparedRequest, requestResult, bool) {
+	// Steps are sent once, so they are not worth caching.
+	if e.requests != nil && !t.step {
+		if p, ok := e.requests.get(e, t, w); ok {
+			// Cached requests are shared by the workers, but not their cookies.
+			scoped := *p
+			scoped.jar = w.jar
+			return &scoped, requestResult{}, true
+		}
+	}
+	row := w.row
+	if e.data != nil && row == nil {
+		var err error
+		row, err = e.data.take()
+		if err != nil {
+			return nil, requestResult{class: t.class, dataExhausted: true}, false
+		}
+	}
+	return e.prepareScoped(t, w, newRenderScope(e.feed, row, w.random))
+}
+
+// prepareScoped is prepareRequest with the templates rendered in scope.
+func (e *engine) prepareScoped(t target, w *worker, scope *renderScope) (*preparedRequest, requestResult, bool) {
+	scope.vars, scope.vu, scope.iteration = e.vars, w.vu, w.iteration
+	url, err := e.renderURL(t.url, scope)
+	if errors.Is(err, errFeedEmpty) {
+		return nil, requestResult{class: t.class, feedMiss: true}, false
+	}
+	if err != nil {
+		e.cfg.log().Error("Error rendering URL", "url", t.url, "error", err)
+		return nil, requestResult{class: t.class, method: t.method, url: t.url, statusCode: -1}, false
+	}
+	failed := requestResult{class: t.class, method: t.method, url: url, statusCode: -1}
+
+	var requestBody []byte
+	var multipartBody *multipartPayload
+	bodyType := e.bodyType
+	if t.withBody && t.body != nil {
+		bodyType = t.bodyType
+		if t.step {
+			requestBody, err = t.body.render(scope)
+		} else {
+			requestBody, err = e.buildBody(w, t.body, t.bodyType, scope)
+		}
+		if errors.Is(err, errFeedEmpty) {
+			return nil, requestResult{class: t.class, feedMiss: true}, false
+		}
+		if err != nil {
+			e.cfg.log().Error("Error building request body", "url", t.url, "error", err)
+			return nil, failed, false
+		}
+	} else if t.withBody && (len(e.multipartFields) > 0 || len(e.multipartFiles) > 0) {
+		multipartBody, err = newMultipartPayload(e.multipartFields, e.multipartFiles, scope)
+		if errors.Is(err, errFeedEmpty) {
+			return nil, requestResult{class: t.class, feedMiss: true}, false
+		}
+		if err != nil {
+			e.cfg.log().Error("Error building multipart body", "url", t.url, "error", err)
+			return nil, failed, false
+		}
+	} else if t.withBody && len(e.form) > 0 {
+		requestBody, err = renderForm(e.form, scope)
+		if errors.Is(err, errFeedEmpty) {
+			return nil, requestResult{class: t.class, feedMiss: true}, false
+		}
+		if err != nil {
+			e.cfg.log().Error("Error building form body", "url", t.url, "error", err)
+			return nil, failed, false
+		}
+	} else if t.withBody && w.body != nil {
+		requestBody, err = e.buildBody(w, w.body, e.bodyType, scope)
+		if errors.Is(err, errFeedEmpty) {
+			return nil, requestResult{class: t.class, feedMiss: true}, false
+		}
+		if err != nil {
+			e.cfg.log().Error("Error building request body", "url", t.url, "error", err)
+			return nil, failed, false
+		}
+	}
+
+	p := &preparedRequest{target: t, url: url, body: requestBody, plainBody: requestBody, header: make(http.Header), multipart: multipartBody}
+	switch {
+	case e.cfg.ContentType != "" && (multipartBody != nil || len(requestBody) > 0):
+		p.header.Set("Content-Type", e.cfg.ContentType)
+	case multipartBody != nil:
+		p.header.Set("Content-Type", multipartBody.contentType())
+	case t.withBody && len(e.form) > 0:
+		p.header.Set("Content-Type", contentTypeForm)
+	case len(requestBody) > 0:
+		p.header.Set("Content-Type", bodyType)
+	}
+	headers := e.headers
+	if t.step {
+		headers = t.headers
+	} else if len(t.headers) > 0 {
+		headers = append(append([]header{}, e.headers...), t.headers...)
+	}
+	for _, h := range headers {
+		value, err := h.value.render(scope)
+		if errors.Is(err, errFeedEmpty) {
+			return nil, requestResult{class: t.class, feedMiss: true}, false
+		}
+		if err != nil {
+			e.cfg.log().Error("Error rendering header", "header", h.name, "error", err)
+			return nil, failed, false
+		}
+		if strings.EqualFold(h.name, "Host") {
+			p.host = string(value)
+			continue
+		}
+		p.header.Set(h.name, string(value))
+	}
+	if !e.finishPrepared(p, w) {
+		return nil, failed, false
+	}
+	return p, requestResult{}, true
+}
+
+// finishPrepared applies the settings of the run that every request gets, whichever
+// generator produced it: the Host and Accept-Encoding defaults, the client mix, header
+// rotation, the deadline header, the before_request hook and body compression. Headers
+// already set on p take precedence over the defaults. It returns false, after reporting
+// why, when the request cannot be sent.
+func (e *engine) finishPrepared(p *preparedRequest, w *worker) bool {
+	p.jar = w.jar
+	if p.host == "" {
+		p.host = e.cfg.HostHeader
+	}
+	if e.cfg.AcceptEncoding != "" && p.header.Get("Accept-Encoding") == "" {
+		p.header.Set("Accept-Encoding", e.cfg.AcceptEncoding)
+	}
+	if w.mix != nil {
+		p.mix = w.mix
+		if p.header.Get("User-Agent") == "" {
+			p.header.Set("User-Agent", w.mix.profile.userAgent)
+		}
+	}
+	for _, rotation := range e.rotations {
+		if p.rotated == nil {
+			p.rotated = make(map[string]string, len(e.rotations))
+		}
+		value := rotation.pick()
+		p.header.Set(rotation.name, value)
+		p.rotated[rotation.name] = value
+	}
+	if e.cfg.DeadlineHeader != "" {
+		if deadline := e.deadline(p.target); deadline > 0 {
+			p.header.Set(e.cfg.DeadlineHeader, formatDeadline(deadline, e.deadlineFormat()))
+		}
+	}
+	if w.script != nil {
+		if err := w.script.beforeRequest(p); err != nil {
+			e.cfg.log().Error("Error in "+hookBeforeRequest, "error", err)
+			return false
+		}
+	}
+	if len(p.plainBody) > 0 {
+		e.checkBodyType(p.header.Get("Content-Type"), p.plainBody)
+	}
+	if e.cfg.GzipBody && len(p.plainBody) > 0 {
+		body, err := gzipBody(p.plainBody)
+		if err != nil {
+			e.cfg.log().Error("Error compressing request body", "error", err)
+			return false
+		}
+		p.body = body
+		p.header.Set("Content-Encoding", "gzip")
+	}
+	return true
+}
+
+// checkBodyType warns once per run when the first rendered body does not look like its
+// declared Content-Type.
+func (e *engine) checkBodyType(contentType string, body []byte) {
+	e.bodyTypeCheck.Do(func() {
+		if !bodyMatchesType(contentType, body) {
+			e.cfg.log().Warn("The request body does not look like its Content-Type", "content_type", contentType)
+		}
+	})
+}
+
+// send performs a prepared request to url, hedged when --hedge-after is set. The client
+// gives up on it after --client-gives-up-after, hedge included.
+func (e *engine) send(p *preparedRequest, url string) requestResult {
+	ctx := context.Background()
+	if e.run != nil {
+		ctx = e.run.inFlight
+	}
+	ctx, cancel := e.withTimeout(ctx, p.target)
+	defer cancel()
+	if e.cfg.GiveUpAfter > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, e.cfg.GiveUpAfter, errGaveUp)
+		defer cancel()
+	}
+	if e.hedge != nil {
+		return e.hedgedAttempt(ctx, p, url)
+	}
+	return e.attempt(ctx, p, url)
+}
+
+// attempt sends a prepared request to url and reports its status code and whether
+// it was served over a reused connection. The response body is drained so the
+// connection can go back to the pool, and captured into the feed pool when
+// response capturing is enabled. Reading stops at the --max-body cap, in which case
+// the connection is closed instead of being drained. Request and response body sizes
+// are recorded both as sent on the wire and uncompressed.
+func (e *engine) attempt(ctx context.Context, p *preparedRequest, url string) requestResult {
+	t := p.target
+	base := requestResult{
+		class:         t.class,
+		target:        t.name,
+		method:        t.method,
+		url:           url,
+		servedBy:      baseOf(url),
+		bodyBytes:     int64(len(p.plainBody)),
+		bodyBytesWire: int64(len(p.body)),
+		rotated:       p.rotated,
+		expectStatus:  t.options.expectStatus,
+	}
+	client := e.client
+	if p.mix != nil {
+		base.profile = p.mix.profile.name
+		client = p.mix.client
+	}
+	if p.jar != nil {
+		// The client of the run with the jar of the virtual user: a copy shares the
+		// transport, and so the connections, of the original.
+		withJar := *client
+		withJar.Jar = p.jar
+		client = &withJar
+	}
+	dumped := e.verbose.take()
+	trace := &requestTrace{dns: e.dns, keepHeaders: dumped}
+
+	var req *http.Request
+	if p.request != nil && url == p.url {
+		req = p.request.Clone(ctx)
+		req.Body, _ = req.GetBody()
+	} else {
+		var err error
+		req, err = http.NewRequestWithContext(ctx, t.method, url, bytes.NewReader(p.body))
+		if err != nil {
+			e.cfg.log().Error("Error creating request", "error", err)
+			base.statusCode = -1
+			return base
+		}
+		for name, values := range p.header {
+			req.Header[name] = values
+		}
+		if p.host != "" {
+			req.Host = p.host
+		}
+	}
+	if p.multipart != nil {
+		req.Body = p.multipart.open()
+		req.GetBody = func() (io.ReadCloser, error) { return p.multipart.open(), nil }
+		req.ContentLength = p.multipart.length
+		base.bodyBytes, base.bodyBytesWire = p.multipart.length, p.multipart.length
+	}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace.clientTrace()))
+	// Requests sent outside of the run, such as probes, are not captured.
+	base.pairSampled = e.capture != nil && e.run != nil && e.capture.sample()
+	bodyText := truncateBody
+	if base.pairSampled {
+		bodyText = func(body []byte) string { return string(body) }
+	}
+	if e.cfg.RawLogPath != "" || base.pairSampled {
+		base.exchange = &exchangeRecord{
+			Time:           time.Now(),
+			Method:         t.method,
+			URL:            url,
+			RequestHeaders: e.redactor.redactHeaders(req.Header),
+			RequestBody:    bodyText(e.redactor.redactBody(p.plainBody)),
+		}
+	}
+	trace.start = time.Now()
+	resp, err := client.Do(req)
+	if err != nil && e.run != nil && e.run.cancelled() {
+		base.drainCancelled = true
+		base.latency = time.Since(trace.start)
+		return base
+	}
+	if err != nil && errors.Is(context.Cause(ctx), errHedgeLost) {
+		return base
+	}
+	if err != nil && errors.Is(context.Cause(ctx), errGaveUp) {
+		return e.abandon(base, trace)
+	}
+	if err != nil {
+		// With an error log, failures of the load go to the log instead of the console.
+		if e.errorLog == nil || e.run == nil {
+			e.cfg.log().Error("Network error", "method", t.method, "url", url, "error", err)
+		}
+		base.errorText = err.Error()
+		if dumped {
+			e.verbose.print(req, trace.headersSent(), p.plainBody, nil, nil, err)
+		}
+		base.statusCode = -1
+		base.latency = time.Since(trace.start)
+		base.reused, base.remoteAddr, base.timing = trace.finish()
+		base.connectAttempts = trace.connectAttempts()
+		if base.exchange != nil {
+			base.exchange.Status = -1
+			base.exchange.Error = err.Error()
+			base.exchange.LatencyMs = float64(base.latency) / float64(time.Millisecond)
+		}
+		return base
+	}
+	defer resp.Body.Close()
+
+	body := newResponseReader(resp, e.cfg.AcceptEncoding != "")
+	capped := newCappedReader(body, e.cfg.MaxBody)
+	// Probe requests, sent outside of the run, are not checked.
+	sampled := (len(e.cfg.BodyAssertions) > 0 || e.responseSchema != nil) && e.run != nil && e.assertSampler.sample()
+	hooked := e.script != nil && e.script.after
+	kept := hooked || len(t.options.captures) > 0
+	logged := e.errorLog != nil && e.run != nil && failedStatus(resp.StatusCode, expectedStatusesOf(base, e.cfg.ExpectStatus))
+	var captured []byte
+	if (e.cfg.FeedCapture != "" && resp.StatusCode < 300) || base.exchange != nil || sampled || kept || logged || (dumped && e.verbose.bodies) {
+		captured, _ = io.ReadAll(capped)
+	}
+	if dumped {
+		e.verbose.print(req, trace.headersSent(), p.plainBody, resp, captured, nil)
+	}
+	drain(capped)
+	if e.run != nil && e.run.cancelled() && ctx.Err() != nil {
+		base.drainCancelled = true
+		base.latency = time.Since(trace.start)
+		return base
+	}
+	if errors.Is(context.Cause(ctx), errHedgeLost) {
+		return base
+	}
+	if errors.Is(context.Cause(ctx), errGaveUp) {
+		return e.abandon(base, trace)
+	}
+	if e.cfg.FeedCapture != "" && resp.StatusCode < 300 && !capped.truncated {
+		base.captured, _ = e.feed.capture(captured, e.cfg.FeedCapture)
+	}
+	base.bodyTruncated = capped.truncated
+	if sampled && len(e.cfg.BodyAssertions) > 0 {
+		base.checks = checkBody(e.cfg.BodyAssertions, captured)
+	}
+	if sampled && e.responseSchema != nil && resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		base.schemaChecked = true
+		base.schemaProblems = e.responseSchema.validate(captured)
+	}
+	base.responseBytesWire, base.responseBytesDecoded = body.counts()
+	base.headerBytesSent = requestFramingSize(req) + trace.headerBytesSent()
+	base.headerBytesReceived = responseHeaderSize(resp)
+	if kept {
+		base.responseHeader, base.responseBody = resp.Header, captured
+	}
+	if logged {
+		base.errorBody = truncateBody(e.redactor.redactBody(captured))
+	}
+
+	base.statusCode = resp.StatusCode
+	base.latency = time.Since(trace.start)
+	base.contentType = resp.Header.Get("Content-Type")
+	base.contentLength = resp.ContentLength
+	base.reused, base.remoteAddr, base.timing = trace.finish()
+	base.connectAttempts = trace.connectAttempts()
+	if e.connLog != nil {
+		if conn := trace.connection(); conn != nil {
+			e.connLog.used(conn, base.reused)
+		}
+	}
+	if base.exchange != nil {
+		base.exchange.Status = resp.StatusCode
+		base.exchange.LatencyMs = float64(base.latency) / float64(time.Millisecond)
+		base.exchange.ResponseHeaders = e.redactor.redactHeaders(resp.Header)
+		base.exchange.ResponseBody = bodyText(e.redactor.redactBody(captured))
+	}
+	return base
+}
+
+// errGaveUp is the cause of the cancellation of requests the client gave up on.
+var errGaveUp = errors.New("the client gave up waiting")
+
+// abandon completes base for a request the client gave up on after --client-gives-up-after,
+// like a user leaving a page that takes too long to load.
+func (e *engine) abandon(base requestResult, trace *requestTrace) requestResult {
+	base.abandoned = true
+	base.latency = time.Since(trace.start)
+	base.reused, base.remoteAddr, base.timing = trace.finish()
+	base.connectAttempts = trace.connectAttempts()
+	base.errorText = fmt.Sprintf("abandoned by the client after %v", e.cfg.GiveUpAfter)
+	if base.exchange != nil {
+		base.exchange.Error = base.errorText
+		base.exchange.LatencyMs = float64(base.latency) / float64(time.Millisecond)
+	}
+	return base
+}
+
+// renderURL renders a URL template for a single request. Parsed URL templates are
+// cached since every target URL is reused for many requests.
+func (e *engine) renderURL(url string, scope *renderScope) (string, error) {
+	source, err := e.urlSource(url)
+	if err != nil {
+		return "", err
+	}
+	rendered, err := source.render(scope)
+	return string(rendered), err
+}
+
+// urlSource returns the parsed template of a URL.
+func (e *engine) urlSource(url string) (*templateSource, error) {
+	e.urlsMu.Lock()
+	defer e.urlsMu.Unlock()
+	source, ok := e.urls[url]
+	if !ok {
+		var err error
+		source, err = newTemplateSource(url, []byte(url))
+		if err != nil {
+			return nil, err
+		}
+		e.urls[url] = source
+	}
+	return source, nil
+}
+
+// buildBody renders the body of a single request and injects the worker's random field values,
+// or fresh random values for every request when re-randomization is enabled. Random fields
+// only apply to JSON bodies; raw bodies are sent unchanged.
+func (e *engine) buildBody(w *worker, source *templateSource, bodyType string, scope *renderScope) ([]byte, error) {
+	body, err := source.render(scope)
+	if err != nil {
+		return nil, err
+	}
+	if !e.randomizesBody(bodyType, body) {
+		return body, nil
+	}
+	values := w.randomValues
+	if e.cfg.Rerandomize {
+		values = make(map[string]interface{}, len(values))
+	}
+	return modifyJSONBody(body, e.randFields, values, w.random)
+}
+
+// randomizesBody reports whether the random fields are set in body, a JSON body that is
+// not sent raw.
+func (e *engine) randomizesBody(bodyType string, body []byte) bool {
+	return len(e.randFields) > 0 && !e.cfg.RawBody && isJSONType(bodyType) && len(body) > 0
+}
+
+// modifyJSONBody modifies the JSON body by setting every field matched by the rules to a
+// random value. Values are looked up in values by concrete path and generated on first
+// use, so the same location keeps its value for as long as values is reused.
+func modifyJSONBody(body []byte, fields []randField, values map[string]interface{}, random *randomStream) ([]byte, error) {
+	var jsonObj interface{}
+	err := json.Unmarshal(body, &jsonObj)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, field := range fields {
+		field := field
+		jsonObj = setAtPath(jsonObj, field.path, "", func(at string) interface{} {
+			key := field.spec + "@" + at
+			value, ok := values[key]
+			if !ok {
+				value = generateRandomID(random, field.idType, field.length)
+				values[key] = value
+			}
+			return value
+		})
+	}
+
+	modifiedBody, err := json.Marshal(jsonObj)
+	if err != nil {
+		return nil, err
+	}
+
+	return modifiedBody, nil
+}
+
+// generateRandomID generates a random ID based on the specified type and length.
+// Supported types are "number", "string", "uuid", "uuidv7", "ulid" and "seq"; the length
+// only applies to numbers and strings. Random values are drawn from r.
+func generateRandomID(r *randomStream, idType string, length int) interface{} {
+	switch idType {
+	case "number":
+		id := r.Intn(int(math.Pow10(length)))
+		return id
+	case "string":
+		const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+		id := make([]byte, length)
+		for i := range id {
+			id[i] = charset[r.Intn(len(charset))]
+		}
+		return string(id)
+	case "uuid":
+		return newUUID(r.ids)
+	case "uuidv7":
+		return newUUIDv7(r.ids)
+	case "ulid":
+		return newULID(r.ids)
+	case "seq":
+		return r.seq.next()
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,78 @@
+package loadtest
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// ResolveOverrides maps host:port addresses to the IP addresses connections to them are
+// made to instead of the resolved ones, like the --resolve option of curl. Only the
+// connection is redirected: requests keep their Host header and TLS server name.
+type ResolveOverrides map[string][]string
+
+// ParseResolve parses --resolve entries of the form host:port:addr, where addr is an IP
+// address or a comma-separated list of them, tried in order. IPv6 addresses may be
+// enclosed in brackets.
+func ParseResolve(entries []string) (ResolveOverrides, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	overrides := make(ResolveOverrides, len(entries))
+	for _, entry := range entries {
+		host, rest, ok := cutHost(strings.TrimSpace(entry))
+		port, addrs, ok2 := strings.Cut(rest, ":")
+		if !ok || !ok2 || host == "" {
+			return nil, fmt.Errorf("%q is not of the form host:port:addr", entry)
+		}
+		if n, err := strconv.Atoi(port); err != nil || n <= 0 || n > 65535 {
+			return nil, fmt.Errorf("invalid port %q in %q", port, entry)
+		}
+		key := net.JoinHostPort(strings.ToLower(host), port)
+		for _, addr := range strings.Split(addrs, ",") {
+			addr = strings.Trim(strings.TrimSpace(addr), "[]")
+			if net.ParseIP(addr) == nil {
+				return nil, fmt.Errorf("%q in %q is not an IP address", addr, entry)
+			}
+			overrides[key] = append(overrides[key], addr)
+		}
+	}
+	return overrides, nil
+}
+
+// cutHost cuts s around the colon after its host, which may be an IPv6 address in
+// brackets.
+func cutHost(s string) (host, rest string, ok bool) {
+	if strings.HasPrefix(s, "[") {
+		end := strings.Index(s, "]:")
+		if end < 0 {
+			return "", "", false
+		}
+		return s[1:end], s[end+2:], true
+	}
+	return strings.Cut(s, ":")
+}
+
+// wrapDial redirects the connections to the overridden addresses, trying their IP
+// addresses in order, and dials any other address as is.
+func (o ResolveOverrides) wrapDial(dial func(ctx context.Context, network, address string) (net.Conn, error)) func(ctx context.Context, network, address string) (net.Conn, error) {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return dial(ctx, network, address)
+		}
+		addrs, ok := o[net.JoinHostPort(strings.ToLower(host), port)]
+		if !ok {
+			return dial(ctx, network, address)
+		}
+		for _, addr := range addrs {
+			var conn net.Conn
+			if conn, err = dial(ctx, network, net.JoinHostPort(addr, port)); err == nil {
+				return conn, nil
+			}
+		}
+		return nil, err
+	}
+}
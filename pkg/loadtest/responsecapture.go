@@ -0,0 +1,142 @@
+package loadtest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ResponseCapture selects the request/response pairs saved by --capture-responses: a
+// number of them spread uniformly over the run, or a percentage of all requests.
+type ResponseCapture struct {
+	count int
+	rate  float64
+}
+
+// ParseResponseCapture parses the --capture-responses value: a number of pairs such as
+// 50, or a percentage of the requests such as 5%.
+func ParseResponseCapture(value string) (*ResponseCapture, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	if strings.HasSuffix(value, "%") {
+		rate, err := ParsePercentage(value)
+		if err != nil {
+			return nil, err
+		}
+		return &ResponseCapture{rate: rate}, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return nil, fmt.Errorf("%q is neither a positive number of responses nor a percentage such as 5%%", value)
+	}
+	return &ResponseCapture{count: n}, nil
+}
+
+// responseCapture saves the sampled pairs of a run to a directory, one JSON file each,
+// with their bodies up to --max-body. With a percentage, pairs are written as their
+// requests complete; with a number, a reservoir keeps a uniform sample of the requests
+// sent so far, and it is written once the run is over.
+type responseCapture struct {
+	dir     string
+	opts    OutputOptions
+	count   int
+	sampler *bodySampler
+
+	mu     sync.Mutex
+	seen   int64
+	random *randomStream
+	logger *slog.Logger
+
+	kept    []*exchangeRecord
+	written int
+	err     error
+}
+
+// newResponseCapture creates the directory the pairs selected by cfg are saved to. The
+// pairs saved are reported through logger.
+func newResponseCapture(cfg *ResponseCapture, dir string, opts OutputOptions, random *randomStream, logger *slog.Logger) (*responseCapture, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	c := &responseCapture{dir: dir, opts: opts, count: cfg.count, random: random, logger: logger}
+	if cfg.rate > 0 {
+		c.sampler = &bodySampler{rate: cfg.rate}
+	}
+	return c, nil
+}
+
+// sample reports whether the next request is captured. It is called by the workers
+// before sending, so that only the captured responses are read in full.
+func (c *responseCapture) sample() bool {
+	if c.sampler != nil {
+		return c.sampler.sample()
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seen++
+	return c.seen <= int64(c.count) || c.random.Int63n(c.seen) < int64(c.count)
+}
+
+// add records the pair of a captured request. It is called by the results loop only.
+func (c *responseCapture) add(rec *exchangeRecord) {
+	if c.sampler != nil {
+		c.write(rec)
+		return
+	}
+	if len(c.kept) < c.count {
+		c.kept = append(c.kept, rec)
+		return
+	}
+	c.mu.Lock()
+	i := c.random.Intn(c.count)
+	c.mu.Unlock()
+	c.kept[i] = rec
+}
+
+// write saves rec to the next file of the directory, named after its number, method and
+// status. Pairs are no longer written after an error.
+func (c *responseCapture) write(rec *exchangeRecord) {
+	if c.err != nil {
+		return
+	}
+	c.written++
+	status := strconv.Itoa(rec.Status)
+	if rec.Status <= 0 {
+		status = "error"
+	}
+	path := filepath.Join(c.dir, fmt.Sprintf("%06d-%s-%s.json", c.written, rec.Method, status))
+	content, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		c.err = err
+		return
+	}
+	out, err := createOutput(path, c.opts)
+	if err != nil {
+		c.err = err
+		return
+	}
+	_, err = out.Write(append(content, '\n'))
+	c.err = errors.Join(err, out.Close())
+}
+
+// close writes the pairs of the reservoir, in the order they were sent, and reports how
+// many pairs were saved.
+func (c *responseCapture) close() error {
+	sort.Slice(c.kept, func(i, j int) bool { return c.kept[i].Time.Before(c.kept[j].Time) })
+	for _, rec := range c.kept {
+		c.write(rec)
+	}
+	if c.err == nil {
+		c.logger.Info("Saved request/response pairs", "count", c.written, "dir", c.dir)
+	}
+	return c.err
+}
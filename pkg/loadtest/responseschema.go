@@ -0,0 +1,120 @@
+package loadtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/fatih/color"
+)
+
+// responseSchema checks response bodies against a JSON Schema. It supports the same
+// keywords as the OpenAPI validation, with $ref pointing to the document root or to its
+// $defs or definitions.
+type responseSchema struct {
+	path string
+	root *openAPISchema
+
+	// mu guards the validator, whose compiled patterns are cached as they are met.
+	mu        sync.Mutex
+	validator *openAPIValidator
+}
+
+// loadResponseSchema reads the JSON Schema document at path.
+func loadResponseSchema(path string) (*responseSchema, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc struct {
+		openAPISchema
+		Defs        map[string]*openAPISchema `json:"$defs"`
+		Definitions map[string]*openAPISchema `json:"definitions"`
+	}
+	if err := json.Unmarshal(content, &doc); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	root := &doc.openAPISchema
+	definitions := map[string]*openAPISchema{"#": root}
+	for name, s := range doc.Definitions {
+		definitions["#/definitions/"+name] = s
+	}
+	for name, s := range doc.Defs {
+		definitions["#/$defs/"+name] = s
+	}
+	return &responseSchema{
+		path: path,
+		root: root,
+		validator: &openAPIValidator{
+			spec:        &openAPISpec{},
+			patterns:    make(map[string]*regexp.Regexp),
+			definitions: definitions,
+		},
+	}, nil
+}
+
+// validate returns the problems found in a response body, each one prefixed by the
+// location it applies to.
+func (r *responseSchema) validate(body []byte) []string {
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return []string{"body: not valid JSON"}
+	}
+	var problems []string
+	r.mu.Lock()
+	r.validator.validateValue(r.root, value, "body", &problems)
+	r.mu.Unlock()
+	return problems
+}
+
+// arrayIndex matches the array indexes in the location of a problem.
+var arrayIndex = regexp.MustCompile(`\[\d+\]`)
+
+// uniqueSchemaProblems returns the distinct problems of a response, with array indexes
+// folded into [*] so the same problem on every item of a list is counted once.
+func uniqueSchemaProblems(problems []string) []string {
+	var unique []string
+	seen := make(map[string]bool)
+	for _, problem := range problems {
+		problem = arrayIndex.ReplaceAllString(problem, "[*]")
+		if !seen[problem] {
+			seen[problem] = true
+			unique = append(unique, problem)
+		}
+	}
+	return unique
+}
+
+// schemaProblemsShown is the number of most frequent problems listed in the report.
+const schemaProblemsShown = 10
+
+// generateSchemaReport prints how many sampled responses matched the response schema
+// and the most frequent problems found in the others.
+func generateSchemaReport(path string, st *stats) {
+	color.Green("\n===== 📐 Response Schema =====")
+	if st.schemaFailed == 0 {
+		color.Cyan("✅ %d of %d sampled responses match %s", st.schemaChecked, st.schemaChecked, path)
+		return
+	}
+	color.Red("❌ %d of %d sampled responses do not match %s:", st.schemaFailed, st.schemaChecked, path)
+	problems := make([]string, 0, len(st.schemaProblems))
+	for problem := range st.schemaProblems {
+		problems = append(problems, problem)
+	}
+	sort.Slice(problems, func(i, j int) bool {
+		if st.schemaProblems[problems[i]] != st.schemaProblems[problems[j]] {
+			return st.schemaProblems[problems[i]] > st.schemaProblems[problems[j]]
+		}
+		return problems[i] < problems[j]
+	})
+	for i, problem := range problems {
+		if i == schemaProblemsShown {
+			color.Red("  ... and %d more", len(problems)-schemaProblemsShown)
+			break
+		}
+		color.Red("  - %s (%d responses)", problem, st.schemaProblems[problem])
+	}
+}
@@ -0,0 +1,419 @@
+package loadtest
+
+import (
+	"bufio"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// resultsVersion is the format version of results files; files of another version are
+// rejected instead of being misread.
+const resultsVersion = 1
+
+// A results file, written with --out, is a gob stream of a resultsHeader followed by one
+// resultsRecord per measured request and a final record holding the resultsFooter. It
+// keeps everything the reports are computed from, so the report subcommand renders them
+// again without rerunning the test.
+
+// resultsHeader opens a results file.
+type resultsHeader struct {
+	Version  int
+	Manifest Manifest
+}
+
+// resultsRecord is a record of a results file: a sample, or the footer closing the file.
+type resultsRecord struct {
+	Sample *sample
+	Footer *resultsFooter
+}
+
+// resultsFooter closes a results file with what is only known once the run is over.
+type resultsFooter struct {
+	StartedAt           time.Time
+	MeasuredFrom        time.Time
+	Duration            time.Duration
+	Warmup              int
+	FeedCaptured        int
+	Interrupted         bool
+	Aborted             string
+	Region              string
+	Settings            reportSettings
+	MaxDurationExceeded bool
+}
+
+// reportSettings are the settings of a run its text report depends on.
+type reportSettings struct {
+	GiveUpAfter  time.Duration
+	Hedge        string
+	ExpectStatus []int
+	// TargetExpectStatus holds the expected statuses of the targets overriding them.
+	TargetExpectStatus map[string][]int
+	BodyAssertions     []string
+	ResponseSchema     string
+	Script             string
+	Rotations          []reportRotation
+	Compression        bool
+	Failover           bool
+	SlowThreshold      time.Duration
+	SlowTop            int
+	Latencies          histogramRange
+
+	ReadAfterWrite      string
+	ReadAfterWriteDelay time.Duration
+
+	Stages []reportStage
+	// MaxQueue is the --max-queue of an open-model run, which dropped arrivals overflowed.
+	MaxQueue int
+}
+
+// reportRotation is a rotated header and its values, in rotation order.
+type reportRotation struct {
+	Header string
+	Values []string
+}
+
+// newReportSettings returns the report settings of a run of e with cfg.
+func newReportSettings(cfg Config, e *engine) reportSettings {
+	reporting := reportSettings{
+		GiveUpAfter:    cfg.GiveUpAfter,
+		ExpectStatus:   cfg.ExpectStatus,
+		ResponseSchema: cfg.ResponseSchemaPath,
+		Script:         cfg.ScriptPath,
+		Compression:    cfg.AcceptEncoding != "" || cfg.GzipBody,
+		Failover:       len(cfg.FailoverURLs) > 0,
+		SlowThreshold:  cfg.SlowThreshold,
+		SlowTop:        cfg.SlowTop,
+		Latencies:      histogramRange{Highest: cfg.MaxLatency, Digits: cfg.LatencyPrecision},
+		MaxQueue:       cfg.MaxQueue,
+
+		ReadAfterWrite:      cfg.ReadAfterWrite,
+		ReadAfterWriteDelay: cfg.ReadAfterWriteDelay,
+	}
+	if e.hedge != nil {
+		reporting.Hedge = e.hedge.describe()
+	}
+	if cfg.Stages != nil {
+		reporting.Stages = cfg.Stages.reportStages()
+	}
+	for _, t := range cfg.Targets {
+		if len(t.options.expectStatus) > 0 {
+			if reporting.TargetExpectStatus == nil {
+				reporting.TargetExpectStatus = make(map[string][]int)
+			}
+			reporting.TargetExpectStatus[t.name] = t.options.expectStatus
+		}
+	}
+	for _, a := range cfg.BodyAssertions {
+		reporting.BodyAssertions = append(reporting.BodyAssertions, a.name)
+	}
+	for _, rotation := range e.rotations {
+		reporting.Rotations = append(reporting.Rotations, reportRotation{Header: rotation.name, Values: rotation.values})
+	}
+	return reporting
+}
+
+// sample is the outcome of a measured request as saved in a results file: the fields
+// of its result the reports use.
+type sample struct {
+	Class          string
+	Target         string
+	Profile        string
+	Status         int
+	Latency        time.Duration
+	Completed      time.Time
+	Reused         bool
+	FeedMiss       bool
+	ArrivalDropped bool
+	Window         string
+	DataExhausted  bool
+	BodyTruncated  bool
+
+	Drained        bool
+	DrainCancelled bool
+	Abandoned      bool
+	Hedged         bool
+	HedgeWon       bool
+
+	UnexpectedStatus bool
+	Checks           []bool
+	SchemaChecked    bool
+	SchemaProblems   []string
+	ScriptChecked    bool
+	ScriptFailure    string
+
+	Method        string
+	URL           string
+	RemoteAddr    string
+	ContentType   string
+	ContentLength int64
+	DNS           time.Duration
+	Connect       time.Duration
+	TLS           time.Duration
+	TTFB          time.Duration
+	Transfer      time.Duration
+
+	ConnectAttempts map[string]int
+	Rotated         map[string]string
+
+	ServedBy      string
+	Failovers     int
+	FailoverDelay time.Duration
+	Retries       int
+	RetryDelay    time.Duration
+
+	ReadBackStatus  int
+	ReadBackLatency time.Duration
+
+	BodyBytes            int64
+	BodyBytesWire        int64
+	ResponseBytesWire    int64
+	ResponseBytesDecoded int64
+	HeaderBytesSent      int64
+	HeaderBytesReceived  int64
+}
+
+// newSample returns the sample of res.
+func newSample(res requestResult) sample {
+	s := sample{
+		Class:                res.class,
+		Target:               res.target,
+		Profile:              res.profile,
+		Status:               res.statusCode,
+		Latency:              res.latency,
+		Completed:            res.completed,
+		Reused:               res.reused,
+		FeedMiss:             res.feedMiss,
+		ArrivalDropped:       res.arrivalDropped,
+		Window:               res.window,
+		DataExhausted:        res.dataExhausted,
+		BodyTruncated:        res.bodyTruncated,
+		Drained:              res.drained,
+		DrainCancelled:       res.drainCancelled,
+		Abandoned:            res.abandoned,
+		Hedged:               res.hedged,
+		HedgeWon:             res.hedgeWon,
+		UnexpectedStatus:     res.unexpectedStatus,
+		Checks:               res.checks,
+		SchemaChecked:        res.schemaChecked,
+		SchemaProblems:       res.schemaProblems,
+		ScriptChecked:        res.scriptChecked,
+		ScriptFailure:        res.scriptFailure,
+		Method:               res.method,
+		URL:                  res.url,
+		RemoteAddr:           res.remoteAddr,
+		ContentType:          res.contentType,
+		ContentLength:        res.contentLength,
+		DNS:                  res.timing.dns,
+		Connect:              res.timing.connect,
+		TLS:                  res.timing.tls,
+		TTFB:                 res.timing.ttfb,
+		Transfer:             res.timing.transfer,
+		ConnectAttempts:      res.connectAttempts,
+		Rotated:              res.rotated,
+		ServedBy:             res.servedBy,
+		Failovers:            res.failovers,
+		FailoverDelay:        res.failoverDelay,
+		Retries:              res.retries,
+		RetryDelay:           res.retryDelay,
+		BodyBytes:            res.bodyBytes,
+		BodyBytesWire:        res.bodyBytesWire,
+		ResponseBytesWire:    res.responseBytesWire,
+		ResponseBytesDecoded: res.responseBytesDecoded,
+		HeaderBytesSent:      res.headerBytesSent,
+		HeaderBytesReceived:  res.headerBytesReceived,
+	}
+	if res.readBack != nil {
+		s.ReadBackStatus, s.ReadBackLatency = res.readBack.status, res.readBack.latency
+	}
+	return s
+}
+
+// result returns the result the sample was saved from.
+func (s sample) result() requestResult {
+	res := requestResult{
+		class:                s.Class,
+		target:               s.Target,
+		profile:              s.Profile,
+		statusCode:           s.Status,
+		latency:              s.Latency,
+		completed:            s.Completed,
+		reused:               s.Reused,
+		feedMiss:             s.FeedMiss,
+		arrivalDropped:       s.ArrivalDropped,
+		window:               s.Window,
+		dataExhausted:        s.DataExhausted,
+		bodyTruncated:        s.BodyTruncated,
+		drained:              s.Drained,
+		drainCancelled:       s.DrainCancelled,
+		abandoned:            s.Abandoned,
+		hedged:               s.Hedged,
+		hedgeWon:             s.HedgeWon,
+		unexpectedStatus:     s.UnexpectedStatus,
+		checks:               s.Checks,
+		schemaChecked:        s.SchemaChecked,
+		schemaProblems:       s.SchemaProblems,
+		scriptChecked:        s.ScriptChecked,
+		scriptFailure:        s.ScriptFailure,
+		method:               s.Method,
+		url:                  s.URL,
+		remoteAddr:           s.RemoteAddr,
+		contentType:          s.ContentType,
+		contentLength:        s.ContentLength,
+		timing:               requestTiming{dns: s.DNS, connect: s.Connect, tls: s.TLS, ttfb: s.TTFB, transfer: s.Transfer},
+		connectAttempts:      s.ConnectAttempts,
+		rotated:              s.Rotated,
+		servedBy:             s.ServedBy,
+		failovers:            s.Failovers,
+		failoverDelay:        s.FailoverDelay,
+		retries:              s.Retries,
+		retryDelay:           s.RetryDelay,
+		bodyBytes:            s.BodyBytes,
+		bodyBytesWire:        s.BodyBytesWire,
+		responseBytesWire:    s.ResponseBytesWire,
+		responseBytesDecoded: s.ResponseBytesDecoded,
+		headerBytesSent:      s.HeaderBytesSent,
+		headerBytesReceived:  s.HeaderBytesReceived,
+	}
+	if s.ReadBackStatus != 0 {
+		res.readBack = &readBack{status: s.ReadBackStatus, latency: s.ReadBackLatency}
+	}
+	return res
+}
+
+// resultsWriter writes a results file. It is only used by the collector goroutine.
+type resultsWriter struct {
+	out io.WriteCloser
+	buf *bufio.Writer
+	enc *gob.Encoder
+}
+
+// newResultsWriter creates the results file at path, encrypted when recipients are
+// configured, and writes its header.
+func newResultsWriter(path string, opts OutputOptions, m Manifest) (*resultsWriter, error) {
+	out, err := createOutput(path, opts)
+	if err != nil {
+		return nil, err
+	}
+	buf := bufio.NewWriter(out)
+	w := &resultsWriter{out: out, buf: buf, enc: gob.NewEncoder(buf)}
+	if err := w.enc.Encode(resultsHeader{Version: resultsVersion, Manifest: m}); err != nil {
+		out.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// write appends the sample of res.
+func (w *resultsWriter) write(res requestResult) error {
+	s := newSample(res)
+	return w.enc.Encode(resultsRecord{Sample: &s})
+}
+
+// close writes the footer and closes the file.
+func (w *resultsWriter) close(footer resultsFooter) error {
+	if err := w.enc.Encode(resultsRecord{Footer: &footer}); err != nil {
+		w.out.Close()
+		return err
+	}
+	if err := w.buf.Flush(); err != nil {
+		w.out.Close()
+		return err
+	}
+	return w.out.Close()
+}
+
+// readResults reads the results file at path, passing every sample to add in the order
+// they were recorded.
+func readResults(path string, add func(requestResult)) (resultsHeader, resultsFooter, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return resultsHeader{}, resultsFooter{}, err
+	}
+	defer f.Close()
+	dec := gob.NewDecoder(bufio.NewReader(f))
+	var header resultsHeader
+	if err := dec.Decode(&header); err != nil {
+		return resultsHeader{}, resultsFooter{}, fmt.Errorf("%s is not a results file written with --out (decrypt encrypted files first): %v", path, err)
+	}
+	if header.Version != resultsVersion {
+		return resultsHeader{}, resultsFooter{}, fmt.Errorf("%s has format version %d, expected %d", path, header.Version, resultsVersion)
+	}
+	for {
+		var record resultsRecord
+		if err := dec.Decode(&record); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				err = errors.New("the file ends before the end of the run, was it interrupted?")
+			}
+			return resultsHeader{}, resultsFooter{}, fmt.Errorf("reading %s: %v", path, err)
+		}
+		if record.Footer != nil {
+			return header, *record.Footer, nil
+		}
+		if record.Sample != nil {
+			add(record.Sample.result())
+		}
+	}
+}
+
+// ReplayOptions selects the reports Replay renders.
+type ReplayOptions struct {
+	ReportJSONPath string
+	ReportHTMLPath string
+	SummaryOnly    bool
+	SigningKey     *SigningKey
+}
+
+// Replay renders the reports of a run saved with Config.ResultsPath: the text report
+// unless SummaryOnly, and the JSON and HTML reports when their paths are set. A report
+// that cannot be written does not keep the others from being rendered; the errors are
+// returned together with the summary of the run.
+func Replay(path string, opts ReplayOptions) (Result, error) {
+	// The slow request settings are in the footer, so results are tallied once read.
+	var results []requestResult
+	header, footer, err := readResults(path, func(res requestResult) { results = append(results, res) })
+	if err != nil {
+		return Result{}, fmt.Errorf("error reading results: %w", err)
+	}
+	return replay(path, header, footer, results, opts)
+}
+
+// replay renders the reports of the results read from the results files at source.
+func replay(source string, header resultsHeader, footer resultsFooter, results []requestResult, opts ReplayOptions) (Result, error) {
+	latencies := footer.Settings.Latencies
+	if latencies.Digits <= 0 {
+		latencies = defaultLatencyRange
+	}
+	measured := newTally(latencies, footer.Settings.SlowThreshold, footer.Settings.SlowTop)
+	measured.timeline.start = footer.MeasuredFrom
+	for _, res := range results {
+		measured.add(res)
+	}
+	measured.all.warmupCount = footer.Warmup
+
+	summary := newReportSummary(footer.StartedAt, footer.Duration, measured.all)
+	summary.Interrupted = footer.Interrupted
+	summary.Aborted = footer.Aborted
+	summary.MaxDurationExceeded = footer.MaxDurationExceeded
+	var errs []error
+	if opts.ReportJSONPath != "" {
+		if err := writeJSONReport(opts.ReportJSONPath, OutputOptions{SigningKey: opts.SigningKey}, Report{Manifest: header.Manifest, Summary: summary, Timeline: measured.timeline.points(footer.Duration)}); err != nil {
+			errs = append(errs, fmt.Errorf("error writing JSON report: %w", err))
+		}
+	}
+	if opts.ReportHTMLPath != "" {
+		if err := writeHTMLReport(opts.ReportHTMLPath, OutputOptions{}, summary, measured); err != nil {
+			errs = append(errs, fmt.Errorf("error writing HTML report: %w", err))
+		}
+	}
+	if !opts.SummaryOnly {
+		color.Cyan("📂 Report of the run of %s saved in %s", footer.StartedAt.Local().Format(time.RFC1123), source)
+		printReports(footer.Settings, footer.Duration, measured, footer.FeedCaptured, footer.Aborted)
+	}
+	return summary, errors.Join(errs...)
+}
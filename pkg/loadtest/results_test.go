@@ -0,0 +1,57 @@
+package loadtest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestReplayMatchesRun(t *testing.T) {
+	var served atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if served.Add(1)%3 == 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "results.bin")
+	summary, err := New(
+		WithConfig(Config{SummaryOnly: true, ResultsPath: path}),
+		WithURL(srv.URL),
+		WithConcurrency(3),
+		WithRequests(30),
+	).Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed, err := Replay(path, ReplayOptions{SummaryOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(replayed, summary) {
+		t.Errorf("Replay() = %+v\nwant the summary of the run %+v", replayed, summary)
+	}
+	if replayed.Requests != 30 || replayed.StatusCodes[500] != 10 {
+		t.Errorf("Replay() has %d requests and %d 500s, want 30 and 10", replayed.Requests, replayed.StatusCodes[500])
+	}
+}
+
+func TestReplayErrors(t *testing.T) {
+	dir := t.TempDir()
+	notResults := filepath.Join(dir, "report.json")
+	if err := os.WriteFile(notResults, []byte(`{"summary": {}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{filepath.Join(dir, "missing.bin"), notResults} {
+		if _, err := Replay(path, ReplayOptions{SummaryOnly: true}); err == nil || !strings.Contains(err.Error(), "error reading results") {
+			t.Errorf("Replay(%s) error = %v, want an error reading results", path, err)
+		}
+	}
+}
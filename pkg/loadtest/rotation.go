@@ -0,0 +1,91 @@
+package loadtest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/fatih/color"
+)
+
+// DefaultAcceptRotation is the set of Accept values used by --rotate-accept=default: the
+// common representations plus a type no server should support.
+var DefaultAcceptRotation = []string{
+	"application/json",
+	"application/xml",
+	"text/html",
+	"application/x-unsupported",
+}
+
+// headerRotation assigns the values of a header to requests in round-robin order, so
+// every value receives the same share of the load.
+type headerRotation struct {
+	name   string
+	values []string
+	next   atomic.Uint64
+}
+
+// newHeaderRotation returns a rotation over values for the header name.
+func newHeaderRotation(name string, values []string) *headerRotation {
+	return &headerRotation{name: name, values: values}
+}
+
+// pick returns the value for the next request.
+func (r *headerRotation) pick() string {
+	i := r.next.Add(1) - 1
+	return r.values[i%uint64(len(r.values))]
+}
+
+// rotationStats aggregates results per header and rotated value.
+type rotationStats map[string]map[string]*stats
+
+// add records res under every rotated value it was sent with, with latencies in the range r.
+func (rs rotationStats) add(r histogramRange, res requestResult) {
+	for name, value := range res.rotated {
+		if rs[name] == nil {
+			rs[name] = make(map[string]*stats)
+		}
+		if rs[name][value] == nil {
+			rs[name][value] = newStats(r)
+		}
+		rs[name][value].add(res)
+	}
+}
+
+// generateRotationReport prints the status codes and latency per rotated value of every
+// rotated header.
+func generateRotationReport(rotations []*headerRotation, rs rotationStats) {
+	for _, rotation := range rotations {
+		byValue := rs[rotation.name]
+		if len(byValue) == 0 {
+			continue
+		}
+		color.Green("\n===== 🔄 %s Rotation =====", rotation.name)
+		for _, value := range rotation.values {
+			st := byValue[value]
+			if st == nil {
+				continue
+			}
+			fmt.Printf("🔹 %s: %d requests, %d network errors, avg latency %v, statuses %s\n",
+				value, st.sentCount(), st.networkErrorCount, st.averageLatency(), formatStatusCodes(st.statusCodeCount))
+		}
+	}
+}
+
+// formatStatusCodes renders a status code distribution in ascending code order.
+func formatStatusCodes(counts map[int]int) string {
+	if len(counts) == 0 {
+		return "none"
+	}
+	codes := make([]int, 0, len(counts))
+	for code := range counts {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	parts := make([]string, 0, len(codes))
+	for _, code := range codes {
+		parts = append(parts, fmt.Sprintf("%d×%d", code, counts[code]))
+	}
+	return strings.Join(parts, ", ")
+}
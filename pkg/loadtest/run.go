@@ -0,0 +1,131 @@
+package loadtest
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// runControl decides when a run ends. Once stop is done (the --duration elapsed, the run
+// was interrupted or aborted) workers stop issuing requests, and requests still in flight get
+// up to the drain timeout to complete before inFlight is cancelled.
+type runControl struct {
+	stop     context.Context
+	inFlight context.Context
+
+	cancelStop     context.CancelFunc
+	cancelCause    context.CancelCauseFunc
+	cancelInFlight context.CancelFunc
+	done           chan struct{}
+}
+
+// newRunControl starts watching for the end of the run. A zero duration lets the run
+// end only when every request was sent or when ctx is done, which is how the caller
+// interrupts it. In-flight requests get the drain timeout, but never outlive the
+// deadline of ctx. The drain is announced through logger.
+func newRunControl(ctx context.Context, duration, drainTimeout time.Duration, logger *slog.Logger) *runControl {
+	stop, cancelCause := context.WithCancelCause(ctx)
+	cancelStop := func() { cancelCause(nil) }
+	if duration > 0 {
+		var cancelTimeout context.CancelFunc
+		stop, cancelTimeout = context.WithTimeout(stop, duration)
+		cancelStop = func() {
+			cancelTimeout()
+			cancelCause(nil)
+		}
+	}
+	inFlight, cancelInFlight := context.WithCancel(context.Background())
+	if deadline, ok := ctx.Deadline(); ok {
+		inFlight, cancelInFlight = context.WithDeadline(context.Background(), deadline)
+	}
+	r := &runControl{
+		stop:           stop,
+		inFlight:       inFlight,
+		cancelStop:     cancelStop,
+		cancelCause:    cancelCause,
+		cancelInFlight: cancelInFlight,
+		done:           make(chan struct{}),
+	}
+
+	go func() {
+		select {
+		case <-stop.Done():
+		case <-r.done:
+			return
+		}
+		select {
+		case <-r.done:
+			return
+		default:
+		}
+		if r.interrupted() {
+			logger.Warn("Interrupted, waiting for in-flight requests", "timeout", drainTimeout)
+		}
+		select {
+		case <-time.After(drainTimeout):
+			cancelInFlight()
+		case <-r.done:
+		}
+	}()
+	return r
+}
+
+// stopped reports whether workers must stop issuing new requests.
+func (r *runControl) stopped() bool {
+	return r.stop.Err() != nil
+}
+
+// cancelled reports whether in-flight requests were cancelled at the drain timeout.
+func (r *runControl) cancelled() bool {
+	return r.inFlight.Err() != nil
+}
+
+// finish releases the run control once every worker has returned.
+func (r *runControl) finish() {
+	close(r.done)
+	r.cancelStop()
+	r.cancelInFlight()
+}
+
+// interrupted reports whether the run was stopped by the cancellation of its context
+// rather than by the --duration deadline or an abort.
+func (r *runControl) interrupted() bool {
+	return errors.Is(context.Cause(r.stop), context.Canceled)
+}
+
+// abort stops the run early because of err.
+func (r *runControl) abort(err error) {
+	r.cancelCause(err)
+}
+
+// aborted returns the reason the run was aborted, or nil.
+func (r *runControl) aborted() error {
+	cause := context.Cause(r.stop)
+	if cause == nil || errors.Is(cause, context.Canceled) || errors.Is(cause, context.DeadlineExceeded) {
+		return nil
+	}
+	return cause
+}
+
+// pause waits for d, returning early when the run stops.
+func (r *runControl) pause(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-r.stop.Done():
+	}
+}
+
+// thinkTime returns the pause of a virtual user between two requests: think, moved by a
+// uniformly distributed deviation of at most jitter and never below zero.
+func thinkTime(r *randomStream, think, jitter time.Duration) time.Duration {
+	if jitter > 0 {
+		think += time.Duration(r.Int63n(2*int64(jitter)+1)) - jitter
+	}
+	return max(think, 0)
+}
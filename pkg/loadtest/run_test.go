@@ -0,0 +1,155 @@
+package loadtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunContextCancelDrainsInFlight(t *testing.T) {
+	var started atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started.Add(1)
+		time.Sleep(100 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(150*time.Millisecond, cancel)
+	summary, err := New(
+		WithConfig(Config{SummaryOnly: true, DrainTimeout: 5 * time.Second}),
+		WithURL(srv.URL),
+		WithConcurrency(4),
+		WithRequests(1000),
+	).Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !summary.Interrupted {
+		t.Error("Interrupted = false, want true")
+	}
+	if summary.NetworkErrors != 0 {
+		t.Errorf("NetworkErrors = %d, want 0: in-flight requests must complete", summary.NetworkErrors)
+	}
+	if got := int64(summary.Requests); got != started.Load() || summary.Successful != summary.Requests {
+		t.Errorf("Requests = %d, Successful = %d, want the %d requests the server received", got, summary.Successful, started.Load())
+	}
+	if summary.Requests >= 1000 {
+		t.Errorf("Requests = %d, want the run stopped early", summary.Requests)
+	}
+}
+
+func TestRunContextCancelsAtDrainTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	summary, err := New(
+		WithConfig(Config{SummaryOnly: true, DrainTimeout: 100 * time.Millisecond}),
+		WithURL(srv.URL),
+		WithConcurrency(2),
+		WithRequests(10),
+	).Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("the run took %v, want in-flight requests cancelled at the drain timeout", elapsed)
+	}
+	if summary.Requests != 2 || summary.Successful != 0 {
+		t.Errorf("Requests = %d, Successful = %d, want the 2 in-flight requests cancelled", summary.Requests, summary.Successful)
+	}
+}
+
+func TestConcurrentRuns(t *testing.T) {
+	var mu sync.Mutex
+	seen := make(map[string][]int)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		id, _ := strconv.Atoi(string(body))
+		mu.Lock()
+		defer mu.Unlock()
+		seen[r.URL.Path] = append(seen[r.URL.Path], id)
+	}))
+	defer srv.Close()
+
+	runs := []struct {
+		path string
+		cfg  Config
+	}{
+		{path: "/a", cfg: Config{SummaryOnly: true, SeqStart: 1, MaxLatency: time.Minute, LatencyPrecision: 2}},
+		{path: "/b", cfg: Config{SummaryOnly: true, SeqStart: 1000, MaxLatency: time.Hour, LatencyPrecision: 4}},
+	}
+	var wg sync.WaitGroup
+	for _, run := range runs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			summary, err := New(
+				WithConfig(run.cfg),
+				WithURL(srv.URL+run.path),
+				WithMethod(http.MethodPost),
+				WithBody("{{seq}}"),
+				WithConcurrency(4),
+				WithRequests(50),
+			).Run(context.Background())
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if summary.Successful != 50 {
+				t.Errorf("%s: Successful = %d, want 50", run.path, summary.Successful)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, run := range runs {
+		ids := seen[run.path]
+		slices.Sort(ids)
+		for i, id := range ids {
+			if want := int(run.cfg.SeqStart) + i; id != want {
+				t.Errorf("%s: sequence IDs %v, want 50 IDs from %d", run.path, ids, run.cfg.SeqStart)
+				break
+			}
+		}
+	}
+}
+
+func TestWithLogger(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	if _, err := New(WithURL(srv.URL), WithRequests(1), WithConcurrency(1), WithLogger(logger)).Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var record struct {
+		Level string `json:"level"`
+		Msg   string `json:"msg"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("the logger of the run got %q, want a JSON record: %v", buf.String(), err)
+	}
+	if record.Level != "ERROR" || record.Msg != "Network error" {
+		t.Errorf("the logger of the run got %+v, want the network error", record)
+	}
+}
@@ -0,0 +1,96 @@
+package loadtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// ParseStartAt parses the start time of a scheduled run: a time of day such as "02:00"
+// or "02:00:30", its next occurrence in local time after now, or an RFC 3339 timestamp.
+func ParseStartAt(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range []string{"15:04", time.TimeOnly} {
+		clock, err := time.ParseInLocation(layout, value, now.Location())
+		if err != nil {
+			continue
+		}
+		start := time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), clock.Second(), 0, now.Location())
+		if start.Before(now) {
+			start = start.AddDate(0, 0, 1)
+		}
+		return start, nil
+	}
+	start, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither a time of day such as 02:00 nor an RFC 3339 time", value)
+	}
+	return start, nil
+}
+
+// schedule is the time window a run must happen in: it starts at start and the load must
+// be over by end. A zero end leaves the run unbounded. The waits and aborts are reported
+// through logger.
+type schedule struct {
+	start  time.Time
+	end    time.Time
+	logger *slog.Logger
+}
+
+// newSchedule returns the window of a run of cfg launched at now. The window opens at
+// cfg.StartAt, or right away without one, and lasts cfg.Window.
+func newSchedule(cfg Config, now time.Time) schedule {
+	s := schedule{start: cfg.StartAt, logger: cfg.log()}
+	if s.start.IsZero() {
+		s.start = now
+	}
+	if cfg.Window > 0 {
+		s.end = s.start.Add(cfg.Window)
+	}
+	return s
+}
+
+// wait blocks until the window opens, then checks that a run lasting planned still fits
+// in it. A zero planned duration, for runs of a number of requests, is only bounded
+// while running. The end of ctx while waiting cancels the run.
+func (s schedule) wait(ctx context.Context, planned time.Duration) error {
+	if delay := time.Until(s.start); delay > 0 {
+		s.logger.Info("Waiting to start the run", "start", s.start.Format(time.RFC1123))
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return errors.New("interrupted before the start of the run")
+		}
+	}
+	if s.end.IsZero() {
+		return nil
+	}
+	left := time.Until(s.end)
+	if left <= 0 {
+		return fmt.Errorf("the time window closed at %s", s.end.Format(time.RFC1123))
+	}
+	if planned > left {
+		return fmt.Errorf("the run takes %v but the time window closes in %v, at %s", planned, left.Round(time.Second), s.end.Format(time.RFC1123))
+	}
+	return nil
+}
+
+// enforce aborts the run when the window closes before it is over. The returned function
+// releases the timer once the run is over.
+func (s schedule) enforce(run *runControl) func() {
+	if s.end.IsZero() {
+		return func() {}
+	}
+	timer := time.AfterFunc(time.Until(s.end), func() {
+		if run.stopped() {
+			return
+		}
+		run.abort(fmt.Errorf("the time window closed at %s before the run was over", s.end.Format(time.TimeOnly)))
+		s.logger.Error("Aborting the run", "reason", run.aborted())
+	})
+	return func() { timer.Stop() }
+}
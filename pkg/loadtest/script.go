@@ -0,0 +1,211 @@
+package loadtest
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
+)
+
+// Names of the hook functions a --script may define.
+const (
+	hookBeforeRequest = "before_request"
+	hookAfterResponse = "after_response"
+)
+
+// script is a compiled Lua script defining request and response hooks. Lua states are
+// not safe for concurrent use, so every worker runs the script in a state of its own.
+type script struct {
+	path   string
+	proto  *lua.FunctionProto
+	before bool
+	after  bool
+}
+
+// loadScript compiles the Lua script at path and checks that it defines at least one
+// hook.
+func loadScript(path string) (*script, error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	chunk, err := parse.Parse(strings.NewReader(string(source)), path)
+	if err != nil {
+		return nil, err
+	}
+	proto, err := lua.Compile(chunk, path)
+	if err != nil {
+		return nil, err
+	}
+	s := &script{path: path, proto: proto}
+	state, err := s.newState()
+	if err != nil {
+		return nil, err
+	}
+	defer state.close()
+	s.before, s.after = state.before != nil, state.after != nil
+	if !s.before && !s.after {
+		return nil, fmt.Errorf("%s defines neither %s nor %s", path, hookBeforeRequest, hookAfterResponse)
+	}
+	return s, nil
+}
+
+// scriptState is the script running in the Lua state of a worker.
+type scriptState struct {
+	l      *lua.LState
+	before *lua.LFunction
+	after  *lua.LFunction
+}
+
+// newState runs the script in a new Lua state with the helper functions defined.
+func (s *script) newState() (*scriptState, error) {
+	l := lua.NewState()
+	l.SetGlobal("sha256", l.NewFunction(luaSHA256))
+	l.SetGlobal("hmac_sha256", l.NewFunction(luaHMACSHA256))
+	l.SetGlobal("base64", l.NewFunction(luaBase64))
+	l.Push(l.NewFunctionFromProto(s.proto))
+	if err := l.PCall(0, lua.MultRet, nil); err != nil {
+		l.Close()
+		return nil, err
+	}
+	state := &scriptState{l: l}
+	state.before, _ = l.GetGlobal(hookBeforeRequest).(*lua.LFunction)
+	state.after, _ = l.GetGlobal(hookAfterResponse).(*lua.LFunction)
+	return state, nil
+}
+
+// close releases the Lua state.
+func (s *scriptState) close() {
+	s.l.Close()
+}
+
+// beforeRequest calls before_request with the method, URL, headers and body of p, and
+// applies the changes the hook made to them.
+func (s *scriptState) beforeRequest(p *preparedRequest) error {
+	if s.before == nil {
+		return nil
+	}
+	req := s.l.NewTable()
+	req.RawSetString("method", lua.LString(p.target.method))
+	req.RawSetString("url", lua.LString(p.url))
+	req.RawSetString("body", lua.LString(p.plainBody))
+	req.RawSetString("headers", s.headerTable(p.header))
+	if err := s.l.CallByParam(lua.P{Fn: s.before, Protect: true}, req); err != nil {
+		return err
+	}
+
+	p.target.method = strings.ToUpper(lua.LVAsString(req.RawGetString("method")))
+	p.url = lua.LVAsString(req.RawGetString("url"))
+	if body := lua.LVAsString(req.RawGetString("body")); body != string(p.plainBody) {
+		p.plainBody, p.body = []byte(body), []byte(body)
+	}
+	headers, ok := req.RawGetString("headers").(*lua.LTable)
+	if !ok {
+		return errors.New("req.headers is not a table")
+	}
+	p.header = make(http.Header)
+	headers.ForEach(func(name, value lua.LValue) {
+		p.header.Set(lua.LVAsString(name), lua.LVAsString(value))
+	})
+	return nil
+}
+
+// afterResponse calls after_response with the request and response of res. The hook
+// fails the request by returning false, optionally followed by a reason; a runtime error
+// fails it too, with the error as the reason. It returns the reason of the failure, or
+// an empty string when the request passed.
+func (s *scriptState) afterResponse(res requestResult) string {
+	req := s.l.NewTable()
+	req.RawSetString("method", lua.LString(res.method))
+	req.RawSetString("url", lua.LString(res.url))
+	resp := s.l.NewTable()
+	resp.RawSetString("status", lua.LNumber(res.statusCode))
+	resp.RawSetString("latency_ms", lua.LNumber(milliseconds(res.latency)))
+	resp.RawSetString("headers", s.headerTable(res.responseHeader))
+	resp.RawSetString("body", lua.LString(res.responseBody))
+	if err := s.l.CallByParam(lua.P{Fn: s.after, NRet: 2, Protect: true}, req, resp); err != nil {
+		var apiErr *lua.ApiError
+		if errors.As(err, &apiErr) {
+			return strings.SplitN(apiErr.Object.String(), "\n", 2)[0]
+		}
+		return err.Error()
+	}
+	passed, reason := s.l.Get(-2), s.l.Get(-1)
+	s.l.Pop(2)
+	if passed != lua.LFalse {
+		return ""
+	}
+	if reason == lua.LNil {
+		return hookAfterResponse + " returned false"
+	}
+	return lua.LVAsString(reason)
+}
+
+// headerTable returns header as a Lua table of header names to values, several values
+// of a header being joined with commas.
+func (s *scriptState) headerTable(header http.Header) *lua.LTable {
+	t := s.l.NewTable()
+	for name, values := range header {
+		t.RawSetString(name, lua.LString(strings.Join(values, ", ")))
+	}
+	return t
+}
+
+// luaSHA256 implements sha256(s), the hex SHA-256 of s.
+func luaSHA256(l *lua.LState) int {
+	sum := sha256.Sum256([]byte(l.CheckString(1)))
+	l.Push(lua.LString(hex.EncodeToString(sum[:])))
+	return 1
+}
+
+// luaHMACSHA256 implements hmac_sha256(key, s), the hex HMAC-SHA256 of s with key.
+func luaHMACSHA256(l *lua.LState) int {
+	mac := hmac.New(sha256.New, []byte(l.CheckString(1)))
+	mac.Write([]byte(l.CheckString(2)))
+	l.Push(lua.LString(hex.EncodeToString(mac.Sum(nil))))
+	return 1
+}
+
+// luaBase64 implements base64(s), the standard base64 encoding of s.
+func luaBase64(l *lua.LState) int {
+	l.Push(lua.LString(base64.StdEncoding.EncodeToString([]byte(l.CheckString(1)))))
+	return 1
+}
+
+// generateScriptReport prints how many requests the after_response hook of the script at
+// path checked and the most frequent reasons it failed the others for.
+func generateScriptReport(path string, st *stats) {
+	color.Green("\n===== 📜 Script Checks =====")
+	if st.scriptFailed == 0 {
+		color.Cyan("✅ %d of %d responses passed %s in %s", st.scriptChecked, st.scriptChecked, hookAfterResponse, path)
+		return
+	}
+	color.Red("❌ %d of %d responses failed %s in %s:", st.scriptFailed, st.scriptChecked, hookAfterResponse, path)
+	reasons := make([]string, 0, len(st.scriptFailures))
+	for reason := range st.scriptFailures {
+		reasons = append(reasons, reason)
+	}
+	sort.Slice(reasons, func(i, j int) bool {
+		if st.scriptFailures[reasons[i]] != st.scriptFailures[reasons[j]] {
+			return st.scriptFailures[reasons[i]] > st.scriptFailures[reasons[j]]
+		}
+		return reasons[i] < reasons[j]
+	})
+	for i, reason := range reasons {
+		if i == schemaProblemsShown {
+			color.Red("  ... and %d more", len(reasons)-schemaProblemsShown)
+			break
+		}
+		color.Red("  - %s (%d responses)", reason, st.scriptFailures[reason])
+	}
+}
@@ -0,0 +1,168 @@
+package loadtest
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+)
+
+// signatureAlgorithm is the only algorithm reports are signed with.
+const signatureAlgorithm = "ed25519"
+
+// ReportSignature is the signature of a JSON report, made with Ed25519 over the canonical
+// encoding of the report without its signature.
+type ReportSignature struct {
+	Algorithm string `json:"algorithm"`
+	// KeyID is the fingerprint of the public key, telling which key signed the report.
+	KeyID string `json:"key_id"`
+	Value string `json:"value"`
+}
+
+// SigningKey is the Ed25519 private key JSON reports are signed with, so the published
+// numbers of a run can be verified as unmodified with VerifyReport.
+type SigningKey struct {
+	key ed25519.PrivateKey
+}
+
+// LoadSigningKey reads an Ed25519 private key in PEM (PKCS #8), as generated by
+// "openssl genpkey -algorithm ed25519".
+func LoadSigningKey(path string) (*SigningKey, error) {
+	der, err := readPEM(path, "PRIVATE KEY")
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	key, ok := parsed.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: only Ed25519 keys are supported", path)
+	}
+	return &SigningKey{key: key}, nil
+}
+
+// LoadVerifyKey reads an Ed25519 public key in PEM, as written by "openssl pkey -pubout".
+func LoadVerifyKey(path string) (ed25519.PublicKey, error) {
+	der, err := readPEM(path, "PUBLIC KEY")
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	key, ok := parsed.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: only Ed25519 keys are supported", path)
+	}
+	return key, nil
+}
+
+// readPEM returns the content of the PEM block of the given type in the file at path.
+func readPEM(path, blockType string) ([]byte, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var block *pem.Block
+		block, content = pem.Decode(content)
+		if block == nil {
+			return nil, fmt.Errorf("%s: no %s PEM block", path, blockType)
+		}
+		if block.Type == blockType {
+			return block.Bytes, nil
+		}
+	}
+}
+
+// keyID returns the fingerprint of a public key: the first bytes of its SHA-256 hash.
+func keyID(key ed25519.PublicKey) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+// sign sets the signature of report.
+func (k *SigningKey) sign(report *Report) error {
+	report.Signature = nil
+	encoded, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	message, err := canonicalJSON(encoded)
+	if err != nil {
+		return err
+	}
+	report.Signature = &ReportSignature{
+		Algorithm: signatureAlgorithm,
+		KeyID:     keyID(k.key.Public().(ed25519.PublicKey)),
+		Value:     base64.StdEncoding.EncodeToString(ed25519.Sign(k.key, message)),
+	}
+	return nil
+}
+
+// VerifyReport checks that the JSON report at path was signed with the private key of
+// key and not modified since: any change to its content, but for whitespace, invalidates
+// the signature. It returns the report.
+func VerifyReport(path string, key ed25519.PublicKey) (Report, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return Report{}, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(content, &fields); err != nil {
+		return Report{}, fmt.Errorf("%s is not a JSON report: %v", path, err)
+	}
+	var signature ReportSignature
+	if raw, ok := fields["signature"]; !ok || json.Unmarshal(raw, &signature) != nil || signature.Value == "" {
+		return Report{}, fmt.Errorf("%s is not signed", path)
+	}
+	if signature.Algorithm != signatureAlgorithm {
+		return Report{}, fmt.Errorf("%s is signed with unsupported algorithm %q", path, signature.Algorithm)
+	}
+	if signature.KeyID != keyID(key) {
+		return Report{}, fmt.Errorf("%s is signed with key %s, not with the given key %s", path, signature.KeyID, keyID(key))
+	}
+	value, err := base64.StdEncoding.DecodeString(signature.Value)
+	if err != nil {
+		return Report{}, fmt.Errorf("%s has an invalid signature: %v", path, err)
+	}
+	delete(fields, "signature")
+	unsigned, err := json.Marshal(fields)
+	if err != nil {
+		return Report{}, err
+	}
+	message, err := canonicalJSON(unsigned)
+	if err != nil {
+		return Report{}, err
+	}
+	if !ed25519.Verify(key, message, value) {
+		return Report{}, fmt.Errorf("%s: the signature does not match, the report was modified after it was signed", path)
+	}
+	var report Report
+	if err := json.Unmarshal(content, &report); err != nil {
+		return Report{}, err
+	}
+	return report, nil
+}
+
+// canonicalJSON returns the canonical encoding of a JSON document: object keys sorted, no
+// whitespace and numbers kept as written, so that formatting a report again does not
+// change what is signed.
+func canonicalJSON(document []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(document))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
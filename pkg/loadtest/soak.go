@@ -0,0 +1,139 @@
+package loadtest
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// InterimReport is a report printed every --report-interval of a long run, and written
+// to the --interim-out NDJSON file.
+type InterimReport struct {
+	Time     time.Time     `json:"time"`
+	ElapsedS float64       `json:"elapsed_s"`
+	Window   InterimWindow `json:"window"`
+	Total    InterimTotals `json:"total"`
+	Memory   InterimMemory `json:"memory"`
+}
+
+// InterimWindow holds the rolling stats of the requests completed since the previous
+// interim report.
+type InterimWindow struct {
+	Requests          int     `json:"requests"`
+	Errors            int     `json:"errors"`
+	RequestsPerSecond float64 `json:"rps"`
+	P50LatencyMs      float64 `json:"p50_latency_ms"`
+	P95LatencyMs      float64 `json:"p95_latency_ms"`
+	P99LatencyMs      float64 `json:"p99_latency_ms"`
+}
+
+// InterimTotals holds the cumulative counts of the run so far.
+type InterimTotals struct {
+	Requests      int `json:"requests"`
+	Errors        int `json:"errors"`
+	NetworkErrors int `json:"network_errors"`
+}
+
+// InterimMemory holds the memory usage of restclient itself, to tell a leak of the
+// tool from a degradation of the target.
+type InterimMemory struct {
+	HeapAllocBytes uint64 `json:"heap_alloc_bytes"`
+	SysBytes       uint64 `json:"sys_bytes"`
+	Goroutines     int    `json:"goroutines"`
+	GCCycles       uint32 `json:"gc_cycles"`
+}
+
+// interimReporter keeps the stats of the requests completed since its previous report.
+// It is only used by the collector goroutine.
+type interimReporter struct {
+	ticker *time.Ticker
+	out    *ndjsonLog
+	start  time.Time
+	last   time.Time
+	window *stats
+}
+
+// newInterimReporter returns a reporter printing every interval from start, and writing
+// every report to out when it is not nil, or nil when interval is 0. Latencies are
+// recorded with the range r.
+func newInterimReporter(interval time.Duration, start time.Time, out *ndjsonLog, r histogramRange) *interimReporter {
+	if interval <= 0 {
+		return nil
+	}
+	return &interimReporter{ticker: time.NewTicker(interval), out: out, start: start, last: start, window: newStats(r)}
+}
+
+// tick returns the channel the reporter ticks on, or nil for a nil reporter, which never
+// ticks.
+func (r *interimReporter) tick() <-chan time.Time {
+	if r == nil {
+		return nil
+	}
+	return r.ticker.C
+}
+
+// add records a measured result in the current window.
+func (r *interimReporter) add(res requestResult) {
+	if r != nil {
+		r.window.add(res)
+	}
+}
+
+// report prints the interim report at now, with the totals of all, and starts a new
+// window.
+func (r *interimReporter) report(now time.Time, all *stats) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	w, length := r.window, now.Sub(r.last)
+	sent := w.sentCount()
+	rep := InterimReport{
+		Time:     now.UTC(),
+		ElapsedS: now.Sub(r.start).Seconds(),
+		Window: InterimWindow{
+			Requests:          sent,
+			Errors:            w.failedCount(),
+			RequestsPerSecond: requestsPerSecond(sent, length),
+			P50LatencyMs:      milliseconds(w.percentile(0.50)),
+			P95LatencyMs:      milliseconds(w.percentile(0.95)),
+			P99LatencyMs:      milliseconds(w.percentile(0.99)),
+		},
+		Total: InterimTotals{
+			Requests:      all.sentCount(),
+			Errors:        all.failedCount(),
+			NetworkErrors: all.networkErrorCount,
+		},
+		Memory: InterimMemory{
+			HeapAllocBytes: mem.HeapAlloc,
+			SysBytes:       mem.Sys,
+			Goroutines:     runtime.NumGoroutine(),
+			GCCycles:       mem.NumGC,
+		},
+	}
+	r.last, r.window = now, newStats(r.window.latencyRange)
+
+	color.Green("\n===== 🕒 Interim Report (%v elapsed) =====", now.Sub(r.start).Round(time.Second))
+	fmt.Printf("Last %v: %d requests (%.2f/s), %d errors, p50 %s, p95 %s, p99 %s\n",
+		length.Round(time.Second), rep.Window.Requests, rep.Window.RequestsPerSecond, rep.Window.Errors,
+		formatMs(rep.Window.P50LatencyMs), formatMs(rep.Window.P95LatencyMs), formatMs(rep.Window.P99LatencyMs))
+	fmt.Printf("Since the start: %d requests, %d errors (%d network errors)\n",
+		rep.Total.Requests, rep.Total.Errors, rep.Total.NetworkErrors)
+	fmt.Printf("restclient memory: heap %s, system %s, %d goroutines, %d GC cycles\n",
+		formatBytes(int64(mem.HeapAlloc)), formatBytes(int64(mem.Sys)), rep.Memory.Goroutines, mem.NumGC)
+	if r.out != nil {
+		r.out.write(rep)
+	}
+}
+
+// stop stops the reporter and closes its file.
+func (r *interimReporter) stop() error {
+	if r == nil {
+		return nil
+	}
+	r.ticker.Stop()
+	if r.out != nil {
+		return r.out.close()
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package loadtest
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// spikeWindows are the windows of a spike run, in order: before, during and after the
+// burst.
+var spikeWindows = []string{"before", "spike", "after"}
+
+// SpikeProfile is a --spike profile: an open-model run at a baseline arrival rate, with
+// a burst at a peak rate starting at an offset of the measured phase.
+type SpikeProfile struct {
+	base   float64
+	peak   float64
+	at     time.Duration
+	length time.Duration
+}
+
+// ParseSpike parses a spike profile such as "base=50rps,peak=500rps,at=2m,for=30s".
+func ParseSpike(value string) (*SpikeProfile, error) {
+	p := &SpikeProfile{}
+	seen := make(map[string]bool)
+	for _, item := range SplitList(value) {
+		key, val, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("%q is not of the form key=value", item)
+		}
+		key, val = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(val)
+		var err error
+		switch key {
+		case "base", "peak":
+			var rate float64
+			rate, err = strconv.ParseFloat(strings.TrimSuffix(strings.ToLower(val), "rps"), 64)
+			if err == nil && rate <= 0 {
+				err = fmt.Errorf("the rate must be positive")
+			}
+			if key == "base" {
+				p.base = rate
+			} else {
+				p.peak = rate
+			}
+		case "at":
+			p.at, err = time.ParseDuration(val)
+			if err == nil && p.at < 0 {
+				err = fmt.Errorf("the offset cannot be negative")
+			}
+		case "for":
+			p.length, err = time.ParseDuration(val)
+			if err == nil && p.length <= 0 {
+				err = fmt.Errorf("the length must be positive")
+			}
+		default:
+			return nil, fmt.Errorf("unknown key %q, expected base, peak, at or for", key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s in %q: %v", key, item, err)
+		}
+		seen[key] = true
+	}
+	for _, key := range []string{"base", "peak", "at", "for"} {
+		if !seen[key] {
+			return nil, fmt.Errorf("missing %s", key)
+		}
+	}
+	if p.peak <= p.base {
+		return nil, fmt.Errorf("the peak rate %v is not above the base rate %v", p.peak, p.base)
+	}
+	return p, nil
+}
+
+// End returns the offset of the measured phase at which the burst ends.
+func (p *SpikeProfile) End() time.Duration {
+	return p.at + p.length
+}
+
+// rateAt returns the arrival rate at an offset of the measured phase.
+func (p *SpikeProfile) rateAt(offset time.Duration) float64 {
+	if p.window(offset) == "spike" {
+		return p.peak
+	}
+	return p.base
+}
+
+// window returns the window an arrival at an offset of the measured phase belongs to.
+func (p *SpikeProfile) window(offset time.Duration) string {
+	switch {
+	case offset < p.at:
+		return "before"
+	case offset < p.End():
+		return "spike"
+	default:
+		return "after"
+	}
+}
+
+// generateSpikeReport prints the results of the requests started before, during and
+// after the burst, to compare how the target held the burst with its baseline and to see
+// whether it recovered.
+func generateSpikeReport(windowStats map[string]*stats) {
+	if len(windowStats) == 0 {
+		return
+	}
+	color.Green("\n===== ⚡ Spike =====")
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "Window\tRequests\tErrors\tDropped\tp50\tp95\tp99\tMax\t")
+	for _, window := range spikeWindows {
+		st, ok := windowStats[window]
+		if !ok {
+			continue
+		}
+		sent := st.sentCount()
+		errors := "-"
+		if sent > 0 {
+			errors = fmt.Sprintf("%.1f%%", float64(st.failedCount())*100/float64(sent))
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%d\t%s\t%s\t%s\t%s\t\n", window, sent, errors, st.droppedCount,
+			formatMs(milliseconds(st.percentile(0.50))), formatMs(milliseconds(st.percentile(0.95))),
+			formatMs(milliseconds(st.percentile(0.99))), formatMs(milliseconds(st.percentile(1))))
+	}
+	tw.Flush()
+}
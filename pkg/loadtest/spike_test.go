@@ -0,0 +1,74 @@
+package loadtest
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSpike(t *testing.T) {
+	tests := []struct {
+		value string
+		want  SpikeProfile
+		err   string
+	}{
+		{
+			value: "base=50rps,peak=500rps,at=2m,for=30s",
+			want:  SpikeProfile{base: 50, peak: 500, at: 2 * time.Minute, length: 30 * time.Second},
+		},
+		{
+			value: " PEAK = 20 , base=2.5RPS, at=0s, for=1s",
+			want:  SpikeProfile{base: 2.5, peak: 20, length: time.Second},
+		},
+		{value: "base=50,peak=500,at=2m", err: "missing for"},
+		{value: "base=50,peak=500,at=2m,for=30s,ramp=1s", err: "unknown key"},
+		{value: "base=50,peak=500,at,for=30s", err: "not of the form key=value"},
+		{value: "base=0,peak=500,at=2m,for=30s", err: "the rate must be positive"},
+		{value: "base=50,peak=500,at=-1s,for=30s", err: "the offset cannot be negative"},
+		{value: "base=50,peak=500,at=2m,for=0s", err: "the length must be positive"},
+		{value: "base=50,peak=fast,at=2m,for=30s", err: "invalid peak"},
+		{value: "base=500,peak=50,at=2m,for=30s", err: "not above the base rate"},
+	}
+	for _, tt := range tests {
+		p, err := ParseSpike(tt.value)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("ParseSpike(%q) error = %v, want one containing %q", tt.value, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseSpike(%q) error = %v", tt.value, err)
+			continue
+		}
+		if *p != tt.want {
+			t.Errorf("ParseSpike(%q) = %+v, want %+v", tt.value, *p, tt.want)
+		}
+	}
+}
+
+func TestSpikeProfileWindows(t *testing.T) {
+	p := &SpikeProfile{base: 50, peak: 500, at: time.Minute, length: 30 * time.Second}
+	if got, want := p.End(), 90*time.Second; got != want {
+		t.Errorf("End() = %v, want %v", got, want)
+	}
+	tests := []struct {
+		offset time.Duration
+		window string
+		rate   float64
+	}{
+		{0, "before", 50},
+		{time.Minute - 1, "before", 50},
+		{time.Minute, "spike", 500},
+		{90*time.Second - 1, "spike", 500},
+		{90 * time.Second, "after", 50},
+	}
+	for _, tt := range tests {
+		if got := p.window(tt.offset); got != tt.window {
+			t.Errorf("window(%v) = %q, want %q", tt.offset, got, tt.window)
+		}
+		if got := p.rateAt(tt.offset); got != tt.rate {
+			t.Errorf("rateAt(%v) = %v, want %v", tt.offset, got, tt.rate)
+		}
+	}
+}
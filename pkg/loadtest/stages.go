@@ -0,0 +1,181 @@
+package loadtest
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// StageProfile is a --steps profile: the load of a run in consecutive stages, each with
+// its length and either its concurrency ("10c") or its arrival rate ("100rps"). All the
+// stages of a profile use the same unit.
+type StageProfile struct {
+	stages []loadStage
+	rates  bool
+}
+
+// loadStage is a stage of a StageProfile. Its label names it in the report.
+type loadStage struct {
+	concurrency int
+	rate        float64
+	length      time.Duration
+	label       string
+}
+
+// ParseStages parses a stage profile such as "10c:1m,50c:1m,100c:1m" or
+// "100rps:30s,200rps:30s".
+func ParseStages(value string) (*StageProfile, error) {
+	p := &StageProfile{}
+	for i, item := range SplitList(value) {
+		load, length, ok := strings.Cut(item, ":")
+		if !ok {
+			return nil, fmt.Errorf("%q is not of the form load:duration", item)
+		}
+		load = strings.ToLower(strings.TrimSpace(load))
+		stage := loadStage{label: fmt.Sprintf("%d: %s", i+1, load)}
+		var err error
+		stage.length, err = time.ParseDuration(strings.TrimSpace(length))
+		if err != nil || stage.length <= 0 {
+			return nil, fmt.Errorf("invalid duration in %q", item)
+		}
+		rates := strings.HasSuffix(load, "rps")
+		switch {
+		case rates:
+			stage.rate, err = strconv.ParseFloat(strings.TrimSuffix(load, "rps"), 64)
+			if err == nil && stage.rate <= 0 {
+				err = fmt.Errorf("the rate must be positive")
+			}
+		case strings.HasSuffix(load, "c"):
+			stage.concurrency, err = strconv.Atoi(strings.TrimSuffix(load, "c"))
+			if err == nil && stage.concurrency <= 0 {
+				err = fmt.Errorf("the concurrency must be positive")
+			}
+		default:
+			err = fmt.Errorf("expected a concurrency such as 10c or a rate such as 100rps")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid load in %q: %v", item, err)
+		}
+		if i > 0 && rates != p.rates {
+			return nil, fmt.Errorf("%q mixes concurrencies and rates", value)
+		}
+		p.rates = rates
+		p.stages = append(p.stages, stage)
+	}
+	if len(p.stages) == 0 {
+		return nil, fmt.Errorf("no stages")
+	}
+	return p, nil
+}
+
+// Rates reports whether the stages set arrival rates rather than concurrencies.
+func (p *StageProfile) Rates() bool {
+	return p.rates
+}
+
+// Duration returns the total length of the stages.
+func (p *StageProfile) Duration() time.Duration {
+	var total time.Duration
+	for _, stage := range p.stages {
+		total += stage.length
+	}
+	return total
+}
+
+// MaxConcurrency returns the concurrency of the busiest stage, or 0 for rate stages.
+func (p *StageProfile) MaxConcurrency() int {
+	highest := 0
+	for _, stage := range p.stages {
+		highest = max(highest, stage.concurrency)
+	}
+	return highest
+}
+
+// stageAt returns the index of the stage running at an offset of the measured phase.
+// The warm-up runs the first stage, and the last one goes on until the run ends.
+func (p *StageProfile) stageAt(offset time.Duration) int {
+	var end time.Duration
+	for i, stage := range p.stages {
+		end += stage.length
+		if offset < end {
+			return i
+		}
+	}
+	return len(p.stages) - 1
+}
+
+// rateAt returns the arrival rate at an offset of the measured phase.
+func (p *StageProfile) rateAt(offset time.Duration) float64 {
+	return p.stages[p.stageAt(offset)].rate
+}
+
+// window returns the label of the stage running at an offset of the measured phase.
+func (p *StageProfile) window(offset time.Duration) string {
+	return p.stages[p.stageAt(offset)].label
+}
+
+// waitActive blocks worker id of a concurrency profile, numbered from 0, until a stage
+// runs enough workers to include it. It reports false when the run stops first or no
+// later stage includes the worker.
+func (p *StageProfile) waitActive(run *runControl, id int, start time.Time) bool {
+	for !run.stopped() {
+		offset := time.Since(start)
+		i := p.stageAt(offset)
+		if id < p.stages[i].concurrency {
+			return true
+		}
+		if i == len(p.stages)-1 {
+			return false
+		}
+		var end time.Duration
+		for _, stage := range p.stages[:i+1] {
+			end += stage.length
+		}
+		run.pause(end - offset)
+	}
+	return false
+}
+
+// reportStages returns the stages as saved in the report settings.
+func (p *StageProfile) reportStages() []reportStage {
+	stages := make([]reportStage, len(p.stages))
+	for i, stage := range p.stages {
+		stages[i] = reportStage{Label: stage.label, Length: stage.length}
+	}
+	return stages
+}
+
+// reportStage is a stage of a --steps run as saved in the report settings.
+type reportStage struct {
+	Label  string
+	Length time.Duration
+}
+
+// generateStageReport prints the requests per second, errors and latency percentiles of
+// every stage of a --steps run, in order, to find the stage where latency takes off.
+func generateStageReport(stages []reportStage, stageStats map[string]*stats) {
+	color.Green("\n===== 🪜 Load Stages =====")
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "Stage\tRequests\tRPS\tErrors\tDropped\tp50\tp95\tp99\tMax\t")
+	for _, stage := range stages {
+		st, ok := stageStats[stage.Label]
+		if !ok {
+			fmt.Fprintf(tw, "%s\t0\t-\t-\t0\t-\t-\t-\t-\t\n", stage.Label)
+			continue
+		}
+		sent := st.sentCount()
+		errors := "-"
+		if sent > 0 {
+			errors = fmt.Sprintf("%.1f%%", float64(st.failedCount())*100/float64(sent))
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%.2f\t%s\t%d\t%s\t%s\t%s\t%s\t\n", stage.Label, sent, float64(sent)/stage.Length.Seconds(),
+			errors, st.droppedCount, formatMs(milliseconds(st.percentile(0.50))), formatMs(milliseconds(st.percentile(0.95))),
+			formatMs(milliseconds(st.percentile(0.99))), formatMs(milliseconds(st.percentile(1))))
+	}
+	tw.Flush()
+}
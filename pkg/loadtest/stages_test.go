@@ -0,0 +1,100 @@
+package loadtest
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseStages(t *testing.T) {
+	tests := []struct {
+		value  string
+		stages []loadStage
+		rates  bool
+		err    string
+	}{
+		{
+			value: "10c:1m,50c:30s",
+			stages: []loadStage{
+				{concurrency: 10, length: time.Minute, label: "1: 10c"},
+				{concurrency: 50, length: 30 * time.Second, label: "2: 50c"},
+			},
+		},
+		{
+			value: " 100RPS : 30s , 2.5rps:1s ",
+			stages: []loadStage{
+				{rate: 100, length: 30 * time.Second, label: "1: 100rps"},
+				{rate: 2.5, length: time.Second, label: "2: 2.5rps"},
+			},
+			rates: true,
+		},
+		{value: "", err: "no stages"},
+		{value: "10c", err: "not of the form load:duration"},
+		{value: "10c:0s", err: "invalid duration"},
+		{value: "10c:soon", err: "invalid duration"},
+		{value: "0c:1m", err: "the concurrency must be positive"},
+		{value: "-5rps:1m", err: "the rate must be positive"},
+		{value: "10:1m", err: "expected a concurrency"},
+		{value: "10c:1m,100rps:1m", err: "mixes concurrencies and rates"},
+	}
+	for _, tt := range tests {
+		p, err := ParseStages(tt.value)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("ParseStages(%q) error = %v, want one containing %q", tt.value, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseStages(%q) error = %v", tt.value, err)
+			continue
+		}
+		if !reflect.DeepEqual(p.stages, tt.stages) || p.Rates() != tt.rates {
+			t.Errorf("ParseStages(%q) = %+v (rates %v), want %+v (rates %v)", tt.value, p.stages, p.Rates(), tt.stages, tt.rates)
+		}
+	}
+}
+
+func TestStageProfileSchedule(t *testing.T) {
+	p, err := ParseStages("10c:1m,50c:30s,20c:1m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := p.Duration(), 150*time.Second; got != want {
+		t.Errorf("Duration() = %v, want %v", got, want)
+	}
+	if got := p.MaxConcurrency(); got != 50 {
+		t.Errorf("MaxConcurrency() = %d, want 50", got)
+	}
+	tests := []struct {
+		offset time.Duration
+		stage  int
+	}{
+		{0, 0},
+		{time.Minute - 1, 0},
+		{time.Minute, 1},
+		{90 * time.Second, 2},
+		// The last stage goes on until the run ends.
+		{time.Hour, 2},
+	}
+	for _, tt := range tests {
+		if got := p.stageAt(tt.offset); got != tt.stage {
+			t.Errorf("stageAt(%v) = %d, want %d", tt.offset, got, tt.stage)
+		}
+	}
+
+	rates, err := ParseStages("100rps:10s,200rps:10s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := rates.rateAt(15 * time.Second); got != 200 {
+		t.Errorf("rateAt(15s) = %v, want 200", got)
+	}
+	if got := rates.window(5 * time.Second); got != "1: 100rps" {
+		t.Errorf("window(5s) = %q, want %q", got, "1: 100rps")
+	}
+	if got := rates.MaxConcurrency(); got != 0 {
+		t.Errorf("MaxConcurrency() of rate stages = %d, want 0", got)
+	}
+}
@@ -0,0 +1,109 @@
+package loadtest
+
+import (
+	"bytes"
+	"net/http"
+	"sync"
+)
+
+// requestCache holds the requests of the targets whose URL, headers and body contain no
+// template, built once and reused by every request to them. A cached request is cloned
+// for every send, since net/http does not allow a request to be sent concurrently, but
+// its URL is not parsed, its headers are not rendered and its body is not compressed
+// again.
+type requestCache struct {
+	mu       sync.Mutex
+	requests map[requestKey]*preparedRequest
+}
+
+// requestKey identifies a target and the client profile sending it. Targets are copied
+// around by value, so the headers and body of a target are identified by the address of
+// their first element, which all copies share.
+type requestKey struct {
+	class, name, method, url string
+	body                     *templateSource
+	headers                  *header
+	mix                      *clientMix
+}
+
+// newRequestCache returns a cache of static requests for e, or nil when the settings of
+// the run render something for every request: data rows, header rotation, multipart
+// bodies or a before_request hook. Random fields only affect the targets with a JSON
+// body, so isStatic leaves those out instead.
+func newRequestCache(e *engine) *requestCache {
+	if e.data != nil || len(e.rotations) > 0 ||
+		len(e.multipartFields) > 0 || len(e.multipartFiles) > 0 || (e.script != nil && e.script.before) {
+		return nil
+	}
+	return &requestCache{requests: make(map[requestKey]*preparedRequest)}
+}
+
+// get returns the cached request to t sent by w, building it on first use. It returns
+// false when t is rendered for every request; the result is cached as well, so the
+// templates of a target are inspected once.
+func (c *requestCache) get(e *engine, t target, w *worker) (*preparedRequest, bool) {
+	key := requestKey{class: t.class, name: t.name, method: t.method, url: t.url, body: t.body, mix: w.mix}
+	if len(t.headers) > 0 {
+		key.headers = &t.headers[0]
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if p, ok := c.requests[key]; ok {
+		return p, p != nil
+	}
+	if !e.isStatic(t, w) {
+		c.requests[key] = nil
+		return nil, false
+	}
+	p, _, ok := e.prepareScoped(t, w, newRenderScope(e.feed, nil, w.random))
+	if !ok {
+		return nil, false
+	}
+	req, err := http.NewRequest(t.method, p.url, bytes.NewReader(p.body))
+	if err != nil {
+		return nil, false
+	}
+	for name, values := range p.header {
+		req.Header[name] = values
+	}
+	req.Host = p.host
+	p.request = req
+	c.requests[key] = p
+	return p, true
+}
+
+// isStatic reports whether the URL, headers and body of t sent by w are the same for
+// every request.
+func (e *engine) isStatic(t target, w *worker) bool {
+	source, err := e.urlSource(t.url)
+	if err != nil || !source.static() {
+		return false
+	}
+	for _, h := range e.headers {
+		if !h.value.static() {
+			return false
+		}
+	}
+	for _, h := range t.headers {
+		if !h.value.static() {
+			return false
+		}
+	}
+	if !t.withBody {
+		return true
+	}
+	switch {
+	case t.body != nil:
+		return t.body.static() && (t.step || !e.randomizesBody(t.bodyType, t.body.raw))
+	case len(e.form) > 0:
+		for _, field := range e.form {
+			if !field.value.static() {
+				return false
+			}
+		}
+		return true
+	case w.body != nil:
+		return w.body.static() && !e.randomizesBody(e.bodyType, w.body.raw)
+	}
+	return true
+}
@@ -0,0 +1,87 @@
+package loadtest
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRequestCache(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   Config
+		body     string
+		distinct int
+	}{
+		{name: "static body", body: `{"id": 1}`, distinct: 1},
+		{name: "templated body", body: `{"id": {{seq}}}`, distinct: 20},
+		{name: "random field", config: Config{RandFields: []string{"id=string:16"}, Rerandomize: true}, body: `{"id": 1}`, distinct: 20},
+		{name: "raw body with a random field", config: Config{RandFields: []string{"id=string:16"}, RawBody: true}, body: `{"id": 1}`, distinct: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			bodies := make(map[string]int)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				mu.Lock()
+				defer mu.Unlock()
+				bodies[r.Header.Get("X-Static")+" "+string(body)]++
+			}))
+			defer srv.Close()
+
+			cfg := tt.config
+			cfg.SummaryOnly = true
+			summary, err := New(
+				WithConfig(cfg),
+				WithURL(srv.URL),
+				WithMethod(http.MethodPost),
+				WithHeader("Content-Type", "application/json"),
+				WithHeader("X-Static", "1"),
+				WithBody(tt.body),
+				WithConcurrency(4),
+				WithRequests(20),
+			).Run(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if summary.Successful != 20 {
+				t.Errorf("Successful = %d, want 20", summary.Successful)
+			}
+			sent := 0
+			for body, n := range bodies {
+				if !strings.HasPrefix(body, `1 {"id":`) || !strings.HasSuffix(body, "}") {
+					t.Errorf("a request was sent with %q, want the headers and the whole body", body)
+				}
+				sent += n
+			}
+			if len(bodies) != tt.distinct || sent != 20 {
+				t.Errorf("%d requests with %d distinct bodies %q, want 20 with %d", sent, len(bodies), bodies, tt.distinct)
+			}
+		})
+	}
+}
+
+func TestNewRequestCache(t *testing.T) {
+	tests := []struct {
+		name   string
+		e      *engine
+		cached bool
+	}{
+		{name: "targets only", e: &engine{}, cached: true},
+		{name: "data rows", e: &engine{data: &dataFeed{}}},
+		{name: "header rotation", e: &engine{rotations: []*headerRotation{{}}}},
+		{name: "multipart fields", e: &engine{multipartFields: []formField{{}}}},
+		{name: "multipart files", e: &engine{multipartFiles: []multipartFile{{}}}},
+		{name: "before_request hook", e: &engine{script: &script{before: true}}},
+	}
+	for _, tt := range tests {
+		if c := newRequestCache(tt.e); (c != nil) != tt.cached {
+			t.Errorf("%s: newRequestCache() = %v, want cached %v", tt.name, c, tt.cached)
+		}
+	}
+}
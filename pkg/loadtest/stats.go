@@ -0,0 +1,399 @@
+package loadtest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// stats aggregates the results of the requests sent during a run.
+type stats struct {
+	statusCodeCount    map[int]int
+	networkErrorCount  int
+	reusedConnCount    int
+	newConnCount       int
+	feedMissCount      int
+	dataExhaustedCount int
+	droppedCount       int
+	truncatedCount     int
+
+	drainedCount          int
+	drainCancelledCount   int
+	abandonedCount        int
+	hedgedCount           int
+	hedgeWins             int
+	warmupCount           int
+	unexpectedStatusCount int
+	checksPassed          []int
+	checksFailed          []int
+	schemaChecked         int
+	schemaFailed          int
+	schemaProblems        map[string]int
+	scriptChecked         int
+	scriptFailed          int
+	scriptFailures        map[string]int
+	totalLatency          time.Duration
+	latencyRange          histogramRange
+	latencies             *latencyHistogram
+	latencyByStatus       map[int]*latencyHistogram
+	phases                [len(timingPhases)]*latencyHistogram
+
+	bodyBytes            int64
+	bodyBytesWire        int64
+	responseBytesWire    int64
+	responseBytesDecoded int64
+	bytesSent            int64
+	bytesReceived        int64
+	requestSizes         sizeDistribution
+	responseSizes        sizeDistribution
+
+	connsByFamily    map[string]int
+	attemptsByFamily map[string]int
+
+	servedBy         map[string]int
+	failedOverCount  int
+	failoverAttempts int
+	failoverDelay    time.Duration
+	retriedCount     int
+	retryAttempts    int
+	retryDelay       time.Duration
+}
+
+// newStats returns an empty stats aggregate recording latencies with the range r.
+func newStats(r histogramRange) *stats {
+	return &stats{
+		latencyRange:     r,
+		statusCodeCount:  make(map[int]int),
+		connsByFamily:    make(map[string]int),
+		attemptsByFamily: make(map[string]int),
+		servedBy:         make(map[string]int),
+		schemaProblems:   make(map[string]int),
+		scriptFailures:   make(map[string]int),
+		latencies:        newLatencyHistogram(r),
+		latencyByStatus:  make(map[int]*latencyHistogram),
+		requestSizes:     newSizeDistribution(),
+		responseSizes:    newSizeDistribution(),
+	}
+}
+
+// add records a single request result.
+func (s *stats) add(res requestResult) {
+	s.totalLatency += res.latency
+	if !res.skipped() {
+		s.latencies.record(res.latency)
+		for i, d := range res.timing.durations() {
+			if d <= 0 {
+				continue
+			}
+			if s.phases[i] == nil {
+				s.phases[i] = newLatencyHistogram(s.latencyRange)
+			}
+			s.phases[i].record(d)
+		}
+	}
+	s.bodyBytes += res.bodyBytes
+	s.bodyBytesWire += res.bodyBytesWire
+	s.responseBytesWire += res.responseBytesWire
+	s.responseBytesDecoded += res.responseBytesDecoded
+	if res.failovers > 0 {
+		s.failedOverCount++
+		s.failoverAttempts += res.failovers
+		s.failoverDelay += res.failoverDelay
+	}
+	if res.retries > 0 {
+		s.retriedCount++
+		s.retryAttempts += res.retries
+		s.retryDelay += res.retryDelay
+	}
+	if res.bodyTruncated {
+		s.truncatedCount++
+	}
+	if res.drained {
+		s.drainedCount++
+	}
+	if res.unexpectedStatus {
+		s.unexpectedStatusCount++
+	}
+	if res.hedged {
+		s.hedgedCount++
+	}
+	if res.hedgeWon {
+		s.hedgeWins++
+	}
+	if res.schemaChecked {
+		s.schemaChecked++
+		if len(res.schemaProblems) > 0 {
+			s.schemaFailed++
+		}
+		for _, problem := range uniqueSchemaProblems(res.schemaProblems) {
+			s.schemaProblems[problem]++
+		}
+	}
+	if res.scriptChecked {
+		s.scriptChecked++
+		if res.scriptFailure != "" {
+			s.scriptFailed++
+			s.scriptFailures[res.scriptFailure]++
+		}
+	}
+	for i, passed := range res.checks {
+		for len(s.checksPassed) <= i {
+			s.checksPassed = append(s.checksPassed, 0)
+			s.checksFailed = append(s.checksFailed, 0)
+		}
+		if passed {
+			s.checksPassed[i]++
+		} else {
+			s.checksFailed[i]++
+		}
+	}
+	if res.servedBy != "" {
+		s.servedBy[res.servedBy]++
+	}
+	for family, count := range res.connectAttempts {
+		s.attemptsByFamily[family] += count
+	}
+	if !res.reused && res.remoteAddr != "" {
+		s.connsByFamily[addressFamily(res.remoteAddr)]++
+	}
+	switch {
+	case res.feedMiss:
+		s.feedMissCount++
+	case res.dataExhausted:
+		s.dataExhaustedCount++
+	case res.arrivalDropped:
+		s.droppedCount++
+	case res.drainCancelled:
+		s.drainCancelledCount++
+	case res.abandoned:
+		s.abandonedCount++
+	case res.statusCode == -1:
+		s.networkErrorCount++
+	default:
+		s.statusCodeCount[res.statusCode]++
+		s.bytesSent += res.bytesSent()
+		s.bytesReceived += res.bytesReceived()
+		s.requestSizes.record(res.bytesSent())
+		s.responseSizes.record(res.bytesReceived())
+		if s.latencyByStatus[res.statusCode] == nil {
+			s.latencyByStatus[res.statusCode] = newLatencyHistogram(s.latencyRange)
+		}
+		s.latencyByStatus[res.statusCode].record(res.latency)
+		if res.reused {
+			s.reusedConnCount++
+		} else {
+			s.newConnCount++
+		}
+	}
+}
+
+// tally aggregates the measured results of a run overall, per workload class, target,
+// client profile, region, rotated header value and --spike window, and per second, and keeps the slowest
+// requests. The timeline only records once its start is set.
+type tally struct {
+	all       *stats
+	byClass   map[string]*stats
+	byTarget  map[string]*stats
+	byProfile map[string]*stats
+	byRegion  map[string]*stats
+	byWindow  map[string]*stats
+	slow      *slowTracker
+	rotated   rotationStats
+	readBacks readBackStats
+	timeline  timeline
+}
+
+// newTally returns an empty tally recording latencies with the range r and keeping the
+// slowTop requests over slowThreshold.
+func newTally(r histogramRange, slowThreshold time.Duration, slowTop int) *tally {
+	return &tally{
+		all:       newStats(r),
+		byClass:   make(map[string]*stats),
+		byTarget:  make(map[string]*stats),
+		byProfile: make(map[string]*stats),
+		byRegion:  make(map[string]*stats),
+		byWindow:  make(map[string]*stats),
+		slow:      newSlowTracker(slowThreshold, slowTop),
+		rotated:   rotationStats{},
+	}
+}
+
+// add records a measured result in every aggregate it belongs to.
+func (t *tally) add(res requestResult) {
+	t.all.add(res)
+	t.addTo(t.byClass, res.class, res)
+	t.addTo(t.byTarget, res.target, res)
+	t.addTo(t.byProfile, res.profile, res)
+	t.addTo(t.byRegion, res.region, res)
+	t.addTo(t.byWindow, res.window, res)
+	t.slow.add(res)
+	t.rotated.add(t.all.latencyRange, res)
+	t.readBacks.add(t.all.latencyRange, res)
+	t.timeline.add(res)
+}
+
+// addTo records res in the stats of key, unless key is empty.
+func (t *tally) addTo(byKey map[string]*stats, key string, res requestResult) {
+	if key == "" {
+		return
+	}
+	if byKey[key] == nil {
+		byKey[key] = newStats(t.all.latencyRange)
+	}
+	byKey[key].add(res)
+}
+
+// total returns the number of requests recorded, including skipped ones.
+func (s *stats) total() int {
+	total := s.networkErrorCount + s.drainCancelledCount + s.abandonedCount + s.skippedCount()
+	for _, count := range s.statusCodeCount {
+		total += count
+	}
+	return total
+}
+
+// successCount returns the number of requests answered with a 2xx status code.
+func (s *stats) successCount() int {
+	success := 0
+	for status, count := range s.statusCodeCount {
+		if status >= 200 && status < 300 {
+			success += count
+		}
+	}
+	return success
+}
+
+// failedCount returns the number of requests that failed with a network error or a
+// 4xx/5xx response.
+func (s *stats) failedCount() int {
+	failed := s.networkErrorCount
+	for status, count := range s.statusCodeCount {
+		if status >= 400 {
+			failed += count
+		}
+	}
+	return failed
+}
+
+// sentCount returns the number of requests that were sent, leaving out skipped ones.
+func (s *stats) sentCount() int {
+	return s.total() - s.skippedCount()
+}
+
+// skippedCount returns the number of requests that were not sent at all.
+func (s *stats) skippedCount() int {
+	return s.feedMissCount + s.dataExhaustedCount + s.droppedCount
+}
+
+// averageLatency returns the mean latency of the requests that were sent.
+func (s *stats) averageLatency() time.Duration {
+	sent := s.sentCount()
+	if sent == 0 {
+		return 0
+	}
+	return s.totalLatency / time.Duration(sent)
+}
+
+// percentile returns the latency below which the fraction q of the sent requests
+// completed, e.g. 0.99 for p99, to the precision of the latency histogram.
+func (s *stats) percentile(q float64) time.Duration {
+	return s.latencies.percentile(q)
+}
+
+// generateClassReport prints a breakdown of the results per workload class.
+// Nothing is printed for runs that do not use workload classes.
+func generateClassReport(classStats map[string]*stats) {
+	if len(classStats) == 0 {
+		return
+	}
+	color.Green("\n===== ⚖️ Per-class Breakdown =====")
+	printBreakdown(classStats)
+}
+
+// generateTargetReport prints the same breakdown per --url target when several are mixed.
+func generateTargetReport(targetStats map[string]*stats) {
+	if len(targetStats) < 2 {
+		return
+	}
+	color.Green("\n===== 🎯 Per-target Breakdown =====")
+	printBreakdown(targetStats)
+}
+
+// generateProfileReport prints the same breakdown per --client-mix profile.
+func generateProfileReport(profileStats map[string]*stats) {
+	if len(profileStats) == 0 {
+		return
+	}
+	color.Green("\n===== 📱 Per-client Breakdown =====")
+	printBreakdown(profileStats)
+}
+
+// generateAbandonmentReport prints how many requests the client gave up on.
+func generateAbandonmentReport(giveUpAfter time.Duration, total int, st *stats) {
+	rate := 0.0
+	if total > 0 {
+		rate = float64(st.abandonedCount) * 100 / float64(total)
+	}
+	color.Yellow("\n🏃 Abandoned by the client after %v: %d (%.2f%% of requests)", giveUpAfter, st.abandonedCount, rate)
+}
+
+// printBreakdown prints one summary line per key, in alphabetical order.
+func printBreakdown(byKey map[string]*stats) {
+	keys := make([]string, 0, len(byKey))
+	for key := range byKey {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		st := byKey[key]
+		fmt.Printf("🔹 %s: %d requests, %d successful (2xx), %d other statuses, %d network errors, avg latency %v\n",
+			key, st.sentCount(), st.successCount(), st.sentCount()-st.successCount()-st.networkErrorCount,
+			st.networkErrorCount, st.averageLatency())
+	}
+}
+
+// generateCompressionReport prints the request and response byte counts on the wire
+// and uncompressed, so the effect of compression can be evaluated.
+func generateCompressionReport(st *stats) {
+	color.Green("\n===== 🗜️ Compression =====")
+	fmt.Printf("📤 Request bodies: %d bytes uncompressed, %d bytes sent%s\n",
+		st.bodyBytes, st.bodyBytesWire, ratioSuffix(st.bodyBytes, st.bodyBytesWire))
+	fmt.Printf("📥 Response bodies: %d bytes received, %d bytes decompressed%s\n",
+		st.responseBytesWire, st.responseBytesDecoded, ratioSuffix(st.responseBytesDecoded, st.responseBytesWire))
+}
+
+// ratioSuffix formats the compression ratio between the uncompressed and compressed sizes.
+func ratioSuffix(uncompressed, compressed int64) string {
+	if compressed == 0 || uncompressed == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (ratio %.2fx)", float64(uncompressed)/float64(compressed))
+}
+
+// generateFamilyReport prints the address family of new connections and the dial
+// attempts per family, to debug dual-stack behavior.
+func generateFamilyReport(st *stats) {
+	if len(st.attemptsByFamily) == 0 {
+		return
+	}
+	fmt.Printf("🌐 New connections by address family: %s\n", formatFamilies(st.connsByFamily))
+	fmt.Printf("🌐 Connection attempts by address family: %s\n", formatFamilies(st.attemptsByFamily))
+}
+
+// formatFamilies renders per-family counts in a stable order.
+func formatFamilies(counts map[string]int) string {
+	parts := make([]string, 0, len(counts))
+	for _, family := range []string{"IPv4", "IPv6", "other"} {
+		if count, ok := counts[family]; ok {
+			parts = append(parts, fmt.Sprintf("%s %d", family, count))
+		}
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,153 @@
+package loadtest
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// statsdPacketSize is the largest packet sent to StatsD, which fits the MTU of most
+// networks once the IP and UDP headers are added.
+const statsdPacketSize = 1432
+
+// StatsDOptions sends the metrics of the measured requests to a StatsD server.
+type StatsDOptions struct {
+	// Addr is the host:port of the server, such as a Datadog agent.
+	Addr string
+	// Prefix starts the name of every metric.
+	Prefix string
+	// DogStatsD tags the metrics with the status, method and target of the requests and
+	// with Tags, instead of naming a counter after every status.
+	DogStatsD bool
+	Tags      []string
+}
+
+// ParseStatsDTags checks the --statsd-tag values, of the form key:value or key.
+func ParseStatsDTags(tags []string) ([]string, error) {
+	for _, tag := range tags {
+		if tag == "" || strings.ContainsAny(tag, ",|#@\n ") {
+			return nil, fmt.Errorf("%q is not a tag of the form key:value", tag)
+		}
+	}
+	return tags, nil
+}
+
+// statsdClient sends a counter and a timing for every measured request. Lines are
+// batched into packets, sent once full and on every tick. It is only used by the
+// collector goroutine.
+type statsdClient struct {
+	opts   StatsDOptions
+	conn   net.Conn
+	ticker *time.Ticker
+	packet []byte
+
+	failed  int
+	lastErr error
+}
+
+// newStatsDClient returns a client sending to opts.Addr, or nil when it is not set. UDP
+// is connectionless, so a server that is not listening only shows once packets are
+// sent.
+func newStatsDClient(opts StatsDOptions) (*statsdClient, error) {
+	if opts.Addr == "" {
+		return nil, nil
+	}
+	conn, err := net.Dial("udp", opts.Addr)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Prefix != "" && !strings.HasSuffix(opts.Prefix, ".") {
+		opts.Prefix += "."
+	}
+	return &statsdClient{opts: opts, conn: conn, ticker: time.NewTicker(time.Second)}, nil
+}
+
+// tick returns the channel the client flushes on, or nil for a nil client, which never
+// ticks.
+func (c *statsdClient) tick() <-chan time.Time {
+	if c == nil {
+		return nil
+	}
+	return c.ticker.C
+}
+
+// add sends the metrics of a measured result: the requests and errors counters and the
+// latency timing, and without DogStatsD a counter named after its status.
+func (c *statsdClient) add(res requestResult) {
+	if c == nil || res.skipped() {
+		return
+	}
+	status := strconv.Itoa(res.statusCode)
+	if res.statusCode == -1 {
+		status = "network_error"
+	}
+	var tags string
+	if c.opts.DogStatsD {
+		list := append([]string{}, c.opts.Tags...)
+		list = append(list, "status:"+status, "method:"+statsdTagValue(res.method))
+		if res.target != "" {
+			list = append(list, "target:"+statsdTagValue(res.target))
+		}
+		tags = "|#" + strings.Join(list, ",")
+	}
+	c.write("requests:1|c" + tags)
+	if res.statusCode == -1 || res.statusCode >= 400 {
+		c.write("errors:1|c" + tags)
+	}
+	c.write("latency:" + strconv.FormatFloat(milliseconds(res.latency), 'f', 3, 64) + "|ms" + tags)
+	if !c.opts.DogStatsD {
+		c.write("status." + status + ":1|c")
+	}
+}
+
+// write adds a metric line to the packet, sending the packet first when the line does
+// not fit in it.
+func (c *statsdClient) write(metric string) {
+	line := c.opts.Prefix + metric
+	if len(c.packet) > 0 && len(c.packet)+1+len(line) > statsdPacketSize {
+		c.flush()
+	}
+	if len(c.packet) > 0 {
+		c.packet = append(c.packet, '\n')
+	}
+	c.packet = append(c.packet, line...)
+}
+
+// flush sends the metrics batched so far.
+func (c *statsdClient) flush() {
+	if c == nil || len(c.packet) == 0 {
+		return
+	}
+	if _, err := c.conn.Write(c.packet); err != nil {
+		c.failed++
+		c.lastErr = err
+	}
+	c.packet = c.packet[:0]
+}
+
+// stop sends the last metrics and closes the connection. It returns an error when
+// packets could not be sent.
+func (c *statsdClient) stop() error {
+	if c == nil {
+		return nil
+	}
+	c.ticker.Stop()
+	c.flush()
+	c.conn.Close()
+	if c.failed > 0 {
+		return fmt.Errorf("%d packets could not be sent to %s: %w", c.failed, c.opts.Addr, c.lastErr)
+	}
+	return nil
+}
+
+// statsdTagValue replaces the characters of value that DogStatsD reads as separators.
+func statsdTagValue(value string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(",|#@ \n", r) {
+			return '_'
+		}
+		return r
+	}, value)
+}
@@ -0,0 +1,96 @@
+package loadtest
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/fatih/color"
+)
+
+// statusClasses are the status classes reported, in order.
+var statusClasses = []string{"1xx", "2xx", "3xx", "4xx", "5xx"}
+
+// statusClass returns the class of an HTTP status code, such as "5xx".
+func statusClass(status int) string {
+	return fmt.Sprintf("%dxx", status/100)
+}
+
+// LatencyPercentiles holds the latency percentiles of a group of requests, such as the
+// responses with one status code.
+type LatencyPercentiles struct {
+	Requests     int64   `json:"requests"`
+	P50LatencyMs float64 `json:"p50_latency_ms"`
+	P90LatencyMs float64 `json:"p90_latency_ms"`
+	P95LatencyMs float64 `json:"p95_latency_ms"`
+	P99LatencyMs float64 `json:"p99_latency_ms"`
+	MaxLatencyMs float64 `json:"max_latency_ms"`
+}
+
+// statusClassCounts returns the number of responses per status class. Classes without
+// responses are left out.
+func (s *stats) statusClassCounts() map[string]int {
+	classes := make(map[string]int)
+	for status, h := range s.latencyByStatus {
+		classes[statusClass(status)] += int(h.count())
+	}
+	return classes
+}
+
+// newLatencyPercentiles returns the percentiles of the latencies recorded in h.
+func newLatencyPercentiles(h *latencyHistogram) LatencyPercentiles {
+	return LatencyPercentiles{
+		Requests:     h.count(),
+		P50LatencyMs: milliseconds(h.percentile(0.50)),
+		P90LatencyMs: milliseconds(h.percentile(0.90)),
+		P95LatencyMs: milliseconds(h.percentile(0.95)),
+		P99LatencyMs: milliseconds(h.percentile(0.99)),
+		MaxLatencyMs: milliseconds(h.percentile(1)),
+	}
+}
+
+// statusLatencies returns the latency percentiles of the responses per status code.
+func (s *stats) statusLatencies() map[int]LatencyPercentiles {
+	latencies := make(map[int]LatencyPercentiles, len(s.latencyByStatus))
+	for status, h := range s.latencyByStatus {
+		latencies[status] = newLatencyPercentiles(h)
+	}
+	return latencies
+}
+
+// generateStatusReport prints the responses per status class and the latency
+// percentiles of every status code, since error responses often take much less, or much
+// more, time than successful ones and the overall percentiles mix them up.
+func generateStatusReport(st *stats) {
+	if len(st.latencyByStatus) == 0 {
+		return
+	}
+	color.Green("\n===== 🚦 Status Codes =====")
+	classes := st.statusClassCounts()
+	total := 0
+	for _, count := range classes {
+		total += count
+	}
+	for _, class := range statusClasses {
+		if count := classes[class]; count > 0 {
+			fmt.Printf("%s: %d (%.2f%%)\n", class, count, float64(count)/float64(total)*100)
+		}
+	}
+
+	latencies := st.statusLatencies()
+	codes := make([]int, 0, len(latencies))
+	for code := range latencies {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	fmt.Println()
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "Status\tRequests\tp50\tp90\tp95\tp99\tMax\t")
+	for _, code := range codes {
+		l := latencies[code]
+		fmt.Fprintf(tw, "%d\t%d\t%s\t%s\t%s\t%s\t%s\t\n", code, l.Requests, formatMs(l.P50LatencyMs),
+			formatMs(l.P90LatencyMs), formatMs(l.P95LatencyMs), formatMs(l.P99LatencyMs), formatMs(l.MaxLatencyMs))
+	}
+	tw.Flush()
+}
@@ -0,0 +1,152 @@
+package loadtest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// stepCapture is a capture=name:path option of a setup or teardown step: the field at
+// path in the JSON response of the step becomes the variable name, read by the templates
+// of later requests with {{var "name"}}.
+type stepCapture struct {
+	name string
+	path string
+}
+
+// parseStepCapture parses the value of a capture option, such as token:data.access_token.
+func parseStepCapture(value string) (stepCapture, error) {
+	name, path, ok := strings.Cut(value, ":")
+	if !ok || name == "" || path == "" {
+		return stepCapture{}, fmt.Errorf("expected name:path, got %q", value)
+	}
+	return stepCapture{name: name, path: path}, nil
+}
+
+// sessionPhase is what a run of a stepSession does.
+type sessionPhase int
+
+const (
+	// sessionSetup only sends the setup steps and keeps the variables they capture.
+	sessionSetup sessionPhase = iota
+	// sessionChecks sends the load with the variables of the setup, and no steps.
+	sessionChecks
+	// sessionTeardown only sends the teardown steps.
+	sessionTeardown
+)
+
+// stepSession shares the setup and teardown steps of a scenario among several runs, so
+// that the checks of RunMonitor do not create and delete their entities every time.
+type stepSession struct {
+	phase sessionPhase
+	vars  map[string]string
+}
+
+// loadSteps reads the setup and teardown steps of cfg, which are targets files. Captures
+// are only supported on steps, since the load phase reads the variables they set.
+func loadSteps(cfg Config) (setup, teardown []WeightedTarget, err error) {
+	for _, t := range cfg.Targets {
+		if len(t.options.captures) > 0 {
+			return nil, nil, fmt.Errorf("target %s: capture is only supported in setup and teardown steps", t.name)
+		}
+	}
+	if cfg.SetupPath != "" {
+		if setup, err = LoadTargetsFile(cfg.SetupPath, cfg.RawBody); err != nil {
+			return nil, nil, fmt.Errorf("error loading setup steps: %w", err)
+		}
+	}
+	if cfg.TeardownPath != "" {
+		if teardown, err = LoadTargetsFile(cfg.TeardownPath, cfg.RawBody); err != nil {
+			return nil, nil, fmt.Errorf("error loading teardown steps: %w", err)
+		}
+	}
+	for _, steps := range [][]WeightedTarget{setup, teardown} {
+		for i := range steps {
+			steps[i].step = true
+		}
+	}
+	return setup, teardown, nil
+}
+
+// newStepWorker returns the worker sending the steps of a phase. Its cookies are kept
+// from one step to the next, so a step can log in for the ones after it.
+func (e *engine) newStepWorker(phase string) *worker {
+	w := &worker{randomValues: make(map[string]interface{}), random: e.random.stream(phase)}
+	if e.cfg.Cookies == CookiesPerVU {
+		w.jar = newCookieJar()
+	}
+	return w
+}
+
+// runSetup sends the setup steps once each, in order, before the load. It stops at the
+// first step that fails, and the run is not started. Steps are left out of the report.
+func (e *engine) runSetup(ctx context.Context, steps []WeightedTarget) error {
+	e.cfg.log().Info("Running setup steps", "count", len(steps))
+	w := e.newStepWorker("setup")
+	for i, step := range steps {
+		if ctx.Err() != nil {
+			return fmt.Errorf("setup stopped after %d of %d steps", i, len(steps))
+		}
+		if err := e.runStep(step.target, w); err != nil {
+			return fmt.Errorf("setup step %s failed: %w", step.name, err)
+		}
+	}
+	return nil
+}
+
+// runTeardown sends the teardown steps once each, in order, after the load. Failed steps
+// are reported and the next ones still run, so that as much as possible is cleaned up.
+func (e *engine) runTeardown(steps []WeightedTarget) {
+	e.cfg.log().Info("Running teardown steps", "count", len(steps))
+	w := e.newStepWorker("teardown")
+	failed := 0
+	for _, step := range steps {
+		if err := e.runStep(step.target, w); err != nil {
+			failed++
+			e.cfg.log().Warn("Teardown step failed", "step", step.name, "error", err)
+		}
+	}
+	if failed > 0 {
+		e.cfg.log().Warn("Teardown steps failed", "failed", failed, "count", len(steps))
+	}
+}
+
+// runStep sends a single step and stores the variables it captures. A step fails on a
+// network error, a status that is not expected (4xx and 5xx without expected statuses)
+// or a capture missing from its response.
+func (e *engine) runStep(t target, w *worker) error {
+	res := e.sendRequest(t, w)
+	e.cfg.log().Debug("Step sent", "method", t.method, "url", t.url, "status", res.statusCode, "latency", res.latency)
+	expected := expectedStatusesOf(res, e.cfg.ExpectStatus)
+	switch {
+	case res.feedMiss:
+		return errFeedEmpty
+	case res.dataExhausted:
+		return errors.New("the data file has no rows left")
+	case res.statusCode <= 0:
+		// sendRequest already reported why.
+		return errors.New("the request could not be sent")
+	case unexpectedStatus(res, expected), len(expected) == 0 && res.statusCode >= 400:
+		return fmt.Errorf("status %d", res.statusCode)
+	}
+	if len(t.options.captures) == 0 {
+		return nil
+	}
+	if res.bodyTruncated {
+		return errors.New("the response is larger than --max-body")
+	}
+	var doc interface{}
+	if err := json.Unmarshal(res.responseBody, &doc); err != nil {
+		return fmt.Errorf("the response is not JSON: %v", err)
+	}
+	for _, c := range t.options.captures {
+		value, ok := lookupJSONPath(doc, c.path)
+		if !ok {
+			return fmt.Errorf("the response has no %s to capture as %s", c.path, c.name)
+		}
+		e.vars[c.name] = stringifyJSONValue(value)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package loadtest
+
+import (
+	"io"
+	"os"
+	"time"
+)
+
+// streamWindow is the number of seconds the percentiles of a stream line cover, so that
+// they do not jump with every slow request of a quiet second.
+const streamWindow = 10
+
+// StreamRecord is a line of the --stream output, written every second of the run: the
+// rate and error rate of the last second, percentiles over the last streamWindow seconds
+// and the totals since the start.
+type StreamRecord struct {
+	Time              time.Time `json:"time"`
+	ElapsedS          float64   `json:"elapsed_s"`
+	RequestsPerSecond float64   `json:"rps"`
+	ErrorRate         float64   `json:"error_rate"`
+	P50LatencyMs      float64   `json:"p50_latency_ms"`
+	P95LatencyMs      float64   `json:"p95_latency_ms"`
+	P99LatencyMs      float64   `json:"p99_latency_ms"`
+	Requests          int       `json:"requests"`
+	Errors            int       `json:"errors"`
+}
+
+// statsStream keeps the requests and latencies of the last seconds for the stream lines.
+// It is only used by the collector goroutine.
+type statsStream struct {
+	ticker *time.Ticker
+	out    *ndjsonLog
+	start  time.Time
+	last   time.Time
+	rng    histogramRange
+
+	// seconds holds the latencies of the last streamWindow seconds, the current one at
+	// current.
+	seconds  [streamWindow]*latencyHistogram
+	current  int
+	requests int
+	errors   int
+}
+
+// newStatsStream returns a stream writing a line to out every second from start, with
+// latencies in the range r, or nil when out is nil.
+func newStatsStream(start time.Time, out *ndjsonLog, r histogramRange) *statsStream {
+	if out == nil {
+		return nil
+	}
+	s := &statsStream{ticker: time.NewTicker(time.Second), out: out, start: start, last: start, rng: r}
+	for i := range s.seconds {
+		s.seconds[i] = newLatencyHistogram(s.rng)
+	}
+	return s
+}
+
+// tick returns the channel the stream ticks on, or nil for a nil stream, which never
+// ticks.
+func (s *statsStream) tick() <-chan time.Time {
+	if s == nil {
+		return nil
+	}
+	return s.ticker.C
+}
+
+// add records a measured result in the current second.
+func (s *statsStream) add(res requestResult) {
+	if s == nil || res.skipped() {
+		return
+	}
+	s.requests++
+	if res.statusCode == -1 || res.statusCode >= 400 {
+		s.errors++
+	}
+	s.seconds[s.current].record(res.latency)
+}
+
+// write writes the line of the second ending at now, with the totals of all, and starts
+// the next second.
+func (s *statsStream) write(now time.Time, all *stats) {
+	window := newLatencyHistogram(s.rng)
+	for _, h := range s.seconds {
+		window.merge(h)
+	}
+	rec := StreamRecord{
+		Time:              now.UTC(),
+		ElapsedS:          now.Sub(s.start).Seconds(),
+		RequestsPerSecond: requestsPerSecond(s.requests, now.Sub(s.last)),
+		P50LatencyMs:      milliseconds(window.percentile(0.50)),
+		P95LatencyMs:      milliseconds(window.percentile(0.95)),
+		P99LatencyMs:      milliseconds(window.percentile(0.99)),
+		Requests:          all.sentCount(),
+		Errors:            all.failedCount(),
+	}
+	if s.requests > 0 {
+		rec.ErrorRate = float64(s.errors) / float64(s.requests)
+	}
+	s.out.write(rec)
+
+	s.last, s.requests, s.errors = now, 0, 0
+	s.current = (s.current + 1) % streamWindow
+	s.seconds[s.current] = newLatencyHistogram(s.rng)
+}
+
+// stop stops the stream and closes its output.
+func (s *statsStream) stop() error {
+	if s == nil {
+		return nil
+	}
+	s.ticker.Stop()
+	return s.out.close()
+}
+
+// standardOutput is the standard output of the process as it started. The command moves
+// its console output to the standard error while streaming to the standard output, so
+// the stream must not follow os.Stdout.
+var standardOutput io.Writer = os.Stdout
+
+// stdoutStream is the output of --stream -: the standard output, left open when the
+// stream is closed.
+type stdoutStream struct {
+	io.Writer
+}
+
+// Close does nothing.
+func (stdoutStream) Close() error {
+	return nil
+}
@@ -0,0 +1,99 @@
+package loadtest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fatih/color"
+)
+
+// sweepLevel is the outcome of the run at one concurrency level of a sweep.
+type sweepLevel struct {
+	concurrency int
+	summary     Result
+}
+
+// ParseConcurrencyLevels parses a comma-separated list of concurrency levels such as
+// "1,10,50,100".
+func ParseConcurrencyLevels(value string) ([]int, error) {
+	var levels []int
+	for _, item := range SplitList(value) {
+		level, err := strconv.Atoi(item)
+		if err != nil || level < 1 {
+			return nil, fmt.Errorf("invalid concurrency level %q", item)
+		}
+		levels = append(levels, level)
+	}
+	return levels, nil
+}
+
+// RunSweep runs the load test once per concurrency level, back to back, and
+// prints a single table comparing throughput and latency across levels. The sweep stops
+// early when ctx is done or a run is aborted. It returns an error when a level could not
+// be run.
+func RunSweep(ctx context.Context, cfg Config, levels []int) error {
+	cfg.SummaryOnly = true
+	var results []sweepLevel
+	for _, level := range levels {
+		cfg.Concurrency = level
+		cfg.log().Info("Running the sweep level", "concurrency", level)
+		summary, err := RunContext(ctx, cfg)
+		if err != nil {
+			return fmt.Errorf("sweep level %d: %w", level, err)
+		}
+		results = append(results, sweepLevel{concurrency: level, summary: summary})
+		if summary.Interrupted || summary.Aborted != "" {
+			cfg.log().Warn("Stopping the sweep", "concurrency", level)
+			break
+		}
+	}
+	knee := findKnee(results)
+	generateSweepReport(results, knee)
+	for _, path := range cfg.CurveOutputs {
+		if err := writeCurve(path, cfg.Output, results, knee); err != nil {
+			cfg.log().Error("Error writing curve", "path", path, "error", err)
+			continue
+		}
+		color.Cyan("📈 Latency vs throughput curve written to %s", path)
+	}
+	return nil
+}
+
+// generateSweepReport prints the requests per second and latency percentiles of every
+// level of a sweep, and the saturation knee when one was found.
+func generateSweepReport(results []sweepLevel, knee int) {
+	color.Green("\n===== 📈 Concurrency Sweep =====")
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "Concurrency\tRequests\tErrors\tRPS\tp50\tp90\tp95\tp99\tMax\t")
+	for _, level := range results {
+		s := level.summary
+		fmt.Fprintf(tw, "%d\t%d\t%s\t%.2f\t%s\t%s\t%s\t%s\t%s\t\n", level.concurrency, s.Requests,
+			formatErrorRate(s), s.RequestsPerSecond, formatMs(s.P50LatencyMs), formatMs(s.P90LatencyMs),
+			formatMs(s.P95LatencyMs), formatMs(s.P99LatencyMs), formatMs(s.MaxLatencyMs))
+	}
+	tw.Flush()
+	if knee >= 0 {
+		s := results[knee].summary
+		color.Yellow("📍 Saturation knee at concurrency %d: %.2f RPS, p99 %s", results[knee].concurrency, s.RequestsPerSecond, formatMs(s.P99LatencyMs))
+	}
+}
+
+// formatErrorRate returns the share of the requests of a run that failed.
+func formatErrorRate(s Result) string {
+	if s.Requests == 0 {
+		return "-"
+	}
+	return strings.TrimSuffix(fmt.Sprintf("%.1f", float64(s.failed())*100/float64(s.Requests)), ".0") + "%"
+}
+
+// formatMs renders a latency in milliseconds with a precision suited to its magnitude.
+func formatMs(ms float64) string {
+	if ms < 10 {
+		return fmt.Sprintf("%.2fms", ms)
+	}
+	return fmt.Sprintf("%.0fms", ms)
+}
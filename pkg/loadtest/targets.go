@@ -0,0 +1,148 @@
+package loadtest
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// MergeTargets returns the --url targets followed by the targets of a targets file. Next
+// to a targets file, the --url targets only send the body when their method does.
+func MergeTargets(urlTargets, fileTargets []WeightedTarget) []WeightedTarget {
+	for i := range urlTargets {
+		urlTargets[i].withBody = sendsBody(urlTargets[i].method)
+	}
+	return append(urlTargets, fileTargets...)
+}
+
+// LoadTargetsFile reads targets in the format used by Vegeta: every target starts with a
+// "METHOD URL" line, optionally followed by header lines ("Name: value") and a body
+// reference ("@path/to/body", relative to the targets file). A target ends at a blank
+// line or at the "METHOD URL" line of the next one, and lines starting with # are
+// comments. Every target gets a weight of 1,
+// and the URL may be followed by the options of ParseTargets. With rawBodies, bodies are
+// never rendered as templates.
+func LoadTargetsFile(path string, rawBodies bool) ([]WeightedTarget, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []WeightedTarget
+	var current *WeightedTarget
+	var headerLines []string
+	flush := func() error {
+		if current == nil {
+			return nil
+		}
+		headers, err := parseHeaders(headerLines)
+		if err != nil {
+			return err
+		}
+		current.headers = headers
+		targets = append(targets, *current)
+		current, headerLines = nil, nil
+		return nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(text, "#"):
+			continue
+		case text == "":
+			if err := flush(); err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+			}
+		case current == nil || isTargetLine(text):
+			if err := flush(); err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+			}
+			words := targetFields(text)
+			if len(words) < 2 {
+				return nil, fmt.Errorf("%s:%d: expected METHOD URL, got %q", path, line, text)
+			}
+			method, url := strings.ToUpper(words[0]), words[1]
+			options, err := parseTargetOptions(words[2:])
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+			}
+			current = &WeightedTarget{
+				target: target{name: method + " " + url, method: method, url: url, withBody: sendsBody(method), options: options},
+				weight: 1,
+			}
+		case strings.HasPrefix(text, "@"):
+			bodyPath := strings.TrimPrefix(text, "@")
+			if !filepath.IsAbs(bodyPath) {
+				bodyPath = filepath.Join(filepath.Dir(path), bodyPath)
+			}
+			if err := current.loadBody(bodyPath, rawBodies); err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+			}
+		default:
+			headerLines = append(headerLines, text)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("%s: no targets", path)
+	}
+	return targets, nil
+}
+
+// targetMethods are the methods a line of a targets file starts with to open a target.
+var targetMethods = map[string]bool{
+	"GET": true, "HEAD": true, "POST": true, "PUT": true, "PATCH": true,
+	"DELETE": true, "OPTIONS": true, "TRACE": true, "CONNECT": true,
+}
+
+// isTargetLine reports whether a line of a targets file is the METHOD URL line of a
+// target rather than a header, which cannot have a space before its colon.
+func isTargetLine(text string) bool {
+	method, rest, ok := strings.Cut(text, " ")
+	return ok && targetMethods[strings.ToUpper(method)] && strings.TrimSpace(rest) != ""
+}
+
+// loadBody sets the body of the target from a file. Text bodies are templates like the
+// --body-file body; binary and raw bodies are sent as is.
+func (t *WeightedTarget) loadBody(path string, raw bool) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	t.bodyType = detectContentType(path, content)
+	t.bodyPath = path
+	t.withBody = true
+	if raw || !isTextType(t.bodyType) {
+		t.body = newRawSource(content)
+		return nil
+	}
+	t.body, err = newTemplateSource(path, content)
+	return err
+}
+
+// setBody sets the body of the target, rendered as a template unless raw, and declared
+// with contentType unless it is empty.
+func (t *WeightedTarget) setBody(name string, body []byte, contentType string, raw bool) error {
+	t.bodyType = detectContentType("", body)
+	if contentType != "" {
+		t.bodyType = contentType
+	}
+	t.withBody = true
+	if raw || !isTextType(t.bodyType) {
+		t.body = newRawSource(body)
+		return nil
+	}
+	var err error
+	t.body, err = newTemplateSource(name, body)
+	return err
+}
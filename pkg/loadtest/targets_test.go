@@ -0,0 +1,116 @@
+package loadtest
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// describeTargets renders targets as "METHOD URL [headers] body" lines for comparison.
+func describeTargets(targets []WeightedTarget) []string {
+	lines := make([]string, len(targets))
+	for i, t := range targets {
+		headers := make([]string, len(t.headers))
+		for j, h := range t.headers {
+			headers[j] = h.name + ": " + string(h.value.raw)
+		}
+		line := t.method + " " + t.url + " [" + strings.Join(headers, ", ") + "]"
+		if t.body != nil {
+			line += " " + string(t.body.raw)
+		}
+		lines[i] = line
+	}
+	return lines
+}
+
+func TestLoadTargetsFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{
+			name:    "single target",
+			content: "GET http://example.com/items\n",
+			want:    []string{"GET http://example.com/items []"},
+		},
+		{
+			name: "targets separated by blank lines",
+			content: `# items
+GET http://example.com/items
+X-Request-ID: 1
+
+POST http://example.com/items
+Authorization: Bearer t
+@body.json
+`,
+			want: []string{
+				"GET http://example.com/items [X-Request-ID: 1]",
+				`POST http://example.com/items [Authorization: Bearer t] {"name":"a"}`,
+			},
+		},
+		{
+			name: "targets without blank lines between them",
+			content: `GET http://example.com/a
+POST http://example.com/b
+Content-Type: application/json
+@body.json
+DELETE http://example.com/c
+get http://example.com/d
+`,
+			want: []string{
+				"GET http://example.com/a []",
+				`POST http://example.com/b [Content-Type: application/json] {"name":"a"}`,
+				"DELETE http://example.com/c []",
+				"GET http://example.com/d []",
+			},
+		},
+		{
+			name:    "header named like a method",
+			content: "GET http://example.com/a\nPost: yes\n",
+			want:    []string{"GET http://example.com/a [Post: yes]"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "body.json"), []byte(`{"name":"a"}`), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join(dir, "targets.txt")
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			targets, err := LoadTargetsFile(path, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := describeTargets(targets); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LoadTargetsFile() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadTargetsFileErrors(t *testing.T) {
+	tests := []struct {
+		content string
+		err     string
+	}{
+		{content: "# nothing\n\n", err: "no targets"},
+		{content: "http://example.com/items\n", err: "expected METHOD URL"},
+		{content: "GET http://example.com/items\nnot a header\n", err: "invalid header"},
+		{content: "GET http://example.com/items\n@missing.json\n", err: "targets.txt:2"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "targets.txt")
+		if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadTargetsFile(path, false); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("LoadTargetsFile(%q) error = %v, want it to contain %q", tt.content, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package loadtest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// errFeedEmpty is returned when a request needs a value from the feed pool while it is empty.
+var errFeedEmpty = errors.New("feed pool is empty")
+
+// templateSource is a request body or header value. Sources containing template
+// actions are parsed as Go templates and rendered for every request; other sources
+// are sent as is.
+type templateSource struct {
+	raw  []byte
+	tmpl *template.Template
+}
+
+// newTemplateSource parses text as a template when it contains template actions.
+func newTemplateSource(name string, text []byte) (*templateSource, error) {
+	if !bytes.Contains(text, []byte("{{")) {
+		return &templateSource{raw: text}, nil
+	}
+	tmpl, err := template.New(name).Option("missingkey=error").Funcs(templateFuncs(nil)).Parse(string(text))
+	if err != nil {
+		return nil, err
+	}
+	return &templateSource{raw: text, tmpl: tmpl}, nil
+}
+
+// newRawSource returns a source that is always sent as is, even if it looks like a template.
+func newRawSource(raw []byte) *templateSource {
+	return &templateSource{raw: raw}
+}
+
+// static reports whether the source renders to the same text for every request.
+func (b *templateSource) static() bool {
+	return b.tmpl == nil
+}
+
+// render returns the text for a single request.
+func (b *templateSource) render(scope *renderScope) ([]byte, error) {
+	if b.tmpl == nil {
+		return b.raw, nil
+	}
+	tmpl, err := b.tmpl.Clone()
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Funcs(templateFuncs(scope)).Execute(&buf, scope.row); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// renderScope carries the per-request state available to templates, so that every
+// reference to {{feed}} within one request resolves to the same pooled value and the
+// URL, headers and body see the same data row. The variables are the values captured
+// by the setup and teardown steps of the run; vu and iteration identify the virtual user
+// sending the request and how many requests it sent before.
+type renderScope struct {
+	feed      *feedPool
+	feedValue string
+	feedTaken bool
+	row       map[string]string
+	vars      map[string]string
+	vu        int
+	iteration int
+	random    *randomStream
+}
+
+// newRenderScope returns the scope of a single request using the given data row and
+// the random stream of the worker sending it.
+func newRenderScope(feed *feedPool, row map[string]string, random *randomStream) *renderScope {
+	return &renderScope{feed: feed, row: row, random: random}
+}
+
+// takeFeed returns the feed value of the request, taking one from the pool on first use.
+func (s *renderScope) takeFeed() (string, error) {
+	if s.feedTaken {
+		return s.feedValue, nil
+	}
+	value, ok := s.feed.take()
+	if !ok {
+		return "", errFeedEmpty
+	}
+	s.feedValue, s.feedTaken = value, true
+	return value, nil
+}
+
+// variable returns the value of a variable captured by a step of the run.
+func (s *renderScope) variable(name string) (string, error) {
+	value, ok := s.vars[name]
+	if !ok {
+		return "", fmt.Errorf("no step captured the variable %q", name)
+	}
+	return value, nil
+}
+
+// templateFuncs returns the functions available in request templates. The feed
+// function and the random functions are bound to scope; a nil scope yields the
+// parse-time placeholders.
+func templateFuncs(scope *renderScope) template.FuncMap {
+	feed := func() (string, error) { return "", errFeedEmpty }
+	variable := func(string) (string, error) { return "", nil }
+	vu, iteration := 0, 0
+	// At parse time the functions are not called; a fresh stream keeps parsing free of
+	// state shared between runs.
+	random := newRandomStreams(0).stream("parse")
+	if scope != nil {
+		feed = scope.takeFeed
+		variable = scope.variable
+		vu, iteration = scope.vu, scope.iteration
+		random = scope.random
+	}
+	funcs := template.FuncMap{
+		"feed":        feed,
+		"var":         variable,
+		"vu":          func() int { return vu },
+		"iteration":   func() int { return iteration },
+		"uuid":        func() string { return newUUID(random.ids) },
+		"uuidv7":      func() string { return newUUIDv7(random.ids) },
+		"ulid":        func() string { return newULID(random.ids) },
+		"randInt":     random.randInt,
+		"randString":  random.randString,
+		"now":         func() string { return time.Now().Format(time.RFC3339) },
+		"timestamp":   func() int64 { return time.Now().Unix() },
+		"env":         os.Getenv,
+		"seq":         random.seq.next,
+		"pathEscape":  url.PathEscape,
+		"queryEscape": url.QueryEscape,
+	}
+	for name, fn := range fakerFuncs(random) {
+		funcs[name] = fn
+	}
+	return funcs
+}
+
+// header is a request header whose value may be a template.
+type header struct {
+	name  string
+	value *templateSource
+}
+
+// parseHeaders parses "Name: value" header definitions.
+func parseHeaders(definitions []string) ([]header, error) {
+	headers := make([]header, 0, len(definitions))
+	for _, definition := range definitions {
+		name, value, ok := strings.Cut(definition, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid header %q, expected Name: value", definition)
+		}
+		source, err := newTemplateSource(name, []byte(strings.TrimSpace(value)))
+		if err != nil {
+			return nil, fmt.Errorf("invalid template in header %s: %w", name, err)
+		}
+		headers = append(headers, header{name: name, value: source})
+	}
+	return headers, nil
+}
+
+// randInt returns a random integer in the inclusive range [min, max].
+func (r *randomStream) randInt(min, max int) int {
+	if max < min {
+		min, max = max, min
+	}
+	return min + r.Intn(max-min+1)
+}
+
+// randString returns a random alphanumeric string of the given length.
+func (r *randomStream) randString(length int) string {
+	return generateRandomID(r, "string", length).(string)
+}
@@ -0,0 +1,116 @@
+package loadtest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// Threshold is a pass/fail condition on a metric of the run, such as "p99<500ms".
+type Threshold struct {
+	expr   string
+	metric string
+	op     string
+	value  float64
+}
+
+// thresholdOperators are the comparison operators of a threshold, two-character ones
+// first so they are matched before their prefixes.
+var thresholdOperators = []string{"<=", ">=", "<", ">"}
+
+// latencyMetrics are the metrics compared with a duration, in milliseconds.
+var latencyMetrics = map[string]func(Result) float64{
+	"avg": func(s Result) float64 { return s.AvgLatencyMs },
+	"p50": func(s Result) float64 { return s.P50LatencyMs },
+	"p90": func(s Result) float64 { return s.P90LatencyMs },
+	"p95": func(s Result) float64 { return s.P95LatencyMs },
+	"p99": func(s Result) float64 { return s.P99LatencyMs },
+	"max": func(s Result) float64 { return s.MaxLatencyMs },
+}
+
+// ParseThreshold parses an expression of the form <metric><operator><value>. Latency
+// metrics (avg, p50, p90, p95, p99, max) take a duration, error_rate and abandonment_rate
+// a percentage and rps a number, e.g. "p99<500ms", "error_rate<1%" or "rps>=1000".
+func ParseThreshold(expr string) (Threshold, error) {
+	for _, op := range thresholdOperators {
+		metric, value, ok := strings.Cut(expr, op)
+		if !ok {
+			continue
+		}
+		t := Threshold{expr: expr, metric: strings.TrimSpace(metric), op: op}
+		value = strings.TrimSpace(value)
+		var err error
+		switch {
+		case latencyMetrics[t.metric] != nil:
+			var d time.Duration
+			d, err = time.ParseDuration(value)
+			t.value = milliseconds(d)
+		case t.metric == "error_rate" || t.metric == "abandonment_rate":
+			t.value, err = strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+		case t.metric == "rps":
+			t.value, err = strconv.ParseFloat(value, 64)
+		default:
+			return Threshold{}, fmt.Errorf("unknown metric %q in threshold %q, expected avg, p50, p90, p95, p99, max, error_rate, abandonment_rate or rps", t.metric, expr)
+		}
+		if err != nil {
+			return Threshold{}, fmt.Errorf("invalid value in threshold %q: %v", expr, err)
+		}
+		return t, nil
+	}
+	return Threshold{}, fmt.Errorf("threshold %q has no comparison operator (<, <=, > or >=)", expr)
+}
+
+// measure returns the value of the metric of the threshold in s and its rendering.
+func (t Threshold) measure(s Result) (float64, string) {
+	switch {
+	case latencyMetrics[t.metric] != nil:
+		ms := latencyMetrics[t.metric](s)
+		return ms, formatMs(ms)
+	case t.metric == "error_rate" || t.metric == "abandonment_rate":
+		count := s.failed()
+		if t.metric == "abandonment_rate" {
+			count = s.Abandoned
+		}
+		rate := 0.0
+		if s.Requests > 0 {
+			rate = float64(count) * 100 / float64(s.Requests)
+		}
+		return rate, fmt.Sprintf("%.2f%%", rate)
+	default:
+		return s.RequestsPerSecond, fmt.Sprintf("%.2f", s.RequestsPerSecond)
+	}
+}
+
+// passes reports whether the measured value meets the threshold.
+func (t Threshold) passes(actual float64) bool {
+	switch t.op {
+	case "<":
+		return actual < t.value
+	case "<=":
+		return actual <= t.value
+	case ">":
+		return actual > t.value
+	default:
+		return actual >= t.value
+	}
+}
+
+// CheckThresholds evaluates every threshold against the run and prints the outcome. It
+// reports whether all of them passed.
+func CheckThresholds(thresholds []Threshold, s Result) bool {
+	color.Green("\n===== 🎯 Thresholds =====")
+	passed := true
+	for _, t := range thresholds {
+		actual, rendered := t.measure(s)
+		if t.passes(actual) {
+			color.Cyan("✅ %s (actual %s)", t.expr, rendered)
+		} else {
+			color.Red("❌ %s (actual %s)", t.expr, rendered)
+			passed = false
+		}
+	}
+	return passed
+}
@@ -0,0 +1,122 @@
+package loadtest
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// sizeRange is the range of the size distributions: up to 1 TiB, to 3 significant digits.
+var sizeRange = histogramRange{Highest: 1 << 40, Digits: 3}
+
+// sizeDistribution is the distribution of the sizes of requests or responses, in bytes.
+// It is kept in a latency histogram, which records any non-negative integer: only the
+// unit of its values differs.
+type sizeDistribution struct {
+	h *latencyHistogram
+}
+
+// newSizeDistribution returns an empty size distribution.
+func newSizeDistribution() sizeDistribution {
+	return sizeDistribution{h: newLatencyHistogram(sizeRange)}
+}
+
+// record adds a size in bytes.
+func (d sizeDistribution) record(n int64) {
+	d.h.record(time.Duration(n))
+}
+
+// percentile returns the size below which the fraction q of the recorded sizes are.
+func (d sizeDistribution) percentile(q float64) int64 {
+	return int64(d.h.percentile(q))
+}
+
+// SizePercentiles holds the percentiles of the sizes of requests or responses.
+type SizePercentiles struct {
+	P50Bytes int64 `json:"p50_bytes"`
+	P90Bytes int64 `json:"p90_bytes"`
+	P99Bytes int64 `json:"p99_bytes"`
+	MaxBytes int64 `json:"max_bytes"`
+}
+
+// percentiles returns the percentiles of d.
+func (d sizeDistribution) percentiles() SizePercentiles {
+	return SizePercentiles{P50Bytes: d.percentile(0.50), P90Bytes: d.percentile(0.90), P99Bytes: d.percentile(0.99), MaxBytes: d.percentile(1)}
+}
+
+// requestFramingSize returns the size of what HTTP/1.1 writes around the headers of req:
+// its request line and the empty line ending the headers.
+func requestFramingSize(req *http.Request) int64 {
+	return int64(len(req.Method) + 1 + len(req.URL.RequestURI()) + len(" HTTP/1.1\r\n") + 2)
+}
+
+// responseHeaderSize returns the size of the status line and headers of resp as written
+// in HTTP/1.1. HTTP/2 compresses headers, so over HTTP/2 it is an upper bound.
+func responseHeaderSize(resp *http.Response) int64 {
+	size := int64(len(resp.Proto) + 1 + len(resp.Status) + 2)
+	for name, values := range resp.Header {
+		for _, value := range values {
+			size += int64(len(name) + len(value) + 4)
+		}
+	}
+	return size + 2
+}
+
+// bytesSent returns the size of a request as sent: its headers and its body on the wire.
+func (r requestResult) bytesSent() int64 {
+	return r.headerBytesSent + r.bodyBytesWire
+}
+
+// bytesReceived returns the size of a response as received: its headers and its body on
+// the wire.
+func (r requestResult) bytesReceived() int64 {
+	return r.headerBytesReceived + r.responseBytesWire
+}
+
+// generateThroughputReport prints the bytes sent and received during the run with their
+// rates, and the size distributions of the requests and responses.
+func generateThroughputReport(totalTime time.Duration, st *stats) {
+	if st.requestSizes.h.count() == 0 {
+		return
+	}
+	color.Green("\n===== 📦 Throughput =====")
+	fmt.Printf("📤 Sent: %s (%s/s)\n", formatBytes(st.bytesSent), formatBytes(bytesPerSecond(st.bytesSent, totalTime)))
+	fmt.Printf("📥 Received: %s (%s/s)\n", formatBytes(st.bytesReceived), formatBytes(bytesPerSecond(st.bytesReceived, totalTime)))
+	printSizes("Request size", st.requestSizes.percentiles())
+	printSizes("Response size", st.responseSizes.percentiles())
+}
+
+// printSizes prints the percentiles of a size distribution on a single line.
+func printSizes(label string, p SizePercentiles) {
+	fmt.Printf("%s: p50 %s, p90 %s, p99 %s, max %s\n", label,
+		formatBytes(p.P50Bytes), formatBytes(p.P90Bytes), formatBytes(p.P99Bytes), formatBytes(p.MaxBytes))
+}
+
+// bytesPerSecond returns the rate of n bytes over d.
+func bytesPerSecond(n int64, d time.Duration) int64 {
+	if d <= 0 {
+		return 0
+	}
+	return int64(float64(n) / d.Seconds())
+}
+
+// megabytesPerSecond returns the rate of n bytes over d in MB/s, for the JSON report.
+func megabytesPerSecond(n int64, d time.Duration) float64 {
+	return float64(bytesPerSecond(n, d)) / 1e6
+}
+
+// formatBytes renders a size in bytes with a decimal unit, such as 1.25 MB.
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1e9:
+		return fmt.Sprintf("%.2f GB", float64(n)/1e9)
+	case n >= 1e6:
+		return fmt.Sprintf("%.2f MB", float64(n)/1e6)
+	case n >= 1e3:
+		return fmt.Sprintf("%.2f kB", float64(n)/1e3)
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
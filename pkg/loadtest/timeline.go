@@ -0,0 +1,96 @@
+package loadtest
+
+import (
+	"time"
+)
+
+// timelineInterval is the width of the buckets of the timeline.
+const timelineInterval = time.Second
+
+// timelineOpenBuckets is how many of the latest buckets keep their latency histogram.
+// Results reach the collector roughly in completion order, so older buckets are closed:
+// their p95 is computed and the histogram released, keeping memory flat on long runs.
+const timelineOpenBuckets = 3
+
+// timelineRange is the precision of the per-bucket histograms, coarser than the overall
+// one since a point of a trend does not need it.
+var timelineRange = histogramRange{Highest: time.Hour, Digits: 2}
+
+// TimelinePoint is a bucket of the timeline as reported: the requests that completed in
+// the second starting OffsetS seconds into the measured run.
+type TimelinePoint struct {
+	OffsetS      int     `json:"offset_s"`
+	Requests     int     `json:"requests"`
+	Errors       int     `json:"errors"`
+	RPS          float64 `json:"rps"`
+	P95LatencyMs float64 `json:"p95_latency_ms"`
+}
+
+// timeline buckets the measured results of a run by completion time, to show how
+// throughput and latency evolve during the run.
+type timeline struct {
+	start   time.Time
+	buckets []timelineBucket
+}
+
+// timelineBucket aggregates the results of a bucket of the timeline.
+type timelineBucket struct {
+	requests  int
+	errors    int
+	latencies *latencyHistogram
+	p95       time.Duration
+}
+
+// add records res in the bucket of its completion time. Requests that were not sent,
+// and results of a timeline that has not started, are left out.
+func (t *timeline) add(res requestResult) {
+	if t.start.IsZero() || res.completed.IsZero() || res.skipped() {
+		return
+	}
+	i := int(max(res.completed.Sub(t.start), 0) / timelineInterval)
+	for len(t.buckets) <= i {
+		t.buckets = append(t.buckets, timelineBucket{latencies: newLatencyHistogram(timelineRange)})
+		if closed := len(t.buckets) - 1 - timelineOpenBuckets; closed >= 0 {
+			t.buckets[closed].close()
+		}
+	}
+	b := &t.buckets[i]
+	b.requests++
+	if res.statusCode == -1 || res.statusCode >= 400 {
+		b.errors++
+	}
+	if b.latencies != nil {
+		b.latencies.record(res.latency)
+	}
+}
+
+// close computes the p95 of the bucket and releases its histogram.
+func (b *timelineBucket) close() {
+	if b.latencies == nil {
+		return
+	}
+	b.p95 = b.latencies.percentile(0.95)
+	b.latencies = nil
+}
+
+// points returns the timeline of a run measured for duration. The last bucket is
+// usually partial, so its rate is computed over the part of it the run lasted.
+func (t *timeline) points(duration time.Duration) []TimelinePoint {
+	points := make([]TimelinePoint, 0, len(t.buckets))
+	for i := range t.buckets {
+		b := &t.buckets[i]
+		b.close()
+		width := timelineInterval
+		if rest := duration - time.Duration(i)*timelineInterval; rest > 0 && rest < width {
+			width = rest
+		}
+		points = append(points, TimelinePoint{
+			OffsetS:      i * int(timelineInterval/time.Second),
+			Requests:     b.requests,
+			Errors:       b.errors,
+			RPS:          float64(b.requests) / width.Seconds(),
+			P95LatencyMs: milliseconds(b.p95),
+		})
+	}
+	return points
+}
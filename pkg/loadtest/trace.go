@@ -0,0 +1,281 @@
+package loadtest
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http/httptrace"
+	"os"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// requestTiming is the phase breakdown of a single request. Phases that did not
+// happen, such as DNS and connect on a reused connection, are zero.
+type requestTiming struct {
+	dns      time.Duration
+	connect  time.Duration
+	tls      time.Duration
+	ttfb     time.Duration
+	transfer time.Duration
+}
+
+// String renders the breakdown on a single line.
+func (t requestTiming) String() string {
+	return fmt.Sprintf("dns %v, connect %v, tls %v, ttfb %v, transfer %v", t.dns, t.connect, t.tls, t.ttfb, t.transfer)
+}
+
+// timingPhases names the phases of requestTiming, in the order they happen.
+var timingPhases = [...]string{"dns", "connect", "tls", "ttfb", "transfer"}
+
+// durations returns the phases of t in the order of timingPhases.
+func (t requestTiming) durations() [len(timingPhases)]time.Duration {
+	return [len(timingPhases)]time.Duration{t.dns, t.connect, t.tls, t.ttfb, t.transfer}
+}
+
+// requestTrace collects connection and timing details of a single request through
+// httptrace hooks. The hooks may fire from several goroutines (e.g. parallel dials),
+// so every access is guarded by the mutex.
+type requestTrace struct {
+	mu           sync.Mutex
+	start        time.Time
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	firstByte    time.Time
+	timing       requestTiming
+	reused       bool
+	remoteAddr   string
+	attempts     map[string]int
+	conn         net.Conn
+	dns          *dnsStats
+	// sentHeaders collects the header lines written on the wire when keepHeaders is set.
+	keepHeaders bool
+	sentHeaders []string
+	headerBytes int64
+}
+
+// clientTrace returns the httptrace hooks feeding this trace.
+func (t *requestTrace) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mu.Lock()
+			t.dnsStart = time.Now()
+			t.mu.Unlock()
+		},
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			t.mu.Lock()
+			t.timing.dns = time.Since(t.dnsStart)
+			if t.dns != nil && !info.Coalesced {
+				t.dns.add(t.timing.dns, info.Err)
+			}
+			t.mu.Unlock()
+		},
+		ConnectStart: func(network, addr string) {
+			t.mu.Lock()
+			if t.connectStart.IsZero() {
+				t.connectStart = time.Now()
+			}
+			if t.attempts == nil {
+				t.attempts = make(map[string]int)
+			}
+			t.attempts[addressFamily(addr)]++
+			t.mu.Unlock()
+		},
+		ConnectDone: func(network, addr string, err error) {
+			t.mu.Lock()
+			if err == nil {
+				t.timing.connect = time.Since(t.connectStart)
+			}
+			t.mu.Unlock()
+		},
+		TLSHandshakeStart: func() {
+			t.mu.Lock()
+			t.tlsStart = time.Now()
+			t.mu.Unlock()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.mu.Lock()
+			t.timing.tls = time.Since(t.tlsStart)
+			t.mu.Unlock()
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			t.reused = info.Reused
+			if info.Conn != nil {
+				t.conn = info.Conn
+				t.remoteAddr = info.Conn.RemoteAddr().String()
+			}
+			t.mu.Unlock()
+		},
+		WroteHeaderField: func(key string, value []string) {
+			t.mu.Lock()
+			for _, v := range value {
+				t.headerBytes += int64(len(key) + len(v) + 4)
+			}
+			if t.keepHeaders {
+				for _, v := range value {
+					t.sentHeaders = append(t.sentHeaders, key+": "+v)
+				}
+			}
+			t.mu.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			t.mu.Lock()
+			t.firstByte = time.Now()
+			t.timing.ttfb = t.firstByte.Sub(t.start)
+			t.mu.Unlock()
+		},
+	}
+}
+
+// connectAttempts returns the number of dial attempts per address family.
+func (t *requestTrace) connectAttempts() map[string]int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.attempts
+}
+
+// headersSent returns the header lines written for the request, in order.
+func (t *requestTrace) headersSent() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.sentHeaders
+}
+
+// headerBytesSent returns the size of the header lines written for the request.
+func (t *requestTrace) headerBytesSent() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.headerBytes
+}
+
+// connection returns the connection the request was sent on, or nil if none was obtained.
+func (t *requestTrace) connection() net.Conn {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.conn
+}
+
+// addressFamily returns "IPv4" or "IPv6" for a host:port address, or "other" when the
+// host is not an IP address.
+func addressFamily(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	ip := net.ParseIP(host)
+	switch {
+	case ip == nil:
+		return "other"
+	case ip.To4() != nil:
+		return "IPv4"
+	default:
+		return "IPv6"
+	}
+}
+
+// finish closes the trace once the response body has been consumed and returns
+// whether the connection was reused, the remote address and the phase timings.
+func (t *requestTrace) finish() (bool, string, requestTiming) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.firstByte.IsZero() {
+		t.timing.transfer = time.Since(t.firstByte)
+	}
+	return t.reused, t.remoteAddr, t.timing
+}
+
+// phaseLatencies returns the percentiles of every timing phase, each over the requests
+// that went through it: DNS, connect and TLS only happen on new connections.
+func (s *stats) phaseLatencies() map[string]LatencyPercentiles {
+	latencies := make(map[string]LatencyPercentiles)
+	for i, h := range s.phases {
+		if h != nil {
+			latencies[timingPhases[i]] = newLatencyPercentiles(h)
+		}
+	}
+	return latencies
+}
+
+// generateTimingReport prints the percentiles of every phase of the requests, to tell
+// time spent on the network (DNS, connect, TLS) from time spent waiting on the server
+// (TTFB) and reading the response (transfer).
+func generateTimingReport(st *stats) {
+	latencies := st.phaseLatencies()
+	if len(latencies) == 0 {
+		return
+	}
+	color.Green("\n===== ⏱️ Timing Breakdown =====")
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "Phase\tRequests\tp50\tp90\tp95\tp99\tMax\t")
+	for _, phase := range timingPhases {
+		l, ok := latencies[phase]
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\t%s\t\n", phase, l.Requests, formatMs(l.P50LatencyMs),
+			formatMs(l.P90LatencyMs), formatMs(l.P95LatencyMs), formatMs(l.P99LatencyMs), formatMs(l.MaxLatencyMs))
+	}
+	tw.Flush()
+	fmt.Println("TTFB runs from the start of the request, so on new connections it includes DNS, connect and TLS.")
+}
+
+// slowTracker counts the requests exceeding the slow threshold and keeps the
+// slowest of them for the report.
+type slowTracker struct {
+	threshold time.Duration
+	limit     int
+	count     int
+	samples   []requestResult
+}
+
+// newSlowTracker returns a tracker for requests slower than threshold, keeping at most limit samples.
+// A zero threshold disables tracking.
+func newSlowTracker(threshold time.Duration, limit int) *slowTracker {
+	return &slowTracker{threshold: threshold, limit: limit}
+}
+
+// add records res if it exceeds the threshold, keeping the samples sorted from slowest to fastest.
+func (s *slowTracker) add(res requestResult) {
+	if s.threshold <= 0 || res.skipped() || res.latency < s.threshold {
+		return
+	}
+	s.count++
+	if s.limit <= 0 {
+		return
+	}
+	i := sort.Search(len(s.samples), func(i int) bool { return s.samples[i].latency < res.latency })
+	if i >= s.limit {
+		return
+	}
+	s.samples = append(s.samples, requestResult{})
+	copy(s.samples[i+1:], s.samples[i:])
+	s.samples[i] = res
+	if len(s.samples) > s.limit {
+		s.samples = s.samples[:s.limit]
+	}
+}
+
+// generateSlowReport lists the slowest requests with their timing breakdown and response metadata.
+func generateSlowReport(s *slowTracker) {
+	if s.threshold <= 0 {
+		return
+	}
+	color.Green("\n===== 🐢 Slow Requests (>= %v) =====", s.threshold)
+	fmt.Printf("Requests over the threshold: %d\n", s.count)
+	for i, res := range s.samples {
+		status := fmt.Sprintf("HTTP %d", res.statusCode)
+		if res.statusCode == -1 {
+			status = "network error"
+		}
+		color.Yellow("%2d. %v %s %s -> %s", i+1, res.latency, res.method, res.url, status)
+		fmt.Printf("    %s\n", res.timing)
+		fmt.Printf("    remote %s, reused connection %t, content-type %q, content-length %d\n",
+			res.remoteAddr, res.reused, res.contentType, res.contentLength)
+	}
+}
@@ -0,0 +1,196 @@
+package loadtest
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// newTransport builds the transport shared by all workers, tuned with the connection
+// reuse and timeout settings from cfg. When connLog is not nil, connection lifecycle
+// events are recorded to it. When profile is not nil, connections are shaped like its
+// network and kept alive as it does. TLS certificates are verified unless cfg skips the
+// verification for every host, or for the host of the request.
+func newTransport(cfg Config, connLog *connLog, profile *clientProfile) http.RoundTripper {
+	transport := newHTTPTransport(cfg, connLog, profile)
+	if cfg.Insecure {
+		transport.TLSClientConfig.InsecureSkipVerify = true
+		return transport
+	}
+	hosts := insecureHosts(cfg)
+	if len(hosts) == 0 {
+		return transport
+	}
+	insecure := transport.Clone()
+	insecure.TLSClientConfig.InsecureSkipVerify = true
+	return &hostScopedTransport{verified: transport, insecure: insecure, hosts: hosts}
+}
+
+// insecureHosts returns the hosts whose certificates are not verified: those of
+// cfg.InsecureHosts and those of the targets skipping the verification, imported from
+// curl -k commands.
+func insecureHosts(cfg Config) []string {
+	hosts := cfg.InsecureHosts
+	for _, t := range cfg.Targets {
+		if !t.options.insecure {
+			continue
+		}
+		if u, err := url.Parse(t.url); err == nil && u.Host != "" {
+			hosts = append(hosts[:len(hosts):len(hosts)], u.Host)
+		}
+	}
+	return hosts
+}
+
+// newHTTPTransport builds the http.Transport of newTransport, verifying certificates.
+// With cfg.SNI, TLS connections present that server name, and certificates are
+// verified against it, instead of the host of the URL.
+func newHTTPTransport(cfg Config, connLog *connLog, profile *clientProfile) *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   cfg.ConnectTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+	if cfg.DisableHappyEyeballs {
+		dialer.FallbackDelay = -1
+	}
+	if cfg.DoH != nil {
+		dialer.Resolver = cfg.DoH.resolver()
+	} else if cfg.DNSDelay > 0 {
+		dialer.Resolver = newDelayedResolver(cfg.DNSDelay)
+	}
+	dial := dialer.DialContext
+	if cfg.DNSCache != nil {
+		dial = cfg.DNSCache.wrapDial(dial, dialer.Resolver)
+	}
+	if len(cfg.Resolve) > 0 {
+		dial = cfg.Resolve.wrapDial(dial)
+	}
+	if cfg.IPVersion != 0 {
+		dial = forceFamily(dial, cfg.IPVersion)
+	}
+	disableKeepAlive := cfg.DisableKeepAlive
+	idleTimeout := 90 * time.Second
+	if profile != nil {
+		dial = profile.wrapDial(dial)
+		disableKeepAlive = disableKeepAlive || !profile.keepAlive
+		idleTimeout = profile.idleTimeout
+	}
+	if connLog != nil {
+		dial = connLog.wrapDial(dial)
+	}
+	return &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dial,
+		ForceAttemptHTTP2:     true,
+		DisableKeepAlives:     disableKeepAlive,
+		MaxIdleConns:          cfg.MaxIdleConns,
+		MaxIdleConnsPerHost:   cfg.MaxIdleConns,
+		MaxConnsPerHost:       cfg.MaxConnsPerHost,
+		IdleConnTimeout:       idleTimeout,
+		TLSHandshakeTimeout:   cfg.TLSHandshakeTimeout,
+		ResponseHeaderTimeout: cfg.ResponseHeaderTimeout,
+		ExpectContinueTimeout: 1 * time.Second,
+		TLSClientConfig:       &tls.Config{ServerName: cfg.SNI},
+	}
+}
+
+// forceFamily restricts the TCP connections of dial to IPv4 or IPv6: names resolve to
+// the addresses of that family only, and addresses of the other family fail to dial.
+func forceFamily(dial func(ctx context.Context, network, address string) (net.Conn, error), version int) func(ctx context.Context, network, address string) (net.Conn, error) {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		if network == "tcp" {
+			network = fmt.Sprintf("tcp%d", version)
+		}
+		return dial(ctx, network, address)
+	}
+}
+
+// hostScopedTransport skips TLS verification for the requests to some hosts only, such
+// as a staging service with a self-signed certificate, and verifies the certificates of
+// every other host. The requests of the two kinds go through separate transports, so a
+// connection never serves both.
+type hostScopedTransport struct {
+	verified *http.Transport
+	insecure *http.Transport
+	hosts    []string
+}
+
+// RoundTrip sends req through the transport matching its host.
+func (t *hostScopedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if matchesHost(t.hosts, req.URL) {
+		return t.insecure.RoundTrip(req)
+	}
+	return t.verified.RoundTrip(req)
+}
+
+// CloseIdleConnections closes the idle connections of both transports.
+func (t *hostScopedTransport) CloseIdleConnections() {
+	t.verified.CloseIdleConnections()
+	t.insecure.CloseIdleConnections()
+}
+
+// matchesHost reports whether the host of u matches one of patterns: a host name or
+// address, optionally with a port, or *.domain for any subdomain of domain.
+func matchesHost(patterns []string, u *url.URL) bool {
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if port == "" && u.Scheme == "http" {
+		port = "80"
+	} else if port == "" {
+		port = "443"
+	}
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		patternHost, patternPort := pattern, ""
+		if h, p, err := net.SplitHostPort(pattern); err == nil {
+			patternHost, patternPort = h, p
+		}
+		if patternPort != "" && patternPort != port {
+			continue
+		}
+		patternHost = strings.Trim(patternHost, "[]")
+		if domain, ok := strings.CutPrefix(patternHost, "*."); ok {
+			if strings.HasSuffix(host, "."+domain) {
+				return true
+			}
+		} else if host == patternHost {
+			return true
+		}
+	}
+	return false
+}
+
+// newRedirectPolicy returns the client redirect policy. Redirects are followed up to
+// cfg.MaxRedirects times; past that, or when following is disabled, the 3xx response
+// itself is returned so it shows up in the status code distribution.
+func newRedirectPolicy(cfg Config) func(req *http.Request, via []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if cfg.NoFollowRedirects || len(via) > cfg.MaxRedirects {
+			return http.ErrUseLastResponse
+		}
+		return nil
+	}
+}
+
+// newDelayedResolver returns a resolver that waits delay before every query it sends to
+// the DNS server, simulating a slow resolver. Only DNS traffic is delayed: names served
+// from the hosts file and the connections themselves are unaffected.
+func newDelayedResolver(delay time.Duration) *net.Resolver {
+	var dialer net.Dialer
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			return dialer.DialContext(ctx, network, address)
+		},
+	}
+}
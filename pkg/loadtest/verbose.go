@@ -0,0 +1,79 @@
+package loadtest
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// verboseDump prints the requests and responses of the first requests of a run, like
+// curl -v: the headers with -v, the bodies as well with -vv. Dumps are printed whole, so
+// those of concurrent requests do not interleave.
+type verboseDump struct {
+	limit  int64
+	bodies bool
+	count  atomic.Int64
+	mu     sync.Mutex
+}
+
+// newVerboseDump returns the dump of the first limit requests at the given verbosity, or
+// nil when verbosity is 0.
+func newVerboseDump(verbosity, limit int) *verboseDump {
+	if verbosity <= 0 || limit <= 0 {
+		return nil
+	}
+	return &verboseDump{limit: int64(limit), bodies: verbosity > 1}
+}
+
+// take reports whether the next request is dumped.
+func (d *verboseDump) take() bool {
+	return d != nil && d.count.Add(1) <= d.limit
+}
+
+// print dumps a request and its response, or the error it failed with. The request
+// headers are those written on the wire; body is the response body when it was read.
+func (d *verboseDump) print(req *http.Request, sent []string, requestBody []byte, resp *http.Response, body []byte, err error) {
+	proto := req.Proto
+	if resp != nil {
+		proto = resp.Proto
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n> %s %s %s\n", req.Method, req.URL.RequestURI(), proto)
+	for _, line := range sent {
+		fmt.Fprintf(&b, "> %s\n", line)
+	}
+	if d.bodies && len(requestBody) > 0 {
+		b.WriteString(">\n")
+		d.writeBody(&b, "> ", req.Header.Get("Content-Type"), requestBody)
+	}
+	if err != nil {
+		fmt.Fprintf(&b, "* %v\n", err)
+	} else {
+		fmt.Fprintf(&b, "< %s %s\n", resp.Proto, resp.Status)
+		for _, name := range sortedHeaderNames(resp.Header) {
+			for _, value := range resp.Header[name] {
+				fmt.Fprintf(&b, "< %s: %s\n", name, value)
+			}
+		}
+		if d.bodies && len(body) > 0 {
+			b.WriteString("<\n")
+			d.writeBody(&b, "< ", resp.Header.Get("Content-Type"), body)
+		}
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	fmt.Print(b.String())
+}
+
+// writeBody writes a text body line by line behind prefix; other bodies are summarized.
+func (d *verboseDump) writeBody(b *strings.Builder, prefix, contentType string, body []byte) {
+	if contentType != "" && !isTextType(contentType) {
+		fmt.Fprintf(b, "%s[%d bytes of %s]\n", prefix, len(body), contentType)
+		return
+	}
+	for _, line := range strings.Split(strings.TrimRight(string(body), "\n"), "\n") {
+		fmt.Fprintf(b, "%s%s\n", prefix, line)
+	}
+}
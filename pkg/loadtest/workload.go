@@ -0,0 +1,257 @@
+package loadtest
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Workload classes used by the read/write mix.
+const (
+	classRead  = "read"
+	classWrite = "write"
+)
+
+// target is an endpoint a single request is sent to. Weighted targets given with
+// several --url flags or a targets file carry a name used for the per-target
+// breakdown; targets from a file may also bring their own headers and body. Setup and
+// teardown steps are sent as written: with their own headers only, without the --header
+// ones, and without random fields.
+type target struct {
+	class    string
+	name     string
+	method   string
+	url      string
+	withBody bool
+	headers  []header
+	body     *templateSource
+	bodyType string
+	bodyPath string
+	options  targetOptions
+
+	step bool
+}
+
+// WeightedTarget is a --url target with its share of the traffic.
+type WeightedTarget struct {
+	target
+	weight int
+}
+
+// Name returns the name of the target in the per-target breakdown, such as "GET URL".
+func (t WeightedTarget) Name() string {
+	return t.name
+}
+
+// ParseTargets parses --url values of the form [weight:][METHOD ]URL [options], e.g.
+// "70:GET http://example.com/list" or "30:POST http://example.com/create". The weight
+// defaults to 1 and the method to verb. The URL may be followed by key=value options
+// overriding the timeout, retries and expected statuses for the target, such as
+// "GET http://example.com/export timeout=120s expect=200,202". A single target keeps the historical behavior of
+// sending the body with every request.
+func ParseTargets(specs []string, verb string) ([]WeightedTarget, error) {
+	targets := make([]WeightedTarget, 0, len(specs))
+	for _, spec := range specs {
+		t := WeightedTarget{target: target{method: verb}, weight: 1}
+		rest := strings.TrimSpace(spec)
+		if prefix, after, ok := strings.Cut(rest, ":"); ok && prefix != "" && strings.Trim(prefix, "0123456789") == "" {
+			weight, err := strconv.Atoi(prefix)
+			if err != nil || weight < 1 {
+				return nil, fmt.Errorf("invalid weight in target %q", spec)
+			}
+			t.weight = weight
+			rest = strings.TrimSpace(after)
+		}
+		if method, after, ok := strings.Cut(rest, " "); ok && method == strings.ToUpper(method) && !strings.Contains(method, "/") {
+			t.method = method
+			rest = strings.TrimSpace(after)
+		}
+		words := targetFields(rest)
+		if len(words) == 0 {
+			return nil, fmt.Errorf("missing URL in target %q", spec)
+		}
+		options, err := parseTargetOptions(words[1:])
+		if err != nil {
+			return nil, fmt.Errorf("%v in target %q", err, spec)
+		}
+		t.url, t.options = words[0], options
+		t.name = t.method + " " + t.url
+		t.withBody = len(specs) == 1 || sendsBody(t.method)
+		targets = append(targets, t)
+	}
+	return targets, nil
+}
+
+// targetFields splits a target line around whitespace like strings.Fields, except inside
+// template actions, so that a URL such as /items/{{var "id"}} stays one field.
+func targetFields(s string) []string {
+	var fields []string
+	var field strings.Builder
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case strings.HasPrefix(s[i:], "{{"):
+			depth++
+			field.WriteString("{{")
+			i++
+			continue
+		case depth > 0 && strings.HasPrefix(s[i:], "}}"):
+			depth--
+			field.WriteString("}}")
+			i++
+			continue
+		case depth == 0 && (s[i] == ' ' || s[i] == '\t'):
+			if field.Len() > 0 {
+				fields = append(fields, field.String())
+				field.Reset()
+			}
+			continue
+		}
+		field.WriteByte(s[i])
+	}
+	if field.Len() > 0 {
+		fields = append(fields, field.String())
+	}
+	return fields
+}
+
+// sendsBody reports whether requests with method carry a body when several targets are mixed.
+func sendsBody(method string) bool {
+	return method != "GET" && method != "HEAD" && method != "OPTIONS"
+}
+
+// pickTarget chooses the endpoint for the next request. Without read or write
+// endpoints every request goes to one of the --url targets; otherwise the class is
+// drawn according to the read/write ratio and an endpoint is picked at random
+// from that class. Draws come from r, the worker's target stream, and are recorded to
+// d when it is not nil.
+func (e *engine) pickTarget(r *randomStream, d *targetDecision) target {
+	cfg := e.cfg
+	if d != nil {
+		d.Share = 1
+	}
+	if len(cfg.ReadURLs) == 0 && len(cfg.WriteURLs) == 0 {
+		t := e.pickWeightedTarget(r, d)
+		d.pick(t)
+		return t
+	}
+
+	read := len(cfg.WriteURLs) == 0
+	if len(cfg.ReadURLs) > 0 && len(cfg.WriteURLs) > 0 {
+		of := cfg.ReadWeight + cfg.WriteWeight
+		n := r.Intn(of)
+		read = n < cfg.ReadWeight
+		if read {
+			d.step("class", n, of, classRead, cfg.ReadWeight)
+		} else {
+			d.step("class", n, of, classWrite, cfg.WriteWeight)
+		}
+	}
+	urls := cfg.WriteURLs
+	if read {
+		urls = cfg.ReadURLs
+	}
+	n := r.Intn(len(urls))
+	d.step("endpoint", n, len(urls), urls[n], 1)
+	t := writeTarget(urls[n], cfg.Verb)
+	if read {
+		t = readTarget(urls[n])
+	}
+	d.pick(t)
+	return t
+}
+
+// readTarget returns the target of a read endpoint, always sent with GET.
+func readTarget(url string) target {
+	return target{class: classRead, method: "GET", url: url}
+}
+
+// writeTarget returns the target of a write endpoint, sent with verb or POST when the
+// verb is GET.
+func writeTarget(url, verb string) target {
+	if verb == "GET" {
+		verb = "POST"
+	}
+	return target{class: classWrite, method: verb, url: url, withBody: true}
+}
+
+// allTargets returns every endpoint requests of the run may be sent to.
+func (e *engine) allTargets() []target {
+	var targets []target
+	for _, t := range e.cfg.Targets {
+		targets = append(targets, t.target)
+	}
+	for _, url := range e.cfg.ReadURLs {
+		targets = append(targets, readTarget(url))
+	}
+	for _, url := range e.cfg.WriteURLs {
+		targets = append(targets, writeTarget(url, e.cfg.Verb))
+	}
+	return targets
+}
+
+// hasBodyTarget reports whether any --url target is sent with POST.
+func hasBodyTarget(targets []WeightedTarget) bool {
+	for _, t := range targets {
+		if t.method == "POST" {
+			return true
+		}
+	}
+	return false
+}
+
+// pickWeightedTarget draws one of the --url targets according to their weights, and
+// records the draw to d when it is not nil.
+func (e *engine) pickWeightedTarget(r *randomStream, d *targetDecision) target {
+	targets := e.cfg.Targets
+	if len(targets) == 1 {
+		return targets[0].target
+	}
+	draw := r.Intn(e.totalWeight)
+	n := draw
+	for _, t := range targets {
+		if n < t.weight {
+			d.step("target", draw, e.totalWeight, t.name, t.weight)
+			return t.target
+		}
+		n -= t.weight
+	}
+	last := targets[len(targets)-1]
+	d.step("target", draw, e.totalWeight, last.name, last.weight)
+	return last.target
+}
+
+// ParseRWRatio parses a read:write ratio such as "90:10". An empty ratio means an even split.
+func ParseRWRatio(ratio string) (int, int, error) {
+	if ratio == "" {
+		return 1, 1, nil
+	}
+	parts := strings.Split(ratio, ":")
+	if len(parts) != 2 {
+		return 0, 0, errors.New("expected the form read:write")
+	}
+	read, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return 0, 0, err
+	}
+	write, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return 0, 0, err
+	}
+	if read < 0 || write < 0 || read+write == 0 {
+		return 0, 0, errors.New("weights must be non-negative and not both zero")
+	}
+	return read, write, nil
+}
+
+// SplitList splits a list separated by commas or newlines, dropping empty items.
+func SplitList(value string) []string {
+	var list []string
+	for _, item := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == '\n' }) {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}